@@ -1,37 +1,329 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/dvdxa/add-to-favorites/internal/configs"
 	"github.com/dvdxa/add-to-favorites/internal/database/postgres"
+	"github.com/dvdxa/add-to-favorites/internal/database/schema"
+	"github.com/dvdxa/add-to-favorites/internal/database/sqlite"
+	"github.com/dvdxa/add-to-favorites/internal/diagnostics"
 	"github.com/dvdxa/add-to-favorites/internal/handlers"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/health"
+	"github.com/dvdxa/add-to-favorites/internal/metrics"
 	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	sqliteRepo "github.com/dvdxa/add-to-favorites/internal/repositories/sqlite"
 	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/internal/services/notification_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/webhook_service"
+	"github.com/dvdxa/add-to-favorites/internal/tracing"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/dvdxa/add-to-favorites/server"
-	"github.com/joho/godotenv"
+	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	err := godotenv.Load("env")
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		os.Exit(printConfig(args[2:]))
+	}
+	os.Exit(run(args))
+}
+
+// printConfig prints the configuration the service would start with, with
+// secrets redacted, and reports any validation errors on stderr.
+func printConfig(args []string) int {
+	cfg, err := configs.Load(args)
+	if configs.IsHelp(err) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	err = cfg.Print(os.Stdout)
 	if err != nil {
-		log.Fatalf("failed to load env files: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	log := logger.GetLogger()
-	cfg, err := configs.InitConfig()
+	err = cfg.Validate()
 	if err != nil {
-		log.Fatalf("failed to initialize configs: %v", err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// run returns the process exit code: 0 after a clean shutdown, 1 if a server
+// failed or requests were still running when the drain deadline passed, and
+// 2 if the configuration is invalid.
+func run(args []string) int {
+	cfg, err := configs.Load(args)
+	if configs.IsHelp(err) {
+		return 0
 	}
-	pgx, err := postgres.ConnectToPostgres(&cfg)
 	if err != nil {
-		log.Fatalf("failed to connect to db: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return 2
 	}
-	repoPort := repositories.NewRepositoryPort(pgx)
-	servicePort := services.NewServicePort(repoPort)
+	err = cfg.Validate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	appLog, closeLog, err := logger.New(cfg.Logger())
+	if err != nil {
+		log.Fatalf("failed to initialize logger: %v", err)
+	}
+	defer closeLog()
+	logger.SetDefault(appLog)
+	log := appLog
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig(cfg))
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+
+	// bgCtx stops background jobs once the HTTP servers have drained.
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var background sync.WaitGroup
+
+	m := metrics.New()
+	diag := diagnostics.New(cfg.RedactedMap())
+	checker := health.NewChecker(cfg.ReadinessTimeout)
+	// closeDB closes the database handles; it runs last during shutdown.
+	var closeDB []func()
+	var repoPort *repositories.RepositoryPort
+	switch cfg.Backend {
+	case configs.BackendSQLite:
+		db, err := sqlite.ConnectToSQLite(&cfg)
+		if err != nil {
+			log.Fatalf("failed to open sqlite db: %v", err)
+		}
+		closeDB = append(closeDB, func() { db.Close() })
+		m.RegisterSQLDB("sqlite", db)
+		diag.AddPool("sqlite", func() any { return db.Stats() })
+		checker.Add("database", db.PingContext)
+		checker.Add("migrations", health.MigrationsCheck(func(ctx context.Context) ([]schema.Migration, error) {
+			return sqlite.PendingMigrations(ctx, db)
+		}))
+		repoPort = sqliteRepo.NewRepositoryPort(db)
+	case configs.BackendPostgres:
+		pgx, err := postgres.NewPool(&cfg)
+		if err != nil {
+			log.Fatalf("failed to configure db: %v", err)
+		}
+		closeDB = append(closeDB, pgx.Close)
+		// Start even if Postgres is down; /readyz reports it until it is up
+		// and migrated.
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			err := postgres.WaitAndMigrate(bgCtx, pgx, cfg.DBRetryInterval)
+			if err != nil && bgCtx.Err() == nil {
+				log.Errorf("failed to migrate db: %v", err)
+			}
+		}()
+		checker.Add("database", pgx.Ping)
+		checker.Add("migrations", health.MigrationsCheck(func(ctx context.Context) ([]schema.Migration, error) {
+			return postgres.PendingMigrations(ctx, pgx)
+		}))
+		replica, err := postgres.ConnectToReplica(&cfg)
+		if err != nil {
+			log.Fatalf("failed to configure read replica: %v", err)
+		}
+		m.RegisterPgxPool("primary", pgx)
+		diag.AddPool("primary", func() any { return diagnostics.PgxPoolStats(pgx) })
+		if replica != nil {
+			closeDB = append(closeDB, replica.Close)
+			m.RegisterPgxPool("replica", replica)
+			diag.AddPool("replica", func() any { return diagnostics.PgxPoolStats(replica) })
+		}
+		repoPort = repositories.NewRepositoryPort(pgx, replica)
+	default:
+		log.Fatalf("unknown storage backend %q", cfg.Backend)
+	}
+	m.RegisterBusiness(repoPort)
+	servicePort := services.Traced(services.Audited(services.NewServicePort(m.InstrumentRepositories(repoPort), authConfig(cfg), webhookConfig(cfg), notificationConfig(cfg))))
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		servicePort.RunPurge(bgCtx, cfg.PurgeRetention, cfg.PurgeInterval)
+	}()
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		servicePort.RunAuditRetention(bgCtx, cfg.AuditRetention, cfg.PurgeInterval)
+	}()
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		servicePort.RunAlertEvaluator(bgCtx, cfg.AlertInterval)
+	}()
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		servicePort.RunWebhookDispatcher(bgCtx, cfg.WebhookInterval)
+	}()
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		servicePort.RunHeartbeatSweeper(bgCtx, cfg.HeartbeatInterval, cfg.HeartbeatTimeout)
+	}()
 	handler := handlers.NewHandler(*log, *servicePort)
+	router := handler.InitRoutes(tracing.GinMiddleware(), m.GinMiddleware())
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
+	diag.Register(router.Group("/admin/debug", handler.ValidateUser, handler.RequireAdmin))
+
+	serverErrs := make(chan error, 3)
+	var metricsSrv *server.Server
+	if cfg.MetricsPort == "" {
+		router.GET("/metrics", gin.WrapH(m.Handler()))
+	} else {
+		metricsSrv = new(server.Server)
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", m.Handler())
+		go func() {
+			serverErrs <- metricsSrv.Run(cfg.MetricsPort, metricsMux)
+		}()
+	}
+	var diagSrv *server.Server
+	if cfg.DiagnosticsPort != "" {
+		diagSrv = new(server.Server)
+		diagRouter := gin.New()
+		diagRouter.Use(middleware.Recovery())
+		diag.Register(diagRouter.Group("/debug"))
+		go func() {
+			serverErrs <- diagSrv.RunLocal(cfg.DiagnosticsPort, diagRouter)
+		}()
+	}
 	srv := new(server.Server)
-	err = srv.Run("0006", handler.InitRoutes())
+	go func() {
+		if !cfg.TLSEnabled() {
+			serverErrs <- srv.Run(cfg.HTTPPort, router)
+			return
+		}
+		tlsCfg := tlsConfig(cfg)
+		tlsCfg.OnReload = func(err error) {
+			if err != nil {
+				log.Errorf("failed to reload TLS certificate, keeping the previous one: %v", err)
+				return
+			}
+			log.Infof("reloaded TLS certificate")
+		}
+		serverErrs <- srv.RunTLS(cfg.HTTPPort, router, tlsCfg)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case sig := <-signals:
+		log.Infof("received %s, shutting down", sig)
+	case err := <-serverErrs:
+		log.Errorf("server stopped: %v", err)
+		exitCode = 1
+	}
+	signal.Stop(signals)
+
+	// Fail readiness first and give the load balancer time to notice before
+	// the listener closes.
+	checker.SetShuttingDown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	start := time.Now()
+	inFlight := srv.InFlight()
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(drainCtx)
+	if err != nil {
+		log.Errorf("drain deadline of %s passed with %d requests still in flight: %v", cfg.ShutdownTimeout, srv.InFlight(), err)
+		exitCode = 1
+	}
+	if metricsSrv != nil {
+		err = metricsSrv.Shutdown(drainCtx)
+		if err != nil {
+			log.Errorf("failed to shut down metrics server: %v", err)
+		}
+	}
+	if diagSrv != nil {
+		err = diagSrv.Shutdown(drainCtx)
+		if err != nil {
+			log.Errorf("failed to shut down diagnostics server: %v", err)
+		}
+	}
+
+	stopBackground()
+	background.Wait()
+	for i := len(closeDB) - 1; i >= 0; i-- {
+		closeDB[i]()
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	err = shutdownTracing(flushCtx)
 	if err != nil {
-		log.Fatalf("failed to run server: %v", err)
+		log.Errorf("failed to flush traces: %v", err)
+	}
+	log.Infof("shutdown complete in %s: %d requests in flight when draining started, %d left unfinished, exit code %d",
+		time.Since(start).Round(time.Millisecond), inFlight, srv.InFlight(), exitCode)
+	return exitCode
+}
+
+func tracingConfig(cfg configs.Config) tracing.Config {
+	return tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		SampleRatio: cfg.TracingSampleRatio,
+		ServiceName: cfg.ApplicationName,
+	}
+}
+
+func tlsConfig(cfg configs.Config) server.TLSConfig {
+	return server.TLSConfig{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		MinVersion:   cfg.TLSMinVersion,
+		ClientCAFile: cfg.TLSClientCAFile,
+		ClientAuth:   cfg.TLSClientAuth,
+	}
+}
+
+func authConfig(cfg configs.Config) user_service.AuthConfig {
+	return user_service.AuthConfig{
+		SigningKey: []byte(cfg.JWTSecret),
+		TokenTTL:   cfg.JWTTTL,
+		BcryptCost: cfg.BcryptCost,
+	}
+}
+
+func webhookConfig(cfg configs.Config) webhook_service.Config {
+	return webhook_service.Config{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Backoff:     cfg.WebhookBackoff,
+		Timeout:     cfg.WebhookTimeout,
+	}
+}
+
+func notificationConfig(cfg configs.Config) notification_service.Config {
+	return notification_service.Config{
+		SMTPAddr:       cfg.SMTPAddr,
+		SMTPFrom:       cfg.SMTPFrom,
+		SMTPUsername:   cfg.SMTPUsername,
+		SMTPPassword:   cfg.SMTPPassword,
+		TelegramToken:  cfg.TelegramToken,
+		TelegramAPIURL: cfg.TelegramAPIURL,
+		DashboardURL:   cfg.DashboardURL,
+		Timeout:        cfg.NotificationTimeout,
 	}
 }
@@ -1,32 +1,159 @@
 package configs
 
 import (
-	"github.com/spf13/viper"
-	"log"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"time"
 )
 
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+)
+
+// The tracing exporters TracingExporter accepts.
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// Config is the whole service configuration. Load fills it from defaults,
+// the config file, environment variables and flags; each key is the field
+// name in lower case. Fields tagged secret are redacted by Print.
 type Config struct {
+	// HTTPPort is the port the API listens on.
+	HTTPPort string
+	// TLSCertFile and TLSKeyFile turn on TLS for the API; they are reloaded
+	// when they change. TLSClientCAFile turns on client certificate
+	// verification, which TLSClientAuth makes "require" (default with a CA),
+	// "optional" or "none".
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSMinVersion   string
+	TLSClientCAFile string
+	TLSClientAuth   string
+
+	// JWTSecret signs the access tokens. JWTTTL is how long a token is valid
+	// and BcryptCost the cost of new password hashes.
+	JWTSecret  string `secret:"true"`
+	JWTTTL     time.Duration
+	BcryptCost int
+
+	// Backend selects the storage backend: "postgres" (default) or "sqlite".
+	Backend    string
+	SQLitePath string
+
+	// DSN is a full Postgres connection string or URL. When set, it is used
+	// instead of Host, Port, Username, Dbname, Password and SSLMode.
+	DSN      string `secret:"dsn"`
 	Host     string
 	Port     string
 	Username string
 	Dbname   string
-	Password string
+	Password string `secret:"true"`
 	SSLMode  string
-}
+	// ReplicaDSN optionally points at a read replica for listing queries.
+	ReplicaDSN string `secret:"dsn"`
 
-func InitConfig() (config Config, err error) {
-	viper.AddConfigPath("./internal/configs")
-	viper.SetConfigName("config")
-	viper.SetConfigType("yml")
+	// Pool settings apply to both the primary and the replica pool. Zero
+	// values keep what the DSN says, or the pgxpool defaults.
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// StatementCacheMode is one of pgx's query exec modes: "cache_statement",
+	// "cache_describe", "describe_exec", "exec" or "simple_protocol". Use
+	// "exec" or "simple_protocol" behind PgBouncer in transaction mode.
+	StatementCacheMode string
+	ApplicationName    string
 
-	err = viper.ReadInConfig()
-	if err != nil {
-		log.Fatal(err)
-	}
+	// PurgeRetention is how long soft-deleted terminals and users are kept
+	// before the background purge removes them for good. Zero disables it.
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+	// AuditRetention is how long audit log entries are kept. They are
+	// checked every PurgeInterval. Zero keeps them forever.
+	AuditRetention time.Duration
+	// AlertInterval is how often alert rules are evaluated. Zero disables
+	// alerting.
+	AlertInterval time.Duration
+	// WebhookInterval is how often webhook deliveries are enqueued and sent.
+	// Zero disables webhooks. A delivery is tried WebhookMaxAttempts times,
+	// waiting WebhookBackoff after the first failure and twice as long after
+	// each further one, and each attempt may take WebhookTimeout. Zero for
+	// any of those three uses its default.
+	WebhookInterval    time.Duration
+	WebhookMaxAttempts int
+	WebhookBackoff     time.Duration
+	WebhookTimeout     time.Duration
+	// HeartbeatInterval is how often terminals that stopped sending
+	// heartbeats are looked for; an active terminal with no heartbeat for
+	// HeartbeatTimeout is marked offline. Zero for either disables it.
+	HeartbeatInterval time.Duration
+	HeartbeatTimeout  time.Duration
+
+	// SMTPAddr, a host:port, and SMTPFrom enable email notification
+	// channels, TelegramToken Telegram ones; TelegramAPIURL may point at a
+	// Bot API compatible server instead of Telegram's. Notifications link to
+	// DashboardURL/terminals/<id> if it is set, and each send may take
+	// NotificationTimeout.
+	SMTPAddr            string
+	SMTPFrom            string
+	SMTPUsername        string
+	SMTPPassword        string `secret:"true"`
+	TelegramToken       string `secret:"true"`
+	TelegramAPIURL      string
+	DashboardURL        string
+	NotificationTimeout time.Duration
+
+	// MetricsPort serves /metrics on a separate listener, for example an
+	// admin port that is not exposed publicly. Empty serves it on the API port.
+	MetricsPort string
+
+	// DiagnosticsPort serves the diagnostics endpoints without
+	// authentication on 127.0.0.1 only. They are always available to admins
+	// under /admin/debug on the API port.
+	DiagnosticsPort string
+
+	// ReadinessTimeout bounds all checks behind /readyz together.
+	ReadinessTimeout time.Duration
+	// DBRetryInterval is how often startup retries an unreachable Postgres.
+	DBRetryInterval time.Duration
+
+	// ShutdownDelay is how long the service keeps serving with readiness
+	// failing after SIGTERM, so load balancers stop routing to it first.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is the deadline for in-flight requests to drain.
+	ShutdownTimeout time.Duration
+
+	// LogLevel, LogFormat and LogOutputs configure the service logger; see
+	// logger.Config.
+	LogLevel   string
+	LogFormat  string
+	LogOutputs []logger.Output
+
+	// TracingExporter is "none", "stdout" or "otlp". TracingEndpoint is the
+	// OTLP/HTTP collector address, empty for the exporter default, and
+	// TracingInsecure sends OTLP over plain HTTP. TracingSampleRatio is the
+	// share of new traces recorded, from 0 to 1. The service name is
+	// ApplicationName.
+	TracingExporter    string
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+}
+
+// TLSEnabled reports whether the API is served over TLS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
 
-	err = viper.Unmarshal(&config)
-	if err != nil {
-		log.Fatal(err)
+// Logger returns the logger settings.
+func (c Config) Logger() logger.Config {
+	return logger.Config{
+		Level:   c.LogLevel,
+		Format:  c.LogFormat,
+		Outputs: c.LogOutputs,
 	}
-	return config, nil
 }
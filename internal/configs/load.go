@@ -0,0 +1,147 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultConfigFile is read when --config is not given, if it exists.
+	DefaultConfigFile = "./internal/configs/config.yml"
+	// EnvPrefix prefixes the environment variable of every key, as in
+	// FAVORITES_HTTPPORT.
+	EnvPrefix = "FAVORITES"
+)
+
+// legacyEnv lists environment variables still accepted for a key, after the
+// prefixed one.
+var legacyEnv = map[string][]string{
+	"jwtsecret": {"SECRET_KEY"},
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("httpport", "0006")
+	v.SetDefault("jwtttl", 24*time.Hour)
+	v.SetDefault("bcryptcost", 14)
+	v.SetDefault("backend", BackendPostgres)
+	v.SetDefault("sqlitepath", "./favorites.db")
+	v.SetDefault("applicationname", "add-to-favorites")
+	v.SetDefault("purgeretention", 30*24*time.Hour)
+	v.SetDefault("purgeinterval", time.Hour)
+	v.SetDefault("auditretention", 365*24*time.Hour)
+	v.SetDefault("alertinterval", 30*time.Second)
+	v.SetDefault("webhookinterval", 10*time.Second)
+	v.SetDefault("webhookmaxattempts", 8)
+	v.SetDefault("webhookbackoff", 30*time.Second)
+	v.SetDefault("webhooktimeout", 10*time.Second)
+	v.SetDefault("telegramapiurl", "https://api.telegram.org")
+	v.SetDefault("heartbeatinterval", 30*time.Second)
+	v.SetDefault("heartbeattimeout", 5*time.Minute)
+	v.SetDefault("notificationtimeout", 10*time.Second)
+	v.SetDefault("readinesstimeout", 2*time.Second)
+	v.SetDefault("dbretryinterval", 5*time.Second)
+	v.SetDefault("shutdowndelay", 5*time.Second)
+	v.SetDefault("shutdowntimeout", 30*time.Second)
+	v.SetDefault("loglevel", "info")
+	v.SetDefault("logformat", logger.FormatText)
+	v.SetDefault("tracingexporter", TracingExporterNone)
+	v.SetDefault("tracingsampleratio", 1.0)
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the config file, environment variables and the flags in
+// args. The file is the one given with --config, or DefaultConfigFile if it
+// exists. Every key can be set as FAVORITES_<KEY> in the environment and as
+// --<key> on the command line, except logoutputs, which only the file can
+// set. Load does not validate the result; call Validate for that. It
+// returns pflag.ErrHelp if args ask for help.
+func Load(args []string) (Config, error) {
+	var config Config
+	v := viper.New()
+	setDefaults(v)
+
+	flags := pflag.NewFlagSet("add-to-favorites", pflag.ContinueOnError)
+	configFile := flags.String("config", "", "path of the config file (default "+DefaultConfigFile+" if it exists)")
+	keys := registerFlags(flags, reflect.TypeOf(config))
+	err := flags.Parse(args)
+	if err != nil {
+		return config, err
+	}
+	if flags.NArg() > 0 {
+		return config, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	for _, key := range keys {
+		env := append([]string{EnvPrefix + "_" + strings.ToUpper(key)}, legacyEnv[key]...)
+		err = v.BindEnv(append([]string{key}, env...)...)
+		if err != nil {
+			return config, err
+		}
+		if flag := flags.Lookup(key); flag != nil {
+			err = v.BindPFlag(key, flag)
+			if err != nil {
+				return config, err
+			}
+		}
+	}
+
+	path := *configFile
+	if path == "" {
+		if _, statErr := os.Stat(DefaultConfigFile); statErr == nil {
+			path = DefaultConfigFile
+		}
+	}
+	if path != "" {
+		v.SetConfigFile(path)
+		v.SetConfigType("yml")
+		err = v.ReadInConfig()
+		if err != nil {
+			return config, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+	}
+
+	err = v.Unmarshal(&config)
+	if err != nil {
+		return config, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return config, nil
+}
+
+// registerFlags adds a flag for every scalar field of the config type and
+// returns the keys of all fields.
+func registerFlags(flags *pflag.FlagSet, t reflect.Type) []string {
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.ToLower(field.Name)
+		keys = append(keys, key)
+		usage := "sets " + key
+		if field.Type == reflect.TypeOf(time.Duration(0)) {
+			flags.Duration(key, 0, usage)
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			flags.String(key, "", usage)
+		case reflect.Bool:
+			flags.Bool(key, false, usage)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			flags.Int64(key, 0, usage)
+		case reflect.Float64:
+			flags.Float64(key, 0, usage)
+		}
+	}
+	return keys
+}
+
+// IsHelp reports whether err is the request for help returned by Load.
+func IsHelp(err error) bool {
+	return errors.Is(err, pflag.ErrHelp)
+}
@@ -0,0 +1,108 @@
+package configs
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Redacted replaces secret values in Print and Redacted output.
+const Redacted = "REDACTED"
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Print writes the configuration as YAML in the config file format, with
+// secrets redacted: fields tagged secret:"true" are replaced entirely and
+// connection strings tagged secret:"dsn" only lose their password.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err := enc.Encode(c.Redacted())
+	if err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return enc.Close()
+}
+
+// Redacted returns the configuration as an ordered YAML mapping keyed like
+// the config file, with secrets redacted as in Print.
+func (c Config) Redacted() *yaml.Node {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i).Interface()
+		switch field.Tag.Get("secret") {
+		case "true":
+			if value != "" {
+				value = Redacted
+			}
+		case "dsn":
+			value = RedactDSN(value.(string))
+		}
+		node := &yaml.Node{}
+		_ = node.Encode(plain(value))
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(field.Name)}, node)
+	}
+	return doc
+}
+
+// RedactedMap is Redacted decoded into a map, for JSON output.
+func (c Config) RedactedMap() map[string]any {
+	var m map[string]any
+	_ = c.Redacted().Decode(&m)
+	return m
+}
+
+// RedactDSN hides the password of a Postgres URL or keyword/value connection
+// string and keeps the rest readable.
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+Redacted)
+}
+
+// plain turns durations into the strings the config file uses and nested
+// structs into lower-cased maps, so the output can be loaded back.
+func plain(value any) any {
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return value
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = plain(v.Index(i).Interface())
+		}
+		return items
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if field.IsZero() {
+				continue
+			}
+			child := &yaml.Node{}
+			_ = child.Encode(plain(field.Interface()))
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(v.Type().Field(i).Name)}, child)
+		}
+		return node
+	}
+	return value
+}
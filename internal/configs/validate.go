@@ -0,0 +1,137 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+)
+
+// tlsVersions are the TLS versions tlsminversion accepts; empty means 1.2.
+var tlsVersions = map[string]bool{"": true, "1.0": true, "1.1": true, "1.2": true, "1.3": true}
+
+var statementCacheModes = map[string]bool{
+	"cache_statement": true,
+	"cache_describe":  true,
+	"describe_exec":   true,
+	"exec":            true,
+	"simple_protocol": true,
+}
+
+// Validate checks the whole configuration and reports every problem it
+// finds, one per line, rather than stopping at the first.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.HTTPPort), "httpport %q is not a port number", c.HTTPPort)
+	check(c.MetricsPort == "" || validPort(c.MetricsPort), "metricsport %q is not a port number", c.MetricsPort)
+	check(c.MetricsPort == "" || c.MetricsPort != c.HTTPPort, "metricsport must differ from httpport")
+	check(c.DiagnosticsPort == "" || validPort(c.DiagnosticsPort), "diagnosticsport %q is not a port number", c.DiagnosticsPort)
+	check(c.DiagnosticsPort == "" || (c.DiagnosticsPort != c.HTTPPort && c.DiagnosticsPort != c.MetricsPort),
+		"diagnosticsport must differ from httpport and metricsport")
+
+	if c.TLSEnabled() {
+		check(c.TLSCertFile != "" && c.TLSKeyFile != "", "both a certificate and a key file are required for TLS")
+		check(tlsVersions[c.TLSMinVersion], "unknown TLS version %q", c.TLSMinVersion)
+		switch c.TLSClientAuth {
+		case "", "none":
+		case "optional", "require":
+			check(c.TLSClientCAFile != "", "tlsclientauth %q needs tlsclientcafile", c.TLSClientAuth)
+		default:
+			check(false, "tlsclientauth must be %q, %q or %q", "none", "optional", "require")
+		}
+	} else {
+		check(c.TLSClientCAFile == "", "tlsclientcafile needs tlscertfile and tlskeyfile")
+	}
+
+	check(c.JWTSecret != "", "jwtsecret is required")
+	check(c.JWTTTL > 0, "jwtttl must be positive")
+	check(c.BcryptCost >= bcrypt.MinCost && c.BcryptCost <= bcrypt.MaxCost,
+		"bcryptcost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	switch c.Backend {
+	case BackendSQLite:
+		check(c.SQLitePath != "", "sqlitepath is required with the sqlite backend")
+	case BackendPostgres:
+		check(c.DSN != "" || c.Host != "", "dsn or host is required with the postgres backend")
+		check(c.MaxConns >= 0 && c.MinConns >= 0, "maxconns and minconns must not be negative")
+		check(c.MaxConns == 0 || c.MinConns <= c.MaxConns, "minconns %d is greater than maxconns %d", c.MinConns, c.MaxConns)
+		check(c.StatementCacheMode == "" || statementCacheModes[c.StatementCacheMode],
+			"unknown statementcachemode %q", c.StatementCacheMode)
+	default:
+		check(false, "backend must be %q or %q, not %q", BackendPostgres, BackendSQLite, c.Backend)
+	}
+
+	for name, d := range map[string]int64{
+		"maxconnlifetime":     int64(c.MaxConnLifetime),
+		"maxconnidletime":     int64(c.MaxConnIdleTime),
+		"healthcheckperiod":   int64(c.HealthCheckPeriod),
+		"purgeretention":      int64(c.PurgeRetention),
+		"purgeinterval":       int64(c.PurgeInterval),
+		"auditretention":      int64(c.AuditRetention),
+		"alertinterval":       int64(c.AlertInterval),
+		"webhookinterval":     int64(c.WebhookInterval),
+		"webhookbackoff":      int64(c.WebhookBackoff),
+		"webhooktimeout":      int64(c.WebhookTimeout),
+		"heartbeatinterval":   int64(c.HeartbeatInterval),
+		"heartbeattimeout":    int64(c.HeartbeatTimeout),
+		"notificationtimeout": int64(c.NotificationTimeout),
+		"shutdowndelay":       int64(c.ShutdownDelay),
+	} {
+		check(d >= 0, "%s must not be negative", name)
+	}
+	check(c.ReadinessTimeout > 0, "readinesstimeout must be positive")
+	check(c.DBRetryInterval > 0, "dbretryinterval must be positive")
+	check(c.ShutdownTimeout > 0, "shutdowntimeout must be positive")
+	check(c.WebhookMaxAttempts >= 0, "webhookmaxattempts must not be negative")
+
+	if c.SMTPAddr != "" {
+		_, _, err := net.SplitHostPort(c.SMTPAddr)
+		check(err == nil, "smtpaddr %q is not a host:port", c.SMTPAddr)
+		_, err = mail.ParseAddress(c.SMTPFrom)
+		check(err == nil, "smtpfrom must be an email address with smtpaddr")
+	}
+	check(c.TelegramAPIURL == "" || absoluteURL(c.TelegramAPIURL), "telegramapiurl must be an absolute http or https URL")
+	check(c.DashboardURL == "" || absoluteURL(c.DashboardURL), "dashboardurl must be an absolute http or https URL")
+
+	_, err := logrus.ParseLevel(c.LogLevel)
+	check(err == nil, "unknown loglevel %q", c.LogLevel)
+	check(c.LogFormat == logger.FormatText || c.LogFormat == logger.FormatJSON,
+		"logformat must be %q or %q", logger.FormatText, logger.FormatJSON)
+	for i, out := range c.LogOutputs {
+		check(out.Path != "", "logoutputs[%d] has no path", i)
+		for _, level := range out.Levels {
+			_, err := logrus.ParseLevel(level)
+			check(err == nil, "logoutputs[%d] has unknown level %q", i, level)
+		}
+	}
+
+	switch c.TracingExporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterOTLP:
+	default:
+		check(false, "tracingexporter must be %q, %q or %q", TracingExporterNone, TracingExporterStdout, TracingExporterOTLP)
+	}
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "tracingsampleratio must be between 0 and 1")
+
+	return errors.Join(errs...)
+}
+
+func absoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
@@ -4,34 +4,146 @@ import (
 	"context"
 	"fmt"
 	"github.com/dvdxa/add-to-favorites/internal/configs"
+	"github.com/dvdxa/add-to-favorites/internal/tracing"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
 func ConnectToPostgres(cfg *configs.Config) (*pgxpool.Pool, error) {
-	connString := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s", cfg.Host, cfg.Port, cfg.Username, cfg.Dbname, cfg.Password, cfg.SSLMode)
-	poolConfig, err := pgxpool.ParseConfig(connString)
+	pool, err := NewPool(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	err = pool.Ping(context.Background())
 	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	err = Migrate(context.Background(), pool)
+	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	err = pool.Ping(context.Background())
+	return pool, nil
+}
+
+// NewPool builds the primary pool without connecting, so the service can start
+// and report itself not ready while the database is down. Use WaitAndMigrate
+// or ConnectToPostgres to get a pool that is known to work.
+func NewPool(cfg *configs.Config) (*pgxpool.Pool, error) {
+	connString := cfg.DSN
+	if connString == "" {
+		connString = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s", cfg.Host, cfg.Port, cfg.Username, cfg.Dbname, cfg.Password, cfg.SSLMode)
+	}
+	poolConfig, err := PoolConfig(cfg, connString)
 	if err != nil {
 		return nil, err
 	}
-	//b, err := os.ReadFile("./internal/database/schema/migrations.up.sql")
-	//if err != nil {
-	//	return nil, err
-	//}
-	//migrationQuery := string(b)
-	//_, err = pool.Exec(context.Background(), migrationQuery)
-	//if err != nil {
-	//	return nil, err
-	//}
+	logPoolConfig("primary", poolConfig)
+	return pgxpool.NewWithConfig(context.Background(), poolConfig)
+}
+
+// WaitAndMigrate pings the database and applies pending migrations, retrying
+// every retryInterval until it succeeds or ctx is done.
+func WaitAndMigrate(ctx context.Context, pool *pgxpool.Pool, retryInterval time.Duration) error {
+	log := logger.GetLogger()
+	for {
+		err := pool.Ping(ctx)
+		if err == nil {
+			err = Migrate(ctx, pool)
+		}
+		if err == nil {
+			return nil
+		}
+		log.Errorf("database is not ready, retrying in %s: %v", retryInterval, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
 
+// ConnectToReplica opens a pool on cfg.ReplicaDSN, or returns nil if none is
+// configured. An unreachable replica is only logged, since reads fall back to
+// the primary until it comes up.
+func ConnectToReplica(cfg *configs.Config) (*pgxpool.Pool, error) {
+	if cfg.ReplicaDSN == "" {
+		return nil, nil
+	}
+	poolConfig, err := PoolConfig(cfg, cfg.ReplicaDSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica dsn: %v", err)
+	}
+	logPoolConfig("replica", poolConfig)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = pool.Ping(context.Background())
+	if err != nil {
+		logger.GetLogger().Warnf("read replica is not reachable, reads use the primary until it is: %v", err)
+	}
 	return pool, nil
 }
+
+// PoolConfig parses connString and applies the pool settings from cfg that
+// are set.
+func PoolConfig(cfg *configs.Config, connString string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		return nil, fmt.Errorf("minconns %d is greater than maxconns %d", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.StatementCacheMode != "" {
+		mode, ok := queryExecModes[cfg.StatementCacheMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown statement cache mode %q", cfg.StatementCacheMode)
+		}
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if cfg.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = cfg.ApplicationName
+	}
+	poolConfig.ConnConfig.Tracer = tracing.NewPgxTracer()
+	return poolConfig, nil
+}
+
+// logPoolConfig logs the effective settings, leaving out the password.
+func logPoolConfig(name string, poolConfig *pgxpool.Config) {
+	connConfig := poolConfig.ConnConfig
+	logger.GetLogger().Infof("%s pool: host=%s port=%d dbname=%s user=%s application_name=%q max_conns=%d min_conns=%d max_conn_lifetime=%s max_conn_idle_time=%s health_check_period=%s statement_cache_mode=%s",
+		name, connConfig.Host, connConfig.Port, connConfig.Database, connConfig.User, connConfig.RuntimeParams["application_name"],
+		poolConfig.MaxConns, poolConfig.MinConns, poolConfig.MaxConnLifetime, poolConfig.MaxConnIdleTime, poolConfig.HealthCheckPeriod,
+		connConfig.DefaultQueryExecMode)
+}
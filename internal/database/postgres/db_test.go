@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/configs"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPoolConfig(t *testing.T) {
+	cfg := &configs.Config{
+		MaxConns:           20,
+		MinConns:           2,
+		MaxConnLifetime:    time.Hour,
+		MaxConnIdleTime:    10 * time.Minute,
+		HealthCheckPeriod:  30 * time.Second,
+		StatementCacheMode: "exec",
+		ApplicationName:    "add-to-favorites",
+	}
+	poolConfig, err := PoolConfig(cfg, "postgres://postgres:secret@db:5433/favorites_db?pool_max_conns=5")
+	require.NoError(t, err)
+	require.Equal(t, "db", poolConfig.ConnConfig.Host)
+	require.Equal(t, uint16(5433), poolConfig.ConnConfig.Port)
+	require.Equal(t, int32(20), poolConfig.MaxConns)
+	require.Equal(t, int32(2), poolConfig.MinConns)
+	require.Equal(t, time.Hour, poolConfig.MaxConnLifetime)
+	require.Equal(t, 10*time.Minute, poolConfig.MaxConnIdleTime)
+	require.Equal(t, 30*time.Second, poolConfig.HealthCheckPeriod)
+	require.Equal(t, pgx.QueryExecModeExec, poolConfig.ConnConfig.DefaultQueryExecMode)
+	require.Equal(t, "add-to-favorites", poolConfig.ConnConfig.RuntimeParams["application_name"])
+}
+
+func TestPoolConfigKeepsDSNSettings(t *testing.T) {
+	poolConfig, err := PoolConfig(&configs.Config{}, "host=db user=postgres dbname=favorites_db pool_max_conns=5 application_name=reports")
+	require.NoError(t, err)
+	require.Equal(t, int32(5), poolConfig.MaxConns)
+	require.Equal(t, "reports", poolConfig.ConnConfig.RuntimeParams["application_name"])
+	require.Equal(t, pgx.QueryExecModeCacheStatement, poolConfig.ConnConfig.DefaultQueryExecMode)
+}
+
+func TestPoolConfigErrors(t *testing.T) {
+	_, err := PoolConfig(&configs.Config{StatementCacheMode: "sometimes"}, "host=db")
+	require.EqualError(t, err, `unknown statement cache mode "sometimes"`)
+
+	_, err = PoolConfig(&configs.Config{MaxConns: 2, MinConns: 4}, "host=db")
+	require.EqualError(t, err, "minconns 4 is greater than maxconns 2")
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/database/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrate applies every embedded postgres migration that is not yet recorded
+// in schema_migrations, each in its own transaction.
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	createTable := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`
+	_, err := pool.Exec(ctx, createTable)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	applied, err := AppliedMigrations(ctx, pool)
+	if err != nil {
+		return err
+	}
+	migrations, err := schema.Migrations(schema.Postgres)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, m.SQL)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func AppliedMigrations(ctx context.Context, pool *pgxpool.Pool) (map[int]bool, error) {
+	rows, err := pool.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		err = rows.Scan(&version)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func PendingMigrations(ctx context.Context, pool *pgxpool.Pool) ([]schema.Migration, error) {
+	applied, err := AppliedMigrations(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Pending(schema.Postgres, applied)
+}
@@ -1,15 +1,15 @@
-CREATE TABLE favorite_terminals (
+CREATE TABLE IF NOT EXISTS favorite_terminals (
     user_id INTEGER,
     terminal_id INTEGER[]
 );
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) UNIQUE NOT NULL,
     password VARCHAR(255) NOT NULL
 );
 
-CREATE TABLE terminals(
+CREATE TABLE IF NOT EXISTS terminals(
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) UNIQUE NOT NULL,
     status VARCHAR(255) NOT NULL
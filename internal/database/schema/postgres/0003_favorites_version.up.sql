@@ -0,0 +1 @@
+ALTER TABLE favorite_terminals ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+-- Tags are key=value labels shared by any number of terminals.
+CREATE TABLE IF NOT EXISTS tags (
+    id    SERIAL PRIMARY KEY,
+    key   VARCHAR(63) NOT NULL,
+    value VARCHAR(63) NOT NULL,
+    UNIQUE (key, value)
+);
+
+CREATE TABLE IF NOT EXISTS terminal_tags (
+    terminal_id INTEGER NOT NULL REFERENCES terminals (id) ON DELETE CASCADE,
+    tag_id      INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (terminal_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS terminal_tags_tag_id_idx ON terminal_tags (tag_id);
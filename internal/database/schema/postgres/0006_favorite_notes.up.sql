@@ -0,0 +1,12 @@
+-- A user's alias, colour and note on one of their favorites. Removing the
+-- favorite sets removed_at instead of deleting the row, so undo can bring it
+-- back; each user keeps at most one removed row, the latest.
+CREATE TABLE IF NOT EXISTS favorite_notes (
+    user_id     INTEGER NOT NULL,
+    terminal_id INTEGER NOT NULL REFERENCES terminals (id) ON DELETE CASCADE,
+    alias       VARCHAR(64) NOT NULL DEFAULT '',
+    color       VARCHAR(7) NOT NULL DEFAULT '',
+    note        TEXT NOT NULL DEFAULT '',
+    removed_at  TIMESTAMPTZ,
+    PRIMARY KEY (user_id, terminal_id)
+);
@@ -0,0 +1,14 @@
+-- Saved views of the terminal list. params is the URL-encoded query string,
+-- so parameters the listing learns later need no migration.
+CREATE TABLE IF NOT EXISTS views (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(63) NOT NULL,
+    params     TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    shared     BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS views_default_idx ON views (user_id) WHERE is_default;
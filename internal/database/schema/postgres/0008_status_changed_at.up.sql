@@ -0,0 +1,17 @@
+-- Terminals are written by the monitoring side as well as by this service,
+-- so the time of the last status change is kept by a trigger rather than by
+-- every writer. Terminals that existed before have no known change time.
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ;
+
+CREATE OR REPLACE FUNCTION terminals_status_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        NEW.status_changed_at := now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS terminals_status_changed ON terminals;
+CREATE TRIGGER terminals_status_changed BEFORE INSERT OR UPDATE OF status ON terminals
+    FOR EACH ROW EXECUTE FUNCTION terminals_status_changed();
@@ -0,0 +1,42 @@
+-- Outbound webhooks. events and labels are JSON arrays; an empty one
+-- matches every event or terminal.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          SERIAL PRIMARY KEY,
+    url         TEXT NOT NULL,
+    secret      VARCHAR(255) NOT NULL,
+    events      TEXT NOT NULL DEFAULT '[]',
+    terminal_id INTEGER REFERENCES terminals (id) ON DELETE CASCADE,
+    labels      TEXT NOT NULL DEFAULT '[]',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One row per event and webhook. payload is kept verbatim so a redelivery
+-- sends, and signs, the same bytes.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event           VARCHAR(64) NOT NULL,
+    payload         TEXT NOT NULL,
+    state           VARCHAR(16) NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE state = 'pending';
+
+-- The last status change and audit log entry turned into deliveries. It
+-- starts at the current end of both, so existing history is not sent.
+CREATE TABLE IF NOT EXISTS webhook_cursor (
+    id               INTEGER PRIMARY KEY CHECK (id = 1),
+    status_change_id BIGINT NOT NULL,
+    audit_id         BIGINT NOT NULL
+);
+
+INSERT INTO webhook_cursor (id, status_change_id, audit_id)
+SELECT 1, COALESCE((SELECT MAX(id) FROM terminal_status_changes), 0), COALESCE((SELECT MAX(id) FROM audit_log), 0)
+ON CONFLICT (id) DO NOTHING;
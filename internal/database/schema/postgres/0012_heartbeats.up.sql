@@ -0,0 +1,12 @@
+-- Terminals that report heartbeats. token_hash is the SHA-256 of the
+-- terminal's token in hex; the token itself is only shown when issued.
+-- last_seen_at is NULL until the first heartbeat.
+CREATE TABLE IF NOT EXISTS terminal_heartbeats (
+    terminal_id  INTEGER PRIMARY KEY REFERENCES terminals (id) ON DELETE CASCADE,
+    token_hash   VARCHAR(64) NOT NULL UNIQUE,
+    last_seen_at TIMESTAMPTZ,
+    firmware     VARCHAR(255) NOT NULL DEFAULT '',
+    ip           VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS terminal_heartbeats_last_seen_at_idx ON terminal_heartbeats (last_seen_at);
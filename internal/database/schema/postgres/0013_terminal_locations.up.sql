@@ -0,0 +1,13 @@
+-- Where a terminal is, in WGS 84 degrees, and its street address. Latitude
+-- and longitude are set together or not at all. Distances are computed by
+-- the service, so no spatial extension is needed.
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS address VARCHAR(255) NOT NULL DEFAULT '';
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'terminals_location_check') THEN
+        ALTER TABLE terminals ADD CONSTRAINT terminals_location_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+    END IF;
+END
+$$;
@@ -0,0 +1,14 @@
+-- The site hierarchy, such as regions containing sites containing floors.
+-- A site with sub-sites or terminals cannot be deleted; soft-deleted
+-- terminals lose their site instead.
+CREATE TABLE IF NOT EXISTS sites (
+    id         SERIAL PRIMARY KEY,
+    parent_id  INTEGER REFERENCES sites (id),
+    name       VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sites_parent_id_idx ON sites (parent_id);
+
+ALTER TABLE terminals ADD COLUMN IF NOT EXISTS site_id INTEGER REFERENCES sites (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS terminals_site_id_idx ON terminals (site_id);
@@ -0,0 +1,10 @@
+-- A terminal with a heartbeat token but no heartbeat yet counts as last
+-- seen when its token was issued, so one that never reports still goes
+-- offline. Tokens issued before this migration count from now.
+ALTER TABLE terminal_heartbeats ADD COLUMN IF NOT EXISTS issued_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Status changes derived by this service rather than written by the
+-- monitoring side say where they came from and why.
+ALTER TABLE terminal_status_changes
+    ADD COLUMN IF NOT EXISTS source VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS reason VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- Status changes are evaluated against alert rules as they come in, and
+-- marked once they have been. Earlier changes were already seen by the
+-- periodic evaluation.
+ALTER TABLE terminal_status_changes ADD COLUMN IF NOT EXISTS evaluated BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE terminal_status_changes SET evaluated = TRUE;
+
+CREATE INDEX IF NOT EXISTS terminal_status_changes_pending_idx ON terminal_status_changes (id) WHERE NOT evaluated;
+
+-- A status whose change time is unknown is taken to have started now, so
+-- it has to last a rule's window like any other before the rule holds.
+UPDATE terminals SET status_changed_at = now() WHERE status_changed_at IS NULL;
@@ -0,0 +1,13 @@
+-- Webhook events are deleted when the dispatcher claims them instead of
+-- being marked enqueued, so the table only holds events whose deliveries
+-- have not been created yet.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'webhook_events' AND column_name = 'state') THEN
+        DELETE FROM webhook_events WHERE state <> 'pending';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS webhook_events_pending_idx;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS state;
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the up migrations for dialect ordered by version. Files
+// are named <version>_<name>.up.sql, and both dialects keep the same versions
+// so a schema change is always made for every backend at once.
+func Migrations(dialect Dialect) ([]Migration, error) {
+	entries, err := files.ReadDir(string(dialect))
+	if err != nil {
+		return nil, fmt.Errorf("unknown dialect %q: %v", dialect, err)
+	}
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if !strings.HasSuffix(fileName, ".up.sql") {
+			continue
+		}
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(fileName, ".up.sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %v", fileName, err)
+		}
+		b, err := files.ReadFile(path.Join(string(dialect), fileName))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			SQL:     string(b),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Pending returns the migrations for dialect whose versions are not in
+// applied, in the order they would be applied.
+func Pending(dialect Dialect, applied map[int]bool) ([]Migration, error) {
+	migrations, err := Migrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
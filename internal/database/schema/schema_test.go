@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	pgMigrations, err := Migrations(Postgres)
+	require.NoError(t, err)
+	sqliteMigrations, err := Migrations(SQLite)
+	require.NoError(t, err)
+	require.NotEmpty(t, pgMigrations)
+	require.Equal(t, len(pgMigrations), len(sqliteMigrations))
+	for i := range pgMigrations {
+		require.Equal(t, pgMigrations[i].Version, sqliteMigrations[i].Version)
+		require.Equal(t, pgMigrations[i].Name, sqliteMigrations[i].Name)
+		if i > 0 {
+			require.Less(t, pgMigrations[i-1].Version, pgMigrations[i].Version)
+		}
+	}
+}
+
+func TestMigrationsUnknownDialect(t *testing.T) {
+	_, err := Migrations("oracle")
+	require.Error(t, err)
+}
+
+func TestPending(t *testing.T) {
+	migrations, err := Migrations(SQLite)
+	require.NoError(t, err)
+
+	pending, err := Pending(SQLite, map[int]bool{})
+	require.NoError(t, err)
+	require.Equal(t, migrations, pending)
+
+	applied := make(map[int]bool)
+	for _, m := range migrations[:len(migrations)-1] {
+		applied[m.Version] = true
+	}
+	pending, err = Pending(SQLite, applied)
+	require.NoError(t, err)
+	require.Equal(t, migrations[len(migrations)-1:], pending)
+}
@@ -0,0 +1,18 @@
+-- terminal_id holds a JSON array of terminal IDs, in the order they were
+-- favorited, mirroring the INTEGER[] column of the postgres schema.
+CREATE TABLE IF NOT EXISTS favorite_terminals (
+    user_id INTEGER,
+    terminal_id TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS terminals(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    status VARCHAR(255) NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE favorite_terminals ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
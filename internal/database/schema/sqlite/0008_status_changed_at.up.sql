@@ -0,0 +1,15 @@
+-- Terminals are written by the monitoring side as well as by this service,
+-- so the time of the last status change is kept by triggers rather than by
+-- every writer. Terminals that existed before have no known change time.
+ALTER TABLE terminals ADD COLUMN status_changed_at TIMESTAMP;
+
+CREATE TRIGGER IF NOT EXISTS terminals_status_inserted AFTER INSERT ON terminals
+BEGIN
+    UPDATE terminals SET status_changed_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS terminals_status_changed AFTER UPDATE OF status ON terminals
+WHEN NEW.status IS NOT OLD.status
+BEGIN
+    UPDATE terminals SET status_changed_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
@@ -0,0 +1,47 @@
+-- Every status change, recorded by a trigger like status_changed_at, so
+-- alert rules can count flaps.
+CREATE TABLE IF NOT EXISTS terminal_status_changes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    terminal_id INTEGER NOT NULL REFERENCES terminals (id) ON DELETE CASCADE,
+    status      VARCHAR(255) NOT NULL,
+    changed_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS terminal_status_changes_changed_at_idx ON terminal_status_changes (changed_at);
+
+CREATE TRIGGER IF NOT EXISTS terminals_record_status_change AFTER UPDATE OF status ON terminals
+WHEN NEW.status IS NOT OLD.status
+BEGIN
+    INSERT INTO terminal_status_changes (terminal_id, status, changed_at)
+    VALUES (NEW.id, NEW.status, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+END;
+
+-- Alert rules on a user's favorites. terminal_id NULL applies the rule to
+-- all of them. window_seconds is how long a status must hold, or the window
+-- flaps are counted in.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    terminal_id    INTEGER REFERENCES terminals (id) ON DELETE CASCADE,
+    kind           VARCHAR(32) NOT NULL,
+    status         VARCHAR(255) NOT NULL DEFAULT '',
+    count          INTEGER NOT NULL DEFAULT 0,
+    window_seconds INTEGER NOT NULL,
+    muted_until    TIMESTAMP,
+    created_at     TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS alert_rules_user_id_idx ON alert_rules (user_id);
+
+-- An alert fires when a rule starts to hold for a terminal and resolves when
+-- it stops; there is at most one firing alert per rule and terminal.
+CREATE TABLE IF NOT EXISTS alerts (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id     INTEGER NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+    terminal_id INTEGER NOT NULL REFERENCES terminals (id) ON DELETE CASCADE,
+    fired_at    TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    acked_at    TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS alerts_firing_idx ON alerts (rule_id, terminal_id) WHERE resolved_at IS NULL;
@@ -0,0 +1,15 @@
+-- Where alert notifications are sent. A channel belongs to one user, or,
+-- with user_id NULL, is a team channel for the users in members, a JSON
+-- array of IDs. An empty template uses the default one.
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(255) NOT NULL,
+    kind       VARCHAR(16) NOT NULL,
+    target     TEXT NOT NULL,
+    template   TEXT NOT NULL DEFAULT '',
+    members    TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notification_channels_user_id_idx ON notification_channels (user_id);
@@ -0,0 +1,6 @@
+-- Where a terminal is, in WGS 84 degrees, and its street address. Latitude
+-- and longitude are set together or not at all. Distances are computed by
+-- the service, so no spatial extension is needed.
+ALTER TABLE terminals ADD COLUMN latitude REAL;
+ALTER TABLE terminals ADD COLUMN longitude REAL CHECK ((latitude IS NULL) = (longitude IS NULL));
+ALTER TABLE terminals ADD COLUMN address VARCHAR(255) NOT NULL DEFAULT '';
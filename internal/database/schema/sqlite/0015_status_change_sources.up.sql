@@ -0,0 +1,11 @@
+-- A terminal with a heartbeat token but no heartbeat yet counts as last
+-- seen when its token was issued, so one that never reports still goes
+-- offline. Tokens issued before this migration count from now.
+ALTER TABLE terminal_heartbeats ADD COLUMN issued_at TIMESTAMP;
+
+UPDATE terminal_heartbeats SET issued_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- Status changes derived by this service rather than written by the
+-- monitoring side say where they came from and why.
+ALTER TABLE terminal_status_changes ADD COLUMN source VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE terminal_status_changes ADD COLUMN reason VARCHAR(255) NOT NULL DEFAULT '';
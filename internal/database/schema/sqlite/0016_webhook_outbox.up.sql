@@ -0,0 +1,42 @@
+-- Webhook events are written in the transaction that makes the change, and
+-- the dispatcher claims pending ones by flipping their state. Unlike the
+-- cursor this replaces, an event whose transaction commits after a later
+-- one is still picked up, and a favorite change no longer depends on its
+-- audit entry being written.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    event       VARCHAR(64) NOT NULL,
+    terminal_id INTEGER NOT NULL,
+    status      VARCHAR(255) NOT NULL DEFAULT '',
+    user_id     INTEGER NOT NULL DEFAULT 0,
+    state       VARCHAR(16) NOT NULL DEFAULT 'pending',
+    occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_events_pending_idx ON webhook_events (id) WHERE state = 'pending';
+
+DROP TRIGGER IF EXISTS terminals_record_status_change;
+
+CREATE TRIGGER terminals_record_status_change AFTER UPDATE OF status ON terminals
+WHEN NEW.status IS NOT OLD.status
+BEGIN
+    INSERT INTO terminal_status_changes (terminal_id, status, changed_at)
+    VALUES (NEW.id, NEW.status, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+    INSERT INTO webhook_events (event, terminal_id, status, occurred_at)
+    VALUES ('terminal.status_change', NEW.id, NEW.status, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+END;
+
+-- Whatever the cursor had not reached yet is carried over.
+INSERT INTO webhook_events (event, terminal_id, status, occurred_at)
+SELECT 'terminal.status_change', c.terminal_id, c.status, c.changed_at
+FROM terminal_status_changes c, webhook_cursor w
+WHERE c.id > w.status_change_id
+ORDER BY c.id;
+
+INSERT INTO webhook_events (event, terminal_id, user_id, occurred_at)
+SELECT a.action, CAST(a.target_id AS INTEGER), COALESCE(a.actor_id, 0), a.created_at
+FROM audit_log a, webhook_cursor w
+WHERE a.id > w.audit_id AND a.action IN ('favorite.add', 'favorite.remove') AND a.outcome = 'success'
+ORDER BY a.id;
+
+DROP TABLE IF EXISTS webhook_cursor;
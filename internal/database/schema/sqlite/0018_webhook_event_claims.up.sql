@@ -0,0 +1,8 @@
+-- Webhook events are deleted when the dispatcher claims them instead of
+-- being marked enqueued, so the table only holds events whose deliveries
+-- have not been created yet.
+DELETE FROM webhook_events WHERE state <> 'pending';
+
+DROP INDEX IF EXISTS webhook_events_pending_idx;
+
+ALTER TABLE webhook_events DROP COLUMN state;
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/configs"
+	_ "modernc.org/sqlite"
+)
+
+func ConnectToSQLite(cfg *configs.Config) (*sql.DB, error) {
+	return Open(cfg.SQLitePath)
+}
+
+// Open opens the database file at path, creating it if needed, and applies
+// pending migrations. Writers are serialized through a single connection, which
+// is what SQLite does internally anyway, so callers never see SQLITE_BUSY from
+// within this process.
+func Open(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	err = db.PingContext(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	err = Migrate(context.Background(), db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
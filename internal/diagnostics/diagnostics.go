@@ -0,0 +1,222 @@
+// Package diagnostics serves runtime introspection for operators: pprof,
+// build and runtime info, pool and cache stats and the effective config.
+package diagnostics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version and Commit can be set at build time with
+// -ldflags "-X github.com/dvdxa/add-to-favorites/internal/diagnostics.Version=v1.2.3".
+// Otherwise they come from the module and VCS info Go embeds in the binary.
+var (
+	Version string
+	Commit  string
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	CommitAt  string `json:"commit_time,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+}
+
+type Info struct {
+	Build         BuildInfo      `json:"build"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	Goroutines    int            `json:"goroutines"`
+	GOMAXPROCS    int            `json:"gomaxprocs"`
+	Memory        MemoryInfo     `json:"memory"`
+	Pools         map[string]any `json:"pools"`
+	Caches        map[string]any `json:"caches"`
+}
+
+type MemoryInfo struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	LastGC         string `json:"last_gc,omitempty"`
+}
+
+// PoolStats is a pgxpool.Stat snapshot.
+type PoolStats struct {
+	TotalConns              int32   `json:"total_conns"`
+	IdleConns               int32   `json:"idle_conns"`
+	AcquiredConns           int32   `json:"acquired_conns"`
+	ConstructingConns       int32   `json:"constructing_conns"`
+	MaxConns                int32   `json:"max_conns"`
+	AcquireCount            int64   `json:"acquire_count"`
+	AcquireDurationMs       float64 `json:"acquire_duration_ms"`
+	EmptyAcquireCount       int64   `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64   `json:"canceled_acquire_count"`
+	NewConnsCount           int64   `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64   `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64   `json:"max_idle_destroy_count"`
+}
+
+// PgxPoolStats snapshots the stats of pool.
+func PgxPoolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		TotalConns:              s.TotalConns(),
+		IdleConns:               s.IdleConns(),
+		AcquiredConns:           s.AcquiredConns(),
+		ConstructingConns:       s.ConstructingConns(),
+		MaxConns:                s.MaxConns(),
+		AcquireCount:            s.AcquireCount(),
+		AcquireDurationMs:       float64(s.AcquireDuration().Microseconds()) / 1000,
+		EmptyAcquireCount:       s.EmptyAcquireCount(),
+		CanceledAcquireCount:    s.CanceledAcquireCount(),
+		NewConnsCount:           s.NewConnsCount(),
+		MaxLifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     s.MaxIdleDestroyCount(),
+	}
+}
+
+// Diagnostics collects what the info endpoint reports. Pools and caches are
+// registered by whoever owns them, as a function returning a JSON-encodable
+// snapshot.
+type Diagnostics struct {
+	startedAt time.Time
+	config    any
+
+	mu     sync.RWMutex
+	pools  map[string]func() any
+	caches map[string]func() any
+}
+
+// New returns diagnostics reporting config, which must already be redacted.
+func New(config any) *Diagnostics {
+	return &Diagnostics{
+		startedAt: time.Now(),
+		config:    config,
+		pools:     map[string]func() any{},
+		caches:    map[string]func() any{},
+	}
+}
+
+func (d *Diagnostics) AddPool(name string, stats func() any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pools[name] = stats
+}
+
+func (d *Diagnostics) AddCache(name string, stats func() any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.caches[name] = stats
+}
+
+// Info takes a snapshot of the process.
+func (d *Diagnostics) Info() Info {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	info := Info{
+		Build:         ReadBuildInfo(),
+		StartedAt:     d.startedAt.UTC(),
+		UptimeSeconds: time.Since(d.startedAt).Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Memory: MemoryInfo{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapInuseBytes: mem.HeapInuse,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+		},
+	}
+	if mem.LastGC != 0 {
+		info.Memory.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339Nano)
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	info.Pools = snapshot(d.pools)
+	info.Caches = snapshot(d.caches)
+	return info
+}
+
+func snapshot(sources map[string]func() any) map[string]any {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make(map[string]any, len(names))
+	for _, name := range names {
+		result[name] = sources[name]()
+	}
+	return result
+}
+
+// ReadBuildInfo reports the version and commit set at build time, falling
+// back to what the Go toolchain embedded.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			info.CommitAt = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// Register adds the diagnostics routes to r: GET info, GET config and the
+// pprof profiles under pprof/.
+func (d *Diagnostics) Register(r gin.IRoutes) {
+	r.GET("/info", func(c *gin.Context) {
+		c.JSON(http.StatusOK, d.Info())
+	})
+	r.GET("/config", func(c *gin.Context) {
+		c.JSON(http.StatusOK, d.config)
+	})
+	r.GET("/pprof/*profile", servePprof)
+	r.POST("/pprof/*profile", servePprof)
+}
+
+// servePprof dispatches to net/http/pprof. Index serves the named profiles
+// but only recognizes them under /debug/pprof/, so the path is rewritten
+// for it, whatever prefix the routes are mounted at.
+func servePprof(c *gin.Context) {
+	switch profile := c.Param("profile"); profile {
+	case "/cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "/profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "/symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "/trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		r := c.Request.Clone(c.Request.Context())
+		r.URL.Path = "/debug/pprof" + profile
+		pprof.Index(c.Writer, r)
+	}
+}
@@ -0,0 +1,81 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func newRouter(d *Diagnostics) *gin.Engine {
+	router := gin.New()
+	d.Register(router.Group("/admin/debug"))
+	return router
+}
+
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestInfo(t *testing.T) {
+	d := New(nil)
+	d.AddPool("primary", func() any { return PoolStats{TotalConns: 3, MaxConns: 10} })
+	d.AddCache("terminals", func() any { return map[string]int{"hits": 5} })
+
+	w := get(newRouter(d), "/admin/debug/info")
+	require.Equal(t, http.StatusOK, w.Code)
+	var info struct {
+		Build struct {
+			GoVersion string `json:"go_version"`
+		} `json:"build"`
+		UptimeSeconds float64                   `json:"uptime_seconds"`
+		Goroutines    int                       `json:"goroutines"`
+		Pools         map[string]map[string]any `json:"pools"`
+		Caches        map[string]map[string]any `json:"caches"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	require.Equal(t, runtime.Version(), info.Build.GoVersion)
+	require.Positive(t, info.Goroutines)
+	require.GreaterOrEqual(t, info.UptimeSeconds, 0.0)
+	require.EqualValues(t, 3, info.Pools["primary"]["total_conns"])
+	require.EqualValues(t, 10, info.Pools["primary"]["max_conns"])
+	require.EqualValues(t, 5, info.Caches["terminals"]["hits"])
+}
+
+func TestReadBuildInfoPrefersLinkerFlags(t *testing.T) {
+	Version, Commit = "v1.2.3", "abc123"
+	t.Cleanup(func() { Version, Commit = "", "" })
+	info := ReadBuildInfo()
+	require.Equal(t, "v1.2.3", info.Version)
+	require.Equal(t, "abc123", info.Commit)
+}
+
+func TestConfig(t *testing.T) {
+	d := New(map[string]any{"httpport": "8080", "jwtsecret": "REDACTED"})
+	w := get(newRouter(d), "/admin/debug/config")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"httpport":"8080","jwtsecret":"REDACTED"}`, w.Body.String())
+}
+
+func TestPprof(t *testing.T) {
+	router := newRouter(New(nil))
+
+	w := get(router, "/admin/debug/pprof/")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "goroutine")
+
+	w = get(router, "/admin/debug/pprof/goroutine?debug=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "goroutine profile")
+
+	w = get(router, "/admin/debug/pprof/cmdline")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = get(router, "/admin/debug/pprof/nosuchprofile")
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
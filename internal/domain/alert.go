@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidAlertRule is wrapped by every AlertRule validation error.
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+// Alert rule kinds.
+const (
+	// AlertRuleStatus holds while a terminal has had Status for at least
+	// Window.
+	AlertRuleStatus = "status"
+	// AlertRuleFlapping holds while a terminal's status changed at least
+	// Count times within the last Window.
+	AlertRuleFlapping = "flapping"
+)
+
+// Alert states, as filtered by GET /alerts.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+const maxAlertWindow = 7 * 24 * time.Hour
+
+// Duration is a time.Duration that is written to and read from JSON as a
+// string such as "5m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// AlertRule is a condition a user wants to be alerted about on one of their
+// favorites, or on all of them if TerminalID is 0. Rules on terminals that
+// are not among the user's favorites never hold. A muted rule raises no new
+// alerts until MutedUntil.
+type AlertRule struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	TerminalID int        `json:"terminal_id,omitempty"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status,omitempty"`
+	Count      int        `json:"count,omitempty"`
+	Window     Duration   `json:"window"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+}
+
+// Validate checks that a status rule names a status and a flapping rule
+// counts at least 2 changes, and that the window is between a second and a
+// week.
+func (r AlertRule) Validate() error {
+	switch r.Kind {
+	case AlertRuleStatus:
+		if r.Status == "" {
+			return fmt.Errorf("%w: a status rule needs a status", ErrInvalidAlertRule)
+		}
+	case AlertRuleFlapping:
+		if r.Count < 2 {
+			return fmt.Errorf("%w: a flapping rule needs a count of at least 2", ErrInvalidAlertRule)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidAlertRule, AlertRuleStatus, AlertRuleFlapping)
+	}
+	if window := time.Duration(r.Window); window < time.Second || window > maxAlertWindow {
+		return fmt.Errorf("%w: window must be between 1s and %s", ErrInvalidAlertRule, maxAlertWindow)
+	}
+	return nil
+}
+
+// Muted reports whether the rule is muted at now.
+func (r AlertRule) Muted(now time.Time) bool {
+	return r.MutedUntil != nil && now.Before(*r.MutedUntil)
+}
+
+// Holds reports whether the rule holds for terminal at now. changes are the
+// times the terminal's status changed, in any order, and must go back at
+// least Window. A status whose change time is unknown is taken to have
+// changed at now.
+func (r AlertRule) Holds(terminal Terminal, changes []time.Time, now time.Time) bool {
+	since := now.Add(-time.Duration(r.Window))
+	switch r.Kind {
+	case AlertRuleStatus:
+		changedAt := now
+		if terminal.StatusChangedAt != nil {
+			changedAt = *terminal.StatusChangedAt
+		}
+		return terminal.Status == r.Status && !changedAt.After(since)
+	case AlertRuleFlapping:
+		n := 0
+		for _, changed := range changes {
+			if changed.After(since) {
+				n++
+			}
+		}
+		return n >= r.Count
+	}
+	return false
+}
+
+// Alert is one firing, or since resolved, occurrence of a rule holding for a
+// terminal.
+type Alert struct {
+	ID           int        `json:"id"`
+	Rule         AlertRule  `json:"rule"`
+	TerminalID   int        `json:"terminal_id"`
+	TerminalName string     `json:"terminal_name"`
+	FiredAt      time.Time  `json:"fired_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	AckedAt      *time.Time `json:"acked_at,omitempty"`
+}
+
+// State is AlertFiring or AlertResolved.
+func (a Alert) State() string {
+	if a.ResolvedAt != nil {
+		return AlertResolved
+	}
+	return AlertFiring
+}
+
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alert Alert
+	return json.Marshal(struct {
+		alert
+		State string `json:"state"`
+	}{alert(a), a.State()})
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAlertRuleValidate(t *testing.T) {
+	require.NoError(t, AlertRule{Kind: AlertRuleStatus, Status: "offline", Window: Duration(5 * time.Minute)}.Validate())
+	require.NoError(t, AlertRule{Kind: AlertRuleFlapping, Count: 3, Window: Duration(10 * time.Minute)}.Validate())
+	require.ErrorIs(t, AlertRule{Kind: "down", Window: Duration(time.Minute)}.Validate(), ErrInvalidAlertRule)
+	require.ErrorIs(t, AlertRule{Kind: AlertRuleStatus, Window: Duration(time.Minute)}.Validate(), ErrInvalidAlertRule)
+	require.ErrorIs(t, AlertRule{Kind: AlertRuleFlapping, Count: 1, Window: Duration(time.Minute)}.Validate(), ErrInvalidAlertRule)
+	require.ErrorIs(t, AlertRule{Kind: AlertRuleStatus, Status: "offline"}.Validate(), ErrInvalidAlertRule)
+	require.ErrorIs(t, AlertRule{Kind: AlertRuleStatus, Status: "offline", Window: Duration(8 * 24 * time.Hour)}.Validate(), ErrInvalidAlertRule)
+}
+
+func TestAlertRuleHolds(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	offline := AlertRule{Kind: AlertRuleStatus, Status: "offline", Window: Duration(5 * time.Minute)}
+	require.True(t, offline.Holds(Terminal{Status: "offline", StatusChangedAt: ago(6 * time.Minute)}, nil, now))
+	require.True(t, offline.Holds(Terminal{Status: "offline", StatusChangedAt: ago(5 * time.Minute)}, nil, now))
+	require.False(t, offline.Holds(Terminal{Status: "offline"}, nil, now), "unknown change time")
+	require.True(t, AlertRule{Kind: AlertRuleStatus, Status: "offline"}.Holds(Terminal{Status: "offline"}, nil, now))
+	require.False(t, offline.Holds(Terminal{Status: "offline", StatusChangedAt: ago(4 * time.Minute)}, nil, now))
+	require.False(t, offline.Holds(Terminal{Status: "active", StatusChangedAt: ago(time.Hour)}, nil, now))
+
+	flapping := AlertRule{Kind: AlertRuleFlapping, Count: 3, Window: Duration(10 * time.Minute)}
+	changes := []time.Time{*ago(time.Minute), *ago(11 * time.Minute), *ago(9 * time.Minute), *ago(2 * time.Minute)}
+	require.True(t, flapping.Holds(Terminal{}, changes, now))
+	require.False(t, flapping.Holds(Terminal{}, changes[:3], now))
+}
+
+func TestAlertJSON(t *testing.T) {
+	var rule AlertRule
+	require.NoError(t, json.Unmarshal([]byte(`{"kind":"status","status":"offline","window":"5m"}`), &rule))
+	require.Equal(t, Duration(5*time.Minute), rule.Window)
+	require.Error(t, json.Unmarshal([]byte(`{"window":300}`), &rule))
+
+	fired := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(Alert{ID: 1, Rule: rule, TerminalID: 2, TerminalName: "T-112", FiredAt: fired})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"rule":{"id":0,"kind":"status","status":"offline","window":"5m0s"},`+
+		`"terminal_id":2,"terminal_name":"T-112","fired_at":"2024-05-01T12:00:00Z","state":"firing"}`, string(data))
+}
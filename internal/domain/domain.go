@@ -1,19 +1,131 @@
 package domain
 
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Role     string `json:"-"`
 }
 
 type Terminal struct {
 	ID     int    `json:"id,omitempty"`
 	Name   string `json:"name"`
 	Status string `json:"status"`
+	// StatusChangedAt is when Status last changed, nil if that is unknown.
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	// Tags are sorted by key, then value.
+	Tags []Tag `json:"tags,omitempty"`
+	// Location is nil while it is not known.
+	Location *GeoPoint `json:"location,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	// SiteID is the site the terminal is assigned to, 0 if none.
+	SiteID int `json:"site_id,omitempty"`
 }
 type FakeTerminal struct {
 	ID         int    `json:"id,omitempty"`
 	Name       string `json:"name"`
 	Status     string `json:"status"`
 	IsFavorite bool   `json:"is_favorite"`
+	Tags       []Tag  `json:"tags,omitempty"`
+	// StatusChangedAt is when Status last changed, nil if that is unknown.
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	// Alias, Color and Note come from the user's FavoriteNote.
+	Alias string `json:"alias,omitempty"`
+	Color string `json:"color,omitempty"`
+	Note  string `json:"note,omitempty"`
+	// Location is nil while it is not known. Distance is how far the
+	// terminal is from the point the listing was searched near, in meters.
+	Location *GeoPoint `json:"location,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	Distance *float64  `json:"distance_m,omitempty"`
+	SiteID   int       `json:"site_id,omitempty"`
+}
+
+// DeletedRecord is a soft-deleted terminal or user as shown to admins.
+type DeletedRecord struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Audited actions.
+const (
+	AuditSignUp               = "user.sign_up"
+	AuditSignIn               = "user.sign_in"
+	AuditFavoriteAdd          = "favorite.add"
+	AuditFavoriteRemove       = "favorite.remove"
+	AuditTerminalDelete       = "admin.terminal.delete"
+	AuditTerminalRestore      = "admin.terminal.restore"
+	AuditTerminalPurge        = "admin.terminal.purge"
+	AuditTerminalTag          = "admin.terminal.tag"
+	AuditTerminalUntag        = "admin.terminal.untag"
+	AuditTerminalLocate       = "admin.terminal.locate"
+	AuditTerminalSite         = "admin.terminal.site"
+	AuditSiteCreate           = "admin.site.create"
+	AuditSiteUpdate           = "admin.site.update"
+	AuditSiteDelete           = "admin.site.delete"
+	AuditUserDelete           = "admin.user.delete"
+	AuditUserRestore          = "admin.user.restore"
+	AuditUserPurge            = "admin.user.purge"
+	AuditWebhookCreate        = "admin.webhook.create"
+	AuditWebhookDelete        = "admin.webhook.delete"
+	AuditWebhookRedeliver     = "admin.webhook.redeliver"
+	AuditChannelCreate        = "admin.notification_channel.create"
+	AuditChannelUpdate        = "admin.notification_channel.update"
+	AuditChannelDelete        = "admin.notification_channel.delete"
+	AuditHeartbeatTokenIssue  = "admin.terminal.heartbeat_token.issue"
+	AuditHeartbeatTokenRevoke = "admin.terminal.heartbeat_token.revoke"
+
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+
+	AuditTargetUser     = "user"
+	AuditTargetTerminal = "terminal"
+	AuditTargetSite     = "site"
+	AuditTargetWebhook  = "webhook"
+	// AuditTargetNotificationChannel is a team notification channel.
+	AuditTargetNotificationChannel = "notification_channel"
+)
+
+// AuditEntry is one record of the append-only audit log. ActorID is 0 when
+// the actor is not signed in; ActorName then holds the name they gave, if
+// any. Before and After are JSON snapshots of the changed values.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Action     string          `json:"action"`
+	Outcome    string          `json:"outcome"`
+	ActorID    int             `json:"actor_id,omitempty"`
+	ActorName  string          `json:"actor_name,omitempty"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything; From is
+// inclusive and To exclusive. Entries are returned newest first.
+type AuditFilter struct {
+	Action     string
+	Outcome    string
+	ActorID    int
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
 }
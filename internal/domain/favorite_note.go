@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxAliasLength = 64
+	maxNoteLength  = 1000
+)
+
+// ErrInvalidFavoriteNote is wrapped by every FavoriteNote validation error.
+var ErrInvalidFavoriteNote = errors.New("invalid favorite note")
+
+// FavoriteNote is what a user privately attaches to one of their favorites:
+// an alias shown instead of or next to the name, a colour marker and a
+// free-text note. It lives and dies with the favorite.
+type FavoriteNote struct {
+	Alias string `json:"alias"`
+	Color string `json:"color"`
+	Note  string `json:"note"`
+}
+
+// IsZero reports whether the note carries nothing, in which case it is not
+// stored at all.
+func (n FavoriteNote) IsZero() bool {
+	return n == FavoriteNote{}
+}
+
+// Normalize trims the alias and lower-cases the colour.
+func (n FavoriteNote) Normalize() FavoriteNote {
+	n.Alias = strings.TrimSpace(n.Alias)
+	n.Color = strings.ToLower(strings.TrimSpace(n.Color))
+	return n
+}
+
+// Validate checks that the alias has at most 64 characters and no line
+// breaks, the note at most 1000 characters and the colour is empty or a hex
+// colour such as #f80 or #ff8800.
+func (n FavoriteNote) Validate() error {
+	if utf8.RuneCountInString(n.Alias) > maxAliasLength || strings.ContainsAny(n.Alias, "\r\n") {
+		return fmt.Errorf("%w: alias must be a single line of at most %d characters", ErrInvalidFavoriteNote, maxAliasLength)
+	}
+	if utf8.RuneCountInString(n.Note) > maxNoteLength {
+		return fmt.Errorf("%w: note must be at most %d characters", ErrInvalidFavoriteNote, maxNoteLength)
+	}
+	if n.Color != "" && !validHexColor(n.Color) {
+		return fmt.Errorf("%w: color %q must be a hex colour such as #f80 or #ff8800", ErrInvalidFavoriteNote, n.Color)
+	}
+	return nil
+}
+
+func validHexColor(s string) bool {
+	if len(s) != 4 && len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether the alias or note contains query, ignoring case.
+// query must already be lower-cased.
+func (n FavoriteNote) Contains(query string) bool {
+	return strings.Contains(strings.ToLower(n.Alias), query) || strings.Contains(strings.ToLower(n.Note), query)
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestFavoriteNoteValidate(t *testing.T) {
+	for _, note := range []FavoriteNote{
+		{},
+		{Alias: "lobby kiosk", Color: "#f80", Note: "call Bob if down"},
+		{Color: "#FF8800"},
+		{Alias: strings.Repeat("ж", 64), Note: strings.Repeat("n", 1000)},
+	} {
+		require.NoError(t, note.Validate(), note)
+	}
+	for _, note := range []FavoriteNote{
+		{Alias: strings.Repeat("a", 65)},
+		{Alias: "two\nlines"},
+		{Note: strings.Repeat("n", 1001)},
+		{Color: "red"},
+		{Color: "#ff88"},
+		{Color: "#gg8800"},
+	} {
+		require.ErrorIs(t, note.Validate(), ErrInvalidFavoriteNote, note)
+	}
+}
+
+func TestFavoriteNoteNormalize(t *testing.T) {
+	note := FavoriteNote{Alias: "  lobby kiosk ", Color: " #FF8800", Note: " as typed "}.Normalize()
+	require.Equal(t, FavoriteNote{Alias: "lobby kiosk", Color: "#ff8800", Note: " as typed "}, note)
+	require.True(t, FavoriteNote{}.IsZero())
+	require.False(t, note.IsZero())
+}
+
+func TestFavoriteNoteContains(t *testing.T) {
+	note := FavoriteNote{Alias: "Lobby Kiosk", Note: "Call Bob if down"}
+	require.True(t, note.Contains("kiosk"))
+	require.True(t, note.Contains("bob"))
+	require.False(t, note.Contains("atm"))
+}
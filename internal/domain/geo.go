@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLocation is wrapped by every GeoPoint and TerminalLocation
+// validation and parsing error.
+var ErrInvalidLocation = errors.New("invalid location")
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+const maxAddressLength = 255
+
+// GeoPoint is a position in WGS 84 degrees.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ParseGeoPoint parses "lat,lon", such as "52.52,13.405".
+func ParseGeoPoint(s string) (GeoPoint, error) {
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return GeoPoint{}, fmt.Errorf("%w: %q must be lat,lon", ErrInvalidLocation, s)
+	}
+	var p GeoPoint
+	var err error
+	if p.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return GeoPoint{}, fmt.Errorf("%w: %q must be lat,lon", ErrInvalidLocation, s)
+	}
+	if p.Lon, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil {
+		return GeoPoint{}, fmt.Errorf("%w: %q must be lat,lon", ErrInvalidLocation, s)
+	}
+	return p, p.Validate()
+}
+
+// Validate checks that the latitude is within ±90 and the longitude within
+// ±180 degrees.
+func (p GeoPoint) Validate() error {
+	if !(p.Lat >= -90 && p.Lat <= 90) {
+		return fmt.Errorf("%w: latitude must be between -90 and 90", ErrInvalidLocation)
+	}
+	if !(p.Lon >= -180 && p.Lon <= 180) {
+		return fmt.Errorf("%w: longitude must be between -180 and 180", ErrInvalidLocation)
+	}
+	return nil
+}
+
+// DistanceTo returns the great-circle distance to q in meters, using the
+// haversine formula on a spherical Earth. It is off by at most about 0.5%,
+// which is plenty for finding nearby terminals.
+func (p GeoPoint) DistanceTo(q GeoPoint) float64 {
+	lat1, lat2 := radians(p.Lat), radians(q.Lat)
+	dLat, dLon := lat2-lat1, radians(q.Lon-p.Lon)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// TerminalLocation is where a terminal is. A nil Location clears it.
+type TerminalLocation struct {
+	Location *GeoPoint `json:"location"`
+	Address  string    `json:"address"`
+}
+
+// Validate checks the point, if any, and that the address fits.
+func (l TerminalLocation) Validate() error {
+	if l.Location != nil {
+		if err := l.Location.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(l.Address) > maxAddressLength {
+		return fmt.Errorf("%w: address must be at most %d characters", ErrInvalidLocation, maxAddressLength)
+	}
+	return nil
+}
+
+// FeatureCollection is a GeoJSON (RFC 7946) feature collection of
+// terminals.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a terminal as a GeoJSON point feature.
+type Feature struct {
+	Type       string            `json:"type"`
+	ID         int               `json:"id"`
+	Geometry   PointGeometry     `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// PointGeometry holds a position as longitude, latitude, in that order.
+type PointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type FeatureProperties struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	IsFavorite bool     `json:"is_favorite"`
+	Address    string   `json:"address,omitempty"`
+	Alias      string   `json:"alias,omitempty"`
+	Color      string   `json:"color,omitempty"`
+	Tags       []Tag    `json:"tags,omitempty"`
+	Distance   *float64 `json:"distance_m,omitempty"`
+}
+
+// NewFeatureCollection turns the terminals that have a location into
+// features, keeping their order.
+func NewFeatureCollection(terminals []FakeTerminal) FeatureCollection {
+	collection := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(terminals))}
+	for _, terminal := range terminals {
+		if terminal.Location == nil {
+			continue
+		}
+		collection.Features = append(collection.Features, Feature{
+			Type: "Feature",
+			ID:   terminal.ID,
+			Geometry: PointGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{terminal.Location.Lon, terminal.Location.Lat},
+			},
+			Properties: FeatureProperties{
+				Name:       terminal.Name,
+				Status:     terminal.Status,
+				IsFavorite: terminal.IsFavorite,
+				Address:    terminal.Address,
+				Alias:      terminal.Alias,
+				Color:      terminal.Color,
+				Tags:       terminal.Tags,
+				Distance:   terminal.Distance,
+			},
+		})
+	}
+	return collection
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestParseGeoPoint(t *testing.T) {
+	p, err := ParseGeoPoint("52.52, 13.405")
+	require.NoError(t, err)
+	require.Equal(t, GeoPoint{Lat: 52.52, Lon: 13.405}, p)
+
+	for _, raw := range []string{"", "52.52", "52.52,east", "91,0", "0,-180.5", "NaN,0"} {
+		_, err = ParseGeoPoint(raw)
+		require.ErrorIs(t, err, ErrInvalidLocation, raw)
+	}
+}
+
+func TestDistanceTo(t *testing.T) {
+	berlin := GeoPoint{Lat: 52.5200, Lon: 13.4050}
+	paris := GeoPoint{Lat: 48.8566, Lon: 2.3522}
+	require.InDelta(t, 877_500, berlin.DistanceTo(paris), 1_000)
+	require.InDelta(t, berlin.DistanceTo(paris), paris.DistanceTo(berlin), 1e-6)
+	require.Zero(t, berlin.DistanceTo(berlin))
+
+	// One degree of latitude is about 111.2 km everywhere.
+	require.InDelta(t, 111_195, GeoPoint{Lat: 0, Lon: 179.5}.DistanceTo(GeoPoint{Lat: 0, Lon: -179.5}), 10)
+}
+
+func TestTerminalLocationValidate(t *testing.T) {
+	require.NoError(t, TerminalLocation{}.Validate())
+	require.NoError(t, TerminalLocation{Location: &GeoPoint{Lat: -33.86, Lon: 151.21}, Address: "Sydney"}.Validate())
+	require.ErrorIs(t, TerminalLocation{Location: &GeoPoint{Lat: 100}}.Validate(), ErrInvalidLocation)
+	require.ErrorIs(t, TerminalLocation{Address: strings.Repeat("a", 256)}.Validate(), ErrInvalidLocation)
+}
+
+func TestNewFeatureCollection(t *testing.T) {
+	distance := 120.0
+	collection := NewFeatureCollection([]FakeTerminal{
+		{ID: 2, Name: "T-2", Status: "active", IsFavorite: true, Location: &GeoPoint{Lat: 52.52, Lon: 13.405}, Address: "Alexanderplatz", Distance: &distance},
+		{ID: 3, Name: "T-3", Status: "offline"},
+	})
+	data, err := json.Marshal(collection)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"FeatureCollection","features":[{"type":"Feature","id":2,
+		"geometry":{"type":"Point","coordinates":[13.405,52.52]},
+		"properties":{"name":"T-2","status":"active","is_favorite":true,"address":"Alexanderplatz","distance_m":120}}]}`, string(data))
+
+	data, err = json.Marshal(NewFeatureCollection(nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"FeatureCollection","features":[]}`, string(data))
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrInvalidHeartbeat is wrapped by every Heartbeat validation error.
+var ErrInvalidHeartbeat = errors.New("invalid heartbeat")
+
+// Terminal statuses derived from heartbeats. A terminal that stops
+// reporting goes from TerminalActive to TerminalOffline and back once it
+// reports again; other statuses are left as they were set.
+const (
+	TerminalActive  = "active"
+	TerminalOffline = "offline"
+)
+
+// StatusSourceHeartbeat is the source recorded with status changes derived
+// from heartbeats.
+const StatusSourceHeartbeat = "heartbeat"
+
+// Heartbeat is what a terminal reports with each heartbeat. Both fields are
+// optional; empty ones keep the last reported value.
+type Heartbeat struct {
+	Firmware string `json:"firmware"`
+	IP       string `json:"ip"`
+}
+
+// Validate checks that the firmware fits and the IP, if any, is an IPv4 or
+// IPv6 address.
+func (h Heartbeat) Validate() error {
+	if len(h.Firmware) > 255 {
+		return fmt.Errorf("%w: firmware must be at most 255 characters", ErrInvalidHeartbeat)
+	}
+	if h.IP != "" && net.ParseIP(h.IP) == nil {
+		return fmt.Errorf("%w: ip must be an IP address", ErrInvalidHeartbeat)
+	}
+	return nil
+}
+
+// TerminalHeartbeat is the last heartbeat of a terminal that has a token,
+// as shown to admins. LastSeenAt is nil until its first heartbeat.
+type TerminalHeartbeat struct {
+	TerminalID   int        `json:"terminal_id"`
+	TerminalName string     `json:"terminal_name"`
+	Status       string     `json:"status"`
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty"`
+	Firmware     string     `json:"firmware,omitempty"`
+	IP           string     `json:"ip,omitempty"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHeartbeatValidate(t *testing.T) {
+	for _, heartbeat := range []Heartbeat{{}, {Firmware: "1.4.2", IP: "10.0.0.7"}, {IP: "2001:db8::1"}} {
+		require.NoError(t, heartbeat.Validate(), heartbeat)
+	}
+	for _, heartbeat := range []Heartbeat{{IP: "10.0.0"}, {IP: "terminal.local"}, {Firmware: strings.Repeat("x", 256)}} {
+		require.ErrorIs(t, heartbeat.Validate(), ErrInvalidHeartbeat, heartbeat)
+	}
+}
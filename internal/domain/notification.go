@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrInvalidNotificationChannel is wrapped by every NotificationChannel
+// validation error.
+var ErrInvalidNotificationChannel = errors.New("invalid notification channel")
+
+// ErrNotificationFailed is wrapped when a channel could not be sent to.
+var ErrNotificationFailed = errors.New("notification failed")
+
+// Notification channel kinds. Target is an email address, a Slack-compatible
+// incoming webhook URL or a Telegram chat ID respectively.
+const (
+	ChannelEmail    = "email"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+)
+
+// DefaultNotificationTemplate renders a Notification when its channel has no
+// template of its own.
+const DefaultNotificationTemplate = `{{if eq .State "firing"}}{{.TerminalName}} has been {{.Status}} for {{.Duration}}` +
+	`{{else}}{{.TerminalName}} is {{.Status}} again after {{.Duration}}{{end}}{{if .Link}}
+{{.Link}}{{end}}`
+
+const maxNotificationTemplate = 4096
+
+// NotificationChannel is where a user's alerts are sent. A channel with a
+// zero UserID is a team channel, which gets the alerts of every user in
+// Members. Template is a text/template executed with a Notification.
+type NotificationChannel struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target"`
+	Template  string    `json:"template,omitempty"`
+	Members   []int     `json:"members,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the name, that the target suits the kind, that only team
+// channels have members and that the template renders.
+func (c NotificationChannel) Validate() error {
+	if strings.TrimSpace(c.Name) == "" || len(c.Name) > 255 {
+		return fmt.Errorf("%w: name must be 1 to 255 characters", ErrInvalidNotificationChannel)
+	}
+	switch c.Kind {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(c.Target); err != nil {
+			return fmt.Errorf("%w: target must be an email address", ErrInvalidNotificationChannel)
+		}
+	case ChannelSlack:
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: target must be an absolute http or https URL", ErrInvalidNotificationChannel)
+		}
+	case ChannelTelegram:
+		if c.Target == "" || strings.ContainsAny(c.Target, " \t\r\n/") {
+			return fmt.Errorf("%w: target must be a chat ID", ErrInvalidNotificationChannel)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q, %q or %q", ErrInvalidNotificationChannel, ChannelEmail, ChannelSlack, ChannelTelegram)
+	}
+	if c.UserID != 0 && len(c.Members) > 0 {
+		return fmt.Errorf("%w: only team channels have members", ErrInvalidNotificationChannel)
+	}
+	for _, member := range c.Members {
+		if member <= 0 {
+			return fmt.Errorf("%w: members must be user IDs", ErrInvalidNotificationChannel)
+		}
+	}
+	if len(c.Template) > maxNotificationTemplate {
+		return fmt.Errorf("%w: template must be at most %d bytes", ErrInvalidNotificationChannel, maxNotificationTemplate)
+	}
+	tmpl, err := c.parse()
+	if err == nil {
+		err = tmpl.Execute(io.Discard, SampleNotification)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: template: %v", ErrInvalidNotificationChannel, err)
+	}
+	return nil
+}
+
+// Render executes the channel's template, or the default one, with n.
+func (c NotificationChannel) Render(n Notification) (string, error) {
+	tmpl, err := c.parse()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, n)
+	return b.String(), err
+}
+
+func (c NotificationChannel) parse() (*template.Template, error) {
+	text := c.Template
+	if text == "" {
+		text = DefaultNotificationTemplate
+	}
+	return template.New("notification").Parse(text)
+}
+
+// Notification is what a template is executed with: an alert on a terminal
+// that fired, or resolved, at At. For a firing alert Duration is how long
+// the terminal has had Status; for a resolved one it is how long the alert
+// fired. Link points at the terminal in the dashboard, if one is configured.
+type Notification struct {
+	State        string
+	Rule         string
+	TerminalID   int
+	TerminalName string
+	Status       string
+	Duration     time.Duration
+	Link         string
+	At           time.Time
+}
+
+// Subject is a one-line summary, used as the email subject.
+func (n Notification) Subject() string {
+	return fmt.Sprintf("[%s] %s is %s", n.State, n.TerminalName, n.Status)
+}
+
+// SampleNotification checks templates and is sent by channel tests.
+var SampleNotification = Notification{
+	State:        AlertFiring,
+	Rule:         AlertRuleStatus,
+	TerminalID:   1,
+	TerminalName: "Sample terminal",
+	Status:       "offline",
+	Duration:     5 * time.Minute,
+	Link:         "https://example.com/terminals/1",
+	At:           time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+}
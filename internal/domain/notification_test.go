@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNotificationChannelValidate(t *testing.T) {
+	for _, channel := range []NotificationChannel{
+		{UserID: 1, Name: "me", Kind: ChannelEmail, Target: "Ops <ops@example.com>"},
+		{UserID: 1, Name: "ops", Kind: ChannelSlack, Target: "https://hooks.slack.com/services/T0/B0/x", Template: "{{.TerminalName}}"},
+		{Name: "team", Kind: ChannelTelegram, Target: "-1001234567890", Members: []int{1, 2}},
+	} {
+		require.NoError(t, channel.Validate(), channel)
+	}
+	for _, channel := range []NotificationChannel{
+		{UserID: 1, Kind: ChannelEmail, Target: "ops@example.com"},
+		{UserID: 1, Name: "me", Kind: "sms", Target: "+100"},
+		{UserID: 1, Name: "me", Kind: ChannelEmail, Target: "ops"},
+		{UserID: 1, Name: "me", Kind: ChannelSlack, Target: "hooks.slack.com"},
+		{UserID: 1, Name: "me", Kind: ChannelTelegram, Target: "12 34"},
+		{UserID: 1, Name: "me", Kind: ChannelTelegram, Target: "1", Members: []int{2}},
+		{Name: "team", Kind: ChannelTelegram, Target: "1", Members: []int{0}},
+		{UserID: 1, Name: "me", Kind: ChannelTelegram, Target: "1", Template: "{{.Terminal"},
+		{UserID: 1, Name: "me", Kind: ChannelTelegram, Target: "1", Template: "{{.Terminal}}"},
+	} {
+		require.ErrorIs(t, channel.Validate(), ErrInvalidNotificationChannel, channel)
+	}
+}
+
+func TestNotificationChannelRender(t *testing.T) {
+	firing := Notification{State: AlertFiring, TerminalName: "T-1", Status: "offline", Duration: 5 * time.Minute, Link: "https://example.com/terminals/1"}
+	text, err := NotificationChannel{}.Render(firing)
+	require.NoError(t, err)
+	require.Equal(t, "T-1 has been offline for 5m0s\nhttps://example.com/terminals/1", text)
+
+	resolved := Notification{State: AlertResolved, TerminalName: "T-1", Status: "active", Duration: time.Hour}
+	text, err = NotificationChannel{}.Render(resolved)
+	require.NoError(t, err)
+	require.Equal(t, "T-1 is active again after 1h0m0s", text)
+
+	text, err = NotificationChannel{Template: "{{.State}}: {{.TerminalName}} ({{.TerminalID}})"}.Render(firing)
+	require.NoError(t, err)
+	require.Equal(t, "firing: T-1 (0)", text)
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidSite is wrapped by every Site validation error and by moves
+	// that would put a site below itself.
+	ErrInvalidSite = errors.New("invalid site")
+	// ErrSiteNotEmpty is returned when deleting a site that still has
+	// sub-sites or terminals.
+	ErrSiteNotEmpty = errors.New("site has sub-sites or terminals")
+)
+
+const maxSiteNameLength = 255
+
+// Site is a node of the site hierarchy, such as a region, a site or a floor.
+// ParentID is 0 for a top-level site.
+type Site struct {
+	ID       int    `json:"id"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+}
+
+// Validate checks that the name is set and fits and that the site is not
+// its own parent.
+func (s Site) Validate() error {
+	if s.Name == "" || len(s.Name) > maxSiteNameLength {
+		return fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidSite, maxSiteNameLength)
+	}
+	if s.ParentID < 0 || (s.ID != 0 && s.ParentID == s.ID) {
+		return fmt.Errorf("%w: a site cannot be its own parent", ErrInvalidSite)
+	}
+	return nil
+}
+
+// Normalize trims the name.
+func (s Site) Normalize() Site {
+	s.Name = strings.TrimSpace(s.Name)
+	return s
+}
+
+// SiteNode is a site with its sub-sites and the number of terminals in it
+// or below it, in total and by status.
+type SiteNode struct {
+	Site
+	Terminals    int            `json:"terminals"`
+	StatusCounts map[string]int `json:"status_counts"`
+	Children     []SiteNode     `json:"children"`
+}
+
+// NewSiteTree arranges sites into trees, top-level sites first, with the
+// children of each site ordered by name, then ID. terminals are counted in
+// their site and every site above it.
+func NewSiteTree(sites []Site, terminals []Terminal) []SiteNode {
+	children := make(map[int][]Site)
+	for _, site := range sites {
+		children[site.ParentID] = append(children[site.ParentID], site)
+	}
+	direct := make(map[int]map[string]int)
+	for _, terminal := range terminals {
+		if terminal.SiteID == 0 {
+			continue
+		}
+		if direct[terminal.SiteID] == nil {
+			direct[terminal.SiteID] = make(map[string]int)
+		}
+		direct[terminal.SiteID][terminal.Status]++
+	}
+
+	var build func(parentID int) []SiteNode
+	build = func(parentID int) []SiteNode {
+		sites := children[parentID]
+		sort.SliceStable(sites, func(i, j int) bool {
+			if sites[i].Name != sites[j].Name {
+				return sites[i].Name < sites[j].Name
+			}
+			return sites[i].ID < sites[j].ID
+		})
+		nodes := make([]SiteNode, 0, len(sites))
+		for _, site := range sites {
+			node := SiteNode{Site: site, StatusCounts: make(map[string]int), Children: build(site.ID)}
+			for status, n := range direct[site.ID] {
+				node.StatusCounts[status] += n
+				node.Terminals += n
+			}
+			for _, child := range node.Children {
+				for status, n := range child.StatusCounts {
+					node.StatusCounts[status] += n
+				}
+				node.Terminals += child.Terminals
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// FindSiteNode looks the site with id up in trees.
+func FindSiteNode(trees []SiteNode, id int) (SiteNode, bool) {
+	for _, node := range trees {
+		if node.ID == id {
+			return node, true
+		}
+		if found, ok := FindSiteNode(node.Children, id); ok {
+			return found, true
+		}
+	}
+	return SiteNode{}, false
+}
+
+// SiteSubtree returns the IDs of the site with id and of every site below
+// it. It is empty if there is no such site.
+func SiteSubtree(sites []Site, id int) map[int]bool {
+	children := make(map[int][]int)
+	subtree := make(map[int]bool)
+	for _, site := range sites {
+		children[site.ParentID] = append(children[site.ParentID], site.ID)
+		if site.ID == id {
+			subtree[id] = true
+		}
+	}
+	queue := []int{}
+	if subtree[id] {
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, child := range children[next] {
+			if !subtree[child] {
+				subtree[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return subtree
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSiteValidate(t *testing.T) {
+	require.NoError(t, Site{Name: "North"}.Validate())
+	require.NoError(t, Site{ID: 2, ParentID: 1, Name: "Site B"}.Validate())
+	for _, site := range []Site{
+		{},
+		{Name: strings.Repeat("a", 256)},
+		{ID: 2, ParentID: 2, Name: "loop"},
+		{ParentID: -1, Name: "negative"},
+	} {
+		require.ErrorIs(t, site.Validate(), ErrInvalidSite, site.Name)
+	}
+	require.Equal(t, "Site B", Site{Name: "  Site B "}.Normalize().Name)
+}
+
+var siteTestSites = []Site{
+	{ID: 1, Name: "North"},
+	{ID: 2, ParentID: 1, Name: "Site B"},
+	{ID: 3, ParentID: 1, Name: "Site A"},
+	{ID: 4, ParentID: 2, Name: "Floor 1"},
+	{ID: 5, Name: "East"},
+}
+
+func TestNewSiteTree(t *testing.T) {
+	trees := NewSiteTree(siteTestSites, []Terminal{
+		{ID: 1, Status: "offline", SiteID: 4},
+		{ID: 2, Status: "offline", SiteID: 4},
+		{ID: 3, Status: "active", SiteID: 2},
+		{ID: 4, Status: "offline", SiteID: 3},
+		{ID: 5, Status: "active"},
+	})
+	data, err := json.Marshal(trees)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"id":5,"name":"East","terminals":0,"status_counts":{},"children":[]},
+		{"id":1,"name":"North","terminals":4,"status_counts":{"active":1,"offline":3},"children":[
+			{"id":3,"parent_id":1,"name":"Site A","terminals":1,"status_counts":{"offline":1},"children":[]},
+			{"id":2,"parent_id":1,"name":"Site B","terminals":3,"status_counts":{"active":1,"offline":2},"children":[
+				{"id":4,"parent_id":2,"name":"Floor 1","terminals":2,"status_counts":{"offline":2},"children":[]}]}]}]`, string(data))
+
+	node, ok := FindSiteNode(trees, 2)
+	require.True(t, ok)
+	require.Equal(t, 2, node.StatusCounts["offline"])
+	_, ok = FindSiteNode(trees, 9)
+	require.False(t, ok)
+}
+
+func TestSiteSubtree(t *testing.T) {
+	require.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true}, SiteSubtree(siteTestSites, 1))
+	require.Equal(t, map[int]bool{4: true}, SiteSubtree(siteTestSites, 4))
+	require.Empty(t, SiteSubtree(siteTestSites, 9))
+}
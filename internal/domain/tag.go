@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const maxTagLength = 63
+
+// ErrInvalidTag is wrapped by every tag and tag selector parsing error.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Tag is a key=value label on a terminal, such as type=atm. A terminal can
+// carry several tags with the same key.
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (t Tag) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// Validate checks that key and value are 1 to 63 letters, digits, '-', '_'
+// or '.', starting with a letter or digit.
+func (t Tag) Validate() error {
+	if !validTagPart(t.Key) {
+		return fmt.Errorf("%w: key %q must be 1-%d letters, digits, '-', '_' or '.', starting with a letter or digit", ErrInvalidTag, t.Key, maxTagLength)
+	}
+	if !validTagPart(t.Value) {
+		return fmt.Errorf("%w: value %q must be 1-%d letters, digits, '-', '_' or '.', starting with a letter or digit", ErrInvalidTag, t.Value, maxTagLength)
+	}
+	return nil
+}
+
+func validTagPart(s string) bool {
+	if s == "" || len(s) > maxTagLength {
+		return false
+	}
+	for i, r := range s {
+		alnum := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if !alnum && (i == 0 || !strings.ContainsRune("-_.", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Tag selector operators.
+const (
+	SelectorEquals    = "="
+	SelectorNotEquals = "!="
+	SelectorExists    = "exists"
+	SelectorNotExists = "!exists"
+)
+
+// TagSelector matches terminals by their tags.
+type TagSelector struct {
+	Op  string
+	Tag Tag
+}
+
+// ParseTagSelector parses "key=value" (or "key==value"), "key!=value",
+// "key" (the terminal has some tag with that key) and "!key" (it has none).
+func ParseTagSelector(s string) (TagSelector, error) {
+	var sel TagSelector
+	switch {
+	case strings.HasPrefix(s, "!") && !strings.Contains(s, "="):
+		sel = TagSelector{Op: SelectorNotExists, Tag: Tag{Key: s[1:]}}
+	case strings.Contains(s, "!="):
+		key, value, _ := strings.Cut(s, "!=")
+		sel = TagSelector{Op: SelectorNotEquals, Tag: Tag{Key: key, Value: value}}
+	case strings.Contains(s, "="):
+		key, value, _ := strings.Cut(s, "=")
+		sel = TagSelector{Op: SelectorEquals, Tag: Tag{Key: key, Value: strings.TrimPrefix(value, "=")}}
+	default:
+		sel = TagSelector{Op: SelectorExists, Tag: Tag{Key: s}}
+	}
+	if sel.Op == SelectorExists || sel.Op == SelectorNotExists {
+		if !validTagPart(sel.Tag.Key) {
+			return sel, fmt.Errorf("%w: selector %q has an invalid key", ErrInvalidTag, s)
+		}
+		return sel, nil
+	}
+	if err := sel.Tag.Validate(); err != nil {
+		return sel, fmt.Errorf("selector %q: %w", s, err)
+	}
+	return sel, nil
+}
+
+func (s TagSelector) String() string {
+	switch s.Op {
+	case SelectorExists:
+		return s.Tag.Key
+	case SelectorNotExists:
+		return "!" + s.Tag.Key
+	}
+	return s.Tag.Key + s.Op + s.Tag.Value
+}
+
+// Matches reports whether a terminal with the given tags is selected.
+func (s TagSelector) Matches(tags []Tag) bool {
+	hasKey, hasTag := false, false
+	for _, tag := range tags {
+		if tag.Key == s.Tag.Key {
+			hasKey = true
+			if tag.Value == s.Tag.Value {
+				hasTag = true
+			}
+		}
+	}
+	switch s.Op {
+	case SelectorEquals:
+		return hasTag
+	case SelectorNotEquals:
+		return !hasTag
+	case SelectorExists:
+		return hasKey
+	case SelectorNotExists:
+		return !hasKey
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestTagValidate(t *testing.T) {
+	require.NoError(t, Tag{Key: "zone", Value: "north-wing_2.b"}.Validate())
+	for _, tag := range []Tag{
+		{Key: "", Value: "atm"},
+		{Key: "type", Value: ""},
+		{Key: "-type", Value: "atm"},
+		{Key: "type", Value: "a=b"},
+		{Key: "ty pe", Value: "atm"},
+		{Key: "zone/wing", Value: "north"},
+		{Key: "type", Value: strings.Repeat("a", 64)},
+	} {
+		require.ErrorIs(t, tag.Validate(), ErrInvalidTag, tag.String())
+	}
+}
+
+func TestParseTagSelector(t *testing.T) {
+	cases := []struct {
+		raw string
+		exp TagSelector
+	}{
+		{raw: "type=atm", exp: TagSelector{Op: SelectorEquals, Tag: Tag{Key: "type", Value: "atm"}}},
+		{raw: "type==atm", exp: TagSelector{Op: SelectorEquals, Tag: Tag{Key: "type", Value: "atm"}}},
+		{raw: "zone!=north", exp: TagSelector{Op: SelectorNotEquals, Tag: Tag{Key: "zone", Value: "north"}}},
+		{raw: "zone", exp: TagSelector{Op: SelectorExists, Tag: Tag{Key: "zone"}}},
+		{raw: "!zone", exp: TagSelector{Op: SelectorNotExists, Tag: Tag{Key: "zone"}}},
+	}
+	for _, tCase := range cases {
+		sel, err := ParseTagSelector(tCase.raw)
+		require.NoError(t, err, tCase.raw)
+		require.Equal(t, tCase.exp, sel)
+		require.Equal(t, strings.Replace(tCase.raw, "==", "=", 1), sel.String())
+	}
+
+	for _, raw := range []string{"", "=atm", "type=", "!", "!zone=north", "type=a=b", "zone!=", "ty pe"} {
+		_, err := ParseTagSelector(raw)
+		require.ErrorIs(t, err, ErrInvalidTag, raw)
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidView is wrapped by every View validation error.
+var ErrInvalidView = errors.New("invalid view")
+
+// ViewParam is the /terminals query parameter that selects a view.
+const ViewParam = "view"
+
+// View is a named set of /terminals query parameters a user saved. Params
+// are kept as given, so a view may hold parameters the listing only learns
+// later. A shared view can be applied, but not changed, by other users.
+type View struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	OwnerID   int        `json:"-"`
+	Owner     string     `json:"owner"`
+	Params    url.Values `json:"params"`
+	IsDefault bool       `json:"default"`
+	Shared    bool       `json:"shared"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Validate checks that the name follows the rules for tag keys and that
+// Params do not select another view.
+func (v View) Validate() error {
+	if !validTagPart(v.Name) {
+		return fmt.Errorf("%w: name %q must be 1-%d letters, digits, '-', '_' or '.', starting with a letter or digit", ErrInvalidView, v.Name, maxTagLength)
+	}
+	if _, ok := v.Params[ViewParam]; ok {
+		return fmt.Errorf("%w: a view cannot select another view", ErrInvalidView)
+	}
+	return nil
+}
+
+// Ref is how v is selected with the view parameter: its name for the owner,
+// owner/name for everyone else.
+func (v View) Ref(userId int) string {
+	if v.OwnerID == userId {
+		return v.Name
+	}
+	return v.Owner + "/" + v.Name
+}
+
+// ParseViewRef splits a view parameter into the owner's name, empty for the
+// caller's own views, and the view name.
+func ParseViewRef(ref string) (owner string, name string) {
+	if owner, name, ok := strings.Cut(ref, "/"); ok {
+		return owner, name
+	}
+	return "", ref
+}
+
+// Merge returns the view's parameters overridden by query: a parameter
+// given in query replaces all of the view's values for it. The view
+// parameter itself is dropped.
+func (v View) Merge(query url.Values) url.Values {
+	merged := make(url.Values, len(v.Params)+len(query))
+	for key, values := range v.Params {
+		merged[key] = values
+	}
+	for key, values := range query {
+		merged[key] = values
+	}
+	delete(merged, ViewParam)
+	return merged
+}
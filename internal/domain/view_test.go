@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestViewValidate(t *testing.T) {
+	require.NoError(t, View{Name: "night-shift", Params: url.Values{"label": {"zone=north"}, "page_size": {"50"}}}.Validate())
+	require.ErrorIs(t, View{Name: ""}.Validate(), ErrInvalidView)
+	require.ErrorIs(t, View{Name: "alice/night"}.Validate(), ErrInvalidView)
+	require.ErrorIs(t, View{Name: "night", Params: url.Values{"view": {"day"}}}.Validate(), ErrInvalidView)
+}
+
+func TestViewRef(t *testing.T) {
+	view := View{Name: "night", OwnerID: 1, Owner: "alice"}
+	require.Equal(t, "night", view.Ref(1))
+	require.Equal(t, "alice/night", view.Ref(2))
+
+	owner, name := ParseViewRef("alice/night")
+	require.Equal(t, "alice", owner)
+	require.Equal(t, "night", name)
+	owner, name = ParseViewRef("night")
+	require.Empty(t, owner)
+	require.Equal(t, "night", name)
+}
+
+func TestViewMerge(t *testing.T) {
+	view := View{Params: url.Values{"label": {"zone=north", "type=atm"}, "q": {"kiosk"}}}
+	merged := view.Merge(url.Values{"view": {"night"}, "label": {"type=kiosk"}, "sort": {"name"}})
+	require.Equal(t, url.Values{"label": {"type=kiosk"}, "q": {"kiosk"}, "sort": {"name"}}, merged)
+	require.Equal(t, url.Values{"label": {"zone=north", "type=atm"}, "q": {"kiosk"}}, view.Params, "the view is not changed")
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidWebhook is wrapped by every Webhook validation error.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhook event types. Favorite events share the names of the audit log
+// actions.
+const (
+	WebhookStatusChange   = "terminal.status_change"
+	WebhookFavoriteAdd    = AuditFavoriteAdd
+	WebhookFavoriteRemove = AuditFavoriteRemove
+)
+
+// WebhookEvents lists every event type a webhook can subscribe to.
+var WebhookEvents = []string{WebhookStatusChange, WebhookFavoriteAdd, WebhookFavoriteRemove}
+
+// Webhook delivery states. A pending delivery is retried until it is
+// delivered or runs out of attempts and is dead.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the request
+// body, keyed with the webhook's secret.
+const SignatureHeader = "X-Webhook-Signature"
+
+const minWebhookSecret = 16
+
+// Webhook is a subscription to events, posted as JSON to URL. Empty Events
+// subscribes to every event type; TerminalID and Labels, tag selectors that
+// must all match, narrow it to some terminals.
+type Webhook struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	Events     []string  `json:"events,omitempty"`
+	TerminalID int       `json:"terminal_id,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate checks the URL, event types, labels and the secret, if set.
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	for _, event := range w.Events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	for _, label := range w.Labels {
+		if _, err := ParseTagSelector(label); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+	}
+	if w.TerminalID < 0 {
+		return fmt.Errorf("%w: terminal_id must not be negative", ErrInvalidWebhook)
+	}
+	if w.Secret != "" && len(w.Secret) < minWebhookSecret {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minWebhookSecret)
+	}
+	return nil
+}
+
+// Matches reports whether the webhook wants event, which happened to a
+// terminal with the given tags. Labels that do not parse never match.
+func (w Webhook) Matches(event WebhookEvent, tags []Tag) bool {
+	if len(w.Events) > 0 && !contains(w.Events, event.Type) {
+		return false
+	}
+	if w.TerminalID != 0 && w.TerminalID != event.TerminalID {
+		return false
+	}
+	for _, label := range w.Labels {
+		sel, err := ParseTagSelector(label)
+		if err != nil || !sel.Matches(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign returns the SignatureHeader value for body.
+func (w Webhook) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookEvent is the JSON payload of a delivery. Status is set for status
+// changes and UserID for favorite changes.
+type WebhookEvent struct {
+	Type         string    `json:"event"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	TerminalID   int       `json:"terminal_id"`
+	TerminalName string    `json:"terminal_name,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	UserID       int       `json:"user_id,omitempty"`
+}
+
+// StatusChange is a row of a terminal's status history. Source and Reason
+// are empty unless this service derived the change itself.
+type StatusChange struct {
+	ID         int64
+	TerminalID int
+	Status     string
+	Source     string
+	Reason     string
+	ChangedAt  time.Time
+}
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	State          string          `json:"state"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// IsDeliveryState reports whether s is a delivery state.
+func IsDeliveryState(s string) bool {
+	return s == DeliveryPending || s == DeliveryDelivered || s == DeliveryDead
+}
+
+// Backoff returns how long to wait before the next attempt after the given
+// number of failed ones: base, doubled after every further failure, and
+// never more than max.
+func Backoff(base, max time.Duration, failures int) time.Duration {
+	d := base
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+func isWebhookEvent(event string) bool {
+	return contains(WebhookEvents, event)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	valid := Webhook{URL: "https://hooks.example.com/terminals", Events: []string{WebhookStatusChange}, Labels: []string{"zone=north"}}
+	require.NoError(t, valid.Validate())
+	for _, hook := range []Webhook{
+		{URL: ""},
+		{URL: "ftp://hooks.example.com"},
+		{URL: "/relative"},
+		{URL: "https://hooks.example.com", Events: []string{"terminal.deleted"}},
+		{URL: "https://hooks.example.com", Labels: []string{"zone=a=b"}},
+		{URL: "https://hooks.example.com", TerminalID: -1},
+		{URL: "https://hooks.example.com", Secret: "short"},
+	} {
+		require.ErrorIs(t, hook.Validate(), ErrInvalidWebhook, hook)
+	}
+}
+
+func TestWebhookMatches(t *testing.T) {
+	north := []Tag{{Key: "zone", Value: "north"}}
+	event := WebhookEvent{Type: WebhookStatusChange, TerminalID: 2}
+
+	require.True(t, Webhook{}.Matches(event, nil))
+	require.True(t, Webhook{Events: []string{WebhookStatusChange}, TerminalID: 2, Labels: []string{"zone=north"}}.Matches(event, north))
+	require.False(t, Webhook{Events: []string{WebhookFavoriteAdd}}.Matches(event, north))
+	require.False(t, Webhook{TerminalID: 3}.Matches(event, north))
+	require.False(t, Webhook{Labels: []string{"zone=north", "type"}}.Matches(event, north))
+	require.True(t, Webhook{Labels: []string{"!type"}}.Matches(event, north))
+}
+
+func TestWebhookSign(t *testing.T) {
+	// echo -n '{"event":"favorite.add"}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	hook := Webhook{Secret: "0123456789abcdef"}
+	require.Equal(t, "sha256=ee9d36c35ac9e7d6261bdee05f6edcd47a781e7ea66c7d29d9045cc1ad3f87d3", hook.Sign([]byte(`{"event":"favorite.add"}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(30*time.Second, time.Hour, 1))
+	require.Equal(t, time.Minute, Backoff(30*time.Second, time.Hour, 2))
+	require.Equal(t, 4*time.Minute, Backoff(30*time.Second, time.Hour, 4))
+	require.Equal(t, time.Hour, Backoff(30*time.Second, time.Hour, 20))
+}
@@ -0,0 +1,144 @@
+package admin_handler
+
+import (
+	"context"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminHandler struct {
+	log              logger.Logger
+	adminServicePort services.AdminServicePort
+}
+
+func NewAdminHandler(log logger.Logger, adminServicePort services.AdminServicePort) *AdminHandler {
+	return &AdminHandler{
+		log:              log,
+		adminServicePort: adminServicePort,
+	}
+}
+
+func (h *AdminHandler) GetDeletedTerminals(c *gin.Context) {
+	records, err := h.adminServicePort.GetDeletedTerminals(c.Request.Context())
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get deleted terminals: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+func (h *AdminHandler) DeleteTerminal(c *gin.Context) {
+	h.handleByID(c, "failed to delete terminal", h.adminServicePort.DeleteTerminal)
+}
+
+func (h *AdminHandler) RestoreTerminal(c *gin.Context) {
+	h.handleByID(c, "failed to restore terminal", h.adminServicePort.RestoreTerminal)
+}
+
+func (h *AdminHandler) PurgeTerminal(c *gin.Context) {
+	h.handleByID(c, "failed to purge terminal", h.adminServicePort.PurgeTerminal)
+}
+
+// AttachTag tags the terminal with the key and value given in the JSON
+// body. Attaching a tag the terminal already has succeeds.
+func (h *AdminHandler) AttachTag(c *gin.Context) {
+	var tag domain.Tag
+	err := c.ShouldBindJSON(&tag)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	h.handleByID(c, "failed to attach tag", func(ctx context.Context, id int) error {
+		return h.adminServicePort.AttachTag(ctx, id, tag)
+	})
+}
+
+// DetachTag removes the tag named by the key and value path parameters.
+func (h *AdminHandler) DetachTag(c *gin.Context) {
+	tag := domain.Tag{Key: c.Param("key"), Value: c.Param("value")}
+	h.handleByID(c, "failed to detach tag", func(ctx context.Context, id int) error {
+		return h.adminServicePort.DetachTag(ctx, id, tag)
+	})
+}
+
+// SetTerminalLocation replaces the terminal's location with the JSON body,
+// such as {"location":{"lat":52.52,"lon":13.405},"address":"Alexanderplatz 1"}.
+// A body without a location clears it.
+func (h *AdminHandler) SetTerminalLocation(c *gin.Context) {
+	var location domain.TerminalLocation
+	err := c.ShouldBindJSON(&location)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	h.handleByID(c, "failed to set terminal location", func(ctx context.Context, id int) error {
+		return h.adminServicePort.SetTerminalLocation(ctx, id, location)
+	})
+}
+
+func (h *AdminHandler) GetDeletedUsers(c *gin.Context) {
+	records, err := h.adminServicePort.GetDeletedUsers(c.Request.Context())
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get deleted users: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
+
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	h.handleByID(c, "failed to delete user", h.adminServicePort.DeleteUser)
+}
+
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	h.handleByID(c, "failed to restore user", h.adminServicePort.RestoreUser)
+}
+
+func (h *AdminHandler) PurgeUser(c *gin.Context) {
+	h.handleByID(c, "failed to purge user", h.adminServicePort.PurgeUser)
+}
+
+func (h *AdminHandler) handleByID(c *gin.Context, failMsg string, action func(ctx context.Context, id int) error) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	err := action(c.Request.Context(), id)
+	if err != nil {
+		h.abort(c, failMsg, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) abort(c *gin.Context, failMsg string, err error) {
+	h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidTag), errors.Is(err, domain.ErrInvalidLocation),
+		errors.Is(err, domain.ErrInvalidSite):
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.ErrSiteNotEmpty):
+		status = http.StatusConflict
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
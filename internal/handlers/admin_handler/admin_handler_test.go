@@ -0,0 +1,257 @@
+package admin_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/admin_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	h := NewAdminHandler(*log, admin_service.NewAdminService(terminalRepo, userRepo))
+
+	router := handlertest.NewRouter(0)
+	router.GET("/admin/terminals/deleted", h.GetDeletedTerminals)
+	router.DELETE("/admin/terminals/:id", h.DeleteTerminal)
+	router.POST("/admin/terminals/:id/restore", h.RestoreTerminal)
+	router.DELETE("/admin/users/:id/purge", h.PurgeUser)
+	router.POST("/admin/terminals/:id/tags", h.AttachTag)
+	router.DELETE("/admin/terminals/:id/tags/:key/:value", h.DetachTag)
+	router.PUT("/admin/terminals/:id/location", h.SetTerminalLocation)
+	router.PUT("/admin/terminals/:id/site", h.SetTerminalSite)
+	router.POST("/admin/sites", h.CreateSite)
+	router.PUT("/admin/sites/:id", h.UpdateSite)
+	router.DELETE("/admin/sites/:id", h.DeleteSite)
+	return router, terminalRepo, userRepo
+}
+
+func TestGetDeletedTerminals(t *testing.T) {
+	router, terminalRepo, _ := newRouter(t)
+	records := []domain.DeletedRecord{
+		{ID: 2, Name: "terminal2", DeletedAt: time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	terminalRepo.EXPECT().GetDeletedTerminals(gomock.Any()).Return(records, nil).Times(1)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/terminals/deleted", nil)
+	router.ServeHTTP(w, req)
+	resp := w.Result()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `[{"id":2,"name":"terminal2","deleted_at":"2023-08-01T12:00:00Z"}]`, string(data))
+}
+
+func TestAdminActions(t *testing.T) {
+	cases := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		expect    func(terminalRepo *repoMock.MockTerminalRepositoryPort, userRepo *repoMock.MockUserRepositoryPort)
+		expStatus int
+	}{
+		{
+			name:   "delete_terminal",
+			method: http.MethodDelete,
+			target: "/admin/terminals/3",
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().SoftDeleteTerminal(gomock.Any(), 3).Return(nil).Times(1)
+			},
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:   "restore_not_deleted",
+			method: http.MethodPost,
+			target: "/admin/terminals/3/restore",
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().RestoreTerminal(gomock.Any(), 3).
+					Return(fmt.Errorf("deleted terminal with ID %d: %w", 3, repositories.ErrNotFound)).Times(1)
+			},
+			expStatus: http.StatusNotFound,
+		},
+		{
+			name:   "purge_user_db_err",
+			method: http.MethodDelete,
+			target: "/admin/users/5/purge",
+			expect: func(_ *repoMock.MockTerminalRepositoryPort, userRepo *repoMock.MockUserRepositoryPort) {
+				userRepo.EXPECT().PurgeUser(gomock.Any(), 5).Return(errors.New("DB is down")).Times(1)
+			},
+			expStatus: http.StatusInternalServerError,
+		},
+		{
+			name:      "invalid_id",
+			method:    http.MethodDelete,
+			target:    "/admin/terminals/abc",
+			expect:    func(*repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "attach_tag",
+			method: http.MethodPost,
+			target: "/admin/terminals/3/tags",
+			body:   `{"key":"type","value":"atm"}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().AttachTag(gomock.Any(), 3, domain.Tag{Key: "type", Value: "atm"}).Return(nil).Times(1)
+			},
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:      "attach_invalid_tag",
+			method:    http.MethodPost,
+			target:    "/admin/terminals/3/tags",
+			body:      `{"key":"type","value":"a=b"}`,
+			expect:    func(*repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "attach_tag_bad_body",
+			method:    http.MethodPost,
+			target:    "/admin/terminals/3/tags",
+			body:      `{"key":`,
+			expect:    func(*repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "attach_tag_unknown_terminal",
+			method: http.MethodPost,
+			target: "/admin/terminals/9/tags",
+			body:   `{"key":"type","value":"atm"}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().AttachTag(gomock.Any(), 9, domain.Tag{Key: "type", Value: "atm"}).
+					Return(fmt.Errorf("terminal with ID %d: %w", 9, repositories.ErrNotFound)).Times(1)
+			},
+			expStatus: http.StatusNotFound,
+		},
+		{
+			name:   "detach_tag",
+			method: http.MethodDelete,
+			target: "/admin/terminals/3/tags/zone/north",
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().DetachTag(gomock.Any(), 3, domain.Tag{Key: "zone", Value: "north"}).
+					Return(fmt.Errorf("tag zone=north on terminal with ID 3: %w", repositories.ErrNotFound)).Times(1)
+			},
+			expStatus: http.StatusNotFound,
+		},
+		{
+			name:   "set_location",
+			method: http.MethodPut,
+			target: "/admin/terminals/3/location",
+			body:   `{"location":{"lat":52.52,"lon":13.405},"address":" Alexanderplatz 1 "}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().SetTerminalLocation(gomock.Any(), 3, domain.TerminalLocation{
+					Location: &domain.GeoPoint{Lat: 52.52, Lon: 13.405}, Address: "Alexanderplatz 1",
+				}).Return(nil).Times(1)
+			},
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:   "clear_location",
+			method: http.MethodPut,
+			target: "/admin/terminals/3/location",
+			body:   `{}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().SetTerminalLocation(gomock.Any(), 3, domain.TerminalLocation{}).Return(nil).Times(1)
+			},
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:      "set_invalid_location",
+			method:    http.MethodPut,
+			target:    "/admin/terminals/3/location",
+			body:      `{"location":{"lat":95,"lon":13.405}}`,
+			expect:    func(*repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "set_site",
+			method: http.MethodPut,
+			target: "/admin/terminals/3/site",
+			body:   `{"site_id":2}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().SetTerminalSite(gomock.Any(), 3, 2).Return(nil).Times(1)
+			},
+			expStatus: http.StatusNoContent,
+		},
+		{
+			name:   "set_unknown_site",
+			method: http.MethodPut,
+			target: "/admin/terminals/3/site",
+			body:   `{"site_id":9}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().SetTerminalSite(gomock.Any(), 3, 9).
+					Return(fmt.Errorf("site with ID %d: %w", 9, repositories.ErrNotFound)).Times(1)
+			},
+			expStatus: http.StatusNotFound,
+		},
+		{
+			name:   "create_site",
+			method: http.MethodPost,
+			target: "/admin/sites",
+			body:   `{"name":"Site B","parent_id":1}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().CreateSite(gomock.Any(), domain.Site{ParentID: 1, Name: "Site B"}).
+					Return(domain.Site{ID: 2, ParentID: 1, Name: "Site B"}, nil).Times(1)
+			},
+			expStatus: http.StatusCreated,
+		},
+		{
+			name:      "create_invalid_site",
+			method:    http.MethodPost,
+			target:    "/admin/sites",
+			body:      `{"name":""}`,
+			expect:    func(*repoMock.MockTerminalRepositoryPort, *repoMock.MockUserRepositoryPort) {},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "move_site_below_itself",
+			method: http.MethodPut,
+			target: "/admin/sites/1",
+			body:   `{"name":"North","parent_id":2}`,
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().UpdateSite(gomock.Any(), domain.Site{ID: 1, ParentID: 2, Name: "North"}).
+					Return(fmt.Errorf("%w: site 1 cannot move below itself", domain.ErrInvalidSite)).Times(1)
+			},
+			expStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "delete_site_not_empty",
+			method: http.MethodDelete,
+			target: "/admin/sites/1",
+			expect: func(terminalRepo *repoMock.MockTerminalRepositoryPort, _ *repoMock.MockUserRepositoryPort) {
+				terminalRepo.EXPECT().DeleteSite(gomock.Any(), 1).Return(domain.ErrSiteNotEmpty).Times(1)
+			},
+			expStatus: http.StatusConflict,
+		},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			router, terminalRepo, userRepo := newRouter(t)
+			tCase.expect(terminalRepo, userRepo)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tCase.method, tCase.target, strings.NewReader(tCase.body))
+			router.ServeHTTP(w, req)
+			require.Equal(t, tCase.expStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package admin_handler
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type siteRequest struct {
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+}
+
+type terminalSiteRequest struct {
+	SiteID int `json:"site_id"`
+}
+
+// CreateSite adds the site in the body, such as
+// {"name":"Site B","parent_id":1}. Without a parent_id it is a top-level
+// site.
+func (h *AdminHandler) CreateSite(c *gin.Context) {
+	var req siteRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	site, err := h.adminServicePort.CreateSite(c.Request.Context(), domain.Site{Name: req.Name, ParentID: req.ParentID})
+	if err != nil {
+		h.abort(c, "failed to create site", err)
+		return
+	}
+	c.JSON(http.StatusCreated, site)
+}
+
+// UpdateSite renames the site and moves it under the parent_id in the body,
+// with everything below it. A move below one of its own sub-sites fails.
+func (h *AdminHandler) UpdateSite(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	var req siteRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	site, err := h.adminServicePort.UpdateSite(c.Request.Context(), domain.Site{ID: id, Name: req.Name, ParentID: req.ParentID})
+	if err != nil {
+		h.abort(c, "failed to update site", err)
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+// DeleteSite deletes a site once its sub-sites and terminals are gone or
+// moved.
+func (h *AdminHandler) DeleteSite(c *gin.Context) {
+	h.handleByID(c, "failed to delete site", h.adminServicePort.DeleteSite)
+}
+
+// SetTerminalSite assigns the terminal to the site in the JSON body, such
+// as {"site_id":3}. A body without a site_id unassigns it.
+func (h *AdminHandler) SetTerminalSite(c *gin.Context) {
+	var req terminalSiteRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil || req.SiteID < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": "site_id must be a site ID",
+		})
+		return
+	}
+	h.handleByID(c, "failed to set terminal site", func(ctx context.Context, id int) error {
+		return h.adminServicePort.SetTerminalSite(ctx, id, req.SiteID)
+	})
+}
@@ -0,0 +1,190 @@
+package alert_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// maxMute is the longest an alert can be muted for at once.
+const maxMute = 30 * 24 * time.Hour
+
+type AlertHandler struct {
+	log              logger.Logger
+	alertServicePort services.AlertServicePort
+}
+
+func NewAlertHandler(log logger.Logger, alertServicePort services.AlertServicePort) *AlertHandler {
+	return &AlertHandler{
+		log:              log,
+		alertServicePort: alertServicePort,
+	}
+}
+
+type muteRequest struct {
+	For domain.Duration `json:"for" binding:"required"`
+}
+
+// GetAlerts lists the user's alerts, firing ones first. state=firing or
+// state=resolved keeps only alerts in that state.
+func (h *AlertHandler) GetAlerts(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	state := c.Query("state")
+	if state != "" && state != domain.AlertFiring && state != domain.AlertResolved {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": fmt.Sprintf("state must be %q or %q", domain.AlertFiring, domain.AlertResolved),
+		})
+		return
+	}
+	alerts, err := h.alertServicePort.GetAlerts(c.Request.Context(), userId, state)
+	if err != nil {
+		h.abort(c, "failed to get alerts", err)
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+// AckAlert acknowledges one of the user's alerts.
+func (h *AlertHandler) AckAlert(c *gin.Context) {
+	userId, id, ok := alertParams(c)
+	if !ok {
+		return
+	}
+	err := h.alertServicePort.AckAlert(c.Request.Context(), userId, id)
+	if err != nil {
+		h.abort(c, "failed to acknowledge alert", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MuteAlert mutes the rule that raised the alert for the duration in the
+// body, such as {"for":"1h"}.
+func (h *AlertHandler) MuteAlert(c *gin.Context) {
+	userId, id, ok := alertParams(c)
+	if !ok {
+		return
+	}
+	var req muteRequest
+	err := c.ShouldBindJSON(&req)
+	if err == nil && (req.For <= 0 || time.Duration(req.For) > maxMute) {
+		err = fmt.Errorf("for must be positive and at most %s", maxMute)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	err = h.alertServicePort.MuteAlert(c.Request.Context(), userId, id, time.Duration(req.For))
+	if err != nil {
+		h.abort(c, "failed to mute alert", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UnmuteAlert lifts the mute on the rule that raised the alert.
+func (h *AlertHandler) UnmuteAlert(c *gin.Context) {
+	userId, id, ok := alertParams(c)
+	if !ok {
+		return
+	}
+	err := h.alertServicePort.UnmuteAlert(c.Request.Context(), userId, id)
+	if err != nil {
+		h.abort(c, "failed to unmute alert", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetAlertRules lists the user's alert rules.
+func (h *AlertHandler) GetAlertRules(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	rules, err := h.alertServicePort.GetAlertRules(c.Request.Context(), userId)
+	if err != nil {
+		h.abort(c, "failed to get alert rules", err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateAlertRule adds the rule in the body, such as
+// {"kind":"status","status":"offline","window":"5m"}, for the user.
+func (h *AlertHandler) CreateAlertRule(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	var rule domain.AlertRule
+	err := c.ShouldBindJSON(&rule)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	rule.UserID = userId
+	rule, err = h.alertServicePort.CreateAlertRule(c.Request.Context(), rule)
+	if err != nil {
+		h.abort(c, "failed to create alert rule", err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteAlertRule deletes one of the user's rules and its alerts.
+func (h *AlertHandler) DeleteAlertRule(c *gin.Context) {
+	userId, id, ok := alertParams(c)
+	if !ok {
+		return
+	}
+	err := h.alertServicePort.DeleteAlertRule(c.Request.Context(), userId, id)
+	if err != nil {
+		h.abort(c, "failed to delete alert rule", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AlertHandler) abort(c *gin.Context, failMsg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidAlertRule):
+		status = http.StatusBadRequest
+	default:
+		h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
+
+// alertParams returns the user's ID and the id path parameter. On failure
+// it aborts the request and returns false.
+func alertParams(c *gin.Context) (int, int, bool) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	return userId, id, true
+}
@@ -0,0 +1,105 @@
+package alert_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/alert_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockAlertRepositoryPort, *repoMock.MockTerminalRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	h := NewAlertHandler(*log, alert_service.NewAlertService(alertRepo, terminalRepo, repoMock.NewMockUnitOfWork(ctl), nil))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/alerts", h.GetAlerts)
+	router.POST("/alerts/:id/ack", h.AckAlert)
+	router.POST("/alerts/:id/mute", h.MuteAlert)
+	router.DELETE("/alerts/:id/mute", h.UnmuteAlert)
+	router.GET("/alerts/rules", h.GetAlertRules)
+	router.POST("/alerts/rules", h.CreateAlertRule)
+	router.DELETE("/alerts/rules/:id", h.DeleteAlertRule)
+	return router, alertRepo, terminalRepo
+}
+
+func TestGetAlerts(t *testing.T) {
+	router, alertRepo, _ := newRouter(t)
+	firedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	alertRepo.EXPECT().GetAlerts(gomock.Any(), 1, domain.AlertFiring).Return([]domain.Alert{{
+		ID:           7,
+		Rule:         domain.AlertRule{ID: 3, UserID: 1, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(5 * time.Minute)},
+		TerminalID:   2,
+		TerminalName: "T-112",
+		FiredAt:      firedAt,
+	}}, nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodGet, "/alerts?state=firing", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id":7,"rule":{"id":3,"kind":"status","status":"offline","window":"5m0s"},
+		"terminal_id":2,"terminal_name":"T-112","fired_at":"2024-05-01T12:00:00Z","state":"firing"}]`, w.Body.String())
+
+	w = handlertest.Serve(router, http.MethodGet, "/alerts?state=acked", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateAlertRule(t *testing.T) {
+	router, alertRepo, terminalRepo := newRouter(t)
+	rule := domain.AlertRule{UserID: 1, TerminalID: 2, Kind: domain.AlertRuleFlapping, Count: 3, Window: domain.Duration(10 * time.Minute)}
+	created := rule
+	created.ID = 4
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{2}, nil).Times(2)
+	alertRepo.EXPECT().CreateAlertRule(gomock.Any(), rule).Return(created, nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodPost, "/alerts/rules", `{"terminal_id":2,"kind":"flapping","count":3,"window":"10m"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.JSONEq(t, `{"id":4,"terminal_id":2,"kind":"flapping","count":3,"window":"10m0s"}`, w.Body.String())
+
+	w = handlertest.Serve(router, http.MethodPost, "/alerts/rules", `{"terminal_id":5,"kind":"flapping","count":3,"window":"10m"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = handlertest.Serve(router, http.MethodPost, "/alerts/rules", `{"kind":"status","window":"5m"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = handlertest.Serve(router, http.MethodPost, "/alerts/rules", `{"kind":"status","status":"offline","window":"soon"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMuteAlert(t *testing.T) {
+	router, alertRepo, _ := newRouter(t)
+	alertRepo.EXPECT().GetAlert(gomock.Any(), 1, 7).Return(domain.Alert{ID: 7, Rule: domain.AlertRule{ID: 3}}, nil).Times(2)
+	alertRepo.EXPECT().MuteAlertRule(gomock.Any(), 1, 3, gomock.Not(gomock.Nil())).Return(nil).Times(1)
+	alertRepo.EXPECT().MuteAlertRule(gomock.Any(), 1, 3, gomock.Nil()).Return(nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodPost, "/alerts/7/mute", `{"for":"1h"}`)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = handlertest.Serve(router, http.MethodDelete, "/alerts/7/mute", "")
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, body := range []string{`{}`, `{"for":"-1h"}`, `{"for":"2000h"}`} {
+		w = handlertest.Serve(router, http.MethodPost, "/alerts/7/mute", body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestAckAndDeleteNotFound(t *testing.T) {
+	router, alertRepo, _ := newRouter(t)
+	alertRepo.EXPECT().AckAlert(gomock.Any(), 1, 7).Return(nil).Times(1)
+	alertRepo.EXPECT().AckAlert(gomock.Any(), 1, 8).Return(repositories.ErrNotFound).Times(1)
+	alertRepo.EXPECT().DeleteAlertRule(gomock.Any(), 1, 3).Return(repositories.ErrNotFound).Times(1)
+
+	require.Equal(t, http.StatusNoContent, handlertest.Serve(router, http.MethodPost, "/alerts/7/ack", "").Code)
+	require.Equal(t, http.StatusNotFound, handlertest.Serve(router, http.MethodPost, "/alerts/8/ack", "").Code)
+	require.Equal(t, http.StatusNotFound, handlertest.Serve(router, http.MethodDelete, "/alerts/rules/3", "").Code)
+	require.Equal(t, http.StatusBadRequest, handlertest.Serve(router, http.MethodPost, "/alerts/abc/ack", "").Code)
+}
@@ -0,0 +1,160 @@
+package audit_handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	// maxExportLimit caps a CSV export, which is not paginated by default.
+	maxExportLimit = 100000
+
+	csvContentType = "text/csv"
+)
+
+var (
+	ErrInvalidOffset  = errors.New("offset must be a non-negative integer")
+	ErrInvalidActorID = errors.New("actor_id must be a positive integer")
+	ErrInvalidTime    = errors.New("from and to must be RFC 3339 timestamps")
+)
+
+var csvHeader = []string{"id", "created_at", "action", "outcome", "actor_id", "actor_name",
+	"target_type", "target_id", "ip", "request_id", "before", "after"}
+
+type AuditHandler struct {
+	log              logger.Logger
+	auditServicePort services.AuditServicePort
+}
+
+func NewAuditHandler(log logger.Logger, auditServicePort services.AuditServicePort) *AuditHandler {
+	return &AuditHandler{
+		log:              log,
+		auditServicePort: auditServicePort,
+	}
+}
+
+// GetAudit lists audit entries, newest first, filtered by the query
+// parameters action, outcome, actor_id, target_type, target_id, request_id,
+// from and to, and paginated by limit and offset. With format=csv or an
+// Accept header of text/csv the entries are exported as CSV instead.
+func (h *AuditHandler) GetAudit(c *gin.Context) {
+	asCSV := c.Query("format") == "csv" || strings.Contains(c.GetHeader("Accept"), csvContentType)
+	filter, err := parseFilter(c, asCSV)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	entries, err := h.auditServicePort.GetAuditEntries(c.Request.Context(), filter)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get audit entries: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	if !asCSV {
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	c.Header("Content-Type", csvContentType+"; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write(csvHeader)
+	for _, entry := range entries {
+		actorID := ""
+		if entry.ActorID != 0 {
+			actorID = strconv.Itoa(entry.ActorID)
+		}
+		w.Write([]string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+			entry.Action,
+			entry.Outcome,
+			actorID,
+			csvSafe(entry.ActorName),
+			entry.TargetType,
+			csvSafe(entry.TargetID),
+			entry.IP,
+			csvSafe(entry.RequestID),
+			string(entry.Before),
+			string(entry.After),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to write audit export: %v", err)
+	}
+}
+
+func parseFilter(c *gin.Context, export bool) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		Action:     c.Query("action"),
+		Outcome:    c.Query("outcome"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		RequestID:  c.Query("request_id"),
+		Limit:      defaultLimit,
+	}
+	if export {
+		filter.Limit = maxExportLimit
+	}
+	if raw := c.Query("actor_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			return filter, ErrInvalidActorID
+		}
+		filter.ActorID = id
+	}
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filter, ErrInvalidTime
+			}
+			*dst = t
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		max := maxLimit
+		if export {
+			max = maxExportLimit
+		}
+		if err != nil || limit <= 0 || limit > max {
+			return filter, fmt.Errorf("limit must be between 1 and %d", max)
+		}
+		filter.Limit = limit
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return filter, ErrInvalidOffset
+		}
+		filter.Offset = offset
+	}
+	return filter, nil
+}
+
+// csvSafe keeps user-supplied values such as a sign-in name from being
+// evaluated as formulas when the export is opened in a spreadsheet.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package audit_handler
+
+import (
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockAuditRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	auditRepo := repoMock.NewMockAuditRepositoryPort(ctl)
+	h := NewAuditHandler(*log, audit_service.NewAuditService(auditRepo))
+
+	router := handlertest.NewRouter(0)
+	router.GET("/admin/audit", h.GetAudit)
+	return router, auditRepo
+}
+
+var entries = []domain.AuditEntry{
+	{
+		ID:         2,
+		CreatedAt:  time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC),
+		Action:     domain.AuditFavoriteRemove,
+		Outcome:    domain.AuditSuccess,
+		ActorID:    1,
+		TargetType: domain.AuditTargetTerminal,
+		TargetID:   "4",
+		IP:         "10.0.0.1",
+		RequestID:  "req-2",
+		Before:     json.RawMessage(`{"favorites":[4]}`),
+		After:      json.RawMessage(`{"favorites":[]}`),
+	},
+	{
+		ID:         1,
+		CreatedAt:  time.Date(2023, 8, 1, 11, 0, 0, 0, time.UTC),
+		Action:     domain.AuditSignIn,
+		Outcome:    domain.AuditFailure,
+		ActorName:  "=cmd",
+		TargetType: domain.AuditTargetUser,
+		TargetID:   "=cmd",
+		IP:         "10.0.0.2",
+		RequestID:  "req-1",
+	},
+}
+
+func TestGetAuditFilters(t *testing.T) {
+	router, auditRepo := newRouter(t)
+	auditRepo.EXPECT().GetAuditEntries(gomock.Any(), domain.AuditFilter{
+		Action:     domain.AuditFavoriteRemove,
+		ActorID:    1,
+		TargetType: domain.AuditTargetTerminal,
+		TargetID:   "4",
+		From:       time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+		Limit:      10,
+		Offset:     20,
+	}).Return(entries[:1], nil).Times(1)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet,
+		"/admin/audit?action=favorite.remove&actor_id=1&target_type=terminal&target_id=4&from=2023-08-01T00:00:00Z&limit=10&offset=20", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id":2,"created_at":"2023-08-01T12:00:00Z","action":"favorite.remove","outcome":"success",
+		"actor_id":1,"target_type":"terminal","target_id":"4","ip":"10.0.0.1","request_id":"req-2",
+		"before":{"favorites":[4]},"after":{"favorites":[]}}]`, w.Body.String())
+}
+
+func TestGetAuditCSV(t *testing.T) {
+	router, auditRepo := newRouter(t)
+	auditRepo.EXPECT().GetAuditEntries(gomock.Any(), domain.AuditFilter{Limit: maxExportLimit}).Return(entries, nil).Times(1)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit?format=csv", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	expected := "id,created_at,action,outcome,actor_id,actor_name,target_type,target_id,ip,request_id,before,after\n" +
+		"2,2023-08-01T12:00:00Z,favorite.remove,success,1,,terminal,4,10.0.0.1,req-2,\"{\"\"favorites\"\":[4]}\",\"{\"\"favorites\"\":[]}\"\n" +
+		"1,2023-08-01T11:00:00Z,user.sign_in,failure,,'=cmd,user,'=cmd,10.0.0.2,req-1,,\n"
+	require.Equal(t, expected, w.Body.String())
+}
+
+func TestGetAuditBadRequest(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+	}{
+		{name: "actor_id", query: "actor_id=abc"},
+		{name: "from", query: "from=yesterday"},
+		{name: "limit", query: "limit=5000"},
+		{name: "offset", query: "offset=-1"},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			router, _ := newRouter(t)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit?"+tCase.query, nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
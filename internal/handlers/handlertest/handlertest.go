@@ -0,0 +1,34 @@
+// Package handlertest holds the router setup the handler tests share.
+package handlertest
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// NewRouter returns a router for handler tests that signs every request in
+// as userID the way ValidateUser does, or leaves it signed out for 0.
+func NewRouter(userID int) *gin.Engine {
+	router := gin.Default()
+	if userID != 0 {
+		router.Use(func(c *gin.Context) {
+			c.Set(middleware.UserIDKey, float64(userID))
+		})
+	}
+	return router
+}
+
+// Serve sends a request with body to router and returns the response.
+func Serve(router http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	return ServeRequest(router, httptest.NewRequest(method, target, strings.NewReader(body)))
+}
+
+// ServeRequest sends req to router and returns the response.
+func ServeRequest(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
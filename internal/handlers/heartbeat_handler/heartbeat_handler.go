@@ -0,0 +1,118 @@
+package heartbeat_handler
+
+import (
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var errMissingToken = errors.New("missing heartbeat token")
+
+type HeartbeatHandler struct {
+	log                  logger.Logger
+	heartbeatServicePort services.HeartbeatServicePort
+}
+
+func NewHeartbeatHandler(log logger.Logger, heartbeatServicePort services.HeartbeatServicePort) *HeartbeatHandler {
+	return &HeartbeatHandler{
+		log:                  log,
+		heartbeatServicePort: heartbeatServicePort,
+	}
+}
+
+// IngestHeartbeat records a heartbeat from the terminal whose token is sent
+// as "Authorization: Bearer <token>". The body, such as
+// {"firmware":"2.1.0","ip":"10.0.0.7"}, is optional.
+func (h *HeartbeatHandler) IngestHeartbeat(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"err": errMissingToken.Error(),
+		})
+		return
+	}
+	var heartbeat domain.Heartbeat
+	err := c.ShouldBindJSON(&heartbeat)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	err = h.heartbeatServicePort.RecordHeartbeat(c.Request.Context(), token, heartbeat)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"err": "unknown heartbeat token",
+			})
+			return
+		}
+		h.abort(c, "failed to record heartbeat", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// IssueHeartbeatToken issues a new heartbeat token for the terminal,
+// replacing its old one. The token is only shown in this response.
+func (h *HeartbeatHandler) IssueHeartbeatToken(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	token, err := h.heartbeatServicePort.IssueHeartbeatToken(c.Request.Context(), id)
+	if err != nil {
+		h.abort(c, "failed to issue heartbeat token", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"token": token,
+	})
+}
+
+// RevokeHeartbeatToken removes the terminal's heartbeat token, after which
+// its heartbeats are rejected and it is no longer swept.
+func (h *HeartbeatHandler) RevokeHeartbeatToken(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	err := h.heartbeatServicePort.RevokeHeartbeatToken(c.Request.Context(), id)
+	if err != nil {
+		h.abort(c, "failed to revoke heartbeat token", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetHeartbeats lists the last heartbeat of every terminal with a token.
+func (h *HeartbeatHandler) GetHeartbeats(c *gin.Context) {
+	heartbeats, err := h.heartbeatServicePort.GetHeartbeats(c.Request.Context())
+	if err != nil {
+		h.abort(c, "failed to get heartbeats", err)
+		return
+	}
+	c.JSON(http.StatusOK, heartbeats)
+}
+
+func (h *HeartbeatHandler) abort(c *gin.Context, failMsg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidHeartbeat):
+		status = http.StatusBadRequest
+	default:
+		h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
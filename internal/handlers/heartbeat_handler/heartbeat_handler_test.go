@@ -0,0 +1,95 @@
+package heartbeat_handler
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/heartbeat_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockHeartbeatRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	heartbeatRepo := repoMock.NewMockHeartbeatRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{HeartbeatRepositoryPort: heartbeatRepo, UnitOfWork: uow})
+		}).AnyTimes()
+	h := NewHeartbeatHandler(*log, heartbeat_service.NewHeartbeatService(heartbeatRepo, uow))
+
+	router := handlertest.NewRouter(0)
+	router.POST("/ingest/heartbeat", h.IngestHeartbeat)
+	router.POST("/admin/terminals/:id/heartbeat-token", h.IssueHeartbeatToken)
+	router.DELETE("/admin/terminals/:id/heartbeat-token", h.RevokeHeartbeatToken)
+	router.GET("/admin/heartbeats", h.GetHeartbeats)
+	return router, heartbeatRepo
+}
+
+// serve sends the request with token as its bearer token, if set.
+func serve(router *gin.Engine, method string, target string, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return handlertest.ServeRequest(router, req)
+}
+
+func TestIngestHeartbeat(t *testing.T) {
+	router, heartbeatRepo := newRouter(t)
+	heartbeatRepo.EXPECT().RecordHeartbeat(gomock.Any(), gomock.Any(), domain.Heartbeat{Firmware: "2.1.0", IP: "10.0.0.7"}, gomock.Any()).
+		Return(3, nil).Times(1)
+	heartbeatRepo.EXPECT().RecordHeartbeat(gomock.Any(), gomock.Any(), domain.Heartbeat{}, gomock.Any()).Return(3, nil).Times(1)
+	heartbeatRepo.EXPECT().MarkTerminalOnline(gomock.Any(), 3, "heartbeat resumed").Return(false, nil).Times(2)
+
+	w := serve(router, http.MethodPost, "/ingest/heartbeat", "abc", `{"firmware":"2.1.0","ip":"10.0.0.7"}`)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	w = serve(router, http.MethodPost, "/ingest/heartbeat", "abc", "")
+	require.Equal(t, http.StatusNoContent, w.Code, "the body is optional")
+
+	require.Equal(t, http.StatusUnauthorized, serve(router, http.MethodPost, "/ingest/heartbeat", "", "").Code)
+	require.Equal(t, http.StatusBadRequest, serve(router, http.MethodPost, "/ingest/heartbeat", "abc", `{"ip":"nope"}`).Code)
+	require.Equal(t, http.StatusBadRequest, serve(router, http.MethodPost, "/ingest/heartbeat", "abc", `{"ip":`).Code)
+
+	heartbeatRepo.EXPECT().RecordHeartbeat(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(0, repositories.ErrNotFound).Times(1)
+	require.Equal(t, http.StatusUnauthorized, serve(router, http.MethodPost, "/ingest/heartbeat", "revoked", "").Code)
+}
+
+func TestHeartbeatTokens(t *testing.T) {
+	router, heartbeatRepo := newRouter(t)
+	heartbeatRepo.EXPECT().SetHeartbeatToken(gomock.Any(), 3, gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	heartbeatRepo.EXPECT().SetHeartbeatToken(gomock.Any(), 4, gomock.Any(), gomock.Any()).Return(repositories.ErrNotFound).Times(1)
+	heartbeatRepo.EXPECT().DeleteHeartbeatToken(gomock.Any(), 3).Return(nil).Times(1)
+
+	w := serve(router, http.MethodPost, "/admin/terminals/3/heartbeat-token", "", "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Regexp(t, `^\{"token":"[0-9a-f]{64}"\}$`, w.Body.String())
+	require.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/admin/terminals/4/heartbeat-token", "", "").Code)
+	require.Equal(t, http.StatusBadRequest, serve(router, http.MethodPost, "/admin/terminals/x/heartbeat-token", "", "").Code)
+	require.Equal(t, http.StatusNoContent, serve(router, http.MethodDelete, "/admin/terminals/3/heartbeat-token", "", "").Code)
+}
+
+func TestGetHeartbeats(t *testing.T) {
+	router, heartbeatRepo := newRouter(t)
+	seen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	heartbeatRepo.EXPECT().GetHeartbeats(gomock.Any()).Return([]domain.TerminalHeartbeat{
+		{TerminalID: 3, TerminalName: "T-3", Status: domain.TerminalActive, LastSeenAt: &seen, Firmware: "2.1.0"},
+		{TerminalID: 4, TerminalName: "T-4", Status: domain.TerminalActive},
+	}, nil).Times(1)
+
+	w := serve(router, http.MethodGet, "/admin/heartbeats", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"terminal_id":3,"terminal_name":"T-3","status":"active","last_seen_at":"2024-05-01T12:00:00Z","firmware":"2.1.0"},
+		{"terminal_id":4,"terminal_name":"T-4","status":"active"}]`, w.Body.String())
+}
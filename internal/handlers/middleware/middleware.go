@@ -0,0 +1,191 @@
+// Package middleware holds the gin middleware every request passes through.
+package middleware
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/dvdxa/add-to-favorites/server"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key holding the request ID.
+	RequestIDKey = "requestId"
+	// ClientIdentityKey is the gin context key holding the verified client
+	// certificate identity of a mutual TLS request.
+	ClientIdentityKey = "clientIdentity"
+	// UserIDKey is the gin context key holding the signed-in user's ID, as
+	// the float64 the token claim decodes to.
+	UserIDKey = "userId"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID keeps the caller's X-Request-ID if it is sane, or assigns a new
+// one, and echoes it in the response. The request context gets a logger with
+// the request_id field, which handlers and repositories log through.
+func RequestID(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		SetLogger(c, log.WithFields(logrus.Fields{"request_id": requestID}))
+		c.Next()
+	}
+}
+
+// SetLogger replaces the request-scoped logger, for example to add the user
+// ID once the caller is authenticated.
+func SetLogger(c *gin.Context, l *logger.Logger) {
+	c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), l))
+}
+
+// AuditClient stores the caller's IP and request ID for audit entries made
+// while serving the request. It must run after RequestID.
+func AuditClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit_service.WithClient(c.Request.Context(), c.ClientIP(), c.GetString(RequestIDKey))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// PinReads sends every read made while serving the request to the same
+// database, see repositories.PinReads.
+func PinReads() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(repositories.PinReads(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// ClientCert exposes the verified client certificate of a mutual TLS request
+// to handlers through ClientIdentity, and adds its common name to the
+// request logger. Requests without one pass through unchanged. It must run
+// after RequestID.
+func ClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, ok := server.ClientIdentityFromRequest(c.Request); ok {
+			c.Set(ClientIdentityKey, id)
+			SetLogger(c, logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{"client_cn": id.CommonName}))
+		}
+		c.Next()
+	}
+}
+
+// ClientIdentity returns the identity stored by ClientCert.
+func ClientIdentity(c *gin.Context) (server.ClientIdentity, bool) {
+	id, ok := c.Get(ClientIdentityKey)
+	if !ok {
+		return server.ClientIdentity{}, false
+	}
+	return id.(server.ClientIdentity), true
+}
+
+// UserID returns the ID ValidateUser stored for the request. On failure it
+// aborts the request and returns false.
+func UserID(c *gin.Context) (int, bool) {
+	value, _ := c.Get(UserIDKey)
+	userId, ok := value.(float64)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": "failed to get user_id",
+		})
+		return 0, false
+	}
+	return int(userId), true
+}
+
+// PathID returns the named path parameter as a positive integer. On failure
+// it aborts the request and returns false.
+func PathID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": name + " must be a positive integer",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog writes one line per request once it is done.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		entry := logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      route,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      c.Writer.Size(),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+		})
+		switch {
+		case c.Writer.Status() >= http.StatusInternalServerError:
+			entry.Error("request completed")
+		case c.Writer.Status() >= http.StatusBadRequest:
+			entry.Warn("request completed")
+		default:
+			entry.Info("request completed")
+		}
+	}
+}
+
+// Recovery turns a panic in a handler into a 500 and logs it with the stack.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{
+				"panic": p,
+				"stack": string(debug.Stack()),
+			}).Error("recovered from panic")
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"err": "internal server error",
+			})
+		}()
+		c.Next()
+	}
+}
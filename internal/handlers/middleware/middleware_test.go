@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/dvdxa/add-to-favorites/server"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRouter() (*gin.Engine, *test.Hook) {
+	l, hook := test.NewNullLogger()
+	router := gin.New()
+	router.Use(RequestID(logger.Logger{Entry: logrus.NewEntry(l)}), AccessLog(), Recovery())
+	return router, hook
+}
+
+func TestRequestID(t *testing.T) {
+	router, _ := newRouter()
+	var ctxRequestID interface{}
+	router.GET("/terminals", func(c *gin.Context) {
+		ctxRequestID = logger.FromContext(c.Request.Context()).Data["request_id"]
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "propagated", header: "req-42", keep: true},
+		{name: "missing", header: ""},
+		{name: "too_long", header: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "control_chars", header: "req\n42"},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/terminals", nil)
+			if tCase.header != "" {
+				req.Header.Set(RequestIDHeader, tCase.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, requestID)
+			require.Equal(t, requestID, ctxRequestID)
+			if tCase.keep {
+				require.Equal(t, tCase.header, requestID)
+			} else {
+				require.NotEqual(t, tCase.header, requestID)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	router, hook := newRouter()
+	router.GET("/terminals/:id", func(c *gin.Context) {
+		SetLogger(c, logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{"user_id": 7}))
+		c.String(http.StatusNotFound, "gone")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/terminals/3", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, hook.AllEntries(), 1)
+	entry := hook.LastEntry()
+	require.Equal(t, logrus.WarnLevel, entry.Level)
+	require.Equal(t, "request completed", entry.Message)
+	require.Equal(t, "req-42", entry.Data["request_id"])
+	require.Equal(t, 7, entry.Data["user_id"])
+	require.Equal(t, "/terminals/:id", entry.Data["route"])
+	require.Equal(t, "/terminals/3", entry.Data["path"])
+	require.Equal(t, http.StatusNotFound, entry.Data["status"])
+	require.Equal(t, 4, entry.Data["bytes"])
+}
+
+func TestRecovery(t *testing.T) {
+	router, hook := newRouter()
+	router.GET("/terminals", func(c *gin.Context) {
+		var userId interface{} = "1"
+		_ = userId.(float64)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/terminals", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, `{"err":"internal server error"}`, w.Body.String())
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "recovered from panic", entries[0].Message)
+	require.Equal(t, "req-42", entries[0].Data["request_id"])
+	require.Contains(t, entries[0].Data["stack"], "runtime/debug.Stack")
+	require.Equal(t, http.StatusInternalServerError, entries[1].Data["status"])
+	require.Equal(t, logrus.ErrorLevel, entries[1].Level)
+}
+
+func TestClientCert(t *testing.T) {
+	router, _ := newRouter()
+	router.Use(ClientCert())
+	var identity server.ClientIdentity
+	var found bool
+	var logCN interface{}
+	router.GET("/terminals", func(c *gin.Context) {
+		identity, found = ClientIdentity(c)
+		logCN = logger.FromContext(c.Request.Context()).Data["client_cn"]
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/terminals", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	require.False(t, found)
+	require.Nil(t, logCN)
+
+	cert := &x509.Certificate{
+		Raw:          []byte("der"),
+		Subject:      pkix.Name{CommonName: "terminal-monitor"},
+		SerialNumber: big.NewInt(7),
+	}
+	req = httptest.NewRequest(http.MethodGet, "/terminals", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	require.True(t, found)
+	require.Equal(t, "terminal-monitor", identity.CommonName)
+	require.Equal(t, "7", identity.SerialNumber)
+	require.Equal(t, "terminal-monitor", logCN)
+}
+
+func TestUserID(t *testing.T) {
+	for _, tCase := range []struct {
+		userId    any
+		expStatus int
+	}{
+		{userId: float64(7), expStatus: http.StatusOK},
+		{userId: nil, expStatus: http.StatusInternalServerError},
+		{userId: "7", expStatus: http.StatusInternalServerError},
+		{userId: 7, expStatus: http.StatusInternalServerError},
+	} {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(UserIDKey, tCase.userId)
+		})
+		router.GET("/", func(c *gin.Context) {
+			if userId, ok := UserID(c); ok {
+				c.String(http.StatusOK, "%d", userId)
+			}
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, tCase.expStatus, w.Code, "%#v", tCase.userId)
+		if tCase.expStatus == http.StatusOK {
+			require.Equal(t, "7", w.Body.String())
+		}
+	}
+}
+
+func TestPathID(t *testing.T) {
+	router := gin.New()
+	router.GET("/terminals/:id", func(c *gin.Context) {
+		if id, ok := PathID(c, "id"); ok {
+			c.String(http.StatusOK, "%d", id)
+		}
+	})
+	for target, expStatus := range map[string]int{
+		"/terminals/4": http.StatusOK, "/terminals/0": http.StatusBadRequest,
+		"/terminals/-1": http.StatusBadRequest, "/terminals/x": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, expStatus, w.Code, target)
+	}
+}
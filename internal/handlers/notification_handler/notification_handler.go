@@ -0,0 +1,188 @@
+package notification_handler
+
+import (
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// team is the owner ID of team channels.
+const team = 0
+
+type NotificationHandler struct {
+	log                     logger.Logger
+	notificationServicePort services.NotificationServicePort
+}
+
+func NewNotificationHandler(log logger.Logger, notificationServicePort services.NotificationServicePort) *NotificationHandler {
+	return &NotificationHandler{
+		log:                     log,
+		notificationServicePort: notificationServicePort,
+	}
+}
+
+// GetNotificationChannels lists the user's notification channels.
+func (h *NotificationHandler) GetNotificationChannels(c *gin.Context) {
+	if userId, ok := middleware.UserID(c); ok {
+		h.getChannels(c, userId)
+	}
+}
+
+// CreateNotificationChannel adds a channel for the user from the body, such
+// as {"name":"ops","kind":"slack","target":"https://hooks.slack.com/services/...",
+// "template":"{{.TerminalName}} is {{.Status}}"}.
+func (h *NotificationHandler) CreateNotificationChannel(c *gin.Context) {
+	if userId, ok := middleware.UserID(c); ok {
+		h.createChannel(c, userId)
+	}
+}
+
+// UpdateNotificationChannel replaces one of the user's channels with the
+// body, as for CreateNotificationChannel.
+func (h *NotificationHandler) UpdateNotificationChannel(c *gin.Context) {
+	if userId, ok := middleware.UserID(c); ok {
+		h.updateChannel(c, userId)
+	}
+}
+
+func (h *NotificationHandler) DeleteNotificationChannel(c *gin.Context) {
+	if userId, ok := middleware.UserID(c); ok {
+		h.deleteChannel(c, userId)
+	}
+}
+
+// TestNotificationChannel sends a sample notification to one of the user's
+// channels.
+func (h *NotificationHandler) TestNotificationChannel(c *gin.Context) {
+	if userId, ok := middleware.UserID(c); ok {
+		h.testChannel(c, userId)
+	}
+}
+
+// GetTeamNotificationChannels lists the team channels.
+func (h *NotificationHandler) GetTeamNotificationChannels(c *gin.Context) {
+	h.getChannels(c, team)
+}
+
+// CreateTeamNotificationChannel adds a team channel from the body, which
+// lists the user IDs it notifies in "members".
+func (h *NotificationHandler) CreateTeamNotificationChannel(c *gin.Context) {
+	h.createChannel(c, team)
+}
+
+func (h *NotificationHandler) UpdateTeamNotificationChannel(c *gin.Context) {
+	h.updateChannel(c, team)
+}
+
+func (h *NotificationHandler) DeleteTeamNotificationChannel(c *gin.Context) {
+	h.deleteChannel(c, team)
+}
+
+func (h *NotificationHandler) TestTeamNotificationChannel(c *gin.Context) {
+	h.testChannel(c, team)
+}
+
+func (h *NotificationHandler) getChannels(c *gin.Context, userId int) {
+	channels, err := h.notificationServicePort.GetNotificationChannels(c.Request.Context(), userId)
+	if err != nil {
+		h.abort(c, "failed to get notification channels", err)
+		return
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+func (h *NotificationHandler) createChannel(c *gin.Context, userId int) {
+	channel, ok := bindChannel(c, userId)
+	if !ok {
+		return
+	}
+	channel, err := h.notificationServicePort.CreateNotificationChannel(c.Request.Context(), channel)
+	if err != nil {
+		h.abort(c, "failed to create notification channel", err)
+		return
+	}
+	c.JSON(http.StatusCreated, channel)
+}
+
+func (h *NotificationHandler) updateChannel(c *gin.Context, userId int) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	channel, ok := bindChannel(c, userId)
+	if !ok {
+		return
+	}
+	channel.ID = id
+	channel, err := h.notificationServicePort.UpdateNotificationChannel(c.Request.Context(), channel)
+	if err != nil {
+		h.abort(c, "failed to update notification channel", err)
+		return
+	}
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *NotificationHandler) deleteChannel(c *gin.Context, userId int) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	err := h.notificationServicePort.DeleteNotificationChannel(c.Request.Context(), userId, id)
+	if err != nil {
+		h.abort(c, "failed to delete notification channel", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) testChannel(c *gin.Context, userId int) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	err := h.notificationServicePort.TestNotificationChannel(c.Request.Context(), userId, id)
+	if err != nil {
+		h.abort(c, "failed to test notification channel", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// abort maps not found to 404, invalid channels to 400 and a channel that
+// could not be sent to 502.
+func (h *NotificationHandler) abort(c *gin.Context, failMsg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidNotificationChannel):
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNotificationFailed):
+		status = http.StatusBadGateway
+	default:
+		h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
+
+// bindChannel reads the channel in the body and makes userId its owner. On
+// failure it aborts the request and returns false.
+func bindChannel(c *gin.Context, userId int) (domain.NotificationChannel, bool) {
+	var channel domain.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return channel, false
+	}
+	channel.ID = 0
+	channel.UserID = userId
+	return channel, true
+}
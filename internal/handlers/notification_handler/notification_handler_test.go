@@ -0,0 +1,114 @@
+package notification_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/notification_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockNotificationRepositoryPort, *repoMock.MockUserRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	notificationRepo := repoMock.NewMockNotificationRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	h := NewNotificationHandler(*log, notification_service.NewNotificationService(notificationRepo, userRepo, notification_service.Config{}))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/notifications/channels", h.GetNotificationChannels)
+	router.POST("/notifications/channels", h.CreateNotificationChannel)
+	router.PUT("/notifications/channels/:id", h.UpdateNotificationChannel)
+	router.DELETE("/notifications/channels/:id", h.DeleteNotificationChannel)
+	router.POST("/notifications/channels/:id/test", h.TestNotificationChannel)
+	router.POST("/admin/notifications/channels", h.CreateTeamNotificationChannel)
+	router.DELETE("/admin/notifications/channels/:id", h.DeleteTeamNotificationChannel)
+	return router, notificationRepo, userRepo
+}
+
+var createdAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestCreateNotificationChannel(t *testing.T) {
+	router, notificationRepo, _ := newRouter(t)
+	notificationRepo.EXPECT().CreateNotificationChannel(gomock.Any(), domain.NotificationChannel{
+		UserID: 1, Name: "ops", Kind: domain.ChannelSlack, Target: "https://hooks.example.com/x", Template: "{{.TerminalName}}",
+	}).DoAndReturn(func(_ any, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+		channel.ID = 4
+		channel.CreatedAt = createdAt
+		return channel, nil
+	}).Times(1)
+
+	w := handlertest.Serve(router, http.MethodPost, "/notifications/channels",
+		`{"id":9,"name":"ops","kind":"slack","target":"https://hooks.example.com/x","template":"{{.TerminalName}}"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.JSONEq(t, `{"id":4,"name":"ops","kind":"slack","target":"https://hooks.example.com/x","template":"{{.TerminalName}}",
+		"created_at":"2024-05-01T12:00:00Z"}`, w.Body.String())
+
+	for _, body := range []string{
+		`{"name":"ops","kind":"slack","target":"https://hooks.example.com/x","template":"{{.Nope}}"}`,
+		`{"name":"ops","kind":"slack","target":"https://hooks.example.com/x","members":[2]}`,
+		`{"name":"mail","kind":"email","target":"ops@example.com"}`,
+	} {
+		require.Equal(t, http.StatusBadRequest, handlertest.Serve(router, http.MethodPost, "/notifications/channels", body).Code, body)
+	}
+}
+
+func TestCreateTeamNotificationChannel(t *testing.T) {
+	router, notificationRepo, userRepo := newRouter(t)
+	userRepo.EXPECT().GetUserByID(gomock.Any(), 2).Return(domain.User{ID: 2}, nil).Times(1)
+	notificationRepo.EXPECT().CreateNotificationChannel(gomock.Any(), domain.NotificationChannel{
+		Name: "ops", Kind: domain.ChannelSlack, Target: "https://hooks.example.com/x", Members: []int{2},
+	}).Return(domain.NotificationChannel{ID: 5}, nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodPost, "/admin/notifications/channels",
+		`{"name":"ops","kind":"slack","target":"https://hooks.example.com/x","members":[2]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	notificationRepo.EXPECT().DeleteNotificationChannel(gomock.Any(), 0, 5).Return(nil).Times(1)
+	require.Equal(t, http.StatusNoContent, handlertest.Serve(router, http.MethodDelete, "/admin/notifications/channels/5", "").Code)
+}
+
+func TestUpdateNotificationChannel(t *testing.T) {
+	router, notificationRepo, _ := newRouter(t)
+	channel := domain.NotificationChannel{ID: 4, UserID: 1, Name: "ops", Kind: domain.ChannelSlack, Target: "https://hooks.example.com/y"}
+	notificationRepo.EXPECT().UpdateNotificationChannel(gomock.Any(), channel).Return(nil).Times(1)
+	notificationRepo.EXPECT().GetNotificationChannel(gomock.Any(), 1, 4).Return(channel, nil).Times(1)
+	w := handlertest.Serve(router, http.MethodPut, "/notifications/channels/4", `{"name":"ops","kind":"slack","target":"https://hooks.example.com/y"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	channel.ID = 5
+	notificationRepo.EXPECT().UpdateNotificationChannel(gomock.Any(), channel).Return(repositories.ErrNotFound).Times(1)
+	w = handlertest.Serve(router, http.MethodPut, "/notifications/channels/5", `{"name":"ops","kind":"slack","target":"https://hooks.example.com/y"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTestNotificationChannel(t *testing.T) {
+	var received int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer receiver.Close()
+
+	router, notificationRepo, _ := newRouter(t)
+	notificationRepo.EXPECT().GetNotificationChannel(gomock.Any(), 1, 4).Return(domain.NotificationChannel{
+		ID: 4, Kind: domain.ChannelSlack, Target: receiver.URL + "/ok",
+	}, nil).Times(1)
+	notificationRepo.EXPECT().GetNotificationChannel(gomock.Any(), 1, 5).Return(domain.NotificationChannel{
+		ID: 5, Kind: domain.ChannelSlack, Target: receiver.URL + "/gone",
+	}, nil).Times(1)
+
+	require.Equal(t, http.StatusNoContent, handlertest.Serve(router, http.MethodPost, "/notifications/channels/4/test", "").Code)
+	require.Equal(t, http.StatusBadGateway, handlertest.Serve(router, http.MethodPost, "/notifications/channels/5/test", "").Code)
+	require.Equal(t, 2, received)
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"github.com/dvdxa/add-to-favorites/internal/handlers/admin_handler"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/alert_handler"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/audit_handler"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/heartbeat_handler"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/notification_handler"
 	"github.com/dvdxa/add-to-favorites/internal/handlers/terminal_handler"
 	"github.com/dvdxa/add-to-favorites/internal/handlers/user_handler"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/webhook_handler"
 	"github.com/dvdxa/add-to-favorites/internal/services"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/gin-gonic/gin"
@@ -11,19 +18,93 @@ import (
 type Handler struct {
 	user_handler.UserHandler
 	terminal_handler.TerminalHandler
+	admin_handler.AdminHandler
+	audit_handler.AuditHandler
+	alert_handler.AlertHandler
+	webhook_handler.WebhookHandler
+	notification_handler.NotificationHandler
+	heartbeat_handler.HeartbeatHandler
+	log logger.Logger
 }
 
 func NewHandler(log logger.Logger, service services.ServicePort) *Handler {
 	return &Handler{
-		UserHandler:     *user_handler.NewUserHandler(log, service.UserServicePort),
-		TerminalHandler: *terminal_handler.NewTerminalHandler(log, service.TerminalServicePort),
+		UserHandler:         *user_handler.NewUserHandler(log, service.UserServicePort),
+		TerminalHandler:     *terminal_handler.NewTerminalHandler(log, service.TerminalServicePort),
+		AdminHandler:        *admin_handler.NewAdminHandler(log, service.AdminServicePort),
+		AuditHandler:        *audit_handler.NewAuditHandler(log, service.AuditServicePort),
+		AlertHandler:        *alert_handler.NewAlertHandler(log, service.AlertServicePort),
+		WebhookHandler:      *webhook_handler.NewWebhookHandler(log, service.WebhookServicePort),
+		NotificationHandler: *notification_handler.NewNotificationHandler(log, service.NotificationServicePort),
+		HeartbeatHandler:    *heartbeat_handler.NewHeartbeatHandler(log, service.HeartbeatServicePort),
+		log:                 log,
 	}
 }
-func (h *Handler) InitRoutes() *gin.Engine {
+
+// InitRoutes registers the API. Every request gets a request ID, an access
+// log line and panic recovery, and its reads are pinned to one database. extra middleware runs for every request too,
+// including unmatched ones, after the request ID is set and outside the
+// recovery, so it sees the final status of a request that panicked.
+func (h *Handler) InitRoutes(extra ...gin.HandlerFunc) *gin.Engine {
 
 	router := gin.New()
+	router.Use(middleware.RequestID(h.log), middleware.ClientCert(), middleware.AuditClient(), middleware.PinReads())
+	router.Use(extra...)
+	router.Use(middleware.AccessLog(), middleware.Recovery())
 	router.POST("/user/sign-up", h.SignUp)
 	router.POST("/user/sign-in", h.SignIn)
+	router.POST("/ingest/heartbeat", h.IngestHeartbeat)
 	router.GET("/terminals", h.ValidateUser, h.GetTerminalsWithFavorites)
+	router.GET("/terminals/geojson", h.ValidateUser, h.GetTerminalsGeoJSON)
+	router.PUT("/favorites/:id/notes", h.ValidateUser, h.SetFavoriteNote)
+	router.POST("/favorites/undo", h.ValidateUser, h.UndoRemoveFavorite)
+	router.GET("/views", h.ValidateUser, h.GetViews)
+	router.PUT("/views/:name", h.ValidateUser, h.SaveView)
+	router.DELETE("/views/:name", h.ValidateUser, h.DeleteView)
+	router.GET("/sites", h.ValidateUser, h.GetSites)
+	router.GET("/sites/:id", h.ValidateUser, h.GetSite)
+	router.GET("/alerts", h.ValidateUser, h.GetAlerts)
+	router.POST("/alerts/:id/ack", h.ValidateUser, h.AckAlert)
+	router.POST("/alerts/:id/mute", h.ValidateUser, h.MuteAlert)
+	router.DELETE("/alerts/:id/mute", h.ValidateUser, h.UnmuteAlert)
+	router.GET("/alerts/rules", h.ValidateUser, h.GetAlertRules)
+	router.POST("/alerts/rules", h.ValidateUser, h.CreateAlertRule)
+	router.DELETE("/alerts/rules/:id", h.ValidateUser, h.DeleteAlertRule)
+	router.GET("/notifications/channels", h.ValidateUser, h.GetNotificationChannels)
+	router.POST("/notifications/channels", h.ValidateUser, h.CreateNotificationChannel)
+	router.PUT("/notifications/channels/:id", h.ValidateUser, h.UpdateNotificationChannel)
+	router.DELETE("/notifications/channels/:id", h.ValidateUser, h.DeleteNotificationChannel)
+	router.POST("/notifications/channels/:id/test", h.ValidateUser, h.TestNotificationChannel)
+
+	admin := router.Group("/admin", h.ValidateUser, h.RequireAdmin)
+	admin.GET("/terminals/deleted", h.GetDeletedTerminals)
+	admin.DELETE("/terminals/:id", h.DeleteTerminal)
+	admin.POST("/terminals/:id/restore", h.RestoreTerminal)
+	admin.DELETE("/terminals/:id/purge", h.PurgeTerminal)
+	admin.POST("/terminals/:id/tags", h.AttachTag)
+	admin.DELETE("/terminals/:id/tags/:key/:value", h.DetachTag)
+	admin.PUT("/terminals/:id/location", h.SetTerminalLocation)
+	admin.PUT("/terminals/:id/site", h.SetTerminalSite)
+	admin.POST("/terminals/:id/heartbeat-token", h.IssueHeartbeatToken)
+	admin.DELETE("/terminals/:id/heartbeat-token", h.RevokeHeartbeatToken)
+	admin.GET("/heartbeats", h.GetHeartbeats)
+	admin.POST("/sites", h.CreateSite)
+	admin.PUT("/sites/:id", h.UpdateSite)
+	admin.DELETE("/sites/:id", h.DeleteSite)
+	admin.GET("/users/deleted", h.GetDeletedUsers)
+	admin.DELETE("/users/:id", h.DeleteUser)
+	admin.POST("/users/:id/restore", h.RestoreUser)
+	admin.DELETE("/users/:id/purge", h.PurgeUser)
+	admin.GET("/audit", h.GetAudit)
+	admin.GET("/webhooks", h.GetWebhooks)
+	admin.POST("/webhooks", h.CreateWebhook)
+	admin.DELETE("/webhooks/:id", h.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+	admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+	admin.GET("/notifications/channels", h.GetTeamNotificationChannels)
+	admin.POST("/notifications/channels", h.CreateTeamNotificationChannel)
+	admin.PUT("/notifications/channels/:id", h.UpdateTeamNotificationChannel)
+	admin.DELETE("/notifications/channels/:id", h.DeleteTeamNotificationChannel)
+	admin.POST("/notifications/channels/:id/test", h.TestTeamNotificationChannel)
 	return router
 }
@@ -0,0 +1,47 @@
+package terminal_handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// favoritesETag combines the user's favorites version with a digest of the
+// rendered list. The digest makes If-None-Match notice changes to terminals
+// themselves, while If-Match only compares the version part.
+func favoritesETag(version int64, body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`"%d-%016x"`, version, h.Sum64())
+}
+
+// ifMatchVersion extracts the favorites version from an If-Match header.
+// conditional is false when the header is absent or "*". A tag that cannot be
+// parsed yields version -1, which never matches, so the request fails with 412.
+func ifMatchVersion(header string) (version int64, conditional bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false
+	}
+	tag := strings.TrimSpace(strings.Split(header, ",")[0])
+	tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+	versionStr, _, _ := strings.Cut(tag, "-")
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil || version < 0 {
+		return -1, true
+	}
+	return version, true
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for that header.
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package terminal_handler
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	cases := []struct {
+		header      string
+		version     int64
+		conditional bool
+	}{
+		{header: "", version: 0, conditional: false},
+		{header: "*", version: 0, conditional: false},
+		{header: `"7-00000000000000ff"`, version: 7, conditional: true},
+		{header: `W/"7-00000000000000ff"`, version: 7, conditional: true},
+		{header: `"12-00000000000000ff", "13-00000000000000ff"`, version: 12, conditional: true},
+		{header: `"garbage"`, version: -1, conditional: true},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.header, func(t *testing.T) {
+			version, conditional := ifMatchVersion(tCase.header)
+			require.Equal(t, tCase.version, version)
+			require.Equal(t, tCase.conditional, conditional)
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := favoritesETag(3, []byte("[]"))
+	require.True(t, etagMatches(etag, etag))
+	require.True(t, etagMatches("W/"+etag, etag))
+	require.True(t, etagMatches(`"1-0000000000000000", `+etag, etag))
+	require.True(t, etagMatches("*", etag))
+	require.False(t, etagMatches("", etag))
+	require.False(t, etagMatches(favoritesETag(3, []byte("[{}]")), etag))
+}
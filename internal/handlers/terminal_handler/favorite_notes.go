@@ -0,0 +1,68 @@
+package terminal_handler
+
+import (
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// SetFavoriteNote replaces the alias, colour and note on one of the user's
+// favorites with the JSON body and answers with the updated listing. An
+// empty body object clears them.
+func (h *TerminalHandler) SetFavoriteNote(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	terminalID, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	var note domain.FavoriteNote
+	err := c.ShouldBindJSON(&note)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	err = h.terminalServicePort.SetFavoriteNote(c.Request.Context(), userId, terminalID, note)
+	if err != nil {
+		h.abortFavoriteChange(c, "failed to set favorite note", err)
+		return
+	}
+	h.writeTerminals(c, userId, http.StatusOK)
+}
+
+// UndoRemoveFavorite adds back the favorite the user removed last, together
+// with its alias, colour and note, and answers with the updated listing.
+func (h *TerminalHandler) UndoRemoveFavorite(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	_, err := h.terminalServicePort.UndoRemoveFavorite(c.Request.Context(), userId)
+	if err != nil {
+		h.abortFavoriteChange(c, "failed to undo favorite removal", err)
+		return
+	}
+	h.writeTerminals(c, userId, http.StatusOK)
+}
+
+func (h *TerminalHandler) abortFavoriteChange(c *gin.Context, failMsg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidFavoriteNote):
+		status = http.StatusBadRequest
+	default:
+		h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
@@ -0,0 +1,125 @@
+package terminal_handler
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var notesTestTerminals = []domain.Terminal{
+	{ID: 1, Name: "T-111", Status: "active"},
+	{ID: 2, Name: "T-112", Status: "offline"},
+}
+
+func newNotesRouter(t *testing.T) (*gin.Engine, *repoMock.MockTerminalRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).AnyTimes()
+	h := NewTerminalHandler(*log, terminal_service.NewTerminalService(terminalRepo, uow))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/terminals", h.GetTerminalsWithFavorites)
+	router.PUT("/favorites/:id/notes", h.SetFavoriteNote)
+	router.POST("/favorites/undo", h.UndoRemoveFavorite)
+	return router, terminalRepo
+}
+
+func expectNotesListing(terminalRepo *repoMock.MockTerminalRepositoryPort, notes map[int]domain.FavoriteNote) {
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(3), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{2}, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(notes, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(notesTestTerminals, nil)
+}
+
+// test case: notes are listed with the favorite and are searchable
+func TestGetTerminalsWithNotes(t *testing.T) {
+	router, terminalRepo := newNotesRouter(t)
+	notes := map[int]domain.FavoriteNote{2: {Alias: "lobby kiosk", Color: "#ff8800", Note: "call Bob if down"}}
+	kiosk := `{"id":2,"name":"T-112","status":"offline","is_favorite":true,"alias":"lobby kiosk","color":"#ff8800","note":"call Bob if down"}`
+
+	expectNotesListing(terminalRepo, notes)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `[`+kiosk+`,{"id":1,"name":"T-111","status":"active","is_favorite":false}]`, w.Body.String())
+
+	for query, exp := range map[string]string{
+		"Lobby": `[` + kiosk + `]`,
+		"bob":   `[` + kiosk + `]`,
+		"t-111": `[{"id":1,"name":"T-111","status":"active","is_favorite":false}]`,
+		"atm":   `[]`,
+	} {
+		expectNotesListing(terminalRepo, notes)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?q="+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, exp, w.Body.String(), query)
+	}
+}
+
+func TestSetFavoriteNote(t *testing.T) {
+	router, terminalRepo := newNotesRouter(t)
+
+	stored := domain.FavoriteNote{Alias: "lobby kiosk", Color: "#ff8800", Note: "call Bob if down"}
+	terminalRepo.EXPECT().SetFavoriteNote(gomock.Any(), 1, 2, stored).Return(nil)
+	expectNotesListing(terminalRepo, map[int]domain.FavoriteNote{2: stored})
+	w := httptest.NewRecorder()
+	body := `{"alias":" lobby kiosk ","color":"#FF8800","note":"call Bob if down"}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/favorites/2/notes", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"alias":"lobby kiosk","color":"#ff8800"`)
+
+	terminalRepo.EXPECT().SetFavoriteNote(gomock.Any(), 1, 1, domain.FavoriteNote{Alias: "atm"}).
+		Return(repositories.ErrNotFound)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/favorites/1/notes", strings.NewReader(`{"alias":"atm"}`)))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	for _, tCase := range []struct{ path, body, exp string }{
+		{path: "/favorites/2/notes", body: `{"color":"orange"}`, exp: "invalid favorite note"},
+		{path: "/favorites/x/notes", body: `{}`, exp: "id must be a positive integer"},
+		{path: "/favorites/2/notes", body: `{"alias":`, exp: "unexpected EOF"},
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tCase.path, strings.NewReader(tCase.body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, tCase.body)
+		require.Contains(t, w.Body.String(), tCase.exp)
+	}
+}
+
+func TestUndoRemoveFavorite(t *testing.T) {
+	router, terminalRepo := newNotesRouter(t)
+
+	note := domain.FavoriteNote{Alias: "lobby kiosk"}
+	gomock.InOrder(
+		terminalRepo.EXPECT().RestoreRemovedFavoriteNote(gomock.Any(), 1).Return(2, nil),
+		terminalRepo.EXPECT().AddToFavorites(gomock.Any(), 2, 1).Return(nil),
+	)
+	expectNotesListing(terminalRepo, map[int]domain.FavoriteNote{2: note})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/favorites/undo", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `{"id":2,"name":"T-112","status":"offline","is_favorite":true,"alias":"lobby kiosk"}`)
+
+	terminalRepo.EXPECT().RestoreRemovedFavoriteNote(gomock.Any(), 1).Return(0, repositories.ErrNotFound)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/favorites/undo", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
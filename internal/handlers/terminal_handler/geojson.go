@@ -0,0 +1,40 @@
+package terminal_handler
+
+import (
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const geoJSONContentType = "application/geo+json"
+
+// GetTerminalsGeoJSON exports the terminals the listing would return, with
+// the same parameters and view, as a GeoJSON feature collection for map
+// UIs. Terminals without a location are left out; favorites_only=true
+// exports just the favorites.
+func (h *TerminalHandler) GetTerminalsGeoJSON(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	opts, ok := h.listOptions(ctx, c, userId)
+	if !ok {
+		return
+	}
+	terminals, ok := h.sortedTerminals(ctx, c, userId, opts)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(domain.NewFeatureCollection(terminals))
+	if err != nil {
+		h.log.For(ctx).Errorf("failed to marshal terminals: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, geoJSONContentType, data)
+}
@@ -0,0 +1,124 @@
+package terminal_handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var etagTestTerminals = []domain.Terminal{
+	{ID: 1, Name: "terminal1", Status: "active"},
+	{ID: 2, Name: "terminal2", Status: "active"},
+}
+
+func newETagRouter(t *testing.T) (*gin.Engine, *repoMock.MockTerminalRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).AnyTimes()
+	h := NewTerminalHandler(*log, terminal_service.NewTerminalService(terminalRepo, uow))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/terminals", h.GetTerminalsWithFavorites)
+	return router, terminalRepo
+}
+
+func expectListing(terminalRepo *repoMock.MockTerminalRepositoryPort, version int64, favoriteIDs []int) {
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(version, nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return(favoriteIDs, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(etagTestTerminals, nil)
+}
+
+func favoritesRequest(t *testing.T, body *Request, headers map[string]string) *http.Request {
+	var req *http.Request
+	if body == nil {
+		req = httptest.NewRequest(http.MethodGet, "/terminals", nil)
+	} else {
+		jsonData, err := json.Marshal(body)
+		require.NoError(t, err)
+		req = httptest.NewRequest(http.MethodGet, "/terminals", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "Application/Json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+// test case: bodyless listing returns an ETag and If-None-Match gets 304
+func TestGetTerminalsIfNoneMatch(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	expectListing(terminalRepo, 3, []int{2})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, favoritesRequest(t, nil, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.Regexp(t, `^"3-[0-9a-f]{16}"$`, etag)
+	require.Equal(t,
+		"[{\"id\":2,\"name\":\"terminal2\",\"status\":\"active\",\"is_favorite\":true},"+
+			"{\"id\":1,\"name\":\"terminal1\",\"status\":\"active\",\"is_favorite\":false}]",
+		w.Body.String())
+
+	expectListing(terminalRepo, 3, []int{2})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, favoritesRequest(t, nil, map[string]string{"If-None-Match": "W/" + etag}))
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Equal(t, etag, w.Header().Get("ETag"))
+	require.Empty(t, w.Body.String())
+
+	expectListing(terminalRepo, 4, []int{1, 2})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, favoritesRequest(t, nil, map[string]string{"If-None-Match": etag}))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+// test case: If-Match with a stale version returns 412 and the current state
+func TestGetTerminalsIfMatchStale(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(5), nil)
+	expectListing(terminalRepo, 5, []int{1})
+
+	body := &Request{TerminalID: 2, IsFavorite: "true"}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, favoritesRequest(t, body, map[string]string{"If-Match": `"4-0123456789abcdef"`}))
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	require.Regexp(t, `^"5-[0-9a-f]{16}"$`, w.Header().Get("ETag"))
+	require.Equal(t,
+		"[{\"id\":1,\"name\":\"terminal1\",\"status\":\"active\",\"is_favorite\":true},"+
+			"{\"id\":2,\"name\":\"terminal2\",\"status\":\"active\",\"is_favorite\":false}]",
+		w.Body.String())
+}
+
+// test case: If-Match with the current version applies the change
+func TestGetTerminalsIfMatchCurrent(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(5), nil)
+	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), 1, 1).Return(nil).Times(1)
+	expectListing(terminalRepo, 6, nil)
+
+	body := &Request{TerminalID: 1, IsFavorite: "false"}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, favoritesRequest(t, body, map[string]string{"If-Match": `"5-0123456789abcdef"`}))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Regexp(t, `^"6-[0-9a-f]{16}"$`, w.Header().Get("ETag"))
+}
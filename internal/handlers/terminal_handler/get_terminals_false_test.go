@@ -2,10 +2,12 @@ package terminal_handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
 	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
@@ -25,8 +27,8 @@ func TestGetTerminalWithFavoritesFalse(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -70,11 +72,14 @@ func TestGetTerminalWithFavoritesFalse(t *testing.T) {
 		TerminalID: 1,
 		IsFavorite: "false",
 	}
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(body.TerminalID, userID).Return(nil)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(favoriteTerminalIDS, nil)
-	terminalRepo.EXPECT().GetDefaultTerminalsList().Return(defaultTerminals, nil)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), body.TerminalID, userID).Return(nil)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(favoriteTerminalIDS, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), userID).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(defaultTerminals, nil)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	router := gin.Default()
@@ -112,8 +117,8 @@ func TestGetTerminalWithFavoritesRemoveErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -135,9 +140,9 @@ func TestGetTerminalWithFavoritesRemoveErr(t *testing.T) {
 		IsFavorite: "false",
 	}
 	repoErr := errors.New("DB is down")
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(body.TerminalID, userID).Return(repoErr)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), body.TerminalID, userID).Return(repoErr)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	router := gin.Default()
@@ -171,8 +176,8 @@ func TestGetTerminalWithFavoritesTermIdsErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -194,10 +199,12 @@ func TestGetTerminalWithFavoritesTermIdsErr(t *testing.T) {
 		IsFavorite: "false",
 	}
 	repoErr := errors.New("DB is down")
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(body.TerminalID, userID).Return(nil)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(nil, repoErr)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), body.TerminalID, userID).Return(nil)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(nil, repoErr)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	router := gin.Default()
@@ -231,8 +238,8 @@ func TestGetTerminalWithFavoritesSortTerminalsError(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	favoriteTerminalIds := []int{1, 2, 3}
@@ -255,11 +262,14 @@ func TestGetTerminalWithFavoritesSortTerminalsError(t *testing.T) {
 		IsFavorite: "false",
 	}
 	repoErr := errors.New("DB is down")
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(body.TerminalID, userID).Return(nil)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(favoriteTerminalIds, nil)
-	terminalRepo.EXPECT().GetDefaultTerminalsList().Return(nil, repoErr)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), body.TerminalID, userID).Return(nil)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(favoriteTerminalIds, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), userID).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(nil, repoErr)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	router := gin.Default()
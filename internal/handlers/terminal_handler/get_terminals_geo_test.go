@@ -0,0 +1,87 @@
+package terminal_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var geoTestTerminals = []domain.Terminal{
+	{ID: 1, Name: "gate", Status: "active", Location: &domain.GeoPoint{Lat: 52.5163, Lon: 13.3777}},
+	{ID: 2, Name: "tower", Status: "offline", Location: &domain.GeoPoint{Lat: 52.5208, Lon: 13.4094}, Address: "Panoramastr. 1A"},
+	{ID: 3, Name: "unknown", Status: "active"},
+}
+
+func expectGeoListing(terminalRepo *repoMock.MockTerminalRepositoryPort) {
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{1}, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(geoTestTerminals, nil)
+}
+
+// test case: near lists located terminals with their distance, radius
+// narrows them down and sort=distance orders by it
+func TestGetTerminalsNear(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	expectGeoListing(terminalRepo)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(1), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?near=52.5219,13.4132&radius=3km&sort=distance&favorites_first=false", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[
+		{"id":2,"name":"tower","status":"offline","is_favorite":false,"location":{"lat":52.5208,"lon":13.4094},"address":"Panoramastr. 1A","distance_m":285},
+		{"id":1,"name":"gate","status":"active","is_favorite":true,"location":{"lat":52.5163,"lon":13.3777},"distance_m":2481}]`, w.Body.String())
+}
+
+// test case: malformed geo parameters are rejected
+func TestGetTerminalsInvalidNear(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	for _, query := range []string{
+		"near=52.52", "near=95,13", "near=52.52,13.4&radius=-1", "near=52.52,13.4&radius=far",
+		"radius=2km", "sort=distance", "favorites_only=yes",
+	} {
+		terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+// test case: the GeoJSON export holds the located terminals the listing
+// selects
+func TestGetTerminalsGeoJSON(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	h := NewTerminalHandler(*logger.GetLogger(), terminal_service.NewTerminalService(terminalRepo, nil))
+	router.GET("/terminals/geojson", func(c *gin.Context) {
+		c.Set("userId", float64(1))
+		h.GetTerminalsGeoJSON(c)
+	})
+
+	expectGeoListing(terminalRepo)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals/geojson", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[13.3777,52.5163]},
+			"properties":{"name":"gate","status":"active","is_favorite":true}},
+		{"type":"Feature","id":2,"geometry":{"type":"Point","coordinates":[13.4094,52.5208]},
+			"properties":{"name":"tower","status":"offline","is_favorite":false,"address":"Panoramastr. 1A"}}]}`, w.Body.String())
+
+	expectGeoListing(terminalRepo)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals/geojson?favorites_only=true", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":1,"geometry":{"type":"Point","coordinates":[13.3777,52.5163]},
+			"properties":{"name":"gate","status":"active","is_favorite":true}}]}`, w.Body.String())
+}
@@ -0,0 +1,47 @@
+package terminal_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// test case: label selectors filter the listing and tags are returned
+func TestGetTerminalsLabelSelectors(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{3}, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{
+		{ID: 1, Name: "terminal1", Status: "active", Tags: []domain.Tag{{Key: "type", Value: "atm"}, {Key: "zone", Value: "north"}}},
+		{ID: 2, Name: "terminal2", Status: "active", Tags: []domain.Tag{{Key: "type", Value: "atm"}, {Key: "zone", Value: "south"}}},
+		{ID: 3, Name: "terminal3", Status: "active", Tags: []domain.Tag{{Key: "type", Value: "atm"}}},
+		{ID: 4, Name: "terminal4", Status: "active", Tags: []domain.Tag{{Key: "type", Value: "kiosk"}}},
+	}, nil)
+
+	query := url.Values{"label": {"type=atm", "zone!=north"}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?"+query.Encode(), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t,
+		`[{"id":3,"name":"terminal3","status":"active","is_favorite":true,"tags":[{"key":"type","value":"atm"}]},`+
+			`{"id":2,"name":"terminal2","status":"active","is_favorite":false,"tags":[{"key":"type","value":"atm"},{"key":"zone","value":"south"}]}]`,
+		w.Body.String())
+}
+
+// test case: an invalid selector is rejected before the listing is read
+func TestGetTerminalsInvalidLabelSelector(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	query := url.Values{"label": {"type=a b"}}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?"+query.Encode(), nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid tag")
+}
@@ -2,10 +2,12 @@ package terminal_handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
 	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
@@ -25,8 +27,8 @@ func TestGetTerminalWithFavorites(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -66,10 +68,13 @@ func TestGetTerminalWithFavorites(t *testing.T) {
 		},
 	}
 
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(favoriteTerminalIDS, nil)
-	terminalRepo.EXPECT().GetDefaultTerminalsList().Return(defaultTerminals, nil)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(favoriteTerminalIDS, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), userID).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(defaultTerminals, nil)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	body := Request{
@@ -112,8 +117,8 @@ func TestGetTerminalWithFavoritesUserIdErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -133,8 +138,8 @@ func TestGetTerminalWithFavoritesUserIdErr(t *testing.T) {
 		TerminalID: 0,
 		IsFavorite: "nil",
 	}
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil)
+	token, err := userService.GenerateToken(context.Background(), user)
 	if err != nil {
 		require.Error(t, err)
 	}
@@ -168,8 +173,8 @@ func TestGetTerminalWithFavoritesBadJSON(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 	userID := 1
 	pass := "123456Khalid"
@@ -188,8 +193,8 @@ func TestGetTerminalWithFavoritesBadJSON(t *testing.T) {
 	body := Request{
 		TerminalID: 0,
 	}
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil)
+	token, err := userService.GenerateToken(context.Background(), user)
 	if err != nil {
 		require.Error(t, err)
 	}
@@ -224,8 +229,8 @@ func TestGetTerminalWithFavoritesServiceErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -243,9 +248,11 @@ func TestGetTerminalWithFavoritesServiceErr(t *testing.T) {
 	}
 
 	repoErr := errors.New("DB is down")
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(nil, repoErr)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(nil, repoErr)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	body := Request{
@@ -284,8 +291,8 @@ func TestGetTerminalWithFavoritesSortErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
 	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	userService := user_service.NewUserService(userRepo)
-	terminalService := terminal_service.NewTerminalService(terminalRepo)
+	userService := user_service.NewUserService(userRepo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	terminalService := terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl))
 	h := NewTerminalHandler(*log, terminalService)
 
 	pass := "123456Khalid"
@@ -304,10 +311,13 @@ func TestGetTerminalWithFavoritesSortErr(t *testing.T) {
 	}
 
 	repoErr := errors.New("DB is down")
-	userRepo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	terminalRepo.EXPECT().GetFavoriteTerminalIds(userID).Return(favoriteTerminalIDs, nil)
-	terminalRepo.EXPECT().GetDefaultTerminalsList().Return(nil, repoErr)
-	token, err := userService.GenerateToken(user)
+	userRepo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), userID).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), userID).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userID).Return(favoriteTerminalIDs, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), userID).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(nil, repoErr)
+	token, err := userService.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 
 	body := Request{
@@ -0,0 +1,47 @@
+package terminal_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// test case: sort, order and favorites_first order the listing
+func TestGetTerminalsSorted(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	changed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{1}, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{
+		{ID: 1, Name: "terminal1", Status: "active", StatusChangedAt: &changed},
+		{ID: 2, Name: "terminal2", Status: "offline"},
+		{ID: 3, Name: "terminal3", Status: "active"},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?sort=status&order=desc&favorites_first=false", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t,
+		`[{"id":2,"name":"terminal2","status":"offline","is_favorite":false},`+
+			`{"id":1,"name":"terminal1","status":"active","is_favorite":true,"status_changed_at":"2024-05-01T12:00:00Z"},`+
+			`{"id":3,"name":"terminal3","status":"active","is_favorite":false}]`,
+		w.Body.String())
+}
+
+// test case: unknown sort parameters are rejected
+func TestGetTerminalsInvalidSort(t *testing.T) {
+	router, terminalRepo := newETagRouter(t)
+	for _, query := range []string{"sort=rank", "order=down", "favorites_first=maybe"} {
+		terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
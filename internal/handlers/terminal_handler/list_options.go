@@ -0,0 +1,147 @@
+package terminal_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseListOptions reads the listing filters and order from query
+// parameters. Every label parameter is a tag selector, such as
+// label=type=atm or label=zone!=north, and a terminal must match all of
+// them. q searches names, aliases and notes. near=lat,lon lists only
+// terminals with a location, with their distance from that point, and
+// radius, in meters or with an m or km suffix, only those that close.
+// favorites_only=true leaves out the other terminals and site=<id> those
+// outside that site and the sites below it. sort picks the order,
+// order=desc reverses it and favorites_first=false mixes favorites in.
+// Unknown parameters are ignored.
+func parseListOptions(query url.Values) (terminal_service.ListOptions, error) {
+	opts := terminal_service.ListOptions{Search: strings.TrimSpace(query.Get("q"))}
+	for _, raw := range query["label"] {
+		sel, err := domain.ParseTagSelector(raw)
+		if err != nil {
+			return opts, err
+		}
+		opts.Selectors = append(opts.Selectors, sel)
+	}
+	var err error
+	opts.SortBy, err = terminal_service.ParseSortKey(query.Get("sort"))
+	if err != nil {
+		return opts, err
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, errors.New("order must be asc or desc")
+	}
+	switch query.Get("favorites_first") {
+	case "", "true":
+	case "false":
+		opts.MixFavorites = true
+	default:
+		return opts, errors.New("favorites_first must be true or false")
+	}
+	switch query.Get("favorites_only") {
+	case "", "false":
+	case "true":
+		opts.FavoritesOnly = true
+	default:
+		return opts, errors.New("favorites_only must be true or false")
+	}
+	if site := query.Get("site"); site != "" {
+		opts.Site, err = strconv.Atoi(site)
+		if err != nil || opts.Site <= 0 {
+			return opts, fmt.Errorf("site %q must be a site ID", site)
+		}
+	}
+	if near := query.Get("near"); near != "" {
+		point, err := domain.ParseGeoPoint(near)
+		if err != nil {
+			return opts, err
+		}
+		opts.Near = &point
+	}
+	if radius := query.Get("radius"); radius != "" {
+		if opts.Near == nil {
+			return opts, errors.New("radius needs near")
+		}
+		opts.Radius, err = parseRadius(radius)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if opts.SortBy == terminal_service.SortByDistance && opts.Near == nil {
+		return opts, errors.New("sort=distance needs near")
+	}
+	return opts, nil
+}
+
+// parseRadius reads a positive distance in meters, such as 2000, 2000m or
+// 2km.
+func parseRadius(raw string) (float64, error) {
+	unit := 1.0
+	number := raw
+	if strings.HasSuffix(raw, "km") {
+		unit, number = 1000, strings.TrimSuffix(raw, "km")
+	} else {
+		number = strings.TrimSuffix(raw, "m")
+	}
+	radius, err := strconv.ParseFloat(number, 64)
+	if err != nil || !(radius > 0) || math.IsInf(radius, 1) {
+		return 0, fmt.Errorf("radius %q must be a positive distance in meters, or with an m or km suffix", raw)
+	}
+	return radius * unit, nil
+}
+
+// listOptions applies the view the request selects, or the user's default
+// view if it has no view parameter, under the request's own parameters.
+// An empty view parameter lists without any view. The applied view is named
+// in the X-View response header.
+// On failure it aborts the request and returns false.
+func (h *TerminalHandler) listOptions(ctx context.Context, c *gin.Context, userId int) (terminal_service.ListOptions, bool) {
+	query := c.Request.URL.Query()
+	ref, selected := query[domain.ViewParam]
+	if !selected || ref[0] != "" {
+		var viewRef string
+		if selected {
+			viewRef = ref[0]
+		}
+		view, err := h.terminalServicePort.ResolveView(ctx, userId, viewRef)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, repositories.ErrNotFound) {
+				status = http.StatusNotFound
+			} else {
+				h.log.For(c.Request.Context()).Errorf("failed to resolve view: %v", err)
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"err": err.Error(),
+			})
+			return terminal_service.ListOptions{}, false
+		}
+		if view.ID != 0 {
+			query = view.Merge(query)
+			c.Header("X-View", view.Ref(userId))
+		}
+	}
+	opts, err := parseListOptions(query)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return opts, false
+	}
+	return opts, true
+}
@@ -0,0 +1,45 @@
+package terminal_handler
+
+import (
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetSites returns the site hierarchy with each site's terminals counted by
+// status, including those in the sites below it.
+func (h *TerminalHandler) GetSites(c *gin.Context) {
+	trees, err := h.terminalServicePort.GetSiteTree(c.Request.Context())
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get sites: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, trees)
+}
+
+// GetSite returns the site with the id in the path the way GetSites does.
+func (h *TerminalHandler) GetSite(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	node, err := h.terminalServicePort.GetSite(c.Request.Context(), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repositories.ErrNotFound) {
+			status = http.StatusNotFound
+		} else {
+			h.log.For(c.Request.Context()).Errorf("failed to get site: %v", err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, node)
+}
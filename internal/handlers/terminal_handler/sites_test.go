@@ -0,0 +1,96 @@
+package terminal_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var sitesTestSites = []domain.Site{
+	{ID: 1, Name: "North"},
+	{ID: 2, ParentID: 1, Name: "Site B"},
+	{ID: 3, ParentID: 1, Name: "Site A"},
+}
+
+var sitesTestTerminals = []domain.Terminal{
+	{ID: 1, Name: "T-111", Status: "active", SiteID: 3},
+	{ID: 2, Name: "T-112", Status: "offline", SiteID: 2},
+	{ID: 3, Name: "T-113", Status: "offline", SiteID: 2},
+	{ID: 4, Name: "T-114", Status: "active"},
+}
+
+func newSitesRouter(t *testing.T) (*gin.Engine, *repoMock.MockTerminalRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	h := NewTerminalHandler(*log, terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl)))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/terminals", h.GetTerminalsWithFavorites)
+	router.GET("/sites", h.GetSites)
+	router.GET("/sites/:id", h.GetSite)
+	return router, terminalRepo
+}
+
+// test case: the tree rolls the status counts of sub-sites up into their
+// parents
+func TestGetSites(t *testing.T) {
+	router, terminalRepo := newSitesRouter(t)
+	terminalRepo.EXPECT().GetSites(gomock.Any()).Return(sitesTestSites, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(sitesTestTerminals, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sites", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id":1,"name":"North","terminals":3,"status_counts":{"active":1,"offline":2},"children":[
+		{"id":3,"parent_id":1,"name":"Site A","terminals":1,"status_counts":{"active":1},"children":[]},
+		{"id":2,"parent_id":1,"name":"Site B","terminals":2,"status_counts":{"offline":2},"children":[]}]}]`, w.Body.String())
+}
+
+// test case: a single site is looked up by ID and unknown ones are 404
+func TestGetSite(t *testing.T) {
+	router, terminalRepo := newSitesRouter(t)
+	terminalRepo.EXPECT().GetSites(gomock.Any()).Return(sitesTestSites, nil).Times(2)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(sitesTestTerminals, nil).Times(2)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sites/2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id":2,"parent_id":1,"name":"Site B","terminals":2,"status_counts":{"offline":2},"children":[]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sites/9", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sites/x", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// test case: site lists the terminals of the site and the sites below it
+func TestGetTerminalsBySite(t *testing.T) {
+	router, terminalRepo := newSitesRouter(t)
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(domain.View{}, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetSites(gomock.Any()).Return(sitesTestSites, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(sitesTestTerminals, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?site=1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[
+		{"id":1,"name":"T-111","status":"active","is_favorite":false,"site_id":3},
+		{"id":2,"name":"T-112","status":"offline","is_favorite":false,"site_id":2},
+		{"id":3,"name":"T-113","status":"offline","is_favorite":false,"site_id":2}]`, w.Body.String())
+}
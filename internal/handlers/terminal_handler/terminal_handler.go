@@ -1,13 +1,22 @@
 package terminal_handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/gin-gonic/gin"
-	"log"
+	"io"
 	"net/http"
 )
 
+const jsonContentType = "application/json; charset=utf-8"
+
 type TerminalHandler struct {
 	log                 logger.Logger
 	terminalServicePort services.TerminalServicePort
@@ -26,100 +35,161 @@ type Request struct {
 }
 
 func (h *TerminalHandler) GetTerminalsWithFavorites(c *gin.Context) {
-	userId, ok := c.Get("userId")
-	if !ok || userId == nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"err": "failed to get user_id",
-		})
+	userIdInt, ok := middleware.UserID(c)
+	if !ok {
 		return
 	}
-	userIdfloat := userId.(float64)
-	userIdInt := int(userIdfloat)
 
 	var body Request
 	err := c.ShouldBindJSON(&body)
-	if err != nil {
+	if errors.Is(err, io.EOF) {
+		// A bodyless request is a plain listing, which is what polling
+		// clients send along with If-None-Match.
+		body.IsFavorite = "nil"
+	} else if err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 			"err": err.Error(),
 		})
 		return
 	}
+
 	if body.TerminalID == 0 && body.IsFavorite == "nil" {
-		userTerminalsIDS, err := h.terminalServicePort.GetFavoriteTerminalIds(userIdInt)
-		if err != nil {
-			h.log.Errorf("failed to get user terminal ids: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to get user terminal ids": err.Error(),
-			})
+		data, etag, ok := h.listTerminals(c.Request.Context(), c, userIdInt)
+		if !ok {
 			return
 		}
-		sortedTerminals, err := h.terminalServicePort.SortTerminals(userTerminalsIDS)
-		if err != nil {
-			h.log.Errorf("failed to sort terminals: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to sort terminals": err.Error(),
-			})
+		c.Header("ETag", etag)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
 			return
 		}
-		c.JSON(http.StatusOK, sortedTerminals)
+		c.Data(http.StatusOK, jsonContentType, data)
 		return
 	}
+
 	if body.IsFavorite == "true" {
-		err = h.terminalServicePort.AddToFavorite(body.TerminalID, userIdInt)
-		if err != nil {
-			h.log.Errorf("failed to add to favorites: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to add to favorites": err.Error(),
-			})
-			return
-		}
-		userTerminalsIDS, err := h.terminalServicePort.GetFavoriteTerminalIds(userIdInt)
-		if err != nil {
-			h.log.Errorf("failed to get user terminal ids: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to get user terminal ids": err.Error(),
-			})
+		err = h.addToFavorite(c, body.TerminalID, userIdInt)
+		if errors.Is(err, terminal_service.ErrVersionMismatch) {
+			h.writeTerminals(c, userIdInt, http.StatusPreconditionFailed)
 			return
 		}
-		log.Println(userTerminalsIDS)
-		sortedTerminals, err := h.terminalServicePort.SortTerminals(userTerminalsIDS)
 		if err != nil {
-			h.log.Errorf("failed to sort terminals: %v", err)
+			h.log.For(c.Request.Context()).Errorf("failed to add to favorites: %v", err)
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to sort terminals": err.Error(),
+				"failed to add to favorites": err.Error(),
 			})
 			return
 		}
-		log.Println(sortedTerminals)
-		c.JSON(http.StatusOK, sortedTerminals)
+		h.writeTerminals(c, userIdInt, http.StatusOK)
 		return
 	}
+
 	if body.IsFavorite == "false" {
-		err = h.terminalServicePort.RemoveFromFavoriteTerminal(body.TerminalID, userIdInt)
-		if err != nil {
-			h.log.Errorf("failed to remove terminal from favorites: %v", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"failed to remove terminal from favorites": err.Error(),
-			})
+		err = h.removeFromFavoriteTerminal(c, body.TerminalID, userIdInt)
+		if errors.Is(err, terminal_service.ErrVersionMismatch) {
+			h.writeTerminals(c, userIdInt, http.StatusPreconditionFailed)
 			return
 		}
-		userTerminalsIDS, err := h.terminalServicePort.GetFavoriteTerminalIds(userIdInt)
 		if err != nil {
-			h.log.Errorf("failed to get user terminal ids: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to get user terminal ids": err.Error(),
-			})
-			return
-		}
-		sortedTerminals, err := h.terminalServicePort.SortTerminals(userTerminalsIDS)
-		if err != nil {
-			h.log.Errorf("failed to sort terminals: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"failed to sort terminals": err.Error(),
+			h.log.For(c.Request.Context()).Errorf("failed to remove terminal from favorites: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"failed to remove terminal from favorites": err.Error(),
 			})
 			return
 		}
-		c.JSON(http.StatusOK, sortedTerminals)
+		h.writeTerminals(c, userIdInt, http.StatusOK)
+		return
+	}
+}
+
+// addToFavorite honours If-Match: when the client sends it, the change is
+// applied only if the favorites are still at the version in the tag.
+func (h *TerminalHandler) addToFavorite(c *gin.Context, terminalId int, userId int) error {
+	version, conditional := ifMatchVersion(c.GetHeader("If-Match"))
+	if !conditional {
+		return h.terminalServicePort.AddToFavorite(c.Request.Context(), terminalId, userId)
+	}
+	return h.terminalServicePort.AddToFavoriteIfMatch(c.Request.Context(), terminalId, userId, version)
+}
+
+func (h *TerminalHandler) removeFromFavoriteTerminal(c *gin.Context, terminalID int, userId int) error {
+	version, conditional := ifMatchVersion(c.GetHeader("If-Match"))
+	if !conditional {
+		return h.terminalServicePort.RemoveFromFavoriteTerminal(c.Request.Context(), terminalID, userId)
+	}
+	return h.terminalServicePort.RemoveFromFavoriteTerminalIfMatch(c.Request.Context(), terminalID, userId, version)
+}
+
+// writeTerminals answers a favorites change, or a 412 for one, with the
+// resulting list. It reads from the primary, since a replica may not have
+// the change yet, and the version the client retries with has to be current.
+func (h *TerminalHandler) writeTerminals(c *gin.Context, userId int, status int) {
+	data, etag, ok := h.listTerminals(repositories.ReadFromPrimary(c.Request.Context()), c, userId)
+	if !ok {
 		return
 	}
+	c.Header("ETag", etag)
+	c.Data(status, jsonContentType, data)
+}
+
+// listTerminals renders the user's sorted terminals, filtered by the query
+// string and view, together with their ETag. The version in the ETag and
+// the list come from the same database, since the request pins its reads.
+// On failure it aborts the request and returns false.
+func (h *TerminalHandler) listTerminals(ctx context.Context, c *gin.Context, userId int) ([]byte, string, bool) {
+	opts, ok := h.listOptions(ctx, c, userId)
+	if !ok {
+		return nil, "", false
+	}
+	version, err := h.terminalServicePort.GetFavoritesVersion(ctx, userId)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get favorites version: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"failed to get favorites version": err.Error(),
+		})
+		return nil, "", false
+	}
+	sortedTerminals, ok := h.sortedTerminals(ctx, c, userId, opts)
+	if !ok {
+		return nil, "", false
+	}
+	data, err := json.Marshal(sortedTerminals)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to marshal terminals: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return nil, "", false
+	}
+	return data, favoritesETag(version, data), true
+}
+
+// sortedTerminals lists the user's terminals as opts ask.
+// On failure it aborts the request and returns false.
+func (h *TerminalHandler) sortedTerminals(ctx context.Context, c *gin.Context, userId int, opts terminal_service.ListOptions) ([]domain.FakeTerminal, bool) {
+	userTerminalsIDS, err := h.terminalServicePort.GetFavoriteTerminalIds(ctx, userId)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get user terminal ids: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"failed to get user terminal ids": err.Error(),
+		})
+		return nil, false
+	}
+	notes, err := h.terminalServicePort.GetFavoriteNotes(ctx, userId)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get favorite notes: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"failed to get favorite notes": err.Error(),
+		})
+		return nil, false
+	}
+	sortedTerminals, err := h.terminalServicePort.SortTerminals(ctx, userTerminalsIDS, notes, opts)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to sort terminals: %v", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"failed to sort terminals": err.Error(),
+		})
+		return nil, false
+	}
+	return sortedTerminals, true
 }
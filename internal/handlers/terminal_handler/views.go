@@ -0,0 +1,97 @@
+package terminal_handler
+
+import (
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+)
+
+type viewRequest struct {
+	Params  url.Values `json:"params"`
+	Default bool       `json:"default"`
+	Shared  bool       `json:"shared"`
+}
+
+// GetViews lists the user's views followed by the ones others shared.
+func (h *TerminalHandler) GetViews(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	views, err := h.terminalServicePort.GetViews(c.Request.Context(), userId)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to get views: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// SaveView creates or replaces the user's view with the name in the path.
+// Its params must be valid /terminals parameters.
+func (h *TerminalHandler) SaveView(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	var req viewRequest
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		_, err = parseListOptions(req.Params)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	view, err := h.terminalServicePort.SaveView(c.Request.Context(), domain.View{
+		Name:      c.Param("name"),
+		OwnerID:   userId,
+		Params:    req.Params,
+		IsDefault: req.Default,
+		Shared:    req.Shared,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidView) {
+			status = http.StatusBadRequest
+		} else {
+			h.log.For(c.Request.Context()).Errorf("failed to save view: %v", err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView deletes the user's view with the name in the path. Views
+// shared by others cannot be deleted.
+func (h *TerminalHandler) DeleteView(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	err := h.terminalServicePort.DeleteView(c.Request.Context(), userId, c.Param("name"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repositories.ErrNotFound) {
+			status = http.StatusNotFound
+		} else {
+			h.log.For(c.Request.Context()).Errorf("failed to delete view: %v", err)
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package terminal_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var viewsTestTerminals = []domain.Terminal{
+	{ID: 1, Name: "T-111", Status: "active", Tags: []domain.Tag{{Key: "zone", Value: "north"}}},
+	{ID: 2, Name: "T-112", Status: "active", Tags: []domain.Tag{{Key: "zone", Value: "south"}}},
+}
+
+func newViewsRouter(t *testing.T) (*gin.Engine, *repoMock.MockTerminalRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	h := NewTerminalHandler(*log, terminal_service.NewTerminalService(terminalRepo, repoMock.NewMockUnitOfWork(ctl)))
+
+	router := handlertest.NewRouter(1)
+	router.GET("/terminals", h.GetTerminalsWithFavorites)
+	router.GET("/views", h.GetViews)
+	router.PUT("/views/:name", h.SaveView)
+	router.DELETE("/views/:name", h.DeleteView)
+	return router, terminalRepo
+}
+
+func expectViewsListing(terminalRepo *repoMock.MockTerminalRepositoryPort) {
+	terminalRepo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(1), nil)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetFavoriteNotes(gomock.Any(), 1).Return(nil, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(viewsTestTerminals, nil)
+}
+
+const (
+	northTerminal = `{"id":1,"name":"T-111","status":"active","is_favorite":false,"tags":[{"key":"zone","value":"north"}]}`
+	southTerminal = `{"id":2,"name":"T-112","status":"active","is_favorite":false,"tags":[{"key":"zone","value":"south"}]}`
+)
+
+// test case: a view is saved for the caller and listed
+func TestSaveView(t *testing.T) {
+	router, terminalRepo := newViewsRouter(t)
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	view := domain.View{Name: "north", OwnerID: 1, Params: url.Values{"label": {"zone=north"}}, IsDefault: true}
+	saved := view
+	saved.ID, saved.Owner, saved.UpdatedAt = 7, "alice", updatedAt
+	terminalRepo.EXPECT().SaveView(gomock.Any(), view).Return(saved, nil)
+
+	body := `{"params":{"label":["zone=north"]},"default":true}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/views/north", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	savedJSON := `{"id":7,"name":"north","owner":"alice","params":{"label":["zone=north"]},"default":true,"shared":false,"updated_at":"2024-05-01T12:00:00Z"}`
+	require.Equal(t, savedJSON, w.Body.String())
+
+	terminalRepo.EXPECT().GetViews(gomock.Any(), 1).Return([]domain.View{saved}, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/views", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "["+savedJSON+"]", w.Body.String())
+}
+
+// test case: invalid params and names are rejected before anything is stored
+func TestSaveInvalidView(t *testing.T) {
+	router, _ := newViewsRouter(t)
+	for path, body := range map[string]string{
+		"/views/north":  `{"params":{"label":["zone=a b"]}}`,
+		"/views/night":  `{"params":{"view":["north"]}}`,
+		"/views/-north": `{"params":{}}`,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+// test case: a shared view filters the listing and query parameters override it
+func TestGetTerminalsWithView(t *testing.T) {
+	router, terminalRepo := newViewsRouter(t)
+	shared := domain.View{ID: 3, Name: "north", OwnerID: 2, Owner: "bob", Params: url.Values{"label": {"zone=north"}}, Shared: true}
+
+	terminalRepo.EXPECT().GetView(gomock.Any(), 1, "bob", "north").Return(shared, nil)
+	expectViewsListing(terminalRepo)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?view=bob/north", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "bob/north", w.Header().Get("X-View"))
+	require.Equal(t, "["+northTerminal+"]", w.Body.String())
+
+	terminalRepo.EXPECT().GetView(gomock.Any(), 1, "bob", "north").Return(shared, nil)
+	expectViewsListing(terminalRepo)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?view=bob/north&label=zone%3Dsouth", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "["+southTerminal+"]", w.Body.String())
+}
+
+// test case: the default view applies unless the view parameter is empty
+func TestGetTerminalsWithDefaultView(t *testing.T) {
+	router, terminalRepo := newViewsRouter(t)
+	view := domain.View{ID: 3, Name: "north", OwnerID: 1, Owner: "alice", Params: url.Values{"label": {"zone=north"}}, IsDefault: true}
+
+	terminalRepo.EXPECT().GetDefaultView(gomock.Any(), 1).Return(view, nil)
+	expectViewsListing(terminalRepo)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "north", w.Header().Get("X-View"))
+	require.Equal(t, "["+northTerminal+"]", w.Body.String())
+
+	expectViewsListing(terminalRepo)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?view=", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("X-View"))
+	require.Equal(t, "["+northTerminal+","+southTerminal+"]", w.Body.String())
+}
+
+// test case: unknown views are not found
+func TestUnknownView(t *testing.T) {
+	router, terminalRepo := newViewsRouter(t)
+	terminalRepo.EXPECT().GetView(gomock.Any(), 1, "bob", "night").Return(domain.View{}, repositories.ErrNotFound)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/terminals?view=bob/night", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	terminalRepo.EXPECT().DeleteView(gomock.Any(), 1, "night").Return(repositories.ErrNotFound)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/views/night", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	terminalRepo.EXPECT().DeleteView(gomock.Any(), 1, "north").Return(nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/views/north", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
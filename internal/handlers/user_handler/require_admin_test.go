@@ -0,0 +1,49 @@
+package user_handler
+
+import (
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	cases := []struct {
+		name      string
+		role      string
+		expStatus int
+	}{
+		{name: "admin", role: domain.RoleAdmin, expStatus: http.StatusOK},
+		{name: "user", role: domain.RoleUser, expStatus: http.StatusForbidden},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			log := logger.GetLogger()
+			ctl := gomock.NewController(t)
+			repo := repoMock.NewMockUserRepositoryPort(ctl)
+			service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+			h := NewUserHandler(*log, service)
+
+			repo.EXPECT().GetUserByID(gomock.Any(), 1).
+				Return(domain.User{ID: 1, Name: "Khalid", Role: tCase.role}, nil).Times(1)
+
+			router := gin.Default()
+			router.GET("/admin/terminals/deleted", func(c *gin.Context) {
+				c.Set("userId", float64(1))
+				c.Next()
+			}, h.RequireAdmin, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/admin/terminals/deleted", nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, tCase.expStatus, w.Code)
+		})
+	}
+}
@@ -32,8 +32,8 @@ func TestSignIn(t *testing.T) {
 		Name:     "Khalid",
 		Password: string(hashBytes),
 	}
-	repo.EXPECT().GetUser(user.Name).Return(user, nil).Times(1)
-	service := user_service.NewUserService(repo)
+	repo.EXPECT().GetUser(gomock.Any(), user.Name).Return(user, nil).Times(1)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := user_handler.NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -66,7 +66,7 @@ func TestSignInBadJSON(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := user_handler.NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -98,7 +98,7 @@ func TestSignInValidateRequestErr(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := user_handler.NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -140,8 +140,8 @@ func TestSignInServiceErr(t *testing.T) {
 		Name:     "Timbersaw",
 		Password: string(hashBytes),
 	}
-	repo.EXPECT().GetUser(user.Name).Return(domain.User{}, repoErr).Times(1)
-	service := user_service.NewUserService(repo)
+	repo.EXPECT().GetUser(gomock.Any(), user.Name).Return(domain.User{}, repoErr).Times(1)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := user_handler.NewUserHandler(*log, service)
 	router := gin.Default()
 	router.POST("/user/sign-in", h.SignIn)
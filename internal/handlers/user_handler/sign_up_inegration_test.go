@@ -2,10 +2,12 @@ package user_handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/gin-gonic/gin"
@@ -22,6 +24,7 @@ func TestSignUp(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
 	pass := "12345Khalid"
 	hashBytes, err := bcrypt.GenerateFromPassword([]byte(pass), 14)
 	if err != nil {
@@ -31,8 +34,14 @@ func TestSignUp(t *testing.T) {
 		Name:     "Khalid",
 		Password: string(hashBytes),
 	}
-	repo.EXPECT().CreateUser(gomock.AssignableToTypeOf(user)).Return(nil).Times(1)
-	service := user_service.NewUserService(repo)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{UserRepositoryPort: repo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.AssignableToTypeOf(user)).Return(1, nil).Times(1)
+	terminalRepo.EXPECT().CreateFavorites(gomock.Any(), 1).Return(nil).Times(1)
+	service := user_service.NewUserService(repo, uow, user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -65,7 +74,7 @@ func TestSignUpBadJSON(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -96,7 +105,7 @@ func TestSignUpValidateRequestErr(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 
 	router := gin.Default()
@@ -128,13 +137,19 @@ func TestSignUpServiceErr(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
 	repoErr := errors.New("can't create user DB is down")
 	user := domain.User{
 		Name:     "Timbersaw",
 		Password: "12345Tiger",
 	}
-	repo.EXPECT().CreateUser(gomock.AssignableToTypeOf(user)).Return(repoErr).Times(1)
-	service := user_service.NewUserService(repo)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{UserRepositoryPort: repo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.AssignableToTypeOf(user)).Return(0, repoErr).Times(1)
+	service := user_service.NewUserService(repo, uow, user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 	router := gin.Default()
 	router.POST("/user/sign-up", h.SignUp)
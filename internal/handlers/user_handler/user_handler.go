@@ -3,9 +3,12 @@ package user_handler
 import (
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
 	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"net/http"
 	"regexp"
 )
@@ -15,6 +18,9 @@ var (
 	ErrInvalidCharacters           = errors.New("username or password can have only underscores, letters and numbers")
 	ErrTooManyUnderscore           = errors.New("username must have 2 underscores maximum")
 	ErrInvalidUnderscore           = errors.New("username or password cannot begin or end with underscore")
+	ErrAdminRequired               = errors.New("admin role required")
+	ErrInvalidUserIdClaim          = errors.New("token has no valid userId")
+	ErrUserInactive                = errors.New("user does not exist or was deleted")
 )
 
 type UserHandler struct {
@@ -33,7 +39,7 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 	var user domain.User
 	err := c.ShouldBindJSON(&user)
 	if err != nil {
-		h.log.Errorf("failed to bind json: %v", err)
+		h.log.For(c.Request.Context()).Errorf("failed to bind json: %v", err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"err": err.Error(),
 		})
@@ -41,15 +47,15 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 	}
 	err = h.ValidateRequest(user)
 	if err != nil {
-		h.log.Errorf("invalid user credentials: %v", err)
+		h.log.For(c.Request.Context()).Errorf("invalid user credentials: %v", err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"err": err.Error(),
 		})
 		return
 	}
-	err = h.userService.CreateUser(user)
+	err = h.userService.CreateUser(c.Request.Context(), user)
 	if err != nil {
-		h.log.Errorf("failed to create user: %v", err)
+		h.log.For(c.Request.Context()).Errorf("failed to create user: %v", err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"err": err.Error(),
 		})
@@ -62,7 +68,7 @@ func (h *UserHandler) SignIn(c *gin.Context) {
 	var user domain.User
 	err := c.ShouldBindJSON(&user)
 	if err != nil {
-		h.log.Errorf("failed to bind json: %v", err)
+		h.log.For(c.Request.Context()).Errorf("failed to bind json: %v", err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"err": err.Error(),
 		})
@@ -70,15 +76,15 @@ func (h *UserHandler) SignIn(c *gin.Context) {
 	}
 	err = h.ValidateRequest(user)
 	if err != nil {
-		h.log.Errorf("invalid user credentials: %v", err)
+		h.log.For(c.Request.Context()).Errorf("invalid user credentials: %v", err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"err": err.Error(),
 		})
 		return
 	}
-	tokenStr, err := h.userService.GenerateToken(user)
+	tokenStr, err := h.userService.GenerateToken(c.Request.Context(), user)
 	if err != nil {
-		h.log.Errorf("failed to generate token: %v", err)
+		h.log.For(c.Request.Context()).Errorf("failed to generate token: %v", err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"err": err.Error(),
 		})
@@ -94,14 +100,60 @@ func (h *UserHandler) ValidateUser(c *gin.Context) {
 	tokenStr := c.GetHeader("token")
 	userId, err := h.userService.ParseToken(tokenStr)
 	if err != nil {
-		h.log.Errorf("failed to parse token: %v", err)
+		h.log.For(c.Request.Context()).Errorf("failed to parse token: %v", err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"err": err.Error(),
 		})
 		return
 	}
-	userIdfloat := userId.(float64)
-	c.Set("userId", userIdfloat)
+	userIdfloat, ok := userId.(float64)
+	if !ok {
+		h.log.For(c.Request.Context()).Errorf("token has no numeric userId claim: %v", userId)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"err": ErrInvalidUserIdClaim.Error(),
+		})
+		return
+	}
+	active, err := h.userService.IsActive(c.Request.Context(), int(userIdfloat))
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to check user: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	if !active {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"err": ErrUserInactive.Error(),
+		})
+		return
+	}
+	c.Set(middleware.UserIDKey, userIdfloat)
+	c.Request = c.Request.WithContext(audit_service.WithActor(c.Request.Context(), int(userIdfloat)))
+	middleware.SetLogger(c, h.log.For(c.Request.Context()).WithFields(logrus.Fields{"user_id": int(userIdfloat)}))
+	c.Next()
+}
+
+// RequireAdmin must run after ValidateUser and lets only admins through.
+func (h *UserHandler) RequireAdmin(c *gin.Context) {
+	userId, ok := middleware.UserID(c)
+	if !ok {
+		return
+	}
+	isAdmin, err := h.userService.IsAdmin(c.Request.Context(), userId)
+	if err != nil {
+		h.log.For(c.Request.Context()).Errorf("failed to check admin role: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	if !isAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"err": ErrAdminRequired.Error(),
+		})
+		return
+	}
 	c.Next()
 }
 
@@ -2,9 +2,11 @@ package user_handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
 	"github.com/gin-gonic/gin"
@@ -21,7 +23,7 @@ func TestValidateUser(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 	pass := "1234567"
 	hashBytes, err := bcrypt.GenerateFromPassword([]byte(pass), 14)
@@ -37,11 +39,12 @@ func TestValidateUser(t *testing.T) {
 		Name:     "Bountyhunter",
 		Password: "1234567",
 	}
-	repo.EXPECT().GetUser(expUser.Name).Return(expUser, nil).Times(1)
-	token, err := service.GenerateToken(user)
+	repo.EXPECT().GetUser(gomock.Any(), expUser.Name).Return(expUser, nil).Times(1)
+	token, err := service.GenerateToken(context.Background(), user)
 	if err != nil {
 		require.NoError(t, err)
 	}
+	repo.EXPECT().GetUserByID(gomock.Any(), 1).Return(expUser, nil).Times(1)
 	router := gin.Default()
 	router.GET("/terminals", h.ValidateUser)
 	w := httptest.NewRecorder()
@@ -67,11 +70,40 @@ func TestValidateUser(t *testing.T) {
 	require.Equal(t, expected, string(data))
 }
 
+func TestValidateUserDeleted(t *testing.T) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
+	h := NewUserHandler(*log, service)
+	hashBytes, err := bcrypt.GenerateFromPassword([]byte("1234567"), bcrypt.MinCost)
+	require.NoError(t, err)
+	repo.EXPECT().GetUser(gomock.Any(), "Bountyhunter").
+		Return(domain.User{ID: 1, Name: "Bountyhunter", Password: string(hashBytes)}, nil).Times(1)
+	token, err := service.GenerateToken(context.Background(), domain.User{Name: "Bountyhunter", Password: "1234567"})
+	require.NoError(t, err)
+
+	// The token is still valid, but its user has since been deleted.
+	repo.EXPECT().GetUserByID(gomock.Any(), 1).Return(domain.User{}, repositories.ErrNotFound).Times(1)
+	router := gin.Default()
+	router.GET("/terminals", h.ValidateUser, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/terminals", nil)
+	req.Header.Set("token", token)
+
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.JSONEq(t, `{"err":"user does not exist or was deleted"}`, w.Body.String())
+}
+
 func TestValidateUserServiceErr(t *testing.T) {
 	log := logger.GetLogger()
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := user_service.NewUserService(repo)
+	service := user_service.NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), user_service.AuthConfig{})
 	h := NewUserHandler(*log, service)
 	token := ""
 	router := gin.Default()
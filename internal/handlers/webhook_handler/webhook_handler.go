@@ -0,0 +1,124 @@
+package webhook_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/middleware"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type WebhookHandler struct {
+	log                logger.Logger
+	webhookServicePort services.WebhookServicePort
+}
+
+func NewWebhookHandler(log logger.Logger, webhookServicePort services.WebhookServicePort) *WebhookHandler {
+	return &WebhookHandler{
+		log:                log,
+		webhookServicePort: webhookServicePort,
+	}
+}
+
+// GetWebhooks lists the webhooks. Their secrets are only shown on creation.
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	hooks, err := h.webhookServicePort.GetWebhooks(c.Request.Context())
+	if err != nil {
+		h.abort(c, "failed to get webhooks", err)
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// CreateWebhook adds the webhook in the body, such as
+// {"url":"https://example.com/hook","events":["terminal.status_change"],"labels":["zone=north"]}.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var hook domain.Webhook
+	err := c.ShouldBindJSON(&hook)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": err.Error(),
+		})
+		return
+	}
+	hook.ID = 0
+	hook, err = h.webhookServicePort.CreateWebhook(c.Request.Context(), hook)
+	if err != nil {
+		h.abort(c, "failed to create webhook", err)
+		return
+	}
+	c.JSON(http.StatusCreated, hook)
+}
+
+// DeleteWebhook deletes a webhook and its delivery log.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	err := h.webhookServicePort.DeleteWebhook(c.Request.Context(), id)
+	if err != nil {
+		h.abort(c, "failed to delete webhook", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists the webhook's deliveries, newest first,
+// optionally only those in the state given by the state parameter.
+func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	state := c.Query("state")
+	if state != "" && !domain.IsDeliveryState(state) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"err": fmt.Sprintf("state must be %q, %q or %q", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryDead),
+		})
+		return
+	}
+	deliveries, err := h.webhookServicePort.GetWebhookDeliveries(c.Request.Context(), id, state)
+	if err != nil {
+		h.abort(c, "failed to get webhook deliveries", err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook queues a delivery to be sent again on the next dispatch.
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	id, ok := middleware.PathID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := middleware.PathID(c, "delivery_id")
+	if !ok {
+		return
+	}
+	err := h.webhookServicePort.RedeliverWebhook(c.Request.Context(), id, int64(deliveryID))
+	if err != nil {
+		h.abort(c, "failed to redeliver webhook", err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) abort(c *gin.Context, failMsg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidWebhook):
+		status = http.StatusBadRequest
+	default:
+		h.log.For(c.Request.Context()).Errorf("%s: %v", failMsg, err)
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"err": err.Error(),
+	})
+}
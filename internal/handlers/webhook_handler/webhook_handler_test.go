@@ -0,0 +1,95 @@
+package webhook_handler
+
+import (
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/handlers/handlertest"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/webhook_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *repoMock.MockWebhookRepositoryPort) {
+	log := logger.GetLogger()
+	ctl := gomock.NewController(t)
+	webhookRepo := repoMock.NewMockWebhookRepositoryPort(ctl)
+	service := webhook_service.NewWebhookService(webhookRepo, repoMock.NewMockTerminalRepositoryPort(ctl), repoMock.NewMockUnitOfWork(ctl), webhook_service.Config{})
+	h := NewWebhookHandler(*log, service)
+
+	router := handlertest.NewRouter(0)
+	router.GET("/admin/webhooks", h.GetWebhooks)
+	router.POST("/admin/webhooks", h.CreateWebhook)
+	router.DELETE("/admin/webhooks/:id", h.DeleteWebhook)
+	router.GET("/admin/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+	router.POST("/admin/webhooks/:id/deliveries/:delivery_id/redeliver", h.RedeliverWebhook)
+	return router, webhookRepo
+}
+
+var createdAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestCreateWebhook(t *testing.T) {
+	router, webhookRepo := newRouter(t)
+	webhookRepo.EXPECT().CreateWebhook(gomock.Any(), domain.Webhook{
+		URL: "https://hooks.example.com", Secret: "0123456789abcdef", Events: []string{domain.WebhookFavoriteAdd},
+	}).DoAndReturn(func(_ any, hook domain.Webhook) (domain.Webhook, error) {
+		hook.ID = 1
+		hook.CreatedAt = createdAt
+		return hook, nil
+	}).Times(1)
+
+	w := handlertest.Serve(router, http.MethodPost, "/admin/webhooks",
+		`{"id":9,"url":"https://hooks.example.com","secret":"0123456789abcdef","events":["favorite.add"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.JSONEq(t, `{"id":1,"url":"https://hooks.example.com","secret":"0123456789abcdef","events":["favorite.add"],
+		"created_at":"2024-05-01T12:00:00Z"}`, w.Body.String())
+
+	w = handlertest.Serve(router, http.MethodPost, "/admin/webhooks", `{"url":"https://hooks.example.com","events":["terminal.deleted"]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetWebhooksHidesSecrets(t *testing.T) {
+	router, webhookRepo := newRouter(t)
+	webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{
+		{ID: 1, URL: "https://hooks.example.com", Secret: "0123456789abcdef", Labels: []string{"zone=north"}, CreatedAt: createdAt},
+	}, nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodGet, "/admin/webhooks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id":1,"url":"https://hooks.example.com","labels":["zone=north"],"created_at":"2024-05-01T12:00:00Z"}]`, w.Body.String())
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	router, webhookRepo := newRouter(t)
+	webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{{ID: 1}}, nil).Times(2)
+	webhookRepo.EXPECT().GetWebhookDeliveries(gomock.Any(), 1, domain.DeliveryDead).Return([]domain.WebhookDelivery{{
+		ID: 7, WebhookID: 1, Event: domain.WebhookFavoriteAdd, Payload: json.RawMessage(`{"event":"favorite.add"}`),
+		State: domain.DeliveryDead, Attempts: 8, ResponseStatus: 500, LastError: "unexpected response 500 Internal Server Error",
+		CreatedAt: createdAt,
+	}}, nil).Times(1)
+
+	w := handlertest.Serve(router, http.MethodGet, "/admin/webhooks/1/deliveries?state=dead", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id":7,"webhook_id":1,"event":"favorite.add","payload":{"event":"favorite.add"},"state":"dead",
+		"attempts":8,"response_status":500,"last_error":"unexpected response 500 Internal Server Error","created_at":"2024-05-01T12:00:00Z"}]`,
+		w.Body.String())
+
+	require.Equal(t, http.StatusNotFound, handlertest.Serve(router, http.MethodGet, "/admin/webhooks/2/deliveries", "").Code)
+	require.Equal(t, http.StatusBadRequest, handlertest.Serve(router, http.MethodGet, "/admin/webhooks/1/deliveries?state=failed", "").Code)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	router, webhookRepo := newRouter(t)
+	webhookRepo.EXPECT().RedeliverWebhookDelivery(gomock.Any(), 1, int64(7)).Return(nil).Times(1)
+	webhookRepo.EXPECT().RedeliverWebhookDelivery(gomock.Any(), 2, int64(7)).Return(repositories.ErrNotFound).Times(1)
+
+	require.Equal(t, http.StatusAccepted, handlertest.Serve(router, http.MethodPost, "/admin/webhooks/1/deliveries/7/redeliver", "").Code)
+	require.Equal(t, http.StatusNotFound, handlertest.Serve(router, http.MethodPost, "/admin/webhooks/2/deliveries/7/redeliver", "").Code)
+	require.Equal(t, http.StatusBadRequest, handlertest.Serve(router, http.MethodPost, "/admin/webhooks/1/deliveries/x/redeliver", "").Code)
+}
@@ -0,0 +1,26 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/database/schema"
+	"strings"
+)
+
+// MigrationsCheck fails while any embedded migration is not applied yet.
+func MigrationsCheck(pending func(ctx context.Context) ([]schema.Migration, error)) Check {
+	return func(ctx context.Context) error {
+		migrations, err := pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(migrations) == 0 {
+			return nil
+		}
+		names := make([]string, len(migrations))
+		for i, m := range migrations {
+			names[i] = fmt.Sprintf("%d_%s", m.Version, m.Name)
+		}
+		return fmt.Errorf("%d pending migrations: %s", len(migrations), strings.Join(names, ", "))
+	}
+}
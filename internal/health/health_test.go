@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/database/schema"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func probe(t *testing.T, checker *Checker, target string) (int, Report) {
+	router := gin.New()
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Add("migrations", func(ctx context.Context) error { return nil })
+
+	code, report := probe(t, checker, "/readyz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "database", report.Checks[0].Name)
+	require.Equal(t, StatusOK, report.Checks[0].Status)
+	require.Empty(t, report.Checks[0].Error)
+}
+
+func TestReadinessFailingCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return errors.New("connection refused") })
+	checker.Add("migrations", func(ctx context.Context) error { return nil })
+
+	code, report := probe(t, checker, "/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusFailing, report.Status)
+	require.Equal(t, CheckResult{Name: "database", Status: StatusFailing, LatencyMs: report.Checks[0].LatencyMs, Error: "connection refused"}, report.Checks[0])
+	require.Equal(t, StatusOK, report.Checks[1].Status)
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	code, report := probe(t, checker, "/readyz")
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+	require.GreaterOrEqual(t, report.Checks[0].LatencyMs, float64(50))
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.SetShuttingDown()
+
+	code, report := probe(t, checker, "/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "shutdown", report.Checks[1].Name)
+	require.Equal(t, ErrShuttingDown.Error(), report.Checks[1].Error)
+
+	code, _ = probe(t, checker, "/healthz")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestMigrationsCheck(t *testing.T) {
+	pending := []schema.Migration{}
+	check := MigrationsCheck(func(ctx context.Context) ([]schema.Migration, error) {
+		return pending, nil
+	})
+	require.NoError(t, check(context.Background()))
+
+	pending = []schema.Migration{{Version: 3, Name: "favorites_version"}, {Version: 4, Name: "tags"}}
+	require.EqualError(t, check(context.Background()), "2 pending migrations: 3_favorites_version, 4_tags")
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// businessQueryTimeout bounds the queries run on each scrape.
+const businessQueryTimeout = 5 * time.Second
+
+// RegisterBusiness exports totals that are queried from repo on every scrape.
+// Pass the uninstrumented repositories so scrapes don't show up as calls.
+func (m *Metrics) RegisterBusiness(repo *repositories.RepositoryPort) {
+	m.registry.MustRegister(&businessCollector{
+		repo: repo,
+		favorites: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "favorited_terminals"),
+			"Favorites of active users on terminals that are not deleted.", nil, nil),
+		activeUsers: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "active_users"),
+			"Users that are not deleted.", nil, nil),
+	})
+}
+
+type businessCollector struct {
+	repo        *repositories.RepositoryPort
+	favorites   *prometheus.Desc
+	activeUsers *prometheus.Desc
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.favorites
+	ch <- c.activeUsers
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessQueryTimeout)
+	defer cancel()
+
+	favorites, err := c.repo.CountFavorites(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.favorites, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.favorites, prometheus.GaugeValue, float64(favorites))
+	}
+	users, err := c.repo.CountActiveUsers(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.activeUsers, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.activeUsers, prometheus.GaugeValue, float64(users))
+	}
+}
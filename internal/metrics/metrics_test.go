@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestGinMiddleware(t *testing.T) {
+	m := New()
+	router := gin.New()
+	router.Use(m.GinMiddleware())
+	router.DELETE("/admin/terminals/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, target := range []string{"/admin/terminals/1", "/admin/terminals/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, target, nil))
+	}
+
+	body := scrape(t, m)
+	require.Contains(t, body, `favorites_http_request_duration_seconds_count{method="DELETE",route="/admin/terminals/:id",status="204"} 2`)
+	require.Contains(t, body, `favorites_http_request_duration_seconds_count{method="DELETE",route="unmatched",status="404"} 1`)
+}
+
+func TestInstrumentRepositories(t *testing.T) {
+	ctl := gomock.NewController(t)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	raw := &repositories.RepositoryPort{UserRepositoryPort: userRepo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow}
+	m := New()
+	repo := m.InstrumentRepositories(raw)
+
+	userRepo.EXPECT().GetUserByID(gomock.Any(), 1).Return(domain.User{ID: 1}, nil).Times(1)
+	userRepo.EXPECT().GetUserByID(gomock.Any(), 2).
+		Return(domain.User{}, fmt.Errorf("user with ID %d: %w", 2, repositories.ErrNotFound)).Times(1)
+	terminalRepo.EXPECT().AddToFavorites(gomock.Any(), 4, 1).Return(errors.New("DB is down")).Times(1)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(raw)
+		}).Times(1)
+	terminalRepo.EXPECT().CreateFavorites(gomock.Any(), 1).Return(nil).Times(1)
+
+	ctx := context.Background()
+	user, err := repo.GetUserByID(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, user.ID)
+	_, err = repo.GetUserByID(ctx, 2)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	require.EqualError(t, repo.AddToFavorites(ctx, 4, 1), "DB is down")
+	err = repo.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		return tx.CreateFavorites(ctx, 1)
+	})
+	require.NoError(t, err)
+
+	body := scrape(t, m)
+	require.Contains(t, body, `favorites_repository_calls_total{method="GetUserByID",repository="user",result="ok"} 1`)
+	require.Contains(t, body, `favorites_repository_calls_total{method="GetUserByID",repository="user",result="not_found"} 1`)
+	require.Contains(t, body, `favorites_repository_calls_total{method="AddToFavorites",repository="terminal",result="error"} 1`)
+	require.Contains(t, body, `favorites_repository_calls_total{method="CreateFavorites",repository="terminal",result="ok"} 1`)
+	require.Contains(t, body, `favorites_repository_call_duration_seconds_count{method="GetUserByID",repository="user"} 2`)
+}
+
+func TestRegisterBusiness(t *testing.T) {
+	ctl := gomock.NewController(t)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	m := New()
+	m.RegisterBusiness(&repositories.RepositoryPort{UserRepositoryPort: userRepo, TerminalRepositoryPort: terminalRepo})
+
+	terminalRepo.EXPECT().CountFavorites(gomock.Any()).Return(12, nil).Times(1)
+	userRepo.EXPECT().CountActiveUsers(gomock.Any()).Return(5, nil).Times(1)
+	body := scrape(t, m)
+	require.Contains(t, body, "favorites_favorited_terminals 12")
+	require.Contains(t, body, "favorites_active_users 5")
+}
+
+func TestRegisterPgxPool(t *testing.T) {
+	// pgxpool connects lazily, so no server is needed to read its stats.
+	pool, err := pgxpool.New(context.Background(), "host=127.0.0.1 port=1 pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+	m := New()
+	m.RegisterPgxPool("primary", pool)
+
+	body := scrape(t, m)
+	require.Contains(t, body, `favorites_db_pool_max_conns{pool="primary"} 7`)
+	require.Contains(t, body, `favorites_db_pool_total_conns{pool="primary"} 0`)
+}
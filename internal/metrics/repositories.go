@@ -0,0 +1,481 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"time"
+)
+
+// InstrumentRepositories wraps the repositories so every call is counted
+// and timed. Repositories handed out by WithTx are wrapped too, so calls
+// inside transactions are recorded as well.
+func (m *Metrics) InstrumentRepositories(repo *repositories.RepositoryPort) *repositories.RepositoryPort {
+	return &repositories.RepositoryPort{
+		UserRepositoryPort:         &userRepository{next: repo.UserRepositoryPort, m: m},
+		TerminalRepositoryPort:     &terminalRepository{next: repo.TerminalRepositoryPort, m: m},
+		AuditRepositoryPort:        &auditRepository{next: repo.AuditRepositoryPort, m: m},
+		AlertRepositoryPort:        &alertRepository{next: repo.AlertRepositoryPort, m: m},
+		WebhookRepositoryPort:      &webhookRepository{next: repo.WebhookRepositoryPort, m: m},
+		NotificationRepositoryPort: &notificationRepository{next: repo.NotificationRepositoryPort, m: m},
+		HeartbeatRepositoryPort:    &heartbeatRepository{next: repo.HeartbeatRepositoryPort, m: m},
+		UnitOfWork:                 &unitOfWork{next: repo.UnitOfWork, m: m},
+	}
+}
+
+func (m *Metrics) observeRepo(repository string, method string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	m.repoCalls.WithLabelValues(repository, method, result).Inc()
+	m.repoDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+}
+
+type unitOfWork struct {
+	next repositories.UnitOfWork
+	m    *Metrics
+}
+
+func (u *unitOfWork) WithTx(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+	return u.next.WithTx(ctx, opts, func(tx *repositories.RepositoryPort) error {
+		return fn(u.m.InstrumentRepositories(tx))
+	})
+}
+
+type userRepository struct {
+	next repositories.UserRepositoryPort
+	m    *Metrics
+}
+
+func (r *userRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("user", method, start, *err)
+}
+
+func (r *userRepository) CreateUser(ctx context.Context, user domain.User) (result int, err error) {
+	defer r.observe("CreateUser", time.Now(), &err)
+	return r.next.CreateUser(ctx, user)
+}
+
+func (r *userRepository) GetUser(ctx context.Context, username string) (result domain.User, err error) {
+	defer r.observe("GetUser", time.Now(), &err)
+	return r.next.GetUser(ctx, username)
+}
+
+func (r *userRepository) GetUserByID(ctx context.Context, id int) (result domain.User, err error) {
+	defer r.observe("GetUserByID", time.Now(), &err)
+	return r.next.GetUserByID(ctx, id)
+}
+
+func (r *userRepository) SoftDeleteUser(ctx context.Context, id int) (err error) {
+	defer r.observe("SoftDeleteUser", time.Now(), &err)
+	return r.next.SoftDeleteUser(ctx, id)
+}
+
+func (r *userRepository) RestoreUser(ctx context.Context, id int) (err error) {
+	defer r.observe("RestoreUser", time.Now(), &err)
+	return r.next.RestoreUser(ctx, id)
+}
+
+func (r *userRepository) GetDeletedUsers(ctx context.Context) (result []domain.DeletedRecord, err error) {
+	defer r.observe("GetDeletedUsers", time.Now(), &err)
+	return r.next.GetDeletedUsers(ctx)
+}
+
+func (r *userRepository) PurgeUser(ctx context.Context, id int) (err error) {
+	defer r.observe("PurgeUser", time.Now(), &err)
+	return r.next.PurgeUser(ctx, id)
+}
+
+func (r *userRepository) GetUsersDeletedBefore(ctx context.Context, before time.Time) (result []int, err error) {
+	defer r.observe("GetUsersDeletedBefore", time.Now(), &err)
+	return r.next.GetUsersDeletedBefore(ctx, before)
+}
+
+func (r *userRepository) CountActiveUsers(ctx context.Context) (result int, err error) {
+	defer r.observe("CountActiveUsers", time.Now(), &err)
+	return r.next.CountActiveUsers(ctx)
+}
+
+type terminalRepository struct {
+	next repositories.TerminalRepositoryPort
+	m    *Metrics
+}
+
+func (r *terminalRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("terminal", method, start, *err)
+}
+
+func (r *terminalRepository) AddToFavorites(ctx context.Context, terminalId int, userId int) (err error) {
+	defer r.observe("AddToFavorites", time.Now(), &err)
+	return r.next.AddToFavorites(ctx, terminalId, userId)
+}
+
+func (r *terminalRepository) CreateFavorites(ctx context.Context, userId int) (err error) {
+	defer r.observe("CreateFavorites", time.Now(), &err)
+	return r.next.CreateFavorites(ctx, userId)
+}
+
+func (r *terminalRepository) GetFavoriteTerminalIds(ctx context.Context, userId int) (result []int, err error) {
+	defer r.observe("GetFavoriteTerminalIds", time.Now(), &err)
+	return r.next.GetFavoriteTerminalIds(ctx, userId)
+}
+
+func (r *terminalRepository) GetFavoritesVersion(ctx context.Context, userId int) (result int64, err error) {
+	defer r.observe("GetFavoritesVersion", time.Now(), &err)
+	return r.next.GetFavoritesVersion(ctx, userId)
+}
+
+func (r *terminalRepository) GetDefaultTerminalsList(ctx context.Context) (result []domain.Terminal, err error) {
+	defer r.observe("GetDefaultTerminalsList", time.Now(), &err)
+	return r.next.GetDefaultTerminalsList(ctx)
+}
+
+func (r *terminalRepository) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) (err error) {
+	defer r.observe("RemoveFromFavoriteTerminal", time.Now(), &err)
+	return r.next.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+}
+
+func (r *terminalRepository) SoftDeleteTerminal(ctx context.Context, id int) (err error) {
+	defer r.observe("SoftDeleteTerminal", time.Now(), &err)
+	return r.next.SoftDeleteTerminal(ctx, id)
+}
+
+func (r *terminalRepository) RestoreTerminal(ctx context.Context, id int) (err error) {
+	defer r.observe("RestoreTerminal", time.Now(), &err)
+	return r.next.RestoreTerminal(ctx, id)
+}
+
+func (r *terminalRepository) GetDeletedTerminals(ctx context.Context) (result []domain.DeletedRecord, err error) {
+	defer r.observe("GetDeletedTerminals", time.Now(), &err)
+	return r.next.GetDeletedTerminals(ctx)
+}
+
+func (r *terminalRepository) PurgeTerminal(ctx context.Context, id int) (err error) {
+	defer r.observe("PurgeTerminal", time.Now(), &err)
+	return r.next.PurgeTerminal(ctx, id)
+}
+
+func (r *terminalRepository) GetTerminalsDeletedBefore(ctx context.Context, before time.Time) (result []int, err error) {
+	defer r.observe("GetTerminalsDeletedBefore", time.Now(), &err)
+	return r.next.GetTerminalsDeletedBefore(ctx, before)
+}
+
+func (r *terminalRepository) CountFavorites(ctx context.Context) (result int, err error) {
+	defer r.observe("CountFavorites", time.Now(), &err)
+	return r.next.CountFavorites(ctx)
+}
+
+func (r *terminalRepository) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) (err error) {
+	defer r.observe("AttachTag", time.Now(), &err)
+	return r.next.AttachTag(ctx, terminalID, tag)
+}
+
+func (r *terminalRepository) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) (err error) {
+	defer r.observe("DetachTag", time.Now(), &err)
+	return r.next.DetachTag(ctx, terminalID, tag)
+}
+
+func (r *terminalRepository) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) (err error) {
+	defer r.observe("SetTerminalLocation", time.Now(), &err)
+	return r.next.SetTerminalLocation(ctx, terminalID, location)
+}
+
+func (r *terminalRepository) CreateSite(ctx context.Context, site domain.Site) (result domain.Site, err error) {
+	defer r.observe("CreateSite", time.Now(), &err)
+	return r.next.CreateSite(ctx, site)
+}
+
+func (r *terminalRepository) GetSites(ctx context.Context) (result []domain.Site, err error) {
+	defer r.observe("GetSites", time.Now(), &err)
+	return r.next.GetSites(ctx)
+}
+
+func (r *terminalRepository) UpdateSite(ctx context.Context, site domain.Site) (err error) {
+	defer r.observe("UpdateSite", time.Now(), &err)
+	return r.next.UpdateSite(ctx, site)
+}
+
+func (r *terminalRepository) DeleteSite(ctx context.Context, id int) (err error) {
+	defer r.observe("DeleteSite", time.Now(), &err)
+	return r.next.DeleteSite(ctx, id)
+}
+
+func (r *terminalRepository) SetTerminalSite(ctx context.Context, terminalID int, siteID int) (err error) {
+	defer r.observe("SetTerminalSite", time.Now(), &err)
+	return r.next.SetTerminalSite(ctx, terminalID, siteID)
+}
+
+func (r *terminalRepository) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) (err error) {
+	defer r.observe("SetFavoriteNote", time.Now(), &err)
+	return r.next.SetFavoriteNote(ctx, userId, terminalID, note)
+}
+
+func (r *terminalRepository) GetFavoriteNotes(ctx context.Context, userId int) (result map[int]domain.FavoriteNote, err error) {
+	defer r.observe("GetFavoriteNotes", time.Now(), &err)
+	return r.next.GetFavoriteNotes(ctx, userId)
+}
+
+func (r *terminalRepository) RestoreRemovedFavoriteNote(ctx context.Context, userId int) (result int, err error) {
+	defer r.observe("RestoreRemovedFavoriteNote", time.Now(), &err)
+	return r.next.RestoreRemovedFavoriteNote(ctx, userId)
+}
+
+func (r *terminalRepository) SaveView(ctx context.Context, view domain.View) (result domain.View, err error) {
+	defer r.observe("SaveView", time.Now(), &err)
+	return r.next.SaveView(ctx, view)
+}
+
+func (r *terminalRepository) GetViews(ctx context.Context, userId int) (result []domain.View, err error) {
+	defer r.observe("GetViews", time.Now(), &err)
+	return r.next.GetViews(ctx, userId)
+}
+
+func (r *terminalRepository) GetView(ctx context.Context, userId int, owner string, name string) (result domain.View, err error) {
+	defer r.observe("GetView", time.Now(), &err)
+	return r.next.GetView(ctx, userId, owner, name)
+}
+
+func (r *terminalRepository) GetDefaultView(ctx context.Context, userId int) (result domain.View, err error) {
+	defer r.observe("GetDefaultView", time.Now(), &err)
+	return r.next.GetDefaultView(ctx, userId)
+}
+
+func (r *terminalRepository) DeleteView(ctx context.Context, userId int, name string) (err error) {
+	defer r.observe("DeleteView", time.Now(), &err)
+	return r.next.DeleteView(ctx, userId, name)
+}
+
+type auditRepository struct {
+	next repositories.AuditRepositoryPort
+	m    *Metrics
+}
+
+func (r *auditRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("audit", method, start, *err)
+}
+
+func (r *auditRepository) InsertAuditEntry(ctx context.Context, entry domain.AuditEntry) (err error) {
+	defer r.observe("InsertAuditEntry", time.Now(), &err)
+	return r.next.InsertAuditEntry(ctx, entry)
+}
+
+func (r *auditRepository) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) (result []domain.AuditEntry, err error) {
+	defer r.observe("GetAuditEntries", time.Now(), &err)
+	return r.next.GetAuditEntries(ctx, filter)
+}
+
+func (r *auditRepository) DeleteAuditEntriesBefore(ctx context.Context, before time.Time) (result int, err error) {
+	defer r.observe("DeleteAuditEntriesBefore", time.Now(), &err)
+	return r.next.DeleteAuditEntriesBefore(ctx, before)
+}
+
+type alertRepository struct {
+	next repositories.AlertRepositoryPort
+	m    *Metrics
+}
+
+func (r *alertRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("alert", method, start, *err)
+}
+
+func (r *alertRepository) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (result domain.AlertRule, err error) {
+	defer r.observe("CreateAlertRule", time.Now(), &err)
+	return r.next.CreateAlertRule(ctx, rule)
+}
+
+func (r *alertRepository) GetAlertRules(ctx context.Context, userId int) (result []domain.AlertRule, err error) {
+	defer r.observe("GetAlertRules", time.Now(), &err)
+	return r.next.GetAlertRules(ctx, userId)
+}
+
+func (r *alertRepository) DeleteAlertRule(ctx context.Context, userId int, id int) (err error) {
+	defer r.observe("DeleteAlertRule", time.Now(), &err)
+	return r.next.DeleteAlertRule(ctx, userId, id)
+}
+
+func (r *alertRepository) MuteAlertRule(ctx context.Context, userId int, id int, until *time.Time) (err error) {
+	defer r.observe("MuteAlertRule", time.Now(), &err)
+	return r.next.MuteAlertRule(ctx, userId, id, until)
+}
+
+func (r *alertRepository) GetAllAlertRules(ctx context.Context) (result []domain.AlertRule, err error) {
+	defer r.observe("GetAllAlertRules", time.Now(), &err)
+	return r.next.GetAllAlertRules(ctx)
+}
+
+func (r *alertRepository) GetStatusChanges(ctx context.Context, since time.Time) (result map[int][]time.Time, err error) {
+	defer r.observe("GetStatusChanges", time.Now(), &err)
+	return r.next.GetStatusChanges(ctx, since)
+}
+
+func (r *alertRepository) ClaimStatusChanges(ctx context.Context, limit int) (result []domain.StatusChange, err error) {
+	defer r.observe("ClaimStatusChanges", time.Now(), &err)
+	return r.next.ClaimStatusChanges(ctx, limit)
+}
+
+func (r *alertRepository) GetAlertFavorites(ctx context.Context) (result map[int][]int, err error) {
+	defer r.observe("GetAlertFavorites", time.Now(), &err)
+	return r.next.GetAlertFavorites(ctx)
+}
+
+func (r *alertRepository) GetFiringAlerts(ctx context.Context) (result []domain.Alert, err error) {
+	defer r.observe("GetFiringAlerts", time.Now(), &err)
+	return r.next.GetFiringAlerts(ctx)
+}
+
+func (r *alertRepository) FireAlert(ctx context.Context, ruleId int, terminalID int) (err error) {
+	defer r.observe("FireAlert", time.Now(), &err)
+	return r.next.FireAlert(ctx, ruleId, terminalID)
+}
+
+func (r *alertRepository) ResolveAlert(ctx context.Context, id int) (err error) {
+	defer r.observe("ResolveAlert", time.Now(), &err)
+	return r.next.ResolveAlert(ctx, id)
+}
+
+func (r *alertRepository) GetAlerts(ctx context.Context, userId int, state string) (result []domain.Alert, err error) {
+	defer r.observe("GetAlerts", time.Now(), &err)
+	return r.next.GetAlerts(ctx, userId, state)
+}
+
+func (r *alertRepository) GetAlert(ctx context.Context, userId int, id int) (result domain.Alert, err error) {
+	defer r.observe("GetAlert", time.Now(), &err)
+	return r.next.GetAlert(ctx, userId, id)
+}
+
+func (r *alertRepository) AckAlert(ctx context.Context, userId int, id int) (err error) {
+	defer r.observe("AckAlert", time.Now(), &err)
+	return r.next.AckAlert(ctx, userId, id)
+}
+
+type webhookRepository struct {
+	next repositories.WebhookRepositoryPort
+	m    *Metrics
+}
+
+func (r *webhookRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("webhook", method, start, *err)
+}
+
+func (r *webhookRepository) CreateWebhook(ctx context.Context, hook domain.Webhook) (result domain.Webhook, err error) {
+	defer r.observe("CreateWebhook", time.Now(), &err)
+	return r.next.CreateWebhook(ctx, hook)
+}
+
+func (r *webhookRepository) GetWebhooks(ctx context.Context) (result []domain.Webhook, err error) {
+	defer r.observe("GetWebhooks", time.Now(), &err)
+	return r.next.GetWebhooks(ctx)
+}
+
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, id int) (err error) {
+	defer r.observe("DeleteWebhook", time.Now(), &err)
+	return r.next.DeleteWebhook(ctx, id)
+}
+
+func (r *webhookRepository) ClaimWebhookEvents(ctx context.Context, limit int) (result []domain.WebhookEvent, err error) {
+	defer r.observe("ClaimWebhookEvents", time.Now(), &err)
+	return r.next.ClaimWebhookEvents(ctx, limit)
+}
+
+func (r *webhookRepository) CreateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) (err error) {
+	defer r.observe("CreateWebhookDelivery", time.Now(), &err)
+	return r.next.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (r *webhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (result []domain.WebhookDelivery, err error) {
+	defer r.observe("ClaimDueWebhookDeliveries", time.Now(), &err)
+	return r.next.ClaimDueWebhookDeliveries(ctx, now, lease, limit)
+}
+
+func (r *webhookRepository) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) (err error) {
+	defer r.observe("UpdateWebhookDelivery", time.Now(), &err)
+	return r.next.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (r *webhookRepository) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) (result []domain.WebhookDelivery, err error) {
+	defer r.observe("GetWebhookDeliveries", time.Now(), &err)
+	return r.next.GetWebhookDeliveries(ctx, webhookID, state)
+}
+
+func (r *webhookRepository) RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64) (err error) {
+	defer r.observe("RedeliverWebhookDelivery", time.Now(), &err)
+	return r.next.RedeliverWebhookDelivery(ctx, webhookID, id)
+}
+
+type notificationRepository struct {
+	next repositories.NotificationRepositoryPort
+	m    *Metrics
+}
+
+func (r *notificationRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("notification", method, start, *err)
+}
+
+func (r *notificationRepository) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (result domain.NotificationChannel, err error) {
+	defer r.observe("CreateNotificationChannel", time.Now(), &err)
+	return r.next.CreateNotificationChannel(ctx, channel)
+}
+
+func (r *notificationRepository) GetNotificationChannels(ctx context.Context, userId int) (result []domain.NotificationChannel, err error) {
+	defer r.observe("GetNotificationChannels", time.Now(), &err)
+	return r.next.GetNotificationChannels(ctx, userId)
+}
+
+func (r *notificationRepository) GetNotificationChannel(ctx context.Context, userId int, id int) (result domain.NotificationChannel, err error) {
+	defer r.observe("GetNotificationChannel", time.Now(), &err)
+	return r.next.GetNotificationChannel(ctx, userId, id)
+}
+
+func (r *notificationRepository) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (err error) {
+	defer r.observe("UpdateNotificationChannel", time.Now(), &err)
+	return r.next.UpdateNotificationChannel(ctx, channel)
+}
+
+func (r *notificationRepository) DeleteNotificationChannel(ctx context.Context, userId int, id int) (err error) {
+	defer r.observe("DeleteNotificationChannel", time.Now(), &err)
+	return r.next.DeleteNotificationChannel(ctx, userId, id)
+}
+
+type heartbeatRepository struct {
+	next repositories.HeartbeatRepositoryPort
+	m    *Metrics
+}
+
+func (r *heartbeatRepository) observe(method string, start time.Time, err *error) {
+	r.m.observeRepo("heartbeat", method, start, *err)
+}
+
+func (r *heartbeatRepository) SetHeartbeatToken(ctx context.Context, terminalID int, tokenHash string, issuedAt time.Time) (err error) {
+	defer r.observe("SetHeartbeatToken", time.Now(), &err)
+	return r.next.SetHeartbeatToken(ctx, terminalID, tokenHash, issuedAt)
+}
+
+func (r *heartbeatRepository) DeleteHeartbeatToken(ctx context.Context, terminalID int) (err error) {
+	defer r.observe("DeleteHeartbeatToken", time.Now(), &err)
+	return r.next.DeleteHeartbeatToken(ctx, terminalID)
+}
+
+func (r *heartbeatRepository) RecordHeartbeat(ctx context.Context, tokenHash string, heartbeat domain.Heartbeat, at time.Time) (result int, err error) {
+	defer r.observe("RecordHeartbeat", time.Now(), &err)
+	return r.next.RecordHeartbeat(ctx, tokenHash, heartbeat, at)
+}
+
+func (r *heartbeatRepository) GetHeartbeats(ctx context.Context) (result []domain.TerminalHeartbeat, err error) {
+	defer r.observe("GetHeartbeats", time.Now(), &err)
+	return r.next.GetHeartbeats(ctx)
+}
+
+func (r *heartbeatRepository) MarkTerminalOnline(ctx context.Context, terminalID int, reason string) (result bool, err error) {
+	defer r.observe("MarkTerminalOnline", time.Now(), &err)
+	return r.next.MarkTerminalOnline(ctx, terminalID, reason)
+}
+
+func (r *heartbeatRepository) MarkTerminalsOffline(ctx context.Context, before time.Time, reason string) (result []domain.Terminal, err error) {
+	defer r.observe("MarkTerminalsOffline", time.Now(), &err)
+	return r.next.MarkTerminalsOffline(ctx, before, reason)
+}
@@ -5,9 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/dvdxa/add-to-favorites/internal/domain"
+	repositories "github.com/dvdxa/add-to-favorites/internal/repositories"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,33 +37,136 @@ func (m *MockUserRepositoryPort) EXPECT() *MockUserRepositoryPortMockRecorder {
 	return m.recorder
 }
 
+// CountActiveUsers mocks base method.
+func (m *MockUserRepositoryPort) CountActiveUsers(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountActiveUsers", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountActiveUsers indicates an expected call of CountActiveUsers.
+func (mr *MockUserRepositoryPortMockRecorder) CountActiveUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountActiveUsers", reflect.TypeOf((*MockUserRepositoryPort)(nil).CountActiveUsers), ctx)
+}
+
 // CreateUser mocks base method.
-func (m *MockUserRepositoryPort) CreateUser(user domain.User) error {
+func (m *MockUserRepositoryPort) CreateUser(ctx context.Context, user domain.User) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", user)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserRepositoryPortMockRecorder) CreateUser(user interface{}) *gomock.Call {
+func (mr *MockUserRepositoryPortMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).CreateUser), ctx, user)
+}
+
+// GetDeletedUsers mocks base method.
+func (m *MockUserRepositoryPort) GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedUsers", ctx)
+	ret0, _ := ret[0].([]domain.DeletedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedUsers indicates an expected call of GetDeletedUsers.
+func (mr *MockUserRepositoryPortMockRecorder) GetDeletedUsers(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).CreateUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedUsers", reflect.TypeOf((*MockUserRepositoryPort)(nil).GetDeletedUsers), ctx)
 }
 
 // GetUser mocks base method.
-func (m *MockUserRepositoryPort) GetUser(username string) (domain.User, error) {
+func (m *MockUserRepositoryPort) GetUser(ctx context.Context, username string) (domain.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", username)
+	ret := m.ctrl.Call(m, "GetUser", ctx, username)
 	ret0, _ := ret[0].(domain.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUser indicates an expected call of GetUser.
-func (mr *MockUserRepositoryPortMockRecorder) GetUser(username interface{}) *gomock.Call {
+func (mr *MockUserRepositoryPortMockRecorder) GetUser(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).GetUser), ctx, username)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserRepositoryPort) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryPortMockRecorder) GetUserByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepositoryPort)(nil).GetUserByID), ctx, id)
+}
+
+// GetUsersDeletedBefore mocks base method.
+func (m *MockUserRepositoryPort) GetUsersDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersDeletedBefore", ctx, before)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersDeletedBefore indicates an expected call of GetUsersDeletedBefore.
+func (mr *MockUserRepositoryPortMockRecorder) GetUsersDeletedBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersDeletedBefore", reflect.TypeOf((*MockUserRepositoryPort)(nil).GetUsersDeletedBefore), ctx, before)
+}
+
+// PurgeUser mocks base method.
+func (m *MockUserRepositoryPort) PurgeUser(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeUser indicates an expected call of PurgeUser.
+func (mr *MockUserRepositoryPortMockRecorder) PurgeUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).PurgeUser), ctx, id)
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserRepositoryPort) RestoreUser(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserRepositoryPortMockRecorder) RestoreUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).RestoreUser), ctx, id)
+}
+
+// SoftDeleteUser mocks base method.
+func (m *MockUserRepositoryPort) SoftDeleteUser(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SoftDeleteUser indicates an expected call of SoftDeleteUser.
+func (mr *MockUserRepositoryPortMockRecorder) SoftDeleteUser(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).GetUser), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteUser", reflect.TypeOf((*MockUserRepositoryPort)(nil).SoftDeleteUser), ctx, id)
 }
 
 // MockTerminalRepositoryPort is a mock of TerminalRepositoryPort interface.
@@ -87,59 +193,1100 @@ func (m *MockTerminalRepositoryPort) EXPECT() *MockTerminalRepositoryPortMockRec
 }
 
 // AddToFavorites mocks base method.
-func (m *MockTerminalRepositoryPort) AddToFavorites(terminalId, userId int) error {
+func (m *MockTerminalRepositoryPort) AddToFavorites(ctx context.Context, terminalId, userId int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddToFavorites", terminalId, userId)
+	ret := m.ctrl.Call(m, "AddToFavorites", ctx, terminalId, userId)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddToFavorites indicates an expected call of AddToFavorites.
-func (mr *MockTerminalRepositoryPortMockRecorder) AddToFavorites(terminalId, userId interface{}) *gomock.Call {
+func (mr *MockTerminalRepositoryPortMockRecorder) AddToFavorites(ctx, terminalId, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToFavorites", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).AddToFavorites), ctx, terminalId, userId)
+}
+
+// AttachTag mocks base method.
+func (m *MockTerminalRepositoryPort) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttachTag", ctx, terminalID, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AttachTag indicates an expected call of AttachTag.
+func (mr *MockTerminalRepositoryPortMockRecorder) AttachTag(ctx, terminalID, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachTag", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).AttachTag), ctx, terminalID, tag)
+}
+
+// CountFavorites mocks base method.
+func (m *MockTerminalRepositoryPort) CountFavorites(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFavorites", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFavorites indicates an expected call of CountFavorites.
+func (mr *MockTerminalRepositoryPortMockRecorder) CountFavorites(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFavorites", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).CountFavorites), ctx)
+}
+
+// CreateFavorites mocks base method.
+func (m *MockTerminalRepositoryPort) CreateFavorites(ctx context.Context, userId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFavorites", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFavorites indicates an expected call of CreateFavorites.
+func (mr *MockTerminalRepositoryPortMockRecorder) CreateFavorites(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFavorites", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).CreateFavorites), ctx, userId)
+}
+
+// CreateSite mocks base method.
+func (m *MockTerminalRepositoryPort) CreateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSite", ctx, site)
+	ret0, _ := ret[0].(domain.Site)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSite indicates an expected call of CreateSite.
+func (mr *MockTerminalRepositoryPortMockRecorder) CreateSite(ctx, site interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSite", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).CreateSite), ctx, site)
+}
+
+// DeleteSite mocks base method.
+func (m *MockTerminalRepositoryPort) DeleteSite(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSite", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSite indicates an expected call of DeleteSite.
+func (mr *MockTerminalRepositoryPortMockRecorder) DeleteSite(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSite", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).DeleteSite), ctx, id)
+}
+
+// DeleteView mocks base method.
+func (m *MockTerminalRepositoryPort) DeleteView(ctx context.Context, userId int, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteView", ctx, userId, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteView indicates an expected call of DeleteView.
+func (mr *MockTerminalRepositoryPortMockRecorder) DeleteView(ctx, userId, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteView", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).DeleteView), ctx, userId, name)
+}
+
+// DetachTag mocks base method.
+func (m *MockTerminalRepositoryPort) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachTag", ctx, terminalID, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachTag indicates an expected call of DetachTag.
+func (mr *MockTerminalRepositoryPortMockRecorder) DetachTag(ctx, terminalID, tag interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToFavorites", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).AddToFavorites), terminalId, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachTag", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).DetachTag), ctx, terminalID, tag)
 }
 
 // GetDefaultTerminalsList mocks base method.
-func (m *MockTerminalRepositoryPort) GetDefaultTerminalsList() ([]domain.Terminal, error) {
+func (m *MockTerminalRepositoryPort) GetDefaultTerminalsList(ctx context.Context) ([]domain.Terminal, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDefaultTerminalsList")
+	ret := m.ctrl.Call(m, "GetDefaultTerminalsList", ctx)
 	ret0, _ := ret[0].([]domain.Terminal)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetDefaultTerminalsList indicates an expected call of GetDefaultTerminalsList.
-func (mr *MockTerminalRepositoryPortMockRecorder) GetDefaultTerminalsList() *gomock.Call {
+func (mr *MockTerminalRepositoryPortMockRecorder) GetDefaultTerminalsList(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultTerminalsList", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetDefaultTerminalsList), ctx)
+}
+
+// GetDefaultView mocks base method.
+func (m *MockTerminalRepositoryPort) GetDefaultView(ctx context.Context, userId int) (domain.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefaultView", ctx, userId)
+	ret0, _ := ret[0].(domain.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefaultView indicates an expected call of GetDefaultView.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetDefaultView(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultView", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetDefaultView), ctx, userId)
+}
+
+// GetDeletedTerminals mocks base method.
+func (m *MockTerminalRepositoryPort) GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedTerminals", ctx)
+	ret0, _ := ret[0].([]domain.DeletedRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedTerminals indicates an expected call of GetDeletedTerminals.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetDeletedTerminals(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedTerminals", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetDeletedTerminals), ctx)
+}
+
+// GetFavoriteNotes mocks base method.
+func (m *MockTerminalRepositoryPort) GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFavoriteNotes", ctx, userId)
+	ret0, _ := ret[0].(map[int]domain.FavoriteNote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFavoriteNotes indicates an expected call of GetFavoriteNotes.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetFavoriteNotes(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefaultTerminalsList", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetDefaultTerminalsList))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFavoriteNotes", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetFavoriteNotes), ctx, userId)
 }
 
 // GetFavoriteTerminalIds mocks base method.
-func (m *MockTerminalRepositoryPort) GetFavoriteTerminalIds(userId int) ([]int, error) {
+func (m *MockTerminalRepositoryPort) GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFavoriteTerminalIds", userId)
+	ret := m.ctrl.Call(m, "GetFavoriteTerminalIds", ctx, userId)
 	ret0, _ := ret[0].([]int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFavoriteTerminalIds indicates an expected call of GetFavoriteTerminalIds.
-func (mr *MockTerminalRepositoryPortMockRecorder) GetFavoriteTerminalIds(userId interface{}) *gomock.Call {
+func (mr *MockTerminalRepositoryPortMockRecorder) GetFavoriteTerminalIds(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFavoriteTerminalIds", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetFavoriteTerminalIds), ctx, userId)
+}
+
+// GetFavoritesVersion mocks base method.
+func (m *MockTerminalRepositoryPort) GetFavoritesVersion(ctx context.Context, userId int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFavoritesVersion", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFavoritesVersion indicates an expected call of GetFavoritesVersion.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetFavoritesVersion(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFavoritesVersion", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetFavoritesVersion), ctx, userId)
+}
+
+// GetSites mocks base method.
+func (m *MockTerminalRepositoryPort) GetSites(ctx context.Context) ([]domain.Site, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSites", ctx)
+	ret0, _ := ret[0].([]domain.Site)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSites indicates an expected call of GetSites.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetSites(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSites", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetSites), ctx)
+}
+
+// GetTerminalsDeletedBefore mocks base method.
+func (m *MockTerminalRepositoryPort) GetTerminalsDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTerminalsDeletedBefore", ctx, before)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTerminalsDeletedBefore indicates an expected call of GetTerminalsDeletedBefore.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetTerminalsDeletedBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTerminalsDeletedBefore", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetTerminalsDeletedBefore), ctx, before)
+}
+
+// GetView mocks base method.
+func (m *MockTerminalRepositoryPort) GetView(ctx context.Context, userId int, owner, name string) (domain.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetView", ctx, userId, owner, name)
+	ret0, _ := ret[0].(domain.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetView indicates an expected call of GetView.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetView(ctx, userId, owner, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetView", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetView), ctx, userId, owner, name)
+}
+
+// GetViews mocks base method.
+func (m *MockTerminalRepositoryPort) GetViews(ctx context.Context, userId int) ([]domain.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetViews", ctx, userId)
+	ret0, _ := ret[0].([]domain.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetViews indicates an expected call of GetViews.
+func (mr *MockTerminalRepositoryPortMockRecorder) GetViews(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetViews", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetViews), ctx, userId)
+}
+
+// PurgeTerminal mocks base method.
+func (m *MockTerminalRepositoryPort) PurgeTerminal(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTerminal", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeTerminal indicates an expected call of PurgeTerminal.
+func (mr *MockTerminalRepositoryPortMockRecorder) PurgeTerminal(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFavoriteTerminalIds", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).GetFavoriteTerminalIds), userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTerminal", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).PurgeTerminal), ctx, id)
 }
 
 // RemoveFromFavoriteTerminal mocks base method.
-func (m *MockTerminalRepositoryPort) RemoveFromFavoriteTerminal(terminalID, userId int) error {
+func (m *MockTerminalRepositoryPort) RemoveFromFavoriteTerminal(ctx context.Context, terminalID, userId int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveFromFavoriteTerminal", terminalID, userId)
+	ret := m.ctrl.Call(m, "RemoveFromFavoriteTerminal", ctx, terminalID, userId)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RemoveFromFavoriteTerminal indicates an expected call of RemoveFromFavoriteTerminal.
-func (mr *MockTerminalRepositoryPortMockRecorder) RemoveFromFavoriteTerminal(terminalID, userId interface{}) *gomock.Call {
+func (mr *MockTerminalRepositoryPortMockRecorder) RemoveFromFavoriteTerminal(ctx, terminalID, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromFavoriteTerminal", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).RemoveFromFavoriteTerminal), ctx, terminalID, userId)
+}
+
+// RestoreRemovedFavoriteNote mocks base method.
+func (m *MockTerminalRepositoryPort) RestoreRemovedFavoriteNote(ctx context.Context, userId int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreRemovedFavoriteNote", ctx, userId)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreRemovedFavoriteNote indicates an expected call of RestoreRemovedFavoriteNote.
+func (mr *MockTerminalRepositoryPortMockRecorder) RestoreRemovedFavoriteNote(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreRemovedFavoriteNote", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).RestoreRemovedFavoriteNote), ctx, userId)
+}
+
+// RestoreTerminal mocks base method.
+func (m *MockTerminalRepositoryPort) RestoreTerminal(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreTerminal", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreTerminal indicates an expected call of RestoreTerminal.
+func (mr *MockTerminalRepositoryPortMockRecorder) RestoreTerminal(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreTerminal", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).RestoreTerminal), ctx, id)
+}
+
+// SaveView mocks base method.
+func (m *MockTerminalRepositoryPort) SaveView(ctx context.Context, view domain.View) (domain.View, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveView", ctx, view)
+	ret0, _ := ret[0].(domain.View)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveView indicates an expected call of SaveView.
+func (mr *MockTerminalRepositoryPortMockRecorder) SaveView(ctx, view interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveView", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).SaveView), ctx, view)
+}
+
+// SetFavoriteNote mocks base method.
+func (m *MockTerminalRepositoryPort) SetFavoriteNote(ctx context.Context, userId, terminalID int, note domain.FavoriteNote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFavoriteNote", ctx, userId, terminalID, note)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFavoriteNote indicates an expected call of SetFavoriteNote.
+func (mr *MockTerminalRepositoryPortMockRecorder) SetFavoriteNote(ctx, userId, terminalID, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFavoriteNote", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).SetFavoriteNote), ctx, userId, terminalID, note)
+}
+
+// SetTerminalLocation mocks base method.
+func (m *MockTerminalRepositoryPort) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTerminalLocation", ctx, terminalID, location)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTerminalLocation indicates an expected call of SetTerminalLocation.
+func (mr *MockTerminalRepositoryPortMockRecorder) SetTerminalLocation(ctx, terminalID, location interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTerminalLocation", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).SetTerminalLocation), ctx, terminalID, location)
+}
+
+// SetTerminalSite mocks base method.
+func (m *MockTerminalRepositoryPort) SetTerminalSite(ctx context.Context, terminalID, siteID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTerminalSite", ctx, terminalID, siteID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTerminalSite indicates an expected call of SetTerminalSite.
+func (mr *MockTerminalRepositoryPortMockRecorder) SetTerminalSite(ctx, terminalID, siteID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTerminalSite", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).SetTerminalSite), ctx, terminalID, siteID)
+}
+
+// SoftDeleteTerminal mocks base method.
+func (m *MockTerminalRepositoryPort) SoftDeleteTerminal(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SoftDeleteTerminal", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SoftDeleteTerminal indicates an expected call of SoftDeleteTerminal.
+func (mr *MockTerminalRepositoryPortMockRecorder) SoftDeleteTerminal(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteTerminal", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).SoftDeleteTerminal), ctx, id)
+}
+
+// UpdateSite mocks base method.
+func (m *MockTerminalRepositoryPort) UpdateSite(ctx context.Context, site domain.Site) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSite", ctx, site)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSite indicates an expected call of UpdateSite.
+func (mr *MockTerminalRepositoryPortMockRecorder) UpdateSite(ctx, site interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSite", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).UpdateSite), ctx, site)
+}
+
+// MockAuditRepositoryPort is a mock of AuditRepositoryPort interface.
+type MockAuditRepositoryPort struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryPortMockRecorder
+}
+
+// MockAuditRepositoryPortMockRecorder is the mock recorder for MockAuditRepositoryPort.
+type MockAuditRepositoryPortMockRecorder struct {
+	mock *MockAuditRepositoryPort
+}
+
+// NewMockAuditRepositoryPort creates a new mock instance.
+func NewMockAuditRepositoryPort(ctrl *gomock.Controller) *MockAuditRepositoryPort {
+	mock := &MockAuditRepositoryPort{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryPortMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepositoryPort) EXPECT() *MockAuditRepositoryPortMockRecorder {
+	return m.recorder
+}
+
+// DeleteAuditEntriesBefore mocks base method.
+func (m *MockAuditRepositoryPort) DeleteAuditEntriesBefore(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuditEntriesBefore", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAuditEntriesBefore indicates an expected call of DeleteAuditEntriesBefore.
+func (mr *MockAuditRepositoryPortMockRecorder) DeleteAuditEntriesBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuditEntriesBefore", reflect.TypeOf((*MockAuditRepositoryPort)(nil).DeleteAuditEntriesBefore), ctx, before)
+}
+
+// GetAuditEntries mocks base method.
+func (m *MockAuditRepositoryPort) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditEntries", ctx, filter)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEntries indicates an expected call of GetAuditEntries.
+func (mr *MockAuditRepositoryPortMockRecorder) GetAuditEntries(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEntries", reflect.TypeOf((*MockAuditRepositoryPort)(nil).GetAuditEntries), ctx, filter)
+}
+
+// InsertAuditEntry mocks base method.
+func (m *MockAuditRepositoryPort) InsertAuditEntry(ctx context.Context, entry domain.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAuditEntry", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertAuditEntry indicates an expected call of InsertAuditEntry.
+func (mr *MockAuditRepositoryPortMockRecorder) InsertAuditEntry(ctx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAuditEntry", reflect.TypeOf((*MockAuditRepositoryPort)(nil).InsertAuditEntry), ctx, entry)
+}
+
+// MockAlertRepositoryPort is a mock of AlertRepositoryPort interface.
+type MockAlertRepositoryPort struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertRepositoryPortMockRecorder
+}
+
+// MockAlertRepositoryPortMockRecorder is the mock recorder for MockAlertRepositoryPort.
+type MockAlertRepositoryPortMockRecorder struct {
+	mock *MockAlertRepositoryPort
+}
+
+// NewMockAlertRepositoryPort creates a new mock instance.
+func NewMockAlertRepositoryPort(ctrl *gomock.Controller) *MockAlertRepositoryPort {
+	mock := &MockAlertRepositoryPort{ctrl: ctrl}
+	mock.recorder = &MockAlertRepositoryPortMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertRepositoryPort) EXPECT() *MockAlertRepositoryPortMockRecorder {
+	return m.recorder
+}
+
+// AckAlert mocks base method.
+func (m *MockAlertRepositoryPort) AckAlert(ctx context.Context, userId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckAlert", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AckAlert indicates an expected call of AckAlert.
+func (mr *MockAlertRepositoryPortMockRecorder) AckAlert(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckAlert", reflect.TypeOf((*MockAlertRepositoryPort)(nil).AckAlert), ctx, userId, id)
+}
+
+// ClaimStatusChanges mocks base method.
+func (m *MockAlertRepositoryPort) ClaimStatusChanges(ctx context.Context, limit int) ([]domain.StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimStatusChanges", ctx, limit)
+	ret0, _ := ret[0].([]domain.StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimStatusChanges indicates an expected call of ClaimStatusChanges.
+func (mr *MockAlertRepositoryPortMockRecorder) ClaimStatusChanges(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimStatusChanges", reflect.TypeOf((*MockAlertRepositoryPort)(nil).ClaimStatusChanges), ctx, limit)
+}
+
+// CreateAlertRule mocks base method.
+func (m *MockAlertRepositoryPort) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlertRule", ctx, rule)
+	ret0, _ := ret[0].(domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlertRule indicates an expected call of CreateAlertRule.
+func (mr *MockAlertRepositoryPortMockRecorder) CreateAlertRule(ctx, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlertRule", reflect.TypeOf((*MockAlertRepositoryPort)(nil).CreateAlertRule), ctx, rule)
+}
+
+// DeleteAlertRule mocks base method.
+func (m *MockAlertRepositoryPort) DeleteAlertRule(ctx context.Context, userId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlertRule", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlertRule indicates an expected call of DeleteAlertRule.
+func (mr *MockAlertRepositoryPortMockRecorder) DeleteAlertRule(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlertRule", reflect.TypeOf((*MockAlertRepositoryPort)(nil).DeleteAlertRule), ctx, userId, id)
+}
+
+// FireAlert mocks base method.
+func (m *MockAlertRepositoryPort) FireAlert(ctx context.Context, ruleId, terminalID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FireAlert", ctx, ruleId, terminalID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FireAlert indicates an expected call of FireAlert.
+func (mr *MockAlertRepositoryPortMockRecorder) FireAlert(ctx, ruleId, terminalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FireAlert", reflect.TypeOf((*MockAlertRepositoryPort)(nil).FireAlert), ctx, ruleId, terminalID)
+}
+
+// GetAlert mocks base method.
+func (m *MockAlertRepositoryPort) GetAlert(ctx context.Context, userId, id int) (domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlert", ctx, userId, id)
+	ret0, _ := ret[0].(domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlert indicates an expected call of GetAlert.
+func (mr *MockAlertRepositoryPortMockRecorder) GetAlert(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlert", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetAlert), ctx, userId, id)
+}
+
+// GetAlertFavorites mocks base method.
+func (m *MockAlertRepositoryPort) GetAlertFavorites(ctx context.Context) (map[int][]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertFavorites", ctx)
+	ret0, _ := ret[0].(map[int][]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertFavorites indicates an expected call of GetAlertFavorites.
+func (mr *MockAlertRepositoryPortMockRecorder) GetAlertFavorites(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertFavorites", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetAlertFavorites), ctx)
+}
+
+// GetAlertRules mocks base method.
+func (m *MockAlertRepositoryPort) GetAlertRules(ctx context.Context, userId int) ([]domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertRules", ctx, userId)
+	ret0, _ := ret[0].([]domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertRules indicates an expected call of GetAlertRules.
+func (mr *MockAlertRepositoryPortMockRecorder) GetAlertRules(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertRules", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetAlertRules), ctx, userId)
+}
+
+// GetAlerts mocks base method.
+func (m *MockAlertRepositoryPort) GetAlerts(ctx context.Context, userId int, state string) ([]domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts", ctx, userId, state)
+	ret0, _ := ret[0].([]domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockAlertRepositoryPortMockRecorder) GetAlerts(ctx, userId, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetAlerts), ctx, userId, state)
+}
+
+// GetAllAlertRules mocks base method.
+func (m *MockAlertRepositoryPort) GetAllAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllAlertRules", ctx)
+	ret0, _ := ret[0].([]domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllAlertRules indicates an expected call of GetAllAlertRules.
+func (mr *MockAlertRepositoryPortMockRecorder) GetAllAlertRules(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllAlertRules", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetAllAlertRules), ctx)
+}
+
+// GetFiringAlerts mocks base method.
+func (m *MockAlertRepositoryPort) GetFiringAlerts(ctx context.Context) ([]domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFiringAlerts", ctx)
+	ret0, _ := ret[0].([]domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFiringAlerts indicates an expected call of GetFiringAlerts.
+func (mr *MockAlertRepositoryPortMockRecorder) GetFiringAlerts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFiringAlerts", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetFiringAlerts), ctx)
+}
+
+// GetStatusChanges mocks base method.
+func (m *MockAlertRepositoryPort) GetStatusChanges(ctx context.Context, since time.Time) (map[int][]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusChanges", ctx, since)
+	ret0, _ := ret[0].(map[int][]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusChanges indicates an expected call of GetStatusChanges.
+func (mr *MockAlertRepositoryPortMockRecorder) GetStatusChanges(ctx, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusChanges", reflect.TypeOf((*MockAlertRepositoryPort)(nil).GetStatusChanges), ctx, since)
+}
+
+// MuteAlertRule mocks base method.
+func (m *MockAlertRepositoryPort) MuteAlertRule(ctx context.Context, userId, id int, until *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteAlertRule", ctx, userId, id, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MuteAlertRule indicates an expected call of MuteAlertRule.
+func (mr *MockAlertRepositoryPortMockRecorder) MuteAlertRule(ctx, userId, id, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteAlertRule", reflect.TypeOf((*MockAlertRepositoryPort)(nil).MuteAlertRule), ctx, userId, id, until)
+}
+
+// ResolveAlert mocks base method.
+func (m *MockAlertRepositoryPort) ResolveAlert(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveAlert", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveAlert indicates an expected call of ResolveAlert.
+func (mr *MockAlertRepositoryPortMockRecorder) ResolveAlert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveAlert", reflect.TypeOf((*MockAlertRepositoryPort)(nil).ResolveAlert), ctx, id)
+}
+
+// MockWebhookRepositoryPort is a mock of WebhookRepositoryPort interface.
+type MockWebhookRepositoryPort struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookRepositoryPortMockRecorder
+}
+
+// MockWebhookRepositoryPortMockRecorder is the mock recorder for MockWebhookRepositoryPort.
+type MockWebhookRepositoryPortMockRecorder struct {
+	mock *MockWebhookRepositoryPort
+}
+
+// NewMockWebhookRepositoryPort creates a new mock instance.
+func NewMockWebhookRepositoryPort(ctrl *gomock.Controller) *MockWebhookRepositoryPort {
+	mock := &MockWebhookRepositoryPort{ctrl: ctrl}
+	mock.recorder = &MockWebhookRepositoryPortMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookRepositoryPort) EXPECT() *MockWebhookRepositoryPortMockRecorder {
+	return m.recorder
+}
+
+// ClaimDueWebhookDeliveries mocks base method.
+func (m *MockWebhookRepositoryPort) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueWebhookDeliveries", ctx, now, lease, limit)
+	ret0, _ := ret[0].([]domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueWebhookDeliveries indicates an expected call of ClaimDueWebhookDeliveries.
+func (mr *MockWebhookRepositoryPortMockRecorder) ClaimDueWebhookDeliveries(ctx, now, lease, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueWebhookDeliveries", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).ClaimDueWebhookDeliveries), ctx, now, lease, limit)
+}
+
+// ClaimWebhookEvents mocks base method.
+func (m *MockWebhookRepositoryPort) ClaimWebhookEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimWebhookEvents", ctx, limit)
+	ret0, _ := ret[0].([]domain.WebhookEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimWebhookEvents indicates an expected call of ClaimWebhookEvents.
+func (mr *MockWebhookRepositoryPortMockRecorder) ClaimWebhookEvents(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimWebhookEvents", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).ClaimWebhookEvents), ctx, limit)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockWebhookRepositoryPort) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, hook)
+	ret0, _ := ret[0].(domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockWebhookRepositoryPortMockRecorder) CreateWebhook(ctx, hook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).CreateWebhook), ctx, hook)
+}
+
+// CreateWebhookDelivery mocks base method.
+func (m *MockWebhookRepositoryPort) CreateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebhookDelivery indicates an expected call of CreateWebhookDelivery.
+func (mr *MockWebhookRepositoryPortMockRecorder) CreateWebhookDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookDelivery", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).CreateWebhookDelivery), ctx, delivery)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookRepositoryPort) DeleteWebhook(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookRepositoryPortMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).DeleteWebhook), ctx, id)
+}
+
+// GetWebhookDeliveries mocks base method.
+func (m *MockWebhookRepositoryPort) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDeliveries", ctx, webhookID, state)
+	ret0, _ := ret[0].([]domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDeliveries indicates an expected call of GetWebhookDeliveries.
+func (mr *MockWebhookRepositoryPortMockRecorder) GetWebhookDeliveries(ctx, webhookID, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDeliveries", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).GetWebhookDeliveries), ctx, webhookID, state)
+}
+
+// GetWebhooks mocks base method.
+func (m *MockWebhookRepositoryPort) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooks", ctx)
+	ret0, _ := ret[0].([]domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooks indicates an expected call of GetWebhooks.
+func (mr *MockWebhookRepositoryPortMockRecorder) GetWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooks", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).GetWebhooks), ctx)
+}
+
+// RedeliverWebhookDelivery mocks base method.
+func (m *MockWebhookRepositoryPort) RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeliverWebhookDelivery", ctx, webhookID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RedeliverWebhookDelivery indicates an expected call of RedeliverWebhookDelivery.
+func (mr *MockWebhookRepositoryPortMockRecorder) RedeliverWebhookDelivery(ctx, webhookID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeliverWebhookDelivery", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).RedeliverWebhookDelivery), ctx, webhookID, id)
+}
+
+// UpdateWebhookDelivery mocks base method.
+func (m *MockWebhookRepositoryPort) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebhookDelivery indicates an expected call of UpdateWebhookDelivery.
+func (mr *MockWebhookRepositoryPortMockRecorder) UpdateWebhookDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookDelivery", reflect.TypeOf((*MockWebhookRepositoryPort)(nil).UpdateWebhookDelivery), ctx, delivery)
+}
+
+// MockNotificationRepositoryPort is a mock of NotificationRepositoryPort interface.
+type MockNotificationRepositoryPort struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotificationRepositoryPortMockRecorder
+}
+
+// MockNotificationRepositoryPortMockRecorder is the mock recorder for MockNotificationRepositoryPort.
+type MockNotificationRepositoryPortMockRecorder struct {
+	mock *MockNotificationRepositoryPort
+}
+
+// NewMockNotificationRepositoryPort creates a new mock instance.
+func NewMockNotificationRepositoryPort(ctrl *gomock.Controller) *MockNotificationRepositoryPort {
+	mock := &MockNotificationRepositoryPort{ctrl: ctrl}
+	mock.recorder = &MockNotificationRepositoryPortMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotificationRepositoryPort) EXPECT() *MockNotificationRepositoryPortMockRecorder {
+	return m.recorder
+}
+
+// CreateNotificationChannel mocks base method.
+func (m *MockNotificationRepositoryPort) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNotificationChannel", ctx, channel)
+	ret0, _ := ret[0].(domain.NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNotificationChannel indicates an expected call of CreateNotificationChannel.
+func (mr *MockNotificationRepositoryPortMockRecorder) CreateNotificationChannel(ctx, channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNotificationChannel", reflect.TypeOf((*MockNotificationRepositoryPort)(nil).CreateNotificationChannel), ctx, channel)
+}
+
+// DeleteNotificationChannel mocks base method.
+func (m *MockNotificationRepositoryPort) DeleteNotificationChannel(ctx context.Context, userId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNotificationChannel", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNotificationChannel indicates an expected call of DeleteNotificationChannel.
+func (mr *MockNotificationRepositoryPortMockRecorder) DeleteNotificationChannel(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNotificationChannel", reflect.TypeOf((*MockNotificationRepositoryPort)(nil).DeleteNotificationChannel), ctx, userId, id)
+}
+
+// GetNotificationChannel mocks base method.
+func (m *MockNotificationRepositoryPort) GetNotificationChannel(ctx context.Context, userId, id int) (domain.NotificationChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotificationChannel", ctx, userId, id)
+	ret0, _ := ret[0].(domain.NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotificationChannel indicates an expected call of GetNotificationChannel.
+func (mr *MockNotificationRepositoryPortMockRecorder) GetNotificationChannel(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationChannel", reflect.TypeOf((*MockNotificationRepositoryPort)(nil).GetNotificationChannel), ctx, userId, id)
+}
+
+// GetNotificationChannels mocks base method.
+func (m *MockNotificationRepositoryPort) GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotificationChannels", ctx, userId)
+	ret0, _ := ret[0].([]domain.NotificationChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotificationChannels indicates an expected call of GetNotificationChannels.
+func (mr *MockNotificationRepositoryPortMockRecorder) GetNotificationChannels(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationChannels", reflect.TypeOf((*MockNotificationRepositoryPort)(nil).GetNotificationChannels), ctx, userId)
+}
+
+// UpdateNotificationChannel mocks base method.
+func (m *MockNotificationRepositoryPort) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNotificationChannel", ctx, channel)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateNotificationChannel indicates an expected call of UpdateNotificationChannel.
+func (mr *MockNotificationRepositoryPortMockRecorder) UpdateNotificationChannel(ctx, channel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNotificationChannel", reflect.TypeOf((*MockNotificationRepositoryPort)(nil).UpdateNotificationChannel), ctx, channel)
+}
+
+// MockHeartbeatRepositoryPort is a mock of HeartbeatRepositoryPort interface.
+type MockHeartbeatRepositoryPort struct {
+	ctrl     *gomock.Controller
+	recorder *MockHeartbeatRepositoryPortMockRecorder
+}
+
+// MockHeartbeatRepositoryPortMockRecorder is the mock recorder for MockHeartbeatRepositoryPort.
+type MockHeartbeatRepositoryPortMockRecorder struct {
+	mock *MockHeartbeatRepositoryPort
+}
+
+// NewMockHeartbeatRepositoryPort creates a new mock instance.
+func NewMockHeartbeatRepositoryPort(ctrl *gomock.Controller) *MockHeartbeatRepositoryPort {
+	mock := &MockHeartbeatRepositoryPort{ctrl: ctrl}
+	mock.recorder = &MockHeartbeatRepositoryPortMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHeartbeatRepositoryPort) EXPECT() *MockHeartbeatRepositoryPortMockRecorder {
+	return m.recorder
+}
+
+// DeleteHeartbeatToken mocks base method.
+func (m *MockHeartbeatRepositoryPort) DeleteHeartbeatToken(ctx context.Context, terminalID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteHeartbeatToken", ctx, terminalID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteHeartbeatToken indicates an expected call of DeleteHeartbeatToken.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) DeleteHeartbeatToken(ctx, terminalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHeartbeatToken", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).DeleteHeartbeatToken), ctx, terminalID)
+}
+
+// GetHeartbeats mocks base method.
+func (m *MockHeartbeatRepositoryPort) GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeartbeats", ctx)
+	ret0, _ := ret[0].([]domain.TerminalHeartbeat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeartbeats indicates an expected call of GetHeartbeats.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) GetHeartbeats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeartbeats", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).GetHeartbeats), ctx)
+}
+
+// MarkTerminalOnline mocks base method.
+func (m *MockHeartbeatRepositoryPort) MarkTerminalOnline(ctx context.Context, terminalID int, reason string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkTerminalOnline", ctx, terminalID, reason)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkTerminalOnline indicates an expected call of MarkTerminalOnline.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) MarkTerminalOnline(ctx, terminalID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTerminalOnline", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).MarkTerminalOnline), ctx, terminalID, reason)
+}
+
+// MarkTerminalsOffline mocks base method.
+func (m *MockHeartbeatRepositoryPort) MarkTerminalsOffline(ctx context.Context, before time.Time, reason string) ([]domain.Terminal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkTerminalsOffline", ctx, before, reason)
+	ret0, _ := ret[0].([]domain.Terminal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkTerminalsOffline indicates an expected call of MarkTerminalsOffline.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) MarkTerminalsOffline(ctx, before, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkTerminalsOffline", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).MarkTerminalsOffline), ctx, before, reason)
+}
+
+// RecordHeartbeat mocks base method.
+func (m *MockHeartbeatRepositoryPort) RecordHeartbeat(ctx context.Context, tokenHash string, heartbeat domain.Heartbeat, at time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordHeartbeat", ctx, tokenHash, heartbeat, at)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordHeartbeat indicates an expected call of RecordHeartbeat.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) RecordHeartbeat(ctx, tokenHash, heartbeat, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordHeartbeat", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).RecordHeartbeat), ctx, tokenHash, heartbeat, at)
+}
+
+// SetHeartbeatToken mocks base method.
+func (m *MockHeartbeatRepositoryPort) SetHeartbeatToken(ctx context.Context, terminalID int, tokenHash string, issuedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHeartbeatToken", ctx, terminalID, tokenHash, issuedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHeartbeatToken indicates an expected call of SetHeartbeatToken.
+func (mr *MockHeartbeatRepositoryPortMockRecorder) SetHeartbeatToken(ctx, terminalID, tokenHash, issuedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeartbeatToken", reflect.TypeOf((*MockHeartbeatRepositoryPort)(nil).SetHeartbeatToken), ctx, terminalID, tokenHash, issuedAt)
+}
+
+// MockUnitOfWork is a mock of UnitOfWork interface.
+type MockUnitOfWork struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnitOfWorkMockRecorder
+}
+
+// MockUnitOfWorkMockRecorder is the mock recorder for MockUnitOfWork.
+type MockUnitOfWorkMockRecorder struct {
+	mock *MockUnitOfWork
+}
+
+// NewMockUnitOfWork creates a new mock instance.
+func NewMockUnitOfWork(ctrl *gomock.Controller) *MockUnitOfWork {
+	mock := &MockUnitOfWork{ctrl: ctrl}
+	mock.recorder = &MockUnitOfWorkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnitOfWork) EXPECT() *MockUnitOfWorkMockRecorder {
+	return m.recorder
+}
+
+// WithTx mocks base method.
+func (m *MockUnitOfWork) WithTx(ctx context.Context, opts repositories.TxOptions, fn func(*repositories.RepositoryPort) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, opts, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockUnitOfWorkMockRecorder) WithTx(ctx, opts, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFromFavoriteTerminal", reflect.TypeOf((*MockTerminalRepositoryPort)(nil).RemoveFromFavoriteTerminal), terminalID, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockUnitOfWork)(nil).WithTx), ctx, opts, fn)
 }
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"strings"
+	"time"
+)
+
+type AuditRepository struct {
+	db Querier
+}
+
+func NewAuditRepository(db Querier) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (ar *AuditRepository) InsertAuditEntry(ctx context.Context, entry domain.AuditEntry) error {
+	command := `INSERT INTO audit_log
+		(action, outcome, actor_id, actor_name, target_type, target_id, ip, request_id, before, after)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9::jsonb, $10::jsonb)`
+	_, err := ar.db.Exec(ctx, command, entry.Action, entry.Outcome, entry.ActorID, entry.ActorName,
+		entry.TargetType, entry.TargetID, entry.IP, entry.RequestID, nullJSON(entry.Before), nullJSON(entry.After))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %v", err)
+	}
+	return nil
+}
+
+func (ar *AuditRepository) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = $%d", filter.Outcome)
+	}
+	if filter.ActorID != 0 {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = $%d", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		where("request_id = $%d", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	query := `SELECT id, created_at, action, outcome, COALESCE(actor_id, 0), actor_name, target_type, target_id,
+		ip, request_id, COALESCE(before::text, ''), COALESCE(after::text, '')
+		FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := ar.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var before, after string
+		err = rows.Scan(&entry.ID, &entry.CreatedAt, &entry.Action, &entry.Outcome, &entry.ActorID, &entry.ActorName,
+			&entry.TargetType, &entry.TargetID, &entry.IP, &entry.RequestID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = rawJSON(before)
+		entry.After = rawJSON(after)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (ar *AuditRepository) DeleteAuditEntriesBefore(ctx context.Context, before time.Time) (int, error) {
+	tag, err := ar.db.Exec(ctx, `DELETE FROM audit_log WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// nullJSON stores an empty snapshot as NULL rather than invalid JSON.
+func nullJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
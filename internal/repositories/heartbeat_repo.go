@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type HeartbeatRepository struct {
+	db Querier
+}
+
+func NewHeartbeatRepository(db Querier) *HeartbeatRepository {
+	return &HeartbeatRepository{
+		db: db,
+	}
+}
+
+func (hr *HeartbeatRepository) SetHeartbeatToken(ctx context.Context, terminalID int, tokenHash string, issuedAt time.Time) error {
+	command := `INSERT INTO terminal_heartbeats (terminal_id, token_hash, issued_at)
+		SELECT id, $2, $3 FROM terminals WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (terminal_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, issued_at = EXCLUDED.issued_at`
+	tag, err := hr.db.Exec(ctx, command, terminalID, tokenHash, issuedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("terminal with ID %d: %w", terminalID, ErrNotFound)
+	}
+	return nil
+}
+
+func (hr *HeartbeatRepository) DeleteHeartbeatToken(ctx context.Context, terminalID int) error {
+	tag, err := hr.db.Exec(ctx, `DELETE FROM terminal_heartbeats WHERE terminal_id = $1`, terminalID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("heartbeat token of terminal %d: %w", terminalID, ErrNotFound)
+	}
+	return nil
+}
+
+func (hr *HeartbeatRepository) RecordHeartbeat(ctx context.Context, tokenHash string, heartbeat domain.Heartbeat, at time.Time) (int, error) {
+	command := `UPDATE terminal_heartbeats SET last_seen_at = $2,
+		firmware = COALESCE(NULLIF($3, ''), firmware), ip = COALESCE(NULLIF($4, ''), ip)
+		WHERE token_hash = $1 AND terminal_id IN (SELECT id FROM terminals WHERE deleted_at IS NULL)
+		RETURNING terminal_id`
+	var terminalID int
+	err := hr.db.QueryRow(ctx, command, tokenHash, at, heartbeat.Firmware, heartbeat.IP).Scan(&terminalID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("heartbeat token: %w", ErrNotFound)
+	}
+	return terminalID, err
+}
+
+func (hr *HeartbeatRepository) GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error) {
+	query := `SELECT t.id, t.name, t.status, h.last_seen_at, h.firmware, h.ip
+		FROM terminal_heartbeats h
+		JOIN terminals t ON t.id = h.terminal_id
+		WHERE t.deleted_at IS NULL
+		ORDER BY t.id`
+	rows, err := hr.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heartbeats := make([]domain.TerminalHeartbeat, 0)
+	for rows.Next() {
+		var heartbeat domain.TerminalHeartbeat
+		err = rows.Scan(&heartbeat.TerminalID, &heartbeat.TerminalName, &heartbeat.Status, &heartbeat.LastSeenAt,
+			&heartbeat.Firmware, &heartbeat.IP)
+		if err != nil {
+			return nil, err
+		}
+		heartbeats = append(heartbeats, heartbeat)
+	}
+	return heartbeats, rows.Err()
+}
+
+func (hr *HeartbeatRepository) MarkTerminalOnline(ctx context.Context, terminalID int, reason string) (bool, error) {
+	var online bool
+	err := WithTx(ctx, hr.db, TxOptions{}, func(tx pgx.Tx) error {
+		command := `UPDATE terminals SET status = $2 WHERE id = $1 AND status = $3 AND deleted_at IS NULL`
+		tag, err := tx.Exec(ctx, command, terminalID, domain.TerminalActive, domain.TerminalOffline)
+		if err != nil {
+			return err
+		}
+		online = tag.RowsAffected() > 0
+		if !online {
+			return nil
+		}
+		return describeStatusChanges(ctx, tx, []int{terminalID}, reason)
+	})
+	return online, err
+}
+
+func (hr *HeartbeatRepository) MarkTerminalsOffline(ctx context.Context, before time.Time, reason string) ([]domain.Terminal, error) {
+	var terminals []domain.Terminal
+	err := WithTx(ctx, hr.db, TxOptions{}, func(tx pgx.Tx) error {
+		command := `UPDATE terminals SET status = $1
+			WHERE status = $2 AND deleted_at IS NULL
+			AND id IN (SELECT terminal_id FROM terminal_heartbeats WHERE COALESCE(last_seen_at, issued_at) < $3)
+			RETURNING id, name, status`
+		rows, err := tx.Query(ctx, command, domain.TerminalOffline, domain.TerminalActive, before)
+		if err != nil {
+			return err
+		}
+		terminals = make([]domain.Terminal, 0)
+		ids := make([]int, 0)
+		for rows.Next() {
+			var terminal domain.Terminal
+			if err = rows.Scan(&terminal.ID, &terminal.Name, &terminal.Status); err != nil {
+				rows.Close()
+				return err
+			}
+			terminals = append(terminals, terminal)
+			ids = append(ids, terminal.ID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return describeStatusChanges(ctx, tx, ids, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return terminals, nil
+}
+
+// describeStatusChanges sets the source and reason of the status changes
+// the trigger just recorded for the terminals. The terminals' rows are
+// locked by the update in tx, so their latest changes are the ones it made.
+func describeStatusChanges(ctx context.Context, tx pgx.Tx, terminalIDs []int, reason string) error {
+	if len(terminalIDs) == 0 {
+		return nil
+	}
+	command := `UPDATE terminal_status_changes SET source = $1, reason = $2
+		WHERE id IN (SELECT MAX(id) FROM terminal_status_changes WHERE terminal_id = ANY($3) GROUP BY terminal_id)`
+	_, err := tx.Exec(ctx, command, domain.StatusSourceHeartbeat, reason, terminalIDs)
+	return err
+}
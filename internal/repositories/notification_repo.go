@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+const selectNotificationChannels = `SELECT id, COALESCE(user_id, 0), name, kind, target, template, members, created_at
+	FROM notification_channels`
+
+type NotificationRepository struct {
+	db Querier
+}
+
+func NewNotificationRepository(db Querier) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (nr *NotificationRepository) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	members, err := marshalMembers(channel.Members)
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	command := `INSERT INTO notification_channels (user_id, name, kind, target, template, members)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err = nr.db.QueryRow(ctx, command, channel.UserID, channel.Name, channel.Kind, channel.Target, channel.Template, members).
+		Scan(&channel.ID, &channel.CreatedAt)
+	return channel, err
+}
+
+func (nr *NotificationRepository) GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error) {
+	rows, err := nr.db.Query(ctx, selectNotificationChannels+` WHERE COALESCE(user_id, 0) = $1 ORDER BY id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make([]domain.NotificationChannel, 0)
+	for rows.Next() {
+		channel, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (nr *NotificationRepository) GetNotificationChannel(ctx context.Context, userId int, id int) (domain.NotificationChannel, error) {
+	row := nr.db.QueryRow(ctx, selectNotificationChannels+` WHERE id = $1 AND COALESCE(user_id, 0) = $2`, id, userId)
+	channel, err := scanNotificationChannel(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.NotificationChannel{}, fmt.Errorf("notification channel %d: %w", id, ErrNotFound)
+	}
+	return channel, err
+}
+
+func (nr *NotificationRepository) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) error {
+	members, err := marshalMembers(channel.Members)
+	if err != nil {
+		return err
+	}
+	command := `UPDATE notification_channels SET name = $3, kind = $4, target = $5, template = $6, members = $7
+		WHERE id = $1 AND COALESCE(user_id, 0) = $2`
+	tag, err := nr.db.Exec(ctx, command, channel.ID, channel.UserID, channel.Name, channel.Kind, channel.Target, channel.Template, members)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("notification channel %d: %w", channel.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (nr *NotificationRepository) DeleteNotificationChannel(ctx context.Context, userId int, id int) error {
+	tag, err := nr.db.Exec(ctx, `DELETE FROM notification_channels WHERE id = $1 AND COALESCE(user_id, 0) = $2`, id, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("notification channel %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func scanNotificationChannel(row pgx.Row) (domain.NotificationChannel, error) {
+	var channel domain.NotificationChannel
+	var members string
+	err := row.Scan(&channel.ID, &channel.UserID, &channel.Name, &channel.Kind, &channel.Target, &channel.Template, &members, &channel.CreatedAt)
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	channel.Members, err = unmarshalMembers(channel.ID, members)
+	return channel, err
+}
+
+// marshalMembers encodes the members column.
+func marshalMembers(members []int) (string, error) {
+	if members == nil {
+		members = []int{}
+	}
+	data, err := json.Marshal(members)
+	return string(data), err
+}
+
+func unmarshalMembers(id int, data string) ([]int, error) {
+	var members []int
+	if err := json.Unmarshal([]byte(data), &members); err != nil {
+		return nil, fmt.Errorf("notification channel %d members: %w", id, err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return members, nil
+}
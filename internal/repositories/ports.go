@@ -1,30 +1,269 @@
 package repositories
 
 import (
+	"context"
+	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
+// ErrNotFound is wrapped by repositories when the addressed record does not
+// exist or is not in the state the operation needs.
+var ErrNotFound = errors.New("not found")
+
+// Soft-deleted users and terminals are invisible to every method except the
+// Restore, Purge and GetDeleted ones. Favorites keep the IDs of soft-deleted
+// terminals, so a restored terminal reappears at its old position.
 type UserRepositoryPort interface {
-	CreateUser(user domain.User) error
-	GetUser(username string) (domain.User, error)
+	CreateUser(ctx context.Context, user domain.User) (int, error)
+	GetUser(ctx context.Context, username string) (domain.User, error)
+	GetUserByID(ctx context.Context, id int) (domain.User, error)
+	SoftDeleteUser(ctx context.Context, id int) error
+	RestoreUser(ctx context.Context, id int) error
+	GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error)
+	PurgeUser(ctx context.Context, id int) error
+	GetUsersDeletedBefore(ctx context.Context, before time.Time) ([]int, error)
+	CountActiveUsers(ctx context.Context) (int, error)
 }
 
 type TerminalRepositoryPort interface {
-	AddToFavorites(terminalId int, userId int) error
-	GetFavoriteTerminalIds(userId int) ([]int, error)
-	GetDefaultTerminalsList() ([]domain.Terminal, error)
-	RemoveFromFavoriteTerminal(terminalID int, userId int) error
+	AddToFavorites(ctx context.Context, terminalId int, userId int) error
+	CreateFavorites(ctx context.Context, userId int) error
+	GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error)
+	GetFavoritesVersion(ctx context.Context, userId int) (int64, error)
+	// GetDefaultTerminalsList returns the terminals that are not
+	// soft-deleted, ordered by ID, with their tags.
+	GetDefaultTerminalsList(ctx context.Context) ([]domain.Terminal, error)
+	RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error
+	SoftDeleteTerminal(ctx context.Context, id int) error
+	RestoreTerminal(ctx context.Context, id int) error
+	GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error)
+	PurgeTerminal(ctx context.Context, id int) error
+	GetTerminalsDeletedBefore(ctx context.Context, before time.Time) ([]int, error)
+	CountFavorites(ctx context.Context) (int, error)
+	// AttachTag tags a terminal that is not soft-deleted; attaching a tag
+	// it already has is a no-op. DetachTag fails with ErrNotFound if the
+	// terminal does not have the tag.
+	AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error
+	DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error
+	// SetTerminalLocation replaces the location and address of a terminal
+	// that is not soft-deleted.
+	SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error
+	// GetSites lists the whole site hierarchy, ordered by ID. CreateSite
+	// and UpdateSite fail with ErrNotFound for an unknown parent, and
+	// UpdateSite with domain.ErrInvalidSite for a move below the site
+	// itself. DeleteSite fails with domain.ErrSiteNotEmpty while the site
+	// has sub-sites or terminals that are not soft-deleted.
+	// SetTerminalSite assigns a terminal that is not soft-deleted to a
+	// site, or to none for siteID 0.
+	CreateSite(ctx context.Context, site domain.Site) (domain.Site, error)
+	GetSites(ctx context.Context) ([]domain.Site, error)
+	UpdateSite(ctx context.Context, site domain.Site) error
+	DeleteSite(ctx context.Context, id int) error
+	SetTerminalSite(ctx context.Context, terminalID int, siteID int) error
+	// SetFavoriteNote replaces the note on one of the user's favorites and
+	// fails with ErrNotFound if the terminal is not among them. A zero note
+	// deletes it. GetFavoriteNotes returns the notes on current favorites by
+	// terminal ID.
+	SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error
+	GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error)
+	// RemoveFromFavoriteTerminal keeps the note of the removed favorite, and
+	// of that one only, until RestoreRemovedFavoriteNote brings it back or
+	// AddToFavorites adds the terminal afresh. RestoreRemovedFavoriteNote
+	// returns the ID of the terminal whose note it restored, or ErrNotFound;
+	// re-adding the favorite itself is up to the caller.
+	RestoreRemovedFavoriteNote(ctx context.Context, userId int) (int, error)
+	// SaveView creates or replaces the user's view with the same name and
+	// returns it as stored; a new default replaces the previous one.
+	// GetViews lists the user's own views, then those other active users
+	// share. GetView looks a view up by its owner's name, empty for the
+	// user's own, and finds other users' views only if they are shared.
+	// GetView, GetDefaultView and DeleteView fail with ErrNotFound.
+	SaveView(ctx context.Context, view domain.View) (domain.View, error)
+	GetViews(ctx context.Context, userId int) ([]domain.View, error)
+	GetView(ctx context.Context, userId int, owner string, name string) (domain.View, error)
+	GetDefaultView(ctx context.Context, userId int) (domain.View, error)
+	DeleteView(ctx context.Context, userId int, name string) error
+}
+
+// AuditRepositoryPort stores the append-only audit log. Entries cannot be
+// changed, only removed once they are older than the retention period.
+type AuditRepositoryPort interface {
+	InsertAuditEntry(ctx context.Context, entry domain.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	DeleteAuditEntriesBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// AlertRepositoryPort stores alert rules, the alerts they raise and the
+// history of terminal status changes. Methods that take a userId only reach
+// that user's rules and alerts and fail with ErrNotFound for anyone else's.
+type AlertRepositoryPort interface {
+	CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error)
+	GetAlertRules(ctx context.Context, userId int) ([]domain.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, userId int, id int) error
+	// MuteAlertRule mutes the rule until the given time; nil unmutes it.
+	MuteAlertRule(ctx context.Context, userId int, id int, until *time.Time) error
+	// GetAllAlertRules returns the rules of every user that is not
+	// soft-deleted, for the evaluator.
+	GetAllAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	// GetStatusChanges returns the times each terminal's status changed
+	// after since, by terminal ID.
+	GetStatusChanges(ctx context.Context, since time.Time) (map[int][]time.Time, error)
+	// ClaimStatusChanges marks up to limit status changes that have not been
+	// evaluated against the alert rules yet and returns them, oldest first.
+	// Call it in the transaction that fires and resolves the alerts they
+	// cause, so a rollback leaves them to be evaluated again.
+	ClaimStatusChanges(ctx context.Context, limit int) ([]domain.StatusChange, error)
+	// GetAlertFavorites returns the favorite terminals of every user with an
+	// alert rule, by user ID, leaving out soft-deleted terminals.
+	GetAlertFavorites(ctx context.Context) (map[int][]int, error)
+	// GetFiringAlerts returns the unresolved alerts of all users. FireAlert
+	// raises an alert unless one is already firing for the rule and
+	// terminal, and ResolveAlert resolves a firing one.
+	GetFiringAlerts(ctx context.Context) ([]domain.Alert, error)
+	FireAlert(ctx context.Context, ruleId int, terminalID int) error
+	ResolveAlert(ctx context.Context, id int) error
+	// GetAlerts lists the user's alerts, firing ones first, then newest
+	// first. A non-empty state keeps only alerts in that state.
+	GetAlerts(ctx context.Context, userId int, state string) ([]domain.Alert, error)
+	GetAlert(ctx context.Context, userId int, id int) (domain.Alert, error)
+	// AckAlert acknowledges the alert; acknowledging it again is a no-op.
+	AckAlert(ctx context.Context, userId int, id int) error
+}
+
+// WebhookRepositoryPort stores webhooks, their deliveries and the events
+// waiting to be turned into deliveries. Methods that take an ID fail with
+// ErrNotFound for an unknown one.
+type WebhookRepositoryPort interface {
+	CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	// ClaimWebhookEvents deletes up to limit events and returns them, oldest
+	// first. Status changes and favorite changes add their events in the
+	// transaction that makes them. Call it in the transaction that creates
+	// the deliveries, so a rollback keeps the events.
+	ClaimWebhookEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error)
+	// CreateWebhookDelivery stores a pending delivery due at its
+	// NextAttemptAt.
+	CreateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	// ClaimDueWebhookDeliveries returns up to limit pending deliveries due
+	// by now, oldest first, and moves their next attempt lease into the
+	// future, so a concurrent dispatcher skips them. UpdateWebhookDelivery
+	// then records the outcome; a delivery whose dispatcher stops before
+	// that is due again once the lease has passed.
+	ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+	// UpdateWebhookDelivery stores the outcome of an attempt: the state,
+	// attempts, next attempt, response status, error and delivery time.
+	UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	// GetWebhookDeliveries lists the webhook's deliveries, newest first. A
+	// non-empty state keeps only deliveries in that state.
+	GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error)
+	// RedeliverWebhookDelivery makes a delivery of the webhook pending again
+	// with no attempts, due at once, whatever its state.
+	RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64) error
+}
+
+// NotificationRepositoryPort stores notification channels. userId 0
+// addresses team channels, which GetNotificationChannels then lists; other
+// methods only see the channels of the given owner and fail with
+// ErrNotFound for any other.
+type NotificationRepositoryPort interface {
+	CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error)
+	GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error)
+	GetNotificationChannel(ctx context.Context, userId int, id int) (domain.NotificationChannel, error)
+	// UpdateNotificationChannel replaces the name, kind, target, template
+	// and members of the channel with channel.ID owned by channel.UserID.
+	UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) error
+	DeleteNotificationChannel(ctx context.Context, userId int, id int) error
+}
+
+// HeartbeatRepositoryPort stores the heartbeat tokens of terminals and
+// their last heartbeats, and derives terminal statuses from them. Status
+// changes are recorded in terminal_status_changes like any other, with
+// source domain.StatusSourceHeartbeat and the given reason.
+type HeartbeatRepositoryPort interface {
+	// SetHeartbeatToken sets, or replaces, the token hash of a terminal
+	// that is not soft-deleted, keeping its last heartbeat.
+	SetHeartbeatToken(ctx context.Context, terminalID int, tokenHash string, issuedAt time.Time) error
+	DeleteHeartbeatToken(ctx context.Context, terminalID int) error
+	// RecordHeartbeat stores a heartbeat at the given time for the terminal
+	// with the token hash and returns its ID. Empty fields keep their last
+	// value. It fails with ErrNotFound for an unknown token or a
+	// soft-deleted terminal.
+	RecordHeartbeat(ctx context.Context, tokenHash string, heartbeat domain.Heartbeat, at time.Time) (int, error)
+	// GetHeartbeats lists the terminals that have a token, by ID.
+	GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error)
+	// MarkTerminalOnline sets an offline terminal active and reports
+	// whether it was offline.
+	MarkTerminalOnline(ctx context.Context, terminalID int, reason string) (bool, error)
+	// MarkTerminalsOffline sets the active terminals whose last heartbeat
+	// is before the given time offline and returns them. A terminal that
+	// has not reported yet counts from when its token was issued.
+	MarkTerminalsOffline(ctx context.Context, before time.Time, reason string) ([]domain.Terminal, error)
+}
+
+// UnitOfWork runs fn inside a single transaction. The RepositoryPort handed
+// to fn is bound to that transaction, so calls made through it commit or
+// roll back together. Calling WithTx on a bound port joins the outer
+// transaction instead of opening a new one.
+type UnitOfWork interface {
+	WithTx(ctx context.Context, opts TxOptions, fn func(tx *RepositoryPort) error) error
+}
+
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = "read committed"
+	RepeatableRead IsolationLevel = "repeatable read"
+	Serializable   IsolationLevel = "serializable"
+)
+
+// TxOptions configures a transaction. An empty IsoLevel uses the server
+// default. MaxRetries is how many times a transaction that failed with a
+// serialization failure or deadlock is re-run from scratch.
+type TxOptions struct {
+	IsoLevel   IsolationLevel
+	ReadOnly   bool
+	MaxRetries int
 }
 
 type RepositoryPort struct {
 	UserRepositoryPort
 	TerminalRepositoryPort
+	AuditRepositoryPort
+	AlertRepositoryPort
+	WebhookRepositoryPort
+	NotificationRepositoryPort
+	HeartbeatRepositoryPort
+	UnitOfWork
+}
+
+// NewRepositoryPort builds the repositories on the primary pool. replica may
+// be nil; otherwise GetDefaultTerminalsList, GetFavoriteTerminalIds,
+// GetFavoritesVersion and GetFavoriteNotes read from it, falling back to the
+// primary while it is unreachable. Requests pin their reads with PinReads, so
+// those behind one response all come from the replica or all from the
+// primary.
+func NewRepositoryPort(pgx *pgxpool.Pool, replica *pgxpool.Pool) *RepositoryPort {
+	port := newRepositoryPort(pgx)
+	if replica != nil {
+		terminalRepository := NewTerminalRepository(pgx)
+		terminalRepository.read = newReplicaRouter(replica, pgx)
+		port.TerminalRepositoryPort = terminalRepository
+	}
+	return port
 }
 
-func NewRepositoryPort(pgx *pgxpool.Pool) *RepositoryPort {
+func newRepositoryPort(db Querier) *RepositoryPort {
 	return &RepositoryPort{
-		UserRepositoryPort:     NewUserRepository(pgx),
-		TerminalRepositoryPort: NewTerminalRepository(pgx),
+		UserRepositoryPort:         NewUserRepository(db),
+		TerminalRepositoryPort:     NewTerminalRepository(db),
+		AuditRepositoryPort:        NewAuditRepository(db),
+		AlertRepositoryPort:        NewAlertRepository(db),
+		WebhookRepositoryPort:      NewWebhookRepository(db),
+		NotificationRepositoryPort: NewNotificationRepository(db),
+		HeartbeatRepositoryPort:    NewHeartbeatRepository(db),
+		UnitOfWork:                 &unitOfWork{db: db},
 	}
 }
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaRetryAfter is how long an unreachable replica is skipped before the
+// next attempt, so requests don't each wait out a connect timeout.
+const replicaRetryAfter = 30 * time.Second
+
+type primaryKey struct{}
+
+// ReadFromPrimary makes reads in ctx skip the replica. Use it to read data the
+// same request has just written, which the replica may not have yet.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func readFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type pinKey struct{}
+
+// readPin holds where the reads of one response go once the first of them
+// has chosen.
+type readPin struct {
+	mu      sync.Mutex
+	chosen  bool
+	primary bool
+}
+
+// PinReads makes all reads in ctx go where the first one went, so the
+// reads behind one response see the same database: a replica that lags, or
+// is marked down halfway through, would otherwise pair data from before a
+// change with data from after it. Only a read the replica fails to serve
+// moves the rest to the primary.
+func PinReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinKey{}, &readPin{})
+}
+
+// replicaRouter is the Querier behind read-only repository calls when a read
+// replica is configured. Queries go to the replica and fall back to the
+// primary when it cannot be reached; Exec always goes to the primary.
+type replicaRouter struct {
+	replica Querier
+	primary Querier
+	// downUntil is the unix time in nanoseconds until which the replica is
+	// skipped.
+	downUntil atomic.Int64
+	now       func() time.Time
+}
+
+func newReplicaRouter(replica Querier, primary Querier) *replicaRouter {
+	return &replicaRouter{
+		replica: replica,
+		primary: primary,
+		now:     time.Now,
+	}
+}
+
+func (r *replicaRouter) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, arguments...)
+}
+
+func (r *replicaRouter) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if r.useReplica(ctx) {
+		rows, err := r.replica.Query(ctx, sql, args...)
+		if !r.replicaFailed(ctx, err) {
+			return rows, err
+		}
+	}
+	return r.primary.Query(ctx, sql, args...)
+}
+
+func (r *replicaRouter) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &fallbackRow{ctx: ctx, router: r, sql: sql, args: args}
+}
+
+func (r *replicaRouter) useReplica(ctx context.Context) bool {
+	if readFromPrimary(ctx) {
+		return false
+	}
+	pin, _ := ctx.Value(pinKey{}).(*readPin)
+	if pin == nil {
+		return r.replicaUp()
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	if !pin.chosen {
+		pin.chosen = true
+		pin.primary = !r.replicaUp()
+	}
+	return !pin.primary
+}
+
+func (r *replicaRouter) replicaUp() bool {
+	return r.now().UnixNano() >= r.downUntil.Load()
+}
+
+// replicaFailed reports whether err means the replica could not serve the
+// query, as opposed to the query itself failing, and if so marks it down.
+func (r *replicaRouter) replicaFailed(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions, 57 is shutdown and
+		// "cannot connect now"; anything else is the query's own fault.
+		if !strings.HasPrefix(pgErr.Code, "08") && !strings.HasPrefix(pgErr.Code, "57") {
+			return false
+		}
+	}
+	r.downUntil.Store(r.now().Add(replicaRetryAfter).UnixNano())
+	if pin, ok := ctx.Value(pinKey{}).(*readPin); ok {
+		pin.mu.Lock()
+		pin.primary = true
+		pin.mu.Unlock()
+	}
+	logger.FromContext(ctx).Warnf("read replica unavailable, using primary for %s: %v", replicaRetryAfter, err)
+	return true
+}
+
+// fallbackRow defers the replica/primary choice to Scan, where pgx reports
+// QueryRow errors.
+type fallbackRow struct {
+	ctx    context.Context
+	router *replicaRouter
+	sql    string
+	args   []any
+}
+
+func (row *fallbackRow) Scan(dest ...any) error {
+	r := row.router
+	if r.useReplica(row.ctx) {
+		err := r.replica.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+		if !r.replicaFailed(row.ctx, err) {
+			return err
+		}
+	}
+	return r.primary.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeQuerier struct {
+	Querier
+	err     error
+	queries int
+	// rows is what every Query returns.
+	rows [][]any
+}
+
+func (q *fakeQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.queries++
+	if q.err != nil {
+		return nil, q.err
+	}
+	return &fakeRows{rows: q.rows}, nil
+}
+
+func (q *fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.queries++
+	return fakeRow{err: q.err}
+}
+
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	return r.err
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	row  []any
+}
+
+func (r *fakeRows) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	r.row, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.row[i]))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+func (r *fakeRows) Close() {}
+
+func TestReplicaRouterUsesReplica(t *testing.T) {
+	replica, primary := &fakeQuerier{}, &fakeQuerier{}
+	router := newReplicaRouter(replica, primary)
+
+	_, err := router.Query(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, router.QueryRow(context.Background(), "SELECT 1").Scan())
+	require.Equal(t, 2, replica.queries)
+	require.Equal(t, 0, primary.queries)
+}
+
+func TestReplicaRouterFallsBackWhenDown(t *testing.T) {
+	replica := &fakeQuerier{err: errors.New("failed to connect to `host=replica`: dial error")}
+	primary := &fakeQuerier{}
+	router := newReplicaRouter(replica, primary)
+	now := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+
+	_, err := router.Query(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, 1, replica.queries)
+	require.Equal(t, 1, primary.queries)
+
+	// the replica is skipped until replicaRetryAfter has passed
+	require.NoError(t, router.QueryRow(context.Background(), "SELECT 1").Scan())
+	require.Equal(t, 1, replica.queries)
+	require.Equal(t, 2, primary.queries)
+
+	replica.err = nil
+	now = now.Add(replicaRetryAfter)
+	_, err = router.Query(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, 2, replica.queries)
+	require.Equal(t, 2, primary.queries)
+}
+
+func TestReplicaRouterKeepsQueryErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{name: "no_rows", err: pgx.ErrNoRows},
+		{name: "syntax_error", err: &pgconn.PgError{Code: "42601"}},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			replica, primary := &fakeQuerier{err: tCase.err}, &fakeQuerier{}
+			router := newReplicaRouter(replica, primary)
+
+			err := router.QueryRow(context.Background(), "SELECT 1").Scan()
+			require.ErrorIs(t, err, tCase.err)
+			require.Equal(t, 0, primary.queries)
+		})
+	}
+}
+
+func TestReplicaRouterReadFromPrimary(t *testing.T) {
+	replica, primary := &fakeQuerier{}, &fakeQuerier{}
+	router := newReplicaRouter(replica, primary)
+
+	_, err := router.Query(ReadFromPrimary(context.Background()), "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, 0, replica.queries)
+	require.Equal(t, 1, primary.queries)
+}
+
+func TestReplicaRouterCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	replica, primary := &fakeQuerier{err: context.Canceled}, &fakeQuerier{}
+	router := newReplicaRouter(replica, primary)
+
+	_, err := router.Query(ctx, "SELECT 1")
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Equal(t, 0, primary.queries)
+}
+
+func TestReplicaRouterPinsReads(t *testing.T) {
+	replica, primary := &fakeQuerier{}, &fakeQuerier{}
+	router := newReplicaRouter(replica, primary)
+	now := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+
+	// A response that started on the replica stays there when another
+	// request marks it down, as long as it still answers.
+	pinned := PinReads(context.Background())
+	_, err := router.Query(pinned, "SELECT 1")
+	require.NoError(t, err)
+	router.downUntil.Store(now.Add(replicaRetryAfter).UnixNano())
+	require.NoError(t, router.QueryRow(pinned, "SELECT 1").Scan())
+	require.Equal(t, 2, replica.queries)
+	require.Equal(t, 0, primary.queries)
+
+	// One that started on the primary stays there when the replica is back.
+	pinned = PinReads(context.Background())
+	_, err = router.Query(pinned, "SELECT 1")
+	require.NoError(t, err)
+	now = now.Add(replicaRetryAfter)
+	_, err = router.Query(pinned, "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, 2, replica.queries)
+	require.Equal(t, 2, primary.queries)
+
+	// Once the replica fails to answer, the rest goes to the primary.
+	pinned = PinReads(context.Background())
+	_, err = router.Query(pinned, "SELECT 1")
+	require.NoError(t, err)
+	replica.err = errors.New("failed to connect to `host=replica`: dial error")
+	require.NoError(t, router.QueryRow(pinned, "SELECT 1").Scan())
+	replica.err = nil
+	now = now.Add(replicaRetryAfter)
+	_, err = router.Query(pinned, "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, 4, replica.queries)
+	require.Equal(t, 4, primary.queries)
+}
+
+func TestTerminalRepositoryReplicaLag(t *testing.T) {
+	// The replica has not caught up with the second favorite yet.
+	primary := &fakeQuerier{rows: [][]any{{1}, {2}}}
+	replica := &fakeQuerier{rows: [][]any{{1}}}
+	repo := NewTerminalRepository(primary)
+	repo.read = newReplicaRouter(replica, primary)
+
+	ids, err := repo.GetFavoriteTerminalIds(context.Background(), 7)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ids)
+
+	ids, err = repo.GetFavoriteTerminalIds(ReadFromPrimary(context.Background()), 7)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, ids)
+
+	// The reads of one response agree even if another request gives up on
+	// the replica halfway through.
+	ctx := PinReads(context.Background())
+	ids, err = repo.GetFavoriteTerminalIds(ctx, 7)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ids)
+	repo.read.(*replicaRouter).downUntil.Store(time.Now().Add(time.Hour).UnixNano())
+	ids, err = repo.GetFavoriteTerminalIds(ctx, 7)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ids)
+	ids, err = repo.GetFavoriteTerminalIds(context.Background(), 7)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, ids)
+}
@@ -0,0 +1,1389 @@
+// Package repotest holds the contract every storage backend must satisfy.
+// Backends run it from their own tests with a fresh, empty database.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Backend struct {
+	Repo *repositories.RepositoryPort
+	// SeedTerminals inserts terminals in order and returns their IDs. The
+	// ports have no way to create terminals, so each backend provides it.
+	SeedTerminals func(t *testing.T, terminals ...domain.Terminal) []int
+	// SetStatus changes a terminal's status, which the ports cannot do either.
+	SetStatus func(t *testing.T, terminalID int, status string)
+}
+
+// Run executes the whole contract. newBackend is called once per subtest and
+// must return a backend on an empty, migrated database.
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b Backend)
+	}{
+		{"CreateAndGetUser", testCreateAndGetUser},
+		{"CreateUserDuplicate", testCreateUserDuplicate},
+		{"GetUserNotFound", testGetUserNotFound},
+		{"DefaultTerminalsList", testDefaultTerminalsList},
+		{"AddToFavorites", testAddToFavorites},
+		{"AddToFavoritesWithoutFavoritesRow", testAddToFavoritesWithoutFavoritesRow},
+		{"AddToFavoritesUnknownTerminal", testAddToFavoritesUnknownTerminal},
+		{"AddToFavoritesDuplicate", testAddToFavoritesDuplicate},
+		{"RemoveFromFavoriteTerminal", testRemoveFromFavoriteTerminal},
+		{"RemoveFromFavoriteTerminalNotFavorited", testRemoveFromFavoriteTerminalNotFavorited},
+		{"GetFavoriteTerminalIdsUnknownUser", testGetFavoriteTerminalIdsUnknownUser},
+		{"FavoritesVersion", testFavoritesVersion},
+		{"FavoritesVersionPurge", testFavoritesVersionPurge},
+		{"WithTxCommit", testWithTxCommit},
+		{"WithTxRollback", testWithTxRollback},
+		{"WithTxNested", testWithTxNested},
+		{"SoftDeleteTerminal", testSoftDeleteTerminal},
+		{"PurgeTerminal", testPurgeTerminal},
+		{"SoftDeleteUser", testSoftDeleteUser},
+		{"PurgeUser", testPurgeUser},
+		{"DeletedBefore", testDeletedBefore},
+		{"Counts", testCounts},
+		{"TerminalTags", testTerminalTags},
+		{"TerminalLocations", testTerminalLocations},
+		{"Sites", testSites},
+		{"ConcurrentSiteMoves", testConcurrentSiteMoves},
+		{"FavoriteNotes", testFavoriteNotes},
+		{"FavoriteNotesUndo", testFavoriteNotesUndo},
+		{"Views", testViews},
+		{"ViewsSharing", testViewsSharing},
+		{"StatusChanges", testStatusChanges},
+		{"AlertRules", testAlertRules},
+		{"AlertFavorites", testAlertFavorites},
+		{"Alerts", testAlerts},
+		{"Webhooks", testWebhooks},
+		{"WebhookEvents", testWebhookEvents},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"NotificationChannels", testNotificationChannels},
+		{"Heartbeats", testHeartbeats},
+		{"AuditLog", testAuditLog},
+		{"AuditLogRetention", testAuditLogRetention},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newBackend(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, b Backend, name string) int {
+	ctx := context.Background()
+	id, err := b.Repo.CreateUser(ctx, domain.User{Name: name, Password: "hash"})
+	require.NoError(t, err)
+	require.NoError(t, b.Repo.CreateFavorites(ctx, id))
+	return id
+}
+
+func seedTerminals(t *testing.T, b Backend, n int) []int {
+	terminals := make([]domain.Terminal, n)
+	for i := range terminals {
+		terminals[i] = domain.Terminal{Name: "terminal" + string(rune('A'+i)), Status: "active"}
+	}
+	return b.SeedTerminals(t, terminals...)
+}
+
+func testCreateAndGetUser(t *testing.T, b Backend) {
+	ctx := context.Background()
+	id, err := b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	user, err := b.Repo.GetUser(ctx, "Khalid")
+	require.NoError(t, err)
+	require.Equal(t, domain.User{ID: id, Name: "Khalid", Password: "hash", Role: domain.RoleUser}, user)
+
+	user, err = b.Repo.GetUserByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "Khalid", user.Name)
+
+	_, err = b.Repo.GetUserByID(ctx, id+1)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testCreateUserDuplicate(t *testing.T, b Backend) {
+	ctx := context.Background()
+	_, err := b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+	require.NoError(t, err)
+	_, err = b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "other"})
+	require.EqualError(t, err, "username already exists")
+}
+
+func testGetUserNotFound(t *testing.T, b Backend) {
+	_, err := b.Repo.GetUser(context.Background(), "nobody")
+	require.EqualError(t, err, "no user found with given name")
+}
+
+func testDefaultTerminalsList(t *testing.T, b Backend) {
+	ids := b.SeedTerminals(t,
+		domain.Terminal{Name: "lobby", Status: "active"},
+		domain.Terminal{Name: "kiosk", Status: "offline"},
+	)
+	terminals, err := b.Repo.GetDefaultTerminalsList(context.Background())
+	require.NoError(t, err)
+	for i := range terminals {
+		require.NotNil(t, terminals[i].StatusChangedAt, "inserting sets the status change time")
+		require.WithinDuration(t, time.Now(), *terminals[i].StatusChangedAt, time.Minute)
+		terminals[i].StatusChangedAt = nil
+	}
+	require.Equal(t, []domain.Terminal{
+		{ID: ids[0], Name: "lobby", Status: "active"},
+		{ID: ids[1], Name: "kiosk", Status: "offline"},
+	}, terminals)
+}
+
+func testAddToFavorites(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userId := createUser(t, b, "Khalid")
+
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, favorites)
+
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[2], userId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+
+	favorites, err = b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[2], ids[0]}, favorites)
+}
+
+func testAddToFavoritesWithoutFavoritesRow(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+	userId, err := b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0]}, favorites)
+}
+
+func testAddToFavoritesUnknownTerminal(t *testing.T, b Backend) {
+	userId := createUser(t, b, "Khalid")
+	err := b.Repo.AddToFavorites(context.Background(), 4321, userId)
+	require.EqualError(t, err, "terminal with ID 4321 doesnt exist in table terminals")
+}
+
+func testAddToFavoritesDuplicate(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+	userId := createUser(t, b, "Khalid")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+
+	err := b.Repo.AddToFavorites(ctx, ids[0], userId)
+	require.Error(t, err)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0]}, favorites)
+}
+
+func testRemoveFromFavoriteTerminal(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userId := createUser(t, b, "Khalid")
+	for _, id := range ids {
+		require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	}
+
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[1], userId))
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0], ids[2]}, favorites)
+}
+
+func testRemoveFromFavoriteTerminalNotFavorited(t *testing.T, b Backend) {
+	ids := seedTerminals(t, b, 1)
+	userId := createUser(t, b, "Khalid")
+	err := b.Repo.RemoveFromFavoriteTerminal(context.Background(), ids[0], userId)
+	require.Error(t, err)
+}
+
+func testGetFavoriteTerminalIdsUnknownUser(t *testing.T, b Backend) {
+	favorites, err := b.Repo.GetFavoriteTerminalIds(context.Background(), 4321)
+	require.NoError(t, err)
+	require.Empty(t, favorites)
+}
+
+func testFavoritesVersion(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+
+	version, err := b.Repo.GetFavoritesVersion(ctx, 42)
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	userID := createUser(t, b, "Khalid")
+	version, err = b.Repo.GetFavoritesVersion(ctx, userID)
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userID))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], userID))
+	require.Error(t, b.Repo.AddToFavorites(ctx, ids[1], userID))
+	version, err = b.Repo.GetFavoritesVersion(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[0], userID))
+	require.Error(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[0], userID))
+	version, err = b.Repo.GetFavoritesVersion(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), version)
+}
+
+func testFavoritesVersionPurge(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	userID := createUser(t, b, "Khalid")
+	otherID := createUser(t, b, "Yusuf")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userID))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], otherID))
+
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	require.NoError(t, b.Repo.PurgeTerminal(ctx, ids[0]))
+
+	version, err := b.Repo.GetFavoritesVersion(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+	version, err = b.Repo.GetFavoritesVersion(ctx, otherID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+}
+
+func testWithTxCommit(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+	err := b.Repo.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		userId, err := tx.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+		if err != nil {
+			return err
+		}
+		err = tx.CreateFavorites(ctx, userId)
+		if err != nil {
+			return err
+		}
+		return tx.AddToFavorites(ctx, ids[0], userId)
+	})
+	require.NoError(t, err)
+
+	user, err := b.Repo.GetUser(ctx, "Khalid")
+	require.NoError(t, err)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0]}, favorites)
+}
+
+func testWithTxRollback(t *testing.T, b Backend) {
+	ctx := context.Background()
+	expErr := errors.New("abort")
+	err := b.Repo.WithTx(ctx, repositories.TxOptions{IsoLevel: repositories.Serializable}, func(tx *repositories.RepositoryPort) error {
+		userId, err := tx.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+		if err != nil {
+			return err
+		}
+		err = tx.CreateFavorites(ctx, userId)
+		if err != nil {
+			return err
+		}
+		return expErr
+	})
+	require.ErrorIs(t, err, expErr)
+
+	_, err = b.Repo.GetUser(ctx, "Khalid")
+	require.EqualError(t, err, "no user found with given name")
+}
+
+func testWithTxNested(t *testing.T, b Backend) {
+	ctx := context.Background()
+	expErr := errors.New("abort")
+	err := b.Repo.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		err := tx.WithTx(ctx, repositories.TxOptions{}, func(inner *repositories.RepositoryPort) error {
+			_, err := inner.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return expErr
+	})
+	require.ErrorIs(t, err, expErr)
+
+	_, err = b.Repo.GetUser(ctx, "Khalid")
+	require.EqualError(t, err, "no user found with given name")
+}
+
+func testSoftDeleteTerminal(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userId := createUser(t, b, "Khalid")
+	for _, id := range ids {
+		require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	}
+
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]), repositories.ErrNotFound)
+
+	terminals, err := b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Len(t, terminals, 2)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0], ids[2]}, favorites)
+	err = b.Repo.AddToFavorites(ctx, ids[1], userId)
+	require.Error(t, err)
+
+	deleted, err := b.Repo.GetDeletedTerminals(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	require.Equal(t, ids[1], deleted[0].ID)
+	require.Equal(t, "terminalB", deleted[0].Name)
+	require.False(t, deleted[0].DeletedAt.IsZero())
+
+	require.NoError(t, b.Repo.RestoreTerminal(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.RestoreTerminal(ctx, ids[1]), repositories.ErrNotFound)
+	favorites, err = b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, ids, favorites)
+	deleted, err = b.Repo.GetDeletedTerminals(ctx)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+}
+
+func testPurgeTerminal(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	userId := createUser(t, b, "Khalid")
+	for _, id := range ids {
+		require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	}
+
+	require.ErrorIs(t, b.Repo.PurgeTerminal(ctx, ids[0]), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	require.NoError(t, b.Repo.PurgeTerminal(ctx, ids[0]))
+
+	require.ErrorIs(t, b.Repo.RestoreTerminal(ctx, ids[0]), repositories.ErrNotFound)
+	deleted, err := b.Repo.GetDeletedTerminals(ctx)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[1]}, favorites)
+}
+
+func testTerminalTags(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	atm := domain.Tag{Key: "type", Value: "atm"}
+	north := domain.Tag{Key: "zone", Value: "north"}
+	south := domain.Tag{Key: "zone", Value: "south"}
+
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[0], north))
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[0], atm))
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[0], atm), "attaching twice is a no-op")
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[1], atm))
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[1], south))
+	require.ErrorIs(t, b.Repo.AttachTag(ctx, ids[2]+100, atm), repositories.ErrNotFound)
+
+	terminals, err := b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Len(t, terminals, 3)
+	require.Equal(t, []domain.Tag{atm, north}, terminals[0].Tags)
+	require.Equal(t, []domain.Tag{atm, south}, terminals[1].Tags)
+	require.Empty(t, terminals[2].Tags)
+
+	require.NoError(t, b.Repo.DetachTag(ctx, ids[0], atm))
+	require.ErrorIs(t, b.Repo.DetachTag(ctx, ids[0], atm), repositories.ErrNotFound)
+	require.ErrorIs(t, b.Repo.DetachTag(ctx, ids[0], domain.Tag{Key: "no", Value: "such"}), repositories.ErrNotFound)
+	terminals, err = b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Tag{north}, terminals[0].Tags)
+	require.Equal(t, []domain.Tag{atm, south}, terminals[1].Tags)
+
+	// Soft-deleted terminals cannot be tagged; purging drops their tags.
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.AttachTag(ctx, ids[1], north), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.PurgeTerminal(ctx, ids[1]))
+	require.NoError(t, b.Repo.AttachTag(ctx, ids[2], atm))
+	terminals, err = b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Len(t, terminals, 2)
+	require.Equal(t, []domain.Tag{atm}, terminals[1].Tags)
+}
+
+func testTerminalLocations(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	berlin := domain.TerminalLocation{Location: &domain.GeoPoint{Lat: 52.520008, Lon: 13.404954}, Address: "Alexanderplatz 1, Berlin"}
+	require.NoError(t, b.Repo.SetTerminalLocation(ctx, ids[0], berlin))
+	require.NoError(t, b.Repo.SetTerminalLocation(ctx, ids[1], domain.TerminalLocation{Address: "somewhere"}))
+	require.ErrorIs(t, b.Repo.SetTerminalLocation(ctx, ids[1]+100, berlin), repositories.ErrNotFound)
+
+	terminals, err := b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Equal(t, berlin.Location, terminals[0].Location)
+	require.Equal(t, berlin.Address, terminals[0].Address)
+	require.Nil(t, terminals[1].Location)
+	require.Equal(t, "somewhere", terminals[1].Address)
+
+	// A location without a point clears it, and setting it leaves the
+	// status history alone.
+	require.NoError(t, b.Repo.SetTerminalLocation(ctx, ids[0], domain.TerminalLocation{}))
+	terminals, err = b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Nil(t, terminals[0].Location)
+	require.Empty(t, terminals[0].Address)
+	changes, err := b.Repo.ClaimStatusChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.SetTerminalLocation(ctx, ids[1], berlin), repositories.ErrNotFound)
+}
+
+func testSites(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	north, err := b.Repo.CreateSite(ctx, domain.Site{Name: "North"})
+	require.NoError(t, err)
+	siteB, err := b.Repo.CreateSite(ctx, domain.Site{ParentID: north.ID, Name: "Site B"})
+	require.NoError(t, err)
+	floor, err := b.Repo.CreateSite(ctx, domain.Site{ParentID: siteB.ID, Name: "Floor 1"})
+	require.NoError(t, err)
+	_, err = b.Repo.CreateSite(ctx, domain.Site{ParentID: floor.ID + 100, Name: "orphan"})
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	sites, err := b.Repo.GetSites(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Site{north, siteB, floor}, sites)
+
+	// A site cannot move below itself, and a failed move changes nothing.
+	require.ErrorIs(t, b.Repo.UpdateSite(ctx, domain.Site{ID: north.ID, ParentID: floor.ID, Name: "North"}), domain.ErrInvalidSite)
+	require.ErrorIs(t, b.Repo.UpdateSite(ctx, domain.Site{ID: north.ID, ParentID: floor.ID + 100, Name: "North"}), repositories.ErrNotFound)
+	require.ErrorIs(t, b.Repo.UpdateSite(ctx, domain.Site{ID: floor.ID + 100, Name: "nowhere"}), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.UpdateSite(ctx, domain.Site{ID: floor.ID, ParentID: north.ID, Name: "Floor 2"}))
+	sites, err = b.Repo.GetSites(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []domain.Site{north, siteB, {ID: floor.ID, ParentID: north.ID, Name: "Floor 2"}}, sites)
+
+	require.NoError(t, b.Repo.SetTerminalSite(ctx, ids[0], siteB.ID))
+	require.ErrorIs(t, b.Repo.SetTerminalSite(ctx, ids[1], floor.ID+100), repositories.ErrNotFound)
+	require.ErrorIs(t, b.Repo.SetTerminalSite(ctx, ids[1]+100, siteB.ID), repositories.ErrNotFound)
+	terminals, err := b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Equal(t, siteB.ID, terminals[0].SiteID)
+	require.Zero(t, terminals[1].SiteID)
+
+	// Only empty sites can be deleted; a soft-deleted terminal does not
+	// count and loses its site.
+	require.ErrorIs(t, b.Repo.DeleteSite(ctx, north.ID), domain.ErrSiteNotEmpty)
+	require.ErrorIs(t, b.Repo.DeleteSite(ctx, siteB.ID), domain.ErrSiteNotEmpty)
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	require.NoError(t, b.Repo.DeleteSite(ctx, siteB.ID))
+	require.ErrorIs(t, b.Repo.DeleteSite(ctx, siteB.ID), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.RestoreTerminal(ctx, ids[0]))
+	terminals, err = b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Zero(t, terminals[0].SiteID)
+}
+
+// testConcurrentSiteMoves moves two sites under each other at once; one
+// move must fail or the pair would form a loop that no longer hangs from a
+// top-level site.
+func testConcurrentSiteMoves(t *testing.T, b Backend) {
+	ctx := context.Background()
+	for round := 0; round < 10; round++ {
+		a, err := b.Repo.CreateSite(ctx, domain.Site{Name: "A"})
+		require.NoError(t, err)
+		c, err := b.Repo.CreateSite(ctx, domain.Site{Name: "C"})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, move := range []domain.Site{{ID: a.ID, ParentID: c.ID, Name: "A"}, {ID: c.ID, ParentID: a.ID, Name: "C"}} {
+			wg.Add(1)
+			go func(i int, move domain.Site) {
+				defer wg.Done()
+				errs[i] = b.Repo.UpdateSite(ctx, move)
+			}(i, move)
+		}
+		wg.Wait()
+		failed := 0
+		for _, err := range errs {
+			if err != nil {
+				require.ErrorIs(t, err, domain.ErrInvalidSite)
+				failed++
+			}
+		}
+		require.Equal(t, 1, failed, "round %d", round)
+
+		sites, err := b.Repo.GetSites(ctx)
+		require.NoError(t, err)
+		roots := 0
+		for _, site := range sites {
+			if (site.ID == a.ID || site.ID == c.ID) && site.ParentID == 0 {
+				roots++
+			}
+		}
+		require.Equal(t, 1, roots, "round %d", round)
+	}
+}
+
+func testFavoriteNotes(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userId := createUser(t, b, "Khalid")
+	otherId := createUser(t, b, "Bob")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], userId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], otherId))
+	kiosk := domain.FavoriteNote{Alias: "lobby kiosk", Color: "#ff8800", Note: "call Bob if down"}
+
+	version, err := b.Repo.GetFavoritesVersion(ctx, userId)
+	require.NoError(t, err)
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[0], kiosk))
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[1], domain.FavoriteNote{Color: "#0f0"}))
+	require.ErrorIs(t, b.Repo.SetFavoriteNote(ctx, userId, ids[2], kiosk), repositories.ErrNotFound, "not a favorite")
+	bumped, err := b.Repo.GetFavoritesVersion(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, version+2, bumped)
+
+	notes, err := b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, map[int]domain.FavoriteNote{ids[0]: kiosk, ids[1]: {Color: "#0f0"}}, notes)
+	notes, err = b.Repo.GetFavoriteNotes(ctx, otherId)
+	require.NoError(t, err)
+	require.Empty(t, notes, "notes are private")
+
+	// A zero note deletes it; so does removing the favorite.
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[1], domain.FavoriteNote{}))
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[0], userId))
+	notes, err = b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, notes)
+
+	// Adding the terminal again starts without the old note, and there is
+	// nothing left to undo.
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+	notes, err = b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, notes)
+	_, err = b.Repo.RestoreRemovedFavoriteNote(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	// Soft-deleted terminals cannot get notes; purging the user drops them.
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[0], kiosk))
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.SetFavoriteNote(ctx, userId, ids[1], kiosk), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, userId))
+	require.NoError(t, b.Repo.PurgeUser(ctx, userId))
+	notes, err = b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, notes)
+}
+
+func testFavoriteNotesUndo(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userId := createUser(t, b, "Khalid")
+	for _, id := range ids {
+		require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	}
+	kiosk := domain.FavoriteNote{Alias: "lobby kiosk", Color: "#ff8800", Note: "call Bob if down"}
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[0], kiosk))
+
+	_, err := b.Repo.RestoreRemovedFavoriteNote(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	// Only the latest removal can be undone.
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[0], userId))
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[2], userId))
+	id, err := b.Repo.RestoreRemovedFavoriteNote(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, ids[2], id)
+	require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	_, err = b.Repo.RestoreRemovedFavoriteNote(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	notes, err := b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, notes, "a favorite removed without a note comes back without one")
+
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[1], userId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+	require.NoError(t, b.Repo.SetFavoriteNote(ctx, userId, ids[0], kiosk))
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[0], userId))
+	id, err = b.Repo.RestoreRemovedFavoriteNote(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, ids[0], id)
+	require.NoError(t, b.Repo.AddToFavorites(ctx, id, userId))
+	notes, err = b.Repo.GetFavoriteNotes(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, map[int]domain.FavoriteNote{ids[0]: kiosk}, notes)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[2], ids[0]}, favorites)
+}
+
+func testViews(t *testing.T, b Backend) {
+	ctx := context.Background()
+	userId := createUser(t, b, "Khalid")
+
+	_, err := b.Repo.GetDefaultView(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	night, err := b.Repo.SaveView(ctx, domain.View{
+		OwnerID:   userId,
+		Name:      "night",
+		Params:    url.Values{"label": {"zone=north", "type=atm"}, "page_size": {"50"}},
+		IsDefault: true,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, night.ID)
+	require.Equal(t, "Khalid", night.Owner)
+	require.Equal(t, url.Values{"label": {"zone=north", "type=atm"}, "page_size": {"50"}}, night.Params)
+	require.WithinDuration(t, time.Now(), night.UpdatedAt, time.Minute)
+
+	view, err := b.Repo.GetDefaultView(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, night.ID, view.ID)
+
+	// A new default replaces the old one; saving by name replaces the view.
+	day, err := b.Repo.SaveView(ctx, domain.View{OwnerID: userId, Name: "day", Params: url.Values{"q": {"kiosk"}}, IsDefault: true})
+	require.NoError(t, err)
+	view, err = b.Repo.GetDefaultView(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, day.ID, view.ID)
+	replaced, err := b.Repo.SaveView(ctx, domain.View{OwnerID: userId, Name: "night", Params: url.Values{}})
+	require.NoError(t, err)
+	require.Equal(t, night.ID, replaced.ID)
+	require.Empty(t, replaced.Params)
+	require.False(t, replaced.IsDefault)
+
+	views, err := b.Repo.GetViews(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+	require.Equal(t, "day", views[0].Name)
+	require.Equal(t, "night", views[1].Name)
+
+	view, err = b.Repo.GetView(ctx, userId, "", "day")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"q": {"kiosk"}}, view.Params)
+	view, err = b.Repo.GetView(ctx, userId, "Khalid", "day")
+	require.NoError(t, err)
+	require.Equal(t, day.ID, view.ID)
+
+	require.NoError(t, b.Repo.DeleteView(ctx, userId, "day"))
+	require.ErrorIs(t, b.Repo.DeleteView(ctx, userId, "day"), repositories.ErrNotFound)
+	_, err = b.Repo.GetView(ctx, userId, "", "day")
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = b.Repo.GetDefaultView(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testViewsSharing(t *testing.T, b Backend) {
+	ctx := context.Background()
+	aliceId := createUser(t, b, "alice")
+	bobId := createUser(t, b, "bob")
+	shared, err := b.Repo.SaveView(ctx, domain.View{OwnerID: aliceId, Name: "night", Params: url.Values{"q": {"kiosk"}}, Shared: true})
+	require.NoError(t, err)
+	_, err = b.Repo.SaveView(ctx, domain.View{OwnerID: aliceId, Name: "private"})
+	require.NoError(t, err)
+	_, err = b.Repo.SaveView(ctx, domain.View{OwnerID: bobId, Name: "night"})
+	require.NoError(t, err)
+
+	views, err := b.Repo.GetViews(ctx, bobId)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+	require.Equal(t, bobId, views[0].OwnerID, "own views come first")
+	require.Equal(t, shared.ID, views[1].ID)
+	require.Equal(t, "alice", views[1].Owner)
+
+	view, err := b.Repo.GetView(ctx, bobId, "alice", "night")
+	require.NoError(t, err)
+	require.Equal(t, shared.ID, view.ID)
+	_, err = b.Repo.GetView(ctx, bobId, "alice", "private")
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	// Others can only read a shared view: deleting by name affects their own.
+	require.NoError(t, b.Repo.DeleteView(ctx, bobId, "night"))
+	_, err = b.Repo.GetView(ctx, bobId, "alice", "night")
+	require.NoError(t, err)
+
+	// Views of soft-deleted users disappear and go with the user on purge.
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, aliceId))
+	_, err = b.Repo.GetView(ctx, bobId, "alice", "night")
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	require.NoError(t, b.Repo.PurgeUser(ctx, aliceId))
+	aliceId = createUser(t, b, "alice")
+	views, err = b.Repo.GetViews(ctx, aliceId)
+	require.NoError(t, err)
+	require.Empty(t, views)
+}
+
+func testStatusChanges(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	start := time.Now().Add(-time.Minute)
+
+	changes, err := b.Repo.GetStatusChanges(ctx, start)
+	require.NoError(t, err)
+	require.Empty(t, changes, "inserting a terminal is not a change")
+
+	b.SetStatus(t, ids[0], "offline")
+	b.SetStatus(t, ids[0], "offline")
+	b.SetStatus(t, ids[0], "active")
+	changes, err = b.Repo.GetStatusChanges(ctx, start)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Len(t, changes[ids[0]], 2, "setting the same status again is not a change")
+	for _, changed := range changes[ids[0]] {
+		require.WithinDuration(t, time.Now(), changed, time.Minute)
+	}
+	changes, err = b.Repo.GetStatusChanges(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	terminals, err := b.Repo.GetDefaultTerminalsList(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "active", terminals[0].Status)
+	require.False(t, terminals[0].StatusChangedAt.Before(*terminals[1].StatusChangedAt))
+
+	claimed, err := b.Repo.ClaimStatusChanges(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, ids[0], claimed[0].TerminalID)
+	require.Equal(t, "offline", claimed[0].Status)
+	b.SetStatus(t, ids[1], "offline")
+	claimed, err = b.Repo.ClaimStatusChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	require.Equal(t, "active", claimed[0].Status)
+	require.Equal(t, ids[1], claimed[1].TerminalID)
+	claimed, err = b.Repo.ClaimStatusChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, claimed, "claimed changes are not claimed again")
+}
+
+func testAlertFavorites(t *testing.T, b Backend) {
+	ctx := context.Background()
+	aliceId := createUser(t, b, "alice")
+	bobId := createUser(t, b, "bob")
+	ids := seedTerminals(t, b, 3)
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[2], aliceId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], aliceId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], aliceId))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], bobId))
+
+	favorites, err := b.Repo.GetAlertFavorites(ctx)
+	require.NoError(t, err)
+	require.Empty(t, favorites, "users without alert rules are left out")
+
+	_, err = b.Repo.CreateAlertRule(ctx, domain.AlertRule{
+		UserID: aliceId, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(time.Minute),
+	})
+	require.NoError(t, err)
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	favorites, err = b.Repo.GetAlertFavorites(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[int][]int{aliceId: {ids[2], ids[1]}}, favorites)
+}
+
+func testAlertRules(t *testing.T, b Backend) {
+	ctx := context.Background()
+	aliceId := createUser(t, b, "alice")
+	bobId := createUser(t, b, "bob")
+	ids := seedTerminals(t, b, 1)
+
+	offline, err := b.Repo.CreateAlertRule(ctx, domain.AlertRule{
+		UserID: aliceId, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(5 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, offline.ID)
+	flapping, err := b.Repo.CreateAlertRule(ctx, domain.AlertRule{
+		UserID: aliceId, TerminalID: ids[0], Kind: domain.AlertRuleFlapping, Count: 3, Window: domain.Duration(10 * time.Minute),
+	})
+	require.NoError(t, err)
+
+	rules, err := b.Repo.GetAlertRules(ctx, aliceId)
+	require.NoError(t, err)
+	require.Equal(t, []domain.AlertRule{offline, flapping}, rules)
+	rules, err = b.Repo.GetAlertRules(ctx, bobId)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+
+	until := time.Now().Add(time.Hour)
+	require.ErrorIs(t, b.Repo.MuteAlertRule(ctx, bobId, offline.ID, &until), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.MuteAlertRule(ctx, aliceId, offline.ID, &until))
+	rules, err = b.Repo.GetAllAlertRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.NotNil(t, rules[0].MutedUntil)
+	require.WithinDuration(t, until, *rules[0].MutedUntil, time.Second)
+	require.NoError(t, b.Repo.MuteAlertRule(ctx, aliceId, offline.ID, nil))
+	rules, err = b.Repo.GetAlertRules(ctx, aliceId)
+	require.NoError(t, err)
+	require.Nil(t, rules[0].MutedUntil)
+
+	require.ErrorIs(t, b.Repo.DeleteAlertRule(ctx, bobId, offline.ID), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.DeleteAlertRule(ctx, aliceId, offline.ID))
+	rules, err = b.Repo.GetAlertRules(ctx, aliceId)
+	require.NoError(t, err)
+	require.Equal(t, []domain.AlertRule{flapping}, rules)
+
+	// Rules of soft-deleted users are not evaluated.
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, aliceId))
+	rules, err = b.Repo.GetAllAlertRules(ctx)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+}
+
+func testAlerts(t *testing.T, b Backend) {
+	ctx := context.Background()
+	aliceId := createUser(t, b, "alice")
+	bobId := createUser(t, b, "bob")
+	ids := b.SeedTerminals(t, domain.Terminal{Name: "lobby", Status: "offline"})
+	rule, err := b.Repo.CreateAlertRule(ctx, domain.AlertRule{
+		UserID: aliceId, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(time.Minute),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Repo.FireAlert(ctx, rule.ID, ids[0]))
+	require.NoError(t, b.Repo.FireAlert(ctx, rule.ID, ids[0]), "firing again is a no-op")
+	firing, err := b.Repo.GetFiringAlerts(ctx)
+	require.NoError(t, err)
+	require.Len(t, firing, 1)
+	alert := firing[0]
+	require.Equal(t, rule, alert.Rule)
+	require.Equal(t, ids[0], alert.TerminalID)
+	require.Equal(t, "lobby", alert.TerminalName)
+	require.Equal(t, domain.AlertFiring, alert.State())
+	require.WithinDuration(t, time.Now(), alert.FiredAt, time.Minute)
+
+	alerts, err := b.Repo.GetAlerts(ctx, bobId, "")
+	require.NoError(t, err)
+	require.Empty(t, alerts)
+	require.ErrorIs(t, b.Repo.AckAlert(ctx, bobId, alert.ID), repositories.ErrNotFound)
+	_, err = b.Repo.GetAlert(ctx, bobId, alert.ID)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	require.NoError(t, b.Repo.AckAlert(ctx, aliceId, alert.ID))
+	acked, err := b.Repo.GetAlert(ctx, aliceId, alert.ID)
+	require.NoError(t, err)
+	require.NotNil(t, acked.AckedAt)
+	require.NoError(t, b.Repo.AckAlert(ctx, aliceId, alert.ID))
+	again, err := b.Repo.GetAlert(ctx, aliceId, alert.ID)
+	require.NoError(t, err)
+	require.Equal(t, acked.AckedAt, again.AckedAt, "acknowledging again keeps the first time")
+
+	require.NoError(t, b.Repo.ResolveAlert(ctx, alert.ID))
+	require.ErrorIs(t, b.Repo.ResolveAlert(ctx, alert.ID), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.FireAlert(ctx, rule.ID, ids[0]))
+
+	alerts, err = b.Repo.GetAlerts(ctx, aliceId, "")
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	require.Equal(t, domain.AlertFiring, alerts[0].State(), "firing alerts come first")
+	require.Equal(t, alert.ID, alerts[1].ID)
+	require.NotNil(t, alerts[1].ResolvedAt)
+	alerts, err = b.Repo.GetAlerts(ctx, aliceId, domain.AlertResolved)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, alert.ID, alerts[0].ID)
+	alerts, err = b.Repo.GetAlerts(ctx, aliceId, domain.AlertFiring)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.NotEqual(t, alert.ID, alerts[0].ID)
+
+	// Alerts go with their rule.
+	require.NoError(t, b.Repo.DeleteAlertRule(ctx, aliceId, rule.ID))
+	firing, err = b.Repo.GetFiringAlerts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, firing)
+}
+
+func testWebhooks(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+
+	all, err := b.Repo.CreateWebhook(ctx, domain.Webhook{URL: "https://hooks.example.com/all", Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+	require.NotZero(t, all.ID)
+	require.WithinDuration(t, time.Now(), all.CreatedAt, time.Minute)
+	narrow, err := b.Repo.CreateWebhook(ctx, domain.Webhook{
+		URL: "https://hooks.example.com/narrow", Secret: "fedcba9876543210",
+		Events: []string{domain.WebhookStatusChange}, TerminalID: ids[0], Labels: []string{"zone=north", "!type"},
+	})
+	require.NoError(t, err)
+
+	hooks, err := b.Repo.GetWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	require.Equal(t, all.ID, hooks[0].ID)
+	require.Equal(t, "0123456789abcdef", hooks[0].Secret)
+	require.Nil(t, hooks[0].Events)
+	require.Nil(t, hooks[0].Labels)
+	require.Equal(t, narrow.Events, hooks[1].Events)
+	require.Equal(t, ids[0], hooks[1].TerminalID)
+	require.Equal(t, narrow.Labels, hooks[1].Labels)
+
+	require.NoError(t, b.Repo.DeleteWebhook(ctx, all.ID))
+	require.ErrorIs(t, b.Repo.DeleteWebhook(ctx, all.ID), repositories.ErrNotFound)
+
+	// A webhook on one terminal goes with it.
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	require.NoError(t, b.Repo.PurgeTerminal(ctx, ids[0]))
+	hooks, err = b.Repo.GetWebhooks(ctx)
+	require.NoError(t, err)
+	require.Empty(t, hooks)
+}
+
+func testWebhookEvents(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	b.SetStatus(t, ids[0], "offline")
+	b.SetStatus(t, ids[1], "offline")
+	b.SetStatus(t, ids[0], "active")
+
+	// Status changes and favorite changes leave pending events behind,
+	// failed changes do not.
+	userId := createUser(t, b, "Khalid")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], userId))
+	require.Error(t, b.Repo.AddToFavorites(ctx, ids[1], userId))
+	require.NoError(t, b.Repo.RemoveFromFavoriteTerminal(ctx, ids[1], userId))
+
+	// Claimed events are kept when the transaction rolls back.
+	expErr := errors.New("abort")
+	err := b.Repo.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		events, err := tx.ClaimWebhookEvents(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 5)
+		return expErr
+	})
+	require.ErrorIs(t, err, expErr)
+
+	events, err := b.Repo.ClaimWebhookEvents(ctx, 4)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	require.Equal(t, domain.WebhookEvent{Type: domain.WebhookStatusChange, OccurredAt: events[0].OccurredAt,
+		TerminalID: ids[0], Status: "offline"}, events[0])
+	require.WithinDuration(t, time.Now(), events[0].OccurredAt, time.Minute)
+	require.Equal(t, ids[1], events[1].TerminalID)
+	require.Equal(t, "active", events[2].Status)
+	require.Equal(t, domain.WebhookEvent{Type: domain.WebhookFavoriteAdd, OccurredAt: events[3].OccurredAt,
+		TerminalID: ids[1], UserID: userId}, events[3])
+	require.WithinDuration(t, time.Now(), events[3].OccurredAt, time.Minute)
+
+	events, err = b.Repo.ClaimWebhookEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1, "claimed events are not claimed again")
+	require.Equal(t, domain.WebhookFavoriteRemove, events[0].Type)
+	events, err = b.Repo.ClaimWebhookEvents(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func testWebhookDeliveries(t *testing.T, b Backend) {
+	ctx := context.Background()
+	hook, err := b.Repo.CreateWebhook(ctx, domain.Webhook{URL: "https://hooks.example.com", Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+	other, err := b.Repo.CreateWebhook(ctx, domain.Webhook{URL: "https://other.example.com", Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+
+	payload := json.RawMessage(`{"event":"favorite.add","terminal_id":4}`)
+	created := time.Now()
+	for _, webhookID := range []int{hook.ID, other.ID} {
+		require.NoError(t, b.Repo.CreateWebhookDelivery(ctx, domain.WebhookDelivery{
+			WebhookID: webhookID, Event: domain.WebhookFavoriteAdd, Payload: payload, NextAttemptAt: &created,
+		}))
+	}
+	due, err := b.Repo.ClaimDueWebhookDeliveries(ctx, created.Add(-time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	due, err = b.Repo.ClaimDueWebhookDeliveries(ctx, created, time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	delivery := due[0]
+	require.Equal(t, hook.ID, delivery.WebhookID)
+	require.Equal(t, domain.DeliveryPending, delivery.State)
+	require.Equal(t, string(payload), string(delivery.Payload), "the payload is kept byte for byte")
+	require.Zero(t, delivery.Attempts)
+	require.NotNil(t, delivery.NextAttemptAt)
+	require.WithinDuration(t, time.Now().Add(time.Minute), *delivery.NextAttemptAt, 30*time.Second)
+	due, err = b.Repo.ClaimDueWebhookDeliveries(ctx, time.Now().Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "a claimed delivery is not claimed again while its lease lasts")
+	require.Equal(t, other.ID, due[0].WebhookID)
+	due, err = b.Repo.ClaimDueWebhookDeliveries(ctx, time.Now().Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2, "an expired lease makes the delivery due again")
+
+	next := time.Now().Add(time.Hour)
+	delivery.Attempts = 1
+	delivery.NextAttemptAt = &next
+	delivery.ResponseStatus = 503
+	delivery.LastError = "503 Service Unavailable"
+	require.NoError(t, b.Repo.UpdateWebhookDelivery(ctx, delivery))
+	due, err = b.Repo.ClaimDueWebhookDeliveries(ctx, time.Now().Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "a failed delivery waits for its next attempt")
+	require.Equal(t, other.ID, due[0].WebhookID)
+
+	delivered := time.Now()
+	delivery.State = domain.DeliveryDelivered
+	delivery.Attempts = 2
+	delivery.NextAttemptAt = nil
+	delivery.ResponseStatus = 204
+	delivery.LastError = ""
+	delivery.DeliveredAt = &delivered
+	require.NoError(t, b.Repo.UpdateWebhookDelivery(ctx, delivery))
+	deliveries, err := b.Repo.GetWebhookDeliveries(ctx, hook.ID, "")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, domain.DeliveryDelivered, deliveries[0].State)
+	require.Equal(t, 2, deliveries[0].Attempts)
+	require.Equal(t, 204, deliveries[0].ResponseStatus)
+	require.NotNil(t, deliveries[0].DeliveredAt)
+	require.Nil(t, deliveries[0].NextAttemptAt)
+	deliveries, err = b.Repo.GetWebhookDeliveries(ctx, hook.ID, domain.DeliveryDead)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	require.ErrorIs(t, b.Repo.RedeliverWebhookDelivery(ctx, other.ID, delivery.ID), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.RedeliverWebhookDelivery(ctx, hook.ID, delivery.ID))
+	deliveries, err = b.Repo.GetWebhookDeliveries(ctx, hook.ID, domain.DeliveryPending)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Zero(t, deliveries[0].Attempts)
+	require.Zero(t, deliveries[0].ResponseStatus)
+	require.Nil(t, deliveries[0].DeliveredAt)
+	due, err = b.Repo.ClaimDueWebhookDeliveries(ctx, time.Now().Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+
+	// Deliveries go with their webhook.
+	require.NoError(t, b.Repo.DeleteWebhook(ctx, hook.ID))
+	deliveries, err = b.Repo.GetWebhookDeliveries(ctx, hook.ID, "")
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
+
+func testNotificationChannels(t *testing.T, b Backend) {
+	ctx := context.Background()
+	userId := createUser(t, b, "Khalid")
+	otherId := createUser(t, b, "Aziz")
+
+	own, err := b.Repo.CreateNotificationChannel(ctx, domain.NotificationChannel{
+		UserID: userId, Name: "mail", Kind: domain.ChannelEmail, Target: "khalid@example.com",
+	})
+	require.NoError(t, err)
+	require.NotZero(t, own.ID)
+	require.WithinDuration(t, time.Now(), own.CreatedAt, time.Minute)
+	team, err := b.Repo.CreateNotificationChannel(ctx, domain.NotificationChannel{
+		Name: "ops", Kind: domain.ChannelTelegram, Target: "-100", Template: "{{.TerminalName}}", Members: []int{userId, otherId},
+	})
+	require.NoError(t, err)
+
+	channels, err := b.Repo.GetNotificationChannels(ctx, userId)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	require.Equal(t, own.ID, channels[0].ID)
+	require.Equal(t, userId, channels[0].UserID)
+	require.Nil(t, channels[0].Members)
+	channels, err = b.Repo.GetNotificationChannels(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	require.Equal(t, []int{userId, otherId}, channels[0].Members)
+	require.Equal(t, "{{.TerminalName}}", channels[0].Template)
+
+	// Channels are only visible to, and changed by, their owner.
+	_, err = b.Repo.GetNotificationChannel(ctx, otherId, own.ID)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = b.Repo.GetNotificationChannel(ctx, userId, team.ID)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	updated := team
+	updated.Target = "-200"
+	updated.Members = []int{otherId}
+	updated.UserID = userId
+	require.ErrorIs(t, b.Repo.UpdateNotificationChannel(ctx, updated), repositories.ErrNotFound)
+	updated.UserID = 0
+	require.NoError(t, b.Repo.UpdateNotificationChannel(ctx, updated))
+	channel, err := b.Repo.GetNotificationChannel(ctx, 0, team.ID)
+	require.NoError(t, err)
+	require.Equal(t, "-200", channel.Target)
+	require.Equal(t, []int{otherId}, channel.Members)
+
+	require.ErrorIs(t, b.Repo.DeleteNotificationChannel(ctx, 0, own.ID), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.DeleteNotificationChannel(ctx, 0, team.ID))
+
+	// A user's channels go with them.
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, userId))
+	require.NoError(t, b.Repo.PurgeUser(ctx, userId))
+	_, err = b.Repo.GetNotificationChannel(ctx, userId, own.ID)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testHeartbeats(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 5)
+	now := time.Now()
+	issued := now.Add(-20 * time.Minute)
+
+	require.ErrorIs(t, b.Repo.SetHeartbeatToken(ctx, 99, "hash-x", issued), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[0], "hash-a", issued))
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[1], "hash-b", issued))
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[2], "hash-c", issued))
+	// Terminals that never report count from when their token was issued.
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[3], "hash-d", issued))
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[4], "hash-e", now))
+	_, err := b.Repo.RecordHeartbeat(ctx, "hash-x", domain.Heartbeat{}, now)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	id, err := b.Repo.RecordHeartbeat(ctx, "hash-a", domain.Heartbeat{Firmware: "1.0", IP: "10.0.0.1"}, now.Add(-10*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, ids[0], id)
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-a", domain.Heartbeat{Firmware: "1.1"}, now.Add(-9*time.Minute))
+	require.NoError(t, err)
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-b", domain.Heartbeat{}, now)
+	require.NoError(t, err)
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-c", domain.Heartbeat{}, now.Add(-10*time.Minute))
+	require.NoError(t, err)
+	b.SetStatus(t, ids[2], "maintenance")
+
+	heartbeats, err := b.Repo.GetHeartbeats(ctx)
+	require.NoError(t, err)
+	require.Len(t, heartbeats, 5)
+	require.Equal(t, ids[0], heartbeats[0].TerminalID)
+	require.Equal(t, "terminalA", heartbeats[0].TerminalName)
+	require.Equal(t, "1.1", heartbeats[0].Firmware)
+	require.Equal(t, "10.0.0.1", heartbeats[0].IP, "an empty field keeps its last value")
+	require.NotNil(t, heartbeats[0].LastSeenAt)
+	require.WithinDuration(t, now.Add(-9*time.Minute), *heartbeats[0].LastSeenAt, time.Second)
+	require.Nil(t, heartbeats[3].LastSeenAt)
+
+	// Only active terminals that stopped reporting, or never did, go
+	// offline, and the changes are recorded with their reason.
+	offline, err := b.Repo.MarkTerminalsOffline(ctx, now.Add(-5*time.Minute), "heartbeat timeout after 5m0s")
+	require.NoError(t, err)
+	require.Equal(t, []domain.Terminal{
+		{ID: ids[0], Name: "terminalA", Status: domain.TerminalOffline},
+		{ID: ids[3], Name: "terminalD", Status: domain.TerminalOffline},
+	}, offline)
+	offline, err = b.Repo.MarkTerminalsOffline(ctx, now.Add(-5*time.Minute), "heartbeat timeout after 5m0s")
+	require.NoError(t, err)
+	require.Empty(t, offline)
+	changes, err := b.Repo.ClaimStatusChanges(ctx, 10)
+	require.NoError(t, err)
+	last := changes[len(changes)-2:]
+	require.ElementsMatch(t, []int{ids[0], ids[3]}, []int{last[0].TerminalID, last[1].TerminalID})
+	for _, change := range last {
+		require.Equal(t, domain.TerminalOffline, change.Status)
+		require.Equal(t, domain.StatusSourceHeartbeat, change.Source)
+		require.Equal(t, "heartbeat timeout after 5m0s", change.Reason)
+	}
+	require.Empty(t, changes[0].Source, "changes made by other writers have no source")
+
+	online, err := b.Repo.MarkTerminalOnline(ctx, ids[0], "heartbeat resumed")
+	require.NoError(t, err)
+	require.True(t, online)
+	online, err = b.Repo.MarkTerminalOnline(ctx, ids[0], "heartbeat resumed")
+	require.NoError(t, err)
+	require.False(t, online)
+	online, err = b.Repo.MarkTerminalOnline(ctx, ids[2], "heartbeat resumed")
+	require.NoError(t, err)
+	require.False(t, online)
+	changes, err = b.Repo.ClaimStatusChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, domain.StatusChange{ID: changes[0].ID, TerminalID: ids[0], Status: domain.TerminalActive,
+		Source: domain.StatusSourceHeartbeat, Reason: "heartbeat resumed", ChangedAt: changes[0].ChangedAt}, changes[0])
+
+	// A new token replaces the old one, and a revoked one stops working.
+	require.NoError(t, b.Repo.SetHeartbeatToken(ctx, ids[0], "hash-a2", now))
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-a", domain.Heartbeat{}, now)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	require.NoError(t, b.Repo.DeleteHeartbeatToken(ctx, ids[1]))
+	require.ErrorIs(t, b.Repo.DeleteHeartbeatToken(ctx, ids[1]), repositories.ErrNotFound)
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-b", domain.Heartbeat{}, now)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	// Soft-deleted terminals cannot report.
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[0]))
+	_, err = b.Repo.RecordHeartbeat(ctx, "hash-a2", domain.Heartbeat{}, now)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testSoftDeleteUser(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+	userId := createUser(t, b, "Khalid")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, userId))
+	require.ErrorIs(t, b.Repo.SoftDeleteUser(ctx, userId), repositories.ErrNotFound)
+	_, err := b.Repo.GetUser(ctx, "Khalid")
+	require.EqualError(t, err, "no user found with given name")
+	_, err = b.Repo.GetUserByID(ctx, userId)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+	require.EqualError(t, err, "username already exists")
+
+	deleted, err := b.Repo.GetDeletedUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	require.Equal(t, userId, deleted[0].ID)
+	require.Equal(t, "Khalid", deleted[0].Name)
+
+	require.NoError(t, b.Repo.RestoreUser(ctx, userId))
+	require.ErrorIs(t, b.Repo.RestoreUser(ctx, userId), repositories.ErrNotFound)
+	user, err := b.Repo.GetUser(ctx, "Khalid")
+	require.NoError(t, err)
+	require.Equal(t, userId, user.ID)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[0]}, favorites)
+}
+
+func testPurgeUser(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 1)
+	userId := createUser(t, b, "Khalid")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userId))
+
+	require.ErrorIs(t, b.Repo.PurgeUser(ctx, userId), repositories.ErrNotFound)
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, userId))
+	require.NoError(t, b.Repo.PurgeUser(ctx, userId))
+
+	require.ErrorIs(t, b.Repo.RestoreUser(ctx, userId), repositories.ErrNotFound)
+	favorites, err := b.Repo.GetFavoriteTerminalIds(ctx, userId)
+	require.NoError(t, err)
+	require.Empty(t, favorites)
+	_, err = b.Repo.CreateUser(ctx, domain.User{Name: "Khalid", Password: "hash"})
+	require.NoError(t, err)
+}
+
+func testDeletedBefore(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 2)
+	userId := createUser(t, b, "Khalid")
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[1]))
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, userId))
+
+	terminalIds, err := b.Repo.GetTerminalsDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, terminalIds)
+	terminalIds, err = b.Repo.GetTerminalsDeletedBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []int{ids[1]}, terminalIds)
+
+	userIds, err := b.Repo.GetUsersDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, userIds)
+	userIds, err = b.Repo.GetUsersDeletedBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []int{userId}, userIds)
+}
+
+func testCounts(t *testing.T, b Backend) {
+	ctx := context.Background()
+	ids := seedTerminals(t, b, 3)
+	userID := createUser(t, b, "Khalid")
+	otherID := createUser(t, b, "Yusuf")
+	deletedID := createUser(t, b, "Bountyhunter")
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], userID))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[1], userID))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[2], otherID))
+	require.NoError(t, b.Repo.AddToFavorites(ctx, ids[0], deletedID))
+	require.NoError(t, b.Repo.SoftDeleteUser(ctx, deletedID))
+	require.NoError(t, b.Repo.SoftDeleteTerminal(ctx, ids[2]))
+
+	users, err := b.Repo.CountActiveUsers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, users)
+	favorites, err := b.Repo.CountFavorites(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, favorites)
+}
+
+func testAuditLog(t *testing.T, b Backend) {
+	ctx := context.Background()
+	entries := []domain.AuditEntry{
+		{Action: domain.AuditSignIn, Outcome: domain.AuditFailure, ActorName: "Khalid", TargetType: domain.AuditTargetUser,
+			TargetID: "Khalid", IP: "10.0.0.1", RequestID: "req-1", After: json.RawMessage(`{"reason":"wrong password"}`)},
+		{Action: domain.AuditSignIn, Outcome: domain.AuditSuccess, ActorID: 1, ActorName: "Khalid", TargetType: domain.AuditTargetUser,
+			TargetID: "Khalid", IP: "10.0.0.1", RequestID: "req-2"},
+		{Action: domain.AuditFavoriteAdd, Outcome: domain.AuditSuccess, ActorID: 1, TargetType: domain.AuditTargetTerminal,
+			TargetID: "4", IP: "10.0.0.1", RequestID: "req-3",
+			Before: json.RawMessage(`{"favorites":[1]}`), After: json.RawMessage(`{"favorites":[1,4]}`)},
+	}
+	start := time.Now().Add(-time.Minute)
+	for _, entry := range entries {
+		require.NoError(t, b.Repo.InsertAuditEntry(ctx, entry))
+	}
+
+	all, err := b.Repo.GetAuditEntries(ctx, domain.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	require.Equal(t, domain.AuditFavoriteAdd, all[0].Action)
+	require.Equal(t, 1, all[0].ActorID)
+	require.Equal(t, "req-3", all[0].RequestID)
+	require.JSONEq(t, `{"favorites":[1]}`, string(all[0].Before))
+	require.JSONEq(t, `{"favorites":[1,4]}`, string(all[0].After))
+	require.WithinDuration(t, time.Now(), all[0].CreatedAt, time.Minute)
+	require.Zero(t, all[2].ActorID)
+	require.Nil(t, all[2].Before)
+	require.Greater(t, all[0].ID, all[1].ID)
+
+	filtered, err := b.Repo.GetAuditEntries(ctx, domain.AuditFilter{Action: domain.AuditSignIn, Outcome: domain.AuditFailure})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, "req-1", filtered[0].RequestID)
+
+	filtered, err = b.Repo.GetAuditEntries(ctx, domain.AuditFilter{ActorID: 1, TargetType: domain.AuditTargetTerminal, TargetID: "4"})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+
+	page, err := b.Repo.GetAuditEntries(ctx, domain.AuditFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, "req-2", page[0].RequestID)
+
+	filtered, err = b.Repo.GetAuditEntries(ctx, domain.AuditFilter{From: start, To: time.Now().Add(time.Minute), RequestID: "req-2"})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	filtered, err = b.Repo.GetAuditEntries(ctx, domain.AuditFilter{To: start})
+	require.NoError(t, err)
+	require.Empty(t, filtered)
+}
+
+func testAuditLogRetention(t *testing.T, b Backend) {
+	ctx := context.Background()
+	require.NoError(t, b.Repo.InsertAuditEntry(ctx, domain.AuditEntry{Action: domain.AuditSignUp, Outcome: domain.AuditSuccess}))
+
+	purged, err := b.Repo.DeleteAuditEntriesBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, purged)
+	purged, err = b.Repo.DeleteAuditEntriesBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	entries, err := b.Repo.GetAuditEntries(ctx, domain.AuditFilter{})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,241 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"time"
+)
+
+const selectAlertRules = `SELECT r.id, r.user_id, COALESCE(r.terminal_id, 0), r.kind, r.status, r.count, r.window_seconds, r.muted_until
+	FROM alert_rules r`
+
+const selectAlerts = `SELECT a.id, a.terminal_id, t.name, a.fired_at, a.resolved_at, a.acked_at,
+	r.id, r.user_id, COALESCE(r.terminal_id, 0), r.kind, r.status, r.count, r.window_seconds, r.muted_until
+	FROM alerts a
+	JOIN alert_rules r ON r.id = a.rule_id
+	JOIN terminals t ON t.id = a.terminal_id`
+
+type AlertRepository struct {
+	db Querier
+}
+
+func NewAlertRepository(db Querier) *AlertRepository {
+	return &AlertRepository{
+		db: db,
+	}
+}
+
+func (ar *AlertRepository) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	command := `INSERT INTO alert_rules (user_id, terminal_id, kind, status, count, window_seconds, created_at)
+		VALUES (?, NULLIF(?, 0), ?, ?, ?, ?, ?) RETURNING id`
+	err := ar.db.QueryRowContext(ctx, command, rule.UserID, rule.TerminalID, rule.Kind, rule.Status, rule.Count,
+		int(time.Duration(rule.Window)/time.Second), now()).Scan(&rule.ID)
+	return rule, err
+}
+
+func (ar *AlertRepository) GetAlertRules(ctx context.Context, userId int) ([]domain.AlertRule, error) {
+	return ar.queryAlertRules(ctx, selectAlertRules+` WHERE r.user_id = ? ORDER BY r.id`, userId)
+}
+
+func (ar *AlertRepository) GetAllAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	query := selectAlertRules + ` JOIN users u ON u.id = r.user_id AND u.deleted_at IS NULL ORDER BY r.id`
+	return ar.queryAlertRules(ctx, query)
+}
+
+func (ar *AlertRepository) DeleteAlertRule(ctx context.Context, userId int, id int) error {
+	res, err := ar.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE user_id = ? AND id = ?`, userId, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("alert rule %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ar *AlertRepository) MuteAlertRule(ctx context.Context, userId int, id int, until *time.Time) error {
+	res, err := ar.db.ExecContext(ctx, `UPDATE alert_rules SET muted_until = ? WHERE user_id = ? AND id = ?`, until, userId, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("alert rule %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ar *AlertRepository) GetStatusChanges(ctx context.Context, since time.Time) (map[int][]time.Time, error) {
+	query := `SELECT terminal_id, changed_at FROM terminal_status_changes WHERE changed_at > ? ORDER BY id`
+	rows, err := ar.db.QueryContext(ctx, query, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make(map[int][]time.Time)
+	for rows.Next() {
+		var terminalID int
+		var changedAt time.Time
+		err = rows.Scan(&terminalID, &changedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes[terminalID] = append(changes[terminalID], changedAt)
+	}
+	return changes, rows.Err()
+}
+
+func (ar *AlertRepository) ClaimStatusChanges(ctx context.Context, limit int) ([]domain.StatusChange, error) {
+	query := `SELECT id, terminal_id, status, source, reason, changed_at FROM terminal_status_changes
+		WHERE NOT evaluated ORDER BY id LIMIT ?`
+	var changes []domain.StatusChange
+	err := WithTx(ctx, ar.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		changes = make([]domain.StatusChange, 0)
+		for rows.Next() {
+			var change domain.StatusChange
+			err = rows.Scan(&change.ID, &change.TerminalID, &change.Status, &change.Source, &change.Reason, &change.ChangedAt)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			changes = append(changes, change)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil || len(changes) == 0 {
+			return err
+		}
+		// SQLite has a single writer, so nothing else can claim the rows
+		// read above before they are marked.
+		_, err = tx.ExecContext(ctx, `UPDATE terminal_status_changes SET evaluated = TRUE WHERE NOT evaluated AND id <= ?`,
+			changes[len(changes)-1].ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (ar *AlertRepository) GetAlertFavorites(ctx context.Context) (map[int][]int, error) {
+	query := `SELECT f.user_id, t.id FROM favorite_terminals f, json_each(f.terminal_id) AS u
+		JOIN terminals t ON t.id = u.value AND t.deleted_at IS NULL
+		WHERE f.user_id IN (SELECT user_id FROM alert_rules)
+		ORDER BY f.user_id, u.key`
+	rows, err := ar.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := make(map[int][]int)
+	for rows.Next() {
+		var userID, terminalID int
+		err = rows.Scan(&userID, &terminalID)
+		if err != nil {
+			return nil, err
+		}
+		favorites[userID] = append(favorites[userID], terminalID)
+	}
+	return favorites, rows.Err()
+}
+
+func (ar *AlertRepository) GetFiringAlerts(ctx context.Context) ([]domain.Alert, error) {
+	return ar.queryAlerts(ctx, selectAlerts+` WHERE a.resolved_at IS NULL ORDER BY a.id`)
+}
+
+func (ar *AlertRepository) FireAlert(ctx context.Context, ruleId int, terminalID int) error {
+	command := `INSERT INTO alerts (rule_id, terminal_id, fired_at) VALUES (?, ?, ?)
+		ON CONFLICT (rule_id, terminal_id) WHERE resolved_at IS NULL DO NOTHING`
+	_, err := ar.db.ExecContext(ctx, command, ruleId, terminalID, now())
+	return err
+}
+
+func (ar *AlertRepository) ResolveAlert(ctx context.Context, id int) error {
+	res, err := ar.db.ExecContext(ctx, `UPDATE alerts SET resolved_at = ? WHERE id = ? AND resolved_at IS NULL`, now(), id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("firing alert %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ar *AlertRepository) GetAlerts(ctx context.Context, userId int, state string) ([]domain.Alert, error) {
+	query := selectAlerts + ` WHERE r.user_id = ?`
+	switch state {
+	case domain.AlertFiring:
+		query += ` AND a.resolved_at IS NULL`
+	case domain.AlertResolved:
+		query += ` AND a.resolved_at IS NOT NULL`
+	}
+	return ar.queryAlerts(ctx, query+` ORDER BY a.resolved_at IS NOT NULL, a.fired_at DESC, a.id DESC`, userId)
+}
+
+func (ar *AlertRepository) GetAlert(ctx context.Context, userId int, id int) (domain.Alert, error) {
+	alert, err := scanAlert(ar.db.QueryRowContext(ctx, selectAlerts+` WHERE r.user_id = ? AND a.id = ?`, userId, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return alert, fmt.Errorf("alert %d: %w", id, repositories.ErrNotFound)
+	}
+	return alert, err
+}
+
+func (ar *AlertRepository) AckAlert(ctx context.Context, userId int, id int) error {
+	command := `UPDATE alerts SET acked_at = COALESCE(acked_at, ?)
+		WHERE id = ? AND rule_id IN (SELECT id FROM alert_rules WHERE user_id = ?)`
+	res, err := ar.db.ExecContext(ctx, command, now(), id, userId)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("alert %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ar *AlertRepository) queryAlertRules(ctx context.Context, query string, args ...any) ([]domain.AlertRule, error) {
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]domain.AlertRule, 0)
+	for rows.Next() {
+		var rule domain.AlertRule
+		var windowSeconds int
+		err = rows.Scan(&rule.ID, &rule.UserID, &rule.TerminalID, &rule.Kind, &rule.Status, &rule.Count, &windowSeconds, &rule.MutedUntil)
+		if err != nil {
+			return nil, err
+		}
+		rule.Window = domain.Duration(time.Duration(windowSeconds) * time.Second)
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (ar *AlertRepository) queryAlerts(ctx context.Context, query string, args ...any) ([]domain.Alert, error) {
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]domain.Alert, 0)
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func scanAlert(row scanner) (domain.Alert, error) {
+	var alert domain.Alert
+	var windowSeconds int
+	rule := &alert.Rule
+	err := row.Scan(&alert.ID, &alert.TerminalID, &alert.TerminalName, &alert.FiredAt, &alert.ResolvedAt, &alert.AckedAt,
+		&rule.ID, &rule.UserID, &rule.TerminalID, &rule.Kind, &rule.Status, &rule.Count, &windowSeconds, &rule.MutedUntil)
+	rule.Window = domain.Duration(time.Duration(windowSeconds) * time.Second)
+	return alert, err
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"strings"
+	"time"
+)
+
+type AuditRepository struct {
+	db Querier
+}
+
+func NewAuditRepository(db Querier) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (ar *AuditRepository) InsertAuditEntry(ctx context.Context, entry domain.AuditEntry) error {
+	command := `INSERT INTO audit_log
+		(created_at, action, outcome, actor_id, actor_name, target_type, target_id, ip, request_id, before, after)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?)`
+	_, err := ar.db.ExecContext(ctx, command, now(), entry.Action, entry.Outcome, entry.ActorID, entry.ActorName,
+		entry.TargetType, entry.TargetID, entry.IP, entry.RequestID, nullJSON(entry.Before), nullJSON(entry.After))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %v", err)
+	}
+	return nil
+}
+
+func (ar *AuditRepository) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+	if filter.Action != "" {
+		where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = ?", filter.Outcome)
+	}
+	if filter.ActorID != 0 {
+		where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("created_at < ?", filter.To.UTC())
+	}
+
+	query := `SELECT id, created_at, action, outcome, COALESCE(actor_id, 0), actor_name, target_type, target_id,
+		ip, request_id, before, after
+		FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	// SQLite only accepts OFFSET after a LIMIT; -1 means no limit.
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var before, after sql.NullString
+		err = rows.Scan(&entry.ID, &entry.CreatedAt, &entry.Action, &entry.Outcome, &entry.ActorID, &entry.ActorName,
+			&entry.TargetType, &entry.TargetID, &entry.IP, &entry.RequestID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = rawJSON(before)
+		entry.After = rawJSON(after)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (ar *AuditRepository) DeleteAuditEntriesBefore(ctx context.Context, before time.Time) (int, error) {
+	res, err := ar.db.ExecContext(ctx, `DELETE FROM audit_log WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	return int(affected), err
+}
+
+// nullJSON stores an empty snapshot as NULL rather than invalid JSON.
+func nullJSON(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+	sqliteDB "github.com/dvdxa/add-to-favorites/internal/database/sqlite"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories/repotest"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		db, err := sqliteDB.Open(filepath.Join(t.TempDir(), "favorites.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		return repotest.Backend{
+			Repo: NewRepositoryPort(db),
+			SeedTerminals: func(t *testing.T, terminals ...domain.Terminal) []int {
+				ids := make([]int, len(terminals))
+				for i, terminal := range terminals {
+					err := db.QueryRowContext(context.Background(), `INSERT INTO terminals (name, status) VALUES (?, ?) RETURNING id`,
+						terminal.Name, terminal.Status).Scan(&ids[i])
+					require.NoError(t, err)
+				}
+				return ids
+			},
+			SetStatus: func(t *testing.T, terminalID int, status string) {
+				_, err := db.ExecContext(context.Background(), `UPDATE terminals SET status = ? WHERE id = ?`, status, terminalID)
+				require.NoError(t, err)
+			},
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"time"
+)
+
+type HeartbeatRepository struct {
+	db Querier
+}
+
+func NewHeartbeatRepository(db Querier) *HeartbeatRepository {
+	return &HeartbeatRepository{
+		db: db,
+	}
+}
+
+func (hr *HeartbeatRepository) SetHeartbeatToken(ctx context.Context, terminalID int, tokenHash string, issuedAt time.Time) error {
+	command := `INSERT INTO terminal_heartbeats (terminal_id, token_hash, issued_at)
+		SELECT id, ?, ? FROM terminals WHERE id = ? AND deleted_at IS NULL
+		ON CONFLICT (terminal_id) DO UPDATE SET token_hash = excluded.token_hash, issued_at = excluded.issued_at`
+	res, err := hr.db.ExecContext(ctx, command, tokenHash, issuedAt.UTC(), terminalID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("terminal with ID %d: %w", terminalID, repositories.ErrNotFound))
+}
+
+func (hr *HeartbeatRepository) DeleteHeartbeatToken(ctx context.Context, terminalID int) error {
+	res, err := hr.db.ExecContext(ctx, `DELETE FROM terminal_heartbeats WHERE terminal_id = ?`, terminalID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("heartbeat token of terminal %d: %w", terminalID, repositories.ErrNotFound))
+}
+
+func (hr *HeartbeatRepository) RecordHeartbeat(ctx context.Context, tokenHash string, heartbeat domain.Heartbeat, at time.Time) (int, error) {
+	command := `UPDATE terminal_heartbeats SET last_seen_at = ?,
+		firmware = COALESCE(NULLIF(?, ''), firmware), ip = COALESCE(NULLIF(?, ''), ip)
+		WHERE token_hash = ? AND terminal_id IN (SELECT id FROM terminals WHERE deleted_at IS NULL)
+		RETURNING terminal_id`
+	var terminalID int
+	err := hr.db.QueryRowContext(ctx, command, at.UTC(), heartbeat.Firmware, heartbeat.IP, tokenHash).Scan(&terminalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("heartbeat token: %w", repositories.ErrNotFound)
+	}
+	return terminalID, err
+}
+
+func (hr *HeartbeatRepository) GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error) {
+	query := `SELECT t.id, t.name, t.status, h.last_seen_at, h.firmware, h.ip
+		FROM terminal_heartbeats h
+		JOIN terminals t ON t.id = h.terminal_id
+		WHERE t.deleted_at IS NULL
+		ORDER BY t.id`
+	rows, err := hr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heartbeats := make([]domain.TerminalHeartbeat, 0)
+	for rows.Next() {
+		var heartbeat domain.TerminalHeartbeat
+		err = rows.Scan(&heartbeat.TerminalID, &heartbeat.TerminalName, &heartbeat.Status, &heartbeat.LastSeenAt,
+			&heartbeat.Firmware, &heartbeat.IP)
+		if err != nil {
+			return nil, err
+		}
+		heartbeats = append(heartbeats, heartbeat)
+	}
+	return heartbeats, rows.Err()
+}
+
+func (hr *HeartbeatRepository) MarkTerminalOnline(ctx context.Context, terminalID int, reason string) (bool, error) {
+	var online bool
+	err := WithTx(ctx, hr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		command := `UPDATE terminals SET status = ? WHERE id = ? AND status = ? AND deleted_at IS NULL`
+		res, err := tx.ExecContext(ctx, command, domain.TerminalActive, terminalID, domain.TerminalOffline)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n == 0 {
+			return err
+		}
+		online = true
+		return describeStatusChanges(ctx, tx, []int{terminalID}, reason)
+	})
+	return online, err
+}
+
+func (hr *HeartbeatRepository) MarkTerminalsOffline(ctx context.Context, before time.Time, reason string) ([]domain.Terminal, error) {
+	var terminals []domain.Terminal
+	err := WithTx(ctx, hr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		command := `UPDATE terminals SET status = ?
+			WHERE status = ? AND deleted_at IS NULL
+			AND id IN (SELECT terminal_id FROM terminal_heartbeats WHERE COALESCE(last_seen_at, issued_at) < ?)
+			RETURNING id, name, status`
+		rows, err := tx.QueryContext(ctx, command, domain.TerminalOffline, domain.TerminalActive, before.UTC())
+		if err != nil {
+			return err
+		}
+		terminals = make([]domain.Terminal, 0)
+		ids := make([]int, 0)
+		for rows.Next() {
+			var terminal domain.Terminal
+			if err = rows.Scan(&terminal.ID, &terminal.Name, &terminal.Status); err != nil {
+				rows.Close()
+				return err
+			}
+			terminals = append(terminals, terminal)
+			ids = append(ids, terminal.ID)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return describeStatusChanges(ctx, tx, ids, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return terminals, nil
+}
+
+// describeStatusChanges sets the source and reason of the status changes
+// the trigger just recorded for the terminals in tx.
+func describeStatusChanges(ctx context.Context, tx *sql.Tx, terminalIDs []int, reason string) error {
+	command := `UPDATE terminal_status_changes SET source = ?, reason = ?
+		WHERE id = (SELECT MAX(id) FROM terminal_status_changes WHERE terminal_id = ?)`
+	for _, terminalID := range terminalIDs {
+		if _, err := tx.ExecContext(ctx, command, domain.StatusSourceHeartbeat, reason, terminalID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
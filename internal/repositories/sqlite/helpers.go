@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"time"
+)
+
+// now returns the current time in UTC. SQLite stores timestamps as text, so
+// every stored and compared time must share one zone to order correctly.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func requireAffected(res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+func queryDeletedRecords(ctx context.Context, db Querier, query string, args ...any) ([]domain.DeletedRecord, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]domain.DeletedRecord, 0)
+	for rows.Next() {
+		var record domain.DeletedRecord
+		err = rows.Scan(&record.ID, &record.Name, &record.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func queryIDs(ctx context.Context, db Querier, query string, args ...any) ([]int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+)
+
+const selectNotificationChannels = `SELECT id, COALESCE(user_id, 0), name, kind, target, template, members, created_at
+	FROM notification_channels`
+
+type NotificationRepository struct {
+	db Querier
+}
+
+func NewNotificationRepository(db Querier) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+func (nr *NotificationRepository) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	members, err := marshalMembers(channel.Members)
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	channel.CreatedAt = now()
+	command := `INSERT INTO notification_channels (user_id, name, kind, target, template, members, created_at)
+		VALUES (NULLIF(?, 0), ?, ?, ?, ?, ?, ?) RETURNING id`
+	err = nr.db.QueryRowContext(ctx, command, channel.UserID, channel.Name, channel.Kind, channel.Target, channel.Template,
+		members, channel.CreatedAt).Scan(&channel.ID)
+	return channel, err
+}
+
+func (nr *NotificationRepository) GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error) {
+	rows, err := nr.db.QueryContext(ctx, selectNotificationChannels+` WHERE COALESCE(user_id, 0) = ? ORDER BY id`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make([]domain.NotificationChannel, 0)
+	for rows.Next() {
+		channel, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (nr *NotificationRepository) GetNotificationChannel(ctx context.Context, userId int, id int) (domain.NotificationChannel, error) {
+	row := nr.db.QueryRowContext(ctx, selectNotificationChannels+` WHERE id = ? AND COALESCE(user_id, 0) = ?`, id, userId)
+	channel, err := scanNotificationChannel(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotificationChannel{}, fmt.Errorf("notification channel %d: %w", id, repositories.ErrNotFound)
+	}
+	return channel, err
+}
+
+func (nr *NotificationRepository) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) error {
+	members, err := marshalMembers(channel.Members)
+	if err != nil {
+		return err
+	}
+	command := `UPDATE notification_channels SET name = ?, kind = ?, target = ?, template = ?, members = ?
+		WHERE id = ? AND COALESCE(user_id, 0) = ?`
+	res, err := nr.db.ExecContext(ctx, command, channel.Name, channel.Kind, channel.Target, channel.Template, members,
+		channel.ID, channel.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("notification channel %d: %w", channel.ID, repositories.ErrNotFound))
+}
+
+func (nr *NotificationRepository) DeleteNotificationChannel(ctx context.Context, userId int, id int) error {
+	res, err := nr.db.ExecContext(ctx, `DELETE FROM notification_channels WHERE id = ? AND COALESCE(user_id, 0) = ?`, id, userId)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("notification channel %d: %w", id, repositories.ErrNotFound))
+}
+
+func scanNotificationChannel(row scanner) (domain.NotificationChannel, error) {
+	var channel domain.NotificationChannel
+	var members string
+	err := row.Scan(&channel.ID, &channel.UserID, &channel.Name, &channel.Kind, &channel.Target, &channel.Template, &members, &channel.CreatedAt)
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	channel.Members, err = unmarshalMembers(channel.ID, members)
+	return channel, err
+}
+
+// marshalMembers encodes the members column.
+func marshalMembers(members []int) (string, error) {
+	if members == nil {
+		members = []int{}
+	}
+	data, err := json.Marshal(members)
+	return string(data), err
+}
+
+func unmarshalMembers(id int, data string) ([]int, error) {
+	var members []int
+	if err := json.Unmarshal([]byte(data), &members); err != nil {
+		return nil, fmt.Errorf("notification channel %d members: %w", id, err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	return members, nil
+}
@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"database/sql"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+)
+
+func NewRepositoryPort(db *sql.DB) *repositories.RepositoryPort {
+	return newRepositoryPort(db)
+}
+
+func newRepositoryPort(db Querier) *repositories.RepositoryPort {
+	return &repositories.RepositoryPort{
+		UserRepositoryPort:         NewUserRepository(db),
+		TerminalRepositoryPort:     NewTerminalRepository(db),
+		AuditRepositoryPort:        NewAuditRepository(db),
+		AlertRepositoryPort:        NewAlertRepository(db),
+		WebhookRepositoryPort:      NewWebhookRepository(db),
+		NotificationRepositoryPort: NewNotificationRepository(db),
+		HeartbeatRepositoryPort:    NewHeartbeatRepository(db),
+		UnitOfWork:                 &unitOfWork{db: db},
+	}
+}
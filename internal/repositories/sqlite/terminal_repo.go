@@ -0,0 +1,409 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"time"
+)
+
+var favoritesTxOptions = repositories.TxOptions{
+	IsoLevel:   repositories.Serializable,
+	MaxRetries: 3,
+}
+
+type TerminalRepository struct {
+	db Querier
+}
+
+func NewTerminalRepository(db Querier) *TerminalRepository {
+	return &TerminalRepository{
+		db: db,
+	}
+}
+
+func (tr *TerminalRepository) AddToFavorites(ctx context.Context, terminalId int, userId int) error {
+	log := logger.FromContext(ctx)
+	preCheckQuery := `SELECT EXISTS (SELECT 1 FROM terminals WHERE id = ? AND deleted_at IS NULL)`
+	preCheckIfTerminalFavorited := `SELECT COUNT(*) FROM favorite_terminals, json_each(favorite_terminals.terminal_id) WHERE user_id = ? AND json_each.value = ?`
+	preCheckUserExists := `SELECT EXISTS (SELECT 1 FROM favorite_terminals WHERE user_id = ?)`
+	insertCommand := `INSERT INTO favorite_terminals(user_id, terminal_id, version) VALUES (?, json_array(?), 1)`
+	updateCommand := `UPDATE favorite_terminals SET terminal_id = json_insert(terminal_id, '$[#]', ?), version = version + 1 WHERE user_id = ?`
+	deleteRemovedNote := `DELETE FROM favorite_notes WHERE user_id = ? AND terminal_id = ? AND removed_at IS NOT NULL`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx, preCheckQuery, terminalId).Scan(&exists)
+		if err != nil {
+			log.Error(err)
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("terminal with ID %d doesnt exist in table %s", terminalId, "terminals")
+		}
+
+		var count int
+		err = tx.QueryRowContext(ctx, preCheckIfTerminalFavorited, userId, terminalId).Scan(&count)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("terminal with given ID %d is alredy favorited by user_id %d", terminalId, userId)
+		}
+
+		// A favorite added afresh does not inherit the note it had when it
+		// was last removed.
+		_, err = tx.ExecContext(ctx, deleteRemovedNote, userId, terminalId)
+		if err != nil {
+			return err
+		}
+
+		var userExists bool
+		err = tx.QueryRowContext(ctx, preCheckUserExists, userId).Scan(&userExists)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if userExists {
+			_, err = tx.ExecContext(ctx, updateCommand, terminalId, userId)
+			if err != nil {
+				log.Errorf("failed to append element to terminal_id array: %v", err)
+				return err
+			}
+		} else {
+			_, err = tx.ExecContext(ctx, insertCommand, userId, terminalId)
+			if err != nil {
+				log.Errorf("failed to insert a value to terminal_id array: %v", err)
+				return err
+			}
+		}
+		return recordWebhookEvent(ctx, tx, domain.WebhookEvent{Type: domain.WebhookFavoriteAdd, TerminalID: terminalId, UserID: userId})
+	})
+}
+
+func (tr *TerminalRepository) CreateFavorites(ctx context.Context, userId int) error {
+	command := `INSERT INTO favorite_terminals(user_id, terminal_id) VALUES (?, '[]')`
+	_, err := tr.db.ExecContext(ctx, command, userId)
+	if err != nil {
+		return fmt.Errorf("failed to create favorites: %v", err)
+	}
+	return nil
+}
+
+// GetFavoriteTerminalIds returns the user's favorites in the order they were
+// added, skipping terminals that are soft-deleted.
+func (tr *TerminalRepository) GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error) {
+	query := `SELECT t.id
+		FROM favorite_terminals f, json_each(f.terminal_id) AS u
+		JOIN terminals t ON t.id = u.value AND t.deleted_at IS NULL
+		WHERE f.user_id = ?
+		ORDER BY u.key`
+	rows, err := tr.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terminalIDs []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		terminalIDs = append(terminalIDs, id)
+	}
+	return terminalIDs, rows.Err()
+}
+
+func (tr *TerminalRepository) GetFavoritesVersion(ctx context.Context, userId int) (int64, error) {
+	query := `SELECT COALESCE(MAX(version), 0) FROM favorite_terminals WHERE user_id = ?`
+	var version int64
+	err := tr.db.QueryRowContext(ctx, query, userId).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (tr *TerminalRepository) GetDefaultTerminalsList(ctx context.Context) ([]domain.Terminal, error) {
+	query := `SELECT id, name, status, status_changed_at, latitude, longitude, address, COALESCE(site_id, 0)
+		FROM terminals WHERE deleted_at IS NULL ORDER BY id`
+	rows, err := tr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terminals := make([]domain.Terminal, 0)
+	for rows.Next() {
+		var terminal domain.Terminal
+		var lat, lon *float64
+		err = rows.Scan(&terminal.ID, &terminal.Name, &terminal.Status, &terminal.StatusChangedAt, &lat, &lon, &terminal.Address, &terminal.SiteID)
+		if err != nil {
+			return nil, err
+		}
+		terminal.Location = geoPoint(lat, lon)
+		terminals = append(terminals, terminal)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return terminals, tr.loadTags(ctx, terminals)
+}
+
+// loadTags fills in the tags of terminals.
+func (tr *TerminalRepository) loadTags(ctx context.Context, terminals []domain.Terminal) error {
+	query := `SELECT tt.terminal_id, tg.key, tg.value
+		FROM terminal_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		JOIN terminals t ON t.id = tt.terminal_id AND t.deleted_at IS NULL
+		ORDER BY tt.terminal_id, tg.key, tg.value`
+	rows, err := tr.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]domain.Tag)
+	for rows.Next() {
+		var id int
+		var tag domain.Tag
+		err = rows.Scan(&id, &tag.Key, &tag.Value)
+		if err != nil {
+			return err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	for i := range terminals {
+		terminals[i].Tags = tags[terminals[i].ID]
+	}
+	return rows.Err()
+}
+
+func (tr *TerminalRepository) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	return WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM terminals WHERE id = ? AND deleted_at IS NULL)`, terminalID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("terminal with ID %d: %w", terminalID, repositories.ErrNotFound)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO tags (key, value) VALUES (?, ?) ON CONFLICT (key, value) DO NOTHING`, tag.Key, tag.Value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO terminal_tags (terminal_id, tag_id)
+			SELECT ?, id FROM tags WHERE key = ? AND value = ?
+			ON CONFLICT DO NOTHING`, terminalID, tag.Key, tag.Value)
+		return err
+	})
+}
+
+func (tr *TerminalRepository) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	command := `DELETE FROM terminal_tags
+		WHERE terminal_id = ? AND tag_id = (SELECT id FROM tags WHERE key = ? AND value = ?)`
+	res, err := tr.db.ExecContext(ctx, command, terminalID, tag.Key, tag.Value)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("tag %s on terminal with ID %d: %w", tag, terminalID, repositories.ErrNotFound))
+}
+
+func (tr *TerminalRepository) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error {
+	var lat, lon *float64
+	if location.Location != nil {
+		lat, lon = &location.Location.Lat, &location.Location.Lon
+	}
+	command := `UPDATE terminals SET latitude = ?, longitude = ?, address = ? WHERE id = ? AND deleted_at IS NULL`
+	res, err := tr.db.ExecContext(ctx, command, lat, lon, location.Address, terminalID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("terminal with ID %d: %w", terminalID, repositories.ErrNotFound))
+}
+
+// geoPoint is the location stored as lat and lon, nil unless both are set.
+func geoPoint(lat, lon *float64) *domain.GeoPoint {
+	if lat == nil || lon == nil {
+		return nil
+	}
+	return &domain.GeoPoint{Lat: *lat, Lon: *lon}
+}
+
+func (tr *TerminalRepository) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error {
+	preCheckQuery := `SELECT COUNT(*) FROM favorite_terminals, json_each(favorite_terminals.terminal_id) WHERE user_id = ? AND json_each.value = ?`
+	command := `UPDATE favorite_terminals SET terminal_id = (
+		SELECT json_group_array(value) FROM (
+			SELECT value FROM json_each(favorite_terminals.terminal_id) WHERE value != ? ORDER BY key
+		)
+	), version = version + 1 WHERE user_id = ?`
+	forgetRemovedNotes := `DELETE FROM favorite_notes WHERE user_id = ? AND removed_at IS NOT NULL`
+	keepRemovedNote := `INSERT INTO favorite_notes (user_id, terminal_id, removed_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, terminal_id) DO UPDATE SET removed_at = excluded.removed_at`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRowContext(ctx, preCheckQuery, userId, terminalID).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("terminal with %v ID has already removed", terminalID)
+		}
+		_, err = tx.ExecContext(ctx, command, terminalID, userId)
+		if err != nil {
+			return err
+		}
+		// The removal is recorded even without a note, so undo knows which
+		// favorite to bring back.
+		_, err = tx.ExecContext(ctx, forgetRemovedNotes, userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, keepRemovedNote, userId, terminalID, now())
+		if err != nil {
+			return err
+		}
+		return recordWebhookEvent(ctx, tx, domain.WebhookEvent{Type: domain.WebhookFavoriteRemove, TerminalID: terminalID, UserID: userId})
+	})
+}
+
+func (tr *TerminalRepository) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error {
+	preCheckQuery := `SELECT EXISTS (SELECT 1 FROM favorite_terminals f, json_each(f.terminal_id) AS u
+		JOIN terminals t ON t.id = u.value AND t.deleted_at IS NULL
+		WHERE f.user_id = ? AND u.value = ?)`
+	upsertCommand := `INSERT INTO favorite_notes (user_id, terminal_id, alias, color, note) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, terminal_id) DO UPDATE
+		SET alias = excluded.alias, color = excluded.color, note = excluded.note, removed_at = NULL`
+	deleteCommand := `DELETE FROM favorite_notes WHERE user_id = ? AND terminal_id = ?`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx *sql.Tx) error {
+		var favorited bool
+		err := tx.QueryRowContext(ctx, preCheckQuery, userId, terminalID).Scan(&favorited)
+		if err != nil {
+			return err
+		}
+		if !favorited {
+			return fmt.Errorf("favorite terminal with ID %d of user_id %d: %w", terminalID, userId, repositories.ErrNotFound)
+		}
+		if note.IsZero() {
+			_, err = tx.ExecContext(ctx, deleteCommand, userId, terminalID)
+		} else {
+			_, err = tx.ExecContext(ctx, upsertCommand, userId, terminalID, note.Alias, note.Color, note.Note)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE favorite_terminals SET version = version + 1 WHERE user_id = ?`, userId)
+		return err
+	})
+}
+
+func (tr *TerminalRepository) GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error) {
+	query := `SELECT terminal_id, alias, color, note FROM favorite_notes WHERE user_id = ? AND removed_at IS NULL`
+	rows, err := tr.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := make(map[int]domain.FavoriteNote)
+	for rows.Next() {
+		var id int
+		var note domain.FavoriteNote
+		err = rows.Scan(&id, &note.Alias, &note.Color, &note.Note)
+		if err != nil {
+			return nil, err
+		}
+		notes[id] = note
+	}
+	return notes, rows.Err()
+}
+
+func (tr *TerminalRepository) RestoreRemovedFavoriteNote(ctx context.Context, userId int) (int, error) {
+	restoreCommand := `UPDATE favorite_notes SET removed_at = NULL WHERE user_id = ? AND removed_at IS NOT NULL RETURNING terminal_id`
+	// An empty note only marked which favorite was removed.
+	deleteEmptyCommand := `DELETE FROM favorite_notes
+		WHERE user_id = ? AND terminal_id = ? AND alias = '' AND color = '' AND note = ''`
+
+	var terminalID int
+	err := WithTx(ctx, tr.db, favoritesTxOptions, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, restoreCommand, userId).Scan(&terminalID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("removed favorite of user_id %d: %w", userId, repositories.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, deleteEmptyCommand, userId, terminalID)
+		return err
+	})
+	return terminalID, err
+}
+
+func (tr *TerminalRepository) SoftDeleteTerminal(ctx context.Context, id int) error {
+	command := `UPDATE terminals SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	res, err := tr.db.ExecContext(ctx, command, now(), id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("terminal with ID %d: %w", id, repositories.ErrNotFound))
+}
+
+func (tr *TerminalRepository) RestoreTerminal(ctx context.Context, id int) error {
+	command := `UPDATE terminals SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+	res, err := tr.db.ExecContext(ctx, command, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("deleted terminal with ID %d: %w", id, repositories.ErrNotFound))
+}
+
+func (tr *TerminalRepository) GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error) {
+	query := `SELECT id, name, deleted_at FROM terminals WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`
+	return queryDeletedRecords(ctx, tr.db, query)
+}
+
+func (tr *TerminalRepository) PurgeTerminal(ctx context.Context, id int) error {
+	removeCommand := `UPDATE favorite_terminals SET terminal_id = (
+		SELECT json_group_array(value) FROM (
+			SELECT value FROM json_each(favorite_terminals.terminal_id) WHERE value != ? ORDER BY key
+		)
+	), version = version + 1 WHERE EXISTS (SELECT 1 FROM json_each(favorite_terminals.terminal_id) WHERE value = ?)`
+
+	return WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM terminals WHERE id = ? AND deleted_at IS NOT NULL`, id)
+		if err != nil {
+			return err
+		}
+		err = requireAffected(res, fmt.Errorf("deleted terminal with ID %d: %w", id, repositories.ErrNotFound))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, removeCommand, id, id)
+		return err
+	})
+}
+
+func (tr *TerminalRepository) CountFavorites(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*)
+		FROM favorite_terminals f, json_each(f.terminal_id) AS u
+		JOIN terminals t ON t.id = u.value AND t.deleted_at IS NULL
+		JOIN users us ON us.id = f.user_id AND us.deleted_at IS NULL`
+	var count int
+	err := tr.db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+func (tr *TerminalRepository) GetTerminalsDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	query := `SELECT id FROM terminals WHERE deleted_at < ? ORDER BY id`
+	return queryIDs(ctx, tr.db, query, before.UTC())
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+)
+
+// siteExists and siteInSubtree are run inside the writing transaction, so
+// the checks hold when the write commits.
+const (
+	siteExists    = `SELECT EXISTS (SELECT 1 FROM sites WHERE id = ?)`
+	siteInSubtree = `WITH RECURSIVE subtree (id) AS (
+			SELECT ?
+			UNION
+			SELECT s.id FROM sites s JOIN subtree ON s.parent_id = subtree.id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = ?)`
+)
+
+var siteMoveTxOptions = repositories.TxOptions{
+	IsoLevel:   repositories.Serializable,
+	MaxRetries: 3,
+}
+
+func (tr *TerminalRepository) CreateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	command := `INSERT INTO sites (parent_id, name) VALUES (NULLIF(?, 0), ?) RETURNING id`
+	err := WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		if err := requireSite(ctx, tx, site.ParentID); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, command, site.ParentID, site.Name).Scan(&site.ID)
+	})
+	return site, err
+}
+
+func (tr *TerminalRepository) GetSites(ctx context.Context) ([]domain.Site, error) {
+	rows, err := tr.db.QueryContext(ctx, `SELECT id, COALESCE(parent_id, 0), name FROM sites ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := make([]domain.Site, 0)
+	for rows.Next() {
+		var site domain.Site
+		err = rows.Scan(&site.ID, &site.ParentID, &site.Name)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+func (tr *TerminalRepository) UpdateSite(ctx context.Context, site domain.Site) error {
+	command := `UPDATE sites SET parent_id = NULLIF(?, 0), name = ? WHERE id = ?`
+	return WithTx(ctx, tr.db, siteMoveTxOptions, func(tx *sql.Tx) error {
+		if err := requireSite(ctx, tx, site.ParentID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, command, site.ParentID, site.Name, site.ID)
+		if err != nil {
+			return err
+		}
+		err = requireAffected(res, fmt.Errorf("site with ID %d: %w", site.ID, repositories.ErrNotFound))
+		if err != nil {
+			return err
+		}
+		// The move is checked once made; it is rolled back if it closed a
+		// loop.
+		var cycle bool
+		err = tx.QueryRowContext(ctx, siteInSubtree, site.ID, site.ParentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("%w: site %d cannot be moved below itself", domain.ErrInvalidSite, site.ID)
+		}
+		return nil
+	})
+}
+
+func (tr *TerminalRepository) DeleteSite(ctx context.Context, id int) error {
+	notEmpty := `SELECT EXISTS (SELECT 1 FROM sites WHERE parent_id = ?)
+		OR EXISTS (SELECT 1 FROM terminals WHERE site_id = ? AND deleted_at IS NULL)`
+	return WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		var inUse bool
+		err := tx.QueryRowContext(ctx, notEmpty, id, id).Scan(&inUse)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return fmt.Errorf("site with ID %d: %w", id, domain.ErrSiteNotEmpty)
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM sites WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		return requireAffected(res, fmt.Errorf("site with ID %d: %w", id, repositories.ErrNotFound))
+	})
+}
+
+func (tr *TerminalRepository) SetTerminalSite(ctx context.Context, terminalID int, siteID int) error {
+	command := `UPDATE terminals SET site_id = NULLIF(?, 0) WHERE id = ? AND deleted_at IS NULL`
+	return WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		if err := requireSite(ctx, tx, siteID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, command, siteID, terminalID)
+		if err != nil {
+			return err
+		}
+		return requireAffected(res, fmt.Errorf("terminal with ID %d: %w", terminalID, repositories.ErrNotFound))
+	})
+}
+
+// requireSite fails with repositories.ErrNotFound unless id is 0 or an
+// existing site.
+func requireSite(ctx context.Context, tx *sql.Tx, id int) error {
+	if id == 0 {
+		return nil
+	}
+	var exists bool
+	err := tx.QueryRowContext(ctx, siteExists, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("site with ID %d: %w", id, repositories.ErrNotFound)
+	}
+	return nil
+}
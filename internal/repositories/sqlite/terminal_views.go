@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"net/url"
+)
+
+const selectViews = `SELECT v.id, v.name, v.user_id, u.name, v.params, v.is_default, v.shared, v.updated_at
+	FROM views v
+	JOIN users u ON u.id = v.user_id AND u.deleted_at IS NULL`
+
+func (tr *TerminalRepository) SaveView(ctx context.Context, view domain.View) (domain.View, error) {
+	clearDefaultCommand := `UPDATE views SET is_default = FALSE WHERE user_id = ? AND name <> ? AND is_default`
+	upsertCommand := `INSERT INTO views (user_id, name, params, is_default, shared, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, name) DO UPDATE
+		SET params = excluded.params, is_default = excluded.is_default, shared = excluded.shared, updated_at = excluded.updated_at`
+
+	var saved domain.View
+	err := WithTx(ctx, tr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		if view.IsDefault {
+			_, err := tx.ExecContext(ctx, clearDefaultCommand, view.OwnerID, view.Name)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, upsertCommand, view.OwnerID, view.Name, view.Params.Encode(), view.IsDefault, view.Shared, now())
+		if err != nil {
+			return err
+		}
+		saved, err = scanView(tx.QueryRowContext(ctx, selectViews+` WHERE v.user_id = ? AND v.name = ?`, view.OwnerID, view.Name))
+		return err
+	})
+	return saved, err
+}
+
+func (tr *TerminalRepository) GetViews(ctx context.Context, userId int) ([]domain.View, error) {
+	query := selectViews + ` WHERE v.user_id = ? OR v.shared ORDER BY v.user_id <> ?, u.name, v.name`
+	rows, err := tr.db.QueryContext(ctx, query, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]domain.View, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (tr *TerminalRepository) GetView(ctx context.Context, userId int, owner string, name string) (domain.View, error) {
+	query, args := selectViews+` WHERE v.user_id = ? AND v.name = ?`, []any{userId, name}
+	if owner != "" {
+		query, args = selectViews+` WHERE u.name = ? AND v.name = ? AND (v.shared OR v.user_id = ?)`, []any{owner, name, userId}
+	}
+	view, err := scanView(tr.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return view, fmt.Errorf("view %q: %w", name, repositories.ErrNotFound)
+	}
+	return view, err
+}
+
+func (tr *TerminalRepository) GetDefaultView(ctx context.Context, userId int) (domain.View, error) {
+	view, err := scanView(tr.db.QueryRowContext(ctx, selectViews+` WHERE v.user_id = ? AND v.is_default`, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return view, fmt.Errorf("default view of user_id %d: %w", userId, repositories.ErrNotFound)
+	}
+	return view, err
+}
+
+func (tr *TerminalRepository) DeleteView(ctx context.Context, userId int, name string) error {
+	res, err := tr.db.ExecContext(ctx, `DELETE FROM views WHERE user_id = ? AND name = ?`, userId, name)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("view %q: %w", name, repositories.ErrNotFound))
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanView(row scanner) (domain.View, error) {
+	var view domain.View
+	var params string
+	err := row.Scan(&view.ID, &view.Name, &view.OwnerID, &view.Owner, &params, &view.IsDefault, &view.Shared, &view.UpdatedAt)
+	if err != nil {
+		return view, err
+	}
+	view.Params, err = url.ParseQuery(params)
+	return view, err
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Querier is implemented by both *sql.DB and *sql.Tx, so a repository can run
+// either on its own or inside a caller's transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// WithTx is the SQLite counterpart of repositories.WithTx. SQLite transactions
+// are always serializable, so opts.IsoLevel is accepted but has no effect, and
+// a busy or locked database takes the place of a serialization failure when
+// deciding whether to retry.
+func WithTx(ctx context.Context, db Querier, opts repositories.TxOptions, fn func(tx *sql.Tx) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		return fn(tx)
+	}
+	sqlDB, ok := db.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("querier %T cannot begin transactions", db)
+	}
+
+	var err error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		err = runTx(ctx, sqlDB, &sql.TxOptions{ReadOnly: opts.ReadOnly}, fn)
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func runTx(ctx context.Context, db *sql.DB, txOptions *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+type unitOfWork struct {
+	db Querier
+}
+
+func (u *unitOfWork) WithTx(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+	return WithTx(ctx, u.db, opts, func(tx *sql.Tx) error {
+		return fn(newRepositoryPort(tx))
+	})
+}
@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"time"
+)
+
+type UserRepository struct {
+	db Querier
+}
+
+func NewUserRepository(db Querier) *UserRepository {
+	return &UserRepository{
+		db: db,
+	}
+}
+
+func (ur *UserRepository) CreateUser(ctx context.Context, user domain.User) (int, error) {
+	existingUserQuery := `SELECT COUNT(*) FROM users WHERE name = ?`
+	var count int
+	err := ur.db.QueryRowContext(ctx, existingUserQuery, user.Name).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, fmt.Errorf("username already exists")
+	}
+	command := `INSERT INTO users (name, password) VALUES (?, ?) RETURNING id`
+	var id int
+	err = ur.db.QueryRowContext(ctx, command, user.Name, user.Password).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (ur *UserRepository) GetUser(ctx context.Context, username string) (domain.User, error) {
+	var user domain.User
+	query := `SELECT id, name, password, role FROM users WHERE name = ? AND deleted_at IS NULL`
+	err := ur.db.QueryRowContext(ctx, query, username).Scan(&user.ID, &user.Name, &user.Password, &user.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, errors.New("no user found with given name")
+		}
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (ur *UserRepository) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	var user domain.User
+	query := `SELECT id, name, password, role FROM users WHERE id = ? AND deleted_at IS NULL`
+	err := ur.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Password, &user.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, fmt.Errorf("user with ID %d: %w", id, repositories.ErrNotFound)
+		}
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (ur *UserRepository) SoftDeleteUser(ctx context.Context, id int) error {
+	command := `UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	res, err := ur.db.ExecContext(ctx, command, now(), id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("user with ID %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ur *UserRepository) RestoreUser(ctx context.Context, id int) error {
+	command := `UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+	res, err := ur.db.ExecContext(ctx, command, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("deleted user with ID %d: %w", id, repositories.ErrNotFound))
+}
+
+func (ur *UserRepository) GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error) {
+	query := `SELECT id, name, deleted_at FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`
+	return queryDeletedRecords(ctx, ur.db, query)
+}
+
+func (ur *UserRepository) PurgeUser(ctx context.Context, id int) error {
+	return WithTx(ctx, ur.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ? AND deleted_at IS NOT NULL`, id)
+		if err != nil {
+			return err
+		}
+		err = requireAffected(res, fmt.Errorf("deleted user with ID %d: %w", id, repositories.ErrNotFound))
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM favorite_terminals WHERE user_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM favorite_notes WHERE user_id = ?`, id)
+		return err
+	})
+}
+
+func (ur *UserRepository) CountActiveUsers(ctx context.Context) (int, error) {
+	var count int
+	err := ur.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`).Scan(&count)
+	return count, err
+}
+
+func (ur *UserRepository) GetUsersDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	query := `SELECT id FROM users WHERE deleted_at < ? ORDER BY id`
+	return queryIDs(ctx, ur.db, query, before.UTC())
+}
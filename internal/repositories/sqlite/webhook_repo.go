@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"time"
+)
+
+const selectWebhookDeliveries = `SELECT id, webhook_id, event, payload, state, attempts, next_attempt_at,
+	response_status, last_error, created_at, delivered_at
+	FROM webhook_deliveries`
+
+type WebhookRepository struct {
+	db Querier
+}
+
+func NewWebhookRepository(db Querier) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (wr *WebhookRepository) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	events, labels, err := marshalWebhookFilters(hook)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	hook.CreatedAt = now()
+	command := `INSERT INTO webhooks (url, secret, events, terminal_id, labels, created_at)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?) RETURNING id`
+	err = wr.db.QueryRowContext(ctx, command, hook.URL, hook.Secret, events, hook.TerminalID, labels, hook.CreatedAt).Scan(&hook.ID)
+	return hook, err
+}
+
+func (wr *WebhookRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	query := `SELECT id, url, secret, events, COALESCE(terminal_id, 0), labels, created_at FROM webhooks ORDER BY id`
+	rows, err := wr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		var hook domain.Webhook
+		var events, labels string
+		err = rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.TerminalID, &labels, &hook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err = unmarshalWebhookFilters(&hook, events, labels); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (wr *WebhookRepository) DeleteWebhook(ctx context.Context, id int) error {
+	res, err := wr.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("webhook %d: %w", id, repositories.ErrNotFound))
+}
+
+func (wr *WebhookRepository) ClaimWebhookEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+	query := `SELECT id, event, occurred_at, terminal_id, status, user_id FROM webhook_events
+		ORDER BY id LIMIT ?`
+	var events []domain.WebhookEvent
+	err := WithTx(ctx, wr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		events = make([]domain.WebhookEvent, 0)
+		var lastID int64
+		for rows.Next() {
+			var event domain.WebhookEvent
+			err = rows.Scan(&lastID, &event.Type, &event.OccurredAt, &event.TerminalID, &event.Status, &event.UserID)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			events = append(events, event)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil || len(events) == 0 {
+			return err
+		}
+		// SQLite has a single writer, so nothing else can claim the rows
+		// read above before they are deleted.
+		_, err = tx.ExecContext(ctx, `DELETE FROM webhook_events WHERE id <= ?`, lastID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// recordWebhookEvent adds a pending webhook event in tx, so it commits or
+// rolls back with the change it describes.
+func recordWebhookEvent(ctx context.Context, tx *sql.Tx, event domain.WebhookEvent) error {
+	command := `INSERT INTO webhook_events (event, terminal_id, user_id, occurred_at) VALUES (?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, command, event.Type, event.TerminalID, event.UserID, now())
+	return err
+}
+
+func (wr *WebhookRepository) CreateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	command := `INSERT INTO webhook_deliveries (webhook_id, event, payload, state, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := wr.db.ExecContext(ctx, command, delivery.WebhookID, delivery.Event, string(delivery.Payload),
+		domain.DeliveryPending, utc(delivery.NextAttemptAt), now())
+	return err
+}
+
+func (wr *WebhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, due time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	query := selectWebhookDeliveries + ` WHERE state = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`
+	var deliveries []domain.WebhookDelivery
+	err := WithTx(ctx, wr.db, repositories.TxOptions{}, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, domain.DeliveryPending, due.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		deliveries = make([]domain.WebhookDelivery, 0)
+		for rows.Next() {
+			delivery, err := scanWebhookDelivery(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil || len(deliveries) == 0 {
+			return err
+		}
+		// SQLite has a single writer, so the rows read above are the ones
+		// the update matches.
+		leased := now().Add(lease)
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ?
+			WHERE state = ? AND next_attempt_at <= ? AND id <= ?`,
+			leased, domain.DeliveryPending, due.UTC(), deliveries[len(deliveries)-1].ID)
+		if err != nil {
+			return err
+		}
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = &leased
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (wr *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	command := `UPDATE webhook_deliveries SET state = ?, attempts = ?, next_attempt_at = ?,
+		response_status = ?, last_error = ?, delivered_at = ? WHERE id = ?`
+	res, err := wr.db.ExecContext(ctx, command, delivery.State, delivery.Attempts, utc(delivery.NextAttemptAt),
+		delivery.ResponseStatus, delivery.LastError, utc(delivery.DeliveredAt), delivery.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("webhook delivery %d: %w", delivery.ID, repositories.ErrNotFound))
+}
+
+func (wr *WebhookRepository) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error) {
+	query := selectWebhookDeliveries + ` WHERE webhook_id = ?`
+	args := []any{webhookID}
+	if state != "" {
+		query += ` AND state = ?`
+		args = append(args, state)
+	}
+	return wr.queryWebhookDeliveries(ctx, query+` ORDER BY id DESC`, args...)
+}
+
+func (wr *WebhookRepository) RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64) error {
+	command := `UPDATE webhook_deliveries SET state = ?, attempts = 0, next_attempt_at = ?,
+		response_status = 0, last_error = '', delivered_at = NULL WHERE webhook_id = ? AND id = ?`
+	res, err := wr.db.ExecContext(ctx, command, domain.DeliveryPending, now(), webhookID, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res, fmt.Errorf("webhook delivery %d: %w", id, repositories.ErrNotFound))
+}
+
+func (wr *WebhookRepository) queryWebhookDeliveries(ctx context.Context, query string, args ...any) ([]domain.WebhookDelivery, error) {
+	rows, err := wr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhookDelivery(row scanner) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var payload string
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.State, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt)
+	delivery.Payload = json.RawMessage(payload)
+	return delivery, err
+}
+
+// marshalWebhookFilters encodes the events and labels columns.
+func marshalWebhookFilters(hook domain.Webhook) (string, string, error) {
+	events, err := json.Marshal(nonNil(hook.Events))
+	if err != nil {
+		return "", "", err
+	}
+	labels, err := json.Marshal(nonNil(hook.Labels))
+	return string(events), string(labels), err
+}
+
+func unmarshalWebhookFilters(hook *domain.Webhook, events string, labels string) error {
+	if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+		return fmt.Errorf("webhook %d events: %w", hook.ID, err)
+	}
+	if err := json.Unmarshal([]byte(labels), &hook.Labels); err != nil {
+		return fmt.Errorf("webhook %d labels: %w", hook.ID, err)
+	}
+	if len(hook.Events) == 0 {
+		hook.Events = nil
+	}
+	if len(hook.Labels) == 0 {
+		hook.Labels = nil
+	}
+	return nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// utc converts t to UTC so stored times compare as text.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -2,183 +2,425 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/dvdxa/add-to-favorites/pkg/logger"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"time"
 )
 
+// favoritesTxOptions guards the check-then-write favorite updates against
+// concurrent requests for the same user.
+var favoritesTxOptions = TxOptions{
+	IsoLevel:   Serializable,
+	MaxRetries: 3,
+}
+
 type TerminalRepository struct {
-	pgxpool *pgxpool.Pool
+	db Querier
+	// read serves listings that tolerate replication lag. It is db unless
+	// a read replica is configured.
+	read Querier
 }
 
-func NewTerminalRepository(pgxpool *pgxpool.Pool) *TerminalRepository {
+func NewTerminalRepository(db Querier) *TerminalRepository {
 	return &TerminalRepository{
-		pgxpool: pgxpool,
+		db:   db,
+		read: db,
 	}
 }
 
-func (tr *TerminalRepository) AddToFavorites(terminalId int, userId int) error {
-	log := logger.GetLogger()
-	preCheckQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)", "terminals")
+func (tr *TerminalRepository) AddToFavorites(ctx context.Context, terminalId int, userId int) error {
+	log := logger.FromContext(ctx)
+	preCheckQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND deleted_at IS NULL)", "terminals")
 	preCheckIfTerminalFavorited := `SELECT COUNT(*) FROM favorite_terminals WHERE user_id = $1 AND $2 = ANY(terminal_id)`
 	preCheckUserExists := `SELECT EXISTS (SELECT 1 FROM favorite_terminals WHERE user_id = $1)`
-	insertCommand := `INSERT INTO favorite_terminals(user_id, terminal_id) VALUES ($1, ARRAY[$2::integer])`
-	updateCommand := `UPDATE favorite_terminals SET terminal_id = array_append(terminal_id, $2) WHERE user_id = $1`
-	tx, err := tr.pgxpool.Begin(context.Background())
+	insertCommand := `INSERT INTO favorite_terminals(user_id, terminal_id, version) VALUES ($1, ARRAY[$2::integer], 1)`
+	updateCommand := `UPDATE favorite_terminals SET terminal_id = array_append(terminal_id, $2), version = version + 1 WHERE user_id = $1`
+	deleteRemovedNote := `DELETE FROM favorite_notes WHERE user_id = $1 AND terminal_id = $2 AND removed_at IS NOT NULL`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx, preCheckQuery, terminalId).Scan(&exists)
+		if err != nil {
+			log.Error(err)
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("terminal with ID %d doesnt exist in table %s", terminalId, "terminals")
+		}
+
+		var count int
+		err = tx.QueryRow(ctx, preCheckIfTerminalFavorited, userId, terminalId).Scan(&count)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("terminal with given ID %d is alredy favorited by user_id %d", terminalId, userId)
+		}
+
+		// A favorite added afresh does not inherit the note it had when it
+		// was last removed.
+		_, err = tx.Exec(ctx, deleteRemovedNote, userId, terminalId)
+		if err != nil {
+			return err
+		}
+
+		//check if user_id exists in favorite_terminals table
+		var userExists bool
+		err = tx.QueryRow(ctx, preCheckUserExists, userId).Scan(&userExists)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if userExists {
+			_, err = tx.Exec(ctx, updateCommand, userId, terminalId)
+			if err != nil {
+				log.Errorf("failed to append element to terminal_id array: %v", err)
+				return err
+			}
+		} else {
+			_, err = tx.Exec(ctx, insertCommand, userId, terminalId)
+			if err != nil {
+				log.Errorf("failed to insert a value to terminal_id array: %v", err)
+				return err
+			}
+		}
+		return recordWebhookEvent(ctx, tx, domain.WebhookEvent{Type: domain.WebhookFavoriteAdd, TerminalID: terminalId, UserID: userId})
+	})
+}
 
+func (tr *TerminalRepository) CreateFavorites(ctx context.Context, userId int) error {
+	command := `INSERT INTO favorite_terminals(user_id, terminal_id) VALUES ($1, '{}')`
+	_, err := tr.db.Exec(ctx, command, userId)
 	if err != nil {
-		log.Error(err)
-		return fmt.Errorf("failed to begin tx: %v", err)
+		return fmt.Errorf("failed to create favorites: %v", err)
 	}
-	var exists bool
-	err = tx.QueryRow(context.Background(), preCheckQuery, terminalId).Scan(&exists)
+	return nil
+}
+
+// GetFavoriteTerminalIds returns the user's favorites in the order they were
+// added, skipping terminals that are soft-deleted.
+func (tr *TerminalRepository) GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error) {
+	query := `SELECT u.id
+		FROM favorite_terminals f
+		CROSS JOIN LATERAL unnest(f.terminal_id) WITH ORDINALITY AS u(id, pos)
+		JOIN terminals t ON t.id = u.id AND t.deleted_at IS NULL
+		WHERE f.user_id = $1
+		ORDER BY u.pos`
+	rows, err := tr.read.Query(ctx, query, userId)
 	if err != nil {
-		err = tx.Rollback(context.Background())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terminalIDs []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
 		if err != nil {
-			log.Error(err)
-			return fmt.Errorf("failed to rollback tx: %v", err)
+			return nil, err
 		}
-		return fmt.Errorf("error executing query: %v", err)
+		terminalIDs = append(terminalIDs, id)
 	}
-	if !exists {
-		return fmt.Errorf("terminal with ID %d doesnt exist in table %s", terminalId, "terminals")
+	return terminalIDs, rows.Err()
+}
+
+// GetFavoritesVersion returns the counter bumped by every change to the
+// user's favorites, or 0 if the user has none yet.
+func (tr *TerminalRepository) GetFavoritesVersion(ctx context.Context, userId int) (int64, error) {
+	query := `SELECT COALESCE(MAX(version), 0) FROM favorite_terminals WHERE user_id = $1`
+	var version int64
+	err := tr.read.QueryRow(ctx, query, userId).Scan(&version)
+	if err != nil {
+		return 0, err
 	}
+	return version, nil
+}
 
-	var count int
-	err = tx.QueryRow(context.Background(), preCheckIfTerminalFavorited, userId, terminalId).Scan(&count)
+func (tr *TerminalRepository) GetDefaultTerminalsList(ctx context.Context) ([]domain.Terminal, error) {
+	query := `SELECT id, name, status, status_changed_at, latitude, longitude, address, COALESCE(site_id, 0)
+		FROM terminals WHERE deleted_at IS NULL ORDER BY id`
+	rows, err := tr.read.Query(ctx, query)
 	if err != nil {
-		log.Error(err)
-		err = tx.Rollback(context.Background())
+		return nil, err
+	}
+	defer rows.Close()
+
+	terminals := make([]domain.Terminal, 0)
+	for rows.Next() {
+		var terminal domain.Terminal
+		var lat, lon *float64
+		err = rows.Scan(&terminal.ID, &terminal.Name, &terminal.Status, &terminal.StatusChangedAt, &lat, &lon, &terminal.Address, &terminal.SiteID)
 		if err != nil {
-			return fmt.Errorf("failed to rollback tx: %v", err)
+			return nil, err
 		}
-		return err
+		terminal.Location = geoPoint(lat, lon)
+		terminals = append(terminals, terminal)
 	}
-	if count > 0 {
-		return fmt.Errorf("terminal with given ID %d is alredy favorited by user_id %d", terminalId, userId)
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
+	return terminals, tr.loadTags(ctx, terminals)
+}
 
-	//check if user_id exists in favorite_terminals table
-	var userExists bool
-	err = tx.QueryRow(context.Background(), preCheckUserExists, userId).Scan(&userExists)
+// loadTags fills in the tags of terminals.
+func (tr *TerminalRepository) loadTags(ctx context.Context, terminals []domain.Terminal) error {
+	query := `SELECT tt.terminal_id, tg.key, tg.value
+		FROM terminal_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		JOIN terminals t ON t.id = tt.terminal_id AND t.deleted_at IS NULL
+		ORDER BY tt.terminal_id, tg.key, tg.value`
+	rows, err := tr.read.Query(ctx, query)
 	if err != nil {
-		log.Error(err)
-		err = tx.Rollback(context.Background())
+		return err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]domain.Tag)
+	for rows.Next() {
+		var id int
+		var tag domain.Tag
+		err = rows.Scan(&id, &tag.Key, &tag.Value)
 		if err != nil {
-			log.Error(err)
-			return fmt.Errorf("failed to rollback tx: %v", err)
+			return err
 		}
-		return err
+		tags[id] = append(tags[id], tag)
+	}
+	for i := range terminals {
+		terminals[i].Tags = tags[terminals[i].ID]
 	}
-	if userExists {
-		_, err = tx.Exec(context.Background(), updateCommand, userId, terminalId)
+	return rows.Err()
+}
+
+func (tr *TerminalRepository) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	return WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM terminals WHERE id = $1 AND deleted_at IS NULL)`, terminalID).Scan(&exists)
 		if err != nil {
-			log.Errorf("failed to append element to terminal_id array: %v", err)
-			err = tx.Rollback(context.Background())
-			if err != nil {
-				log.Errorf("failed to rollback: %v", err)
-				return fmt.Errorf("failed to rollback tx: %v", err)
-			}
 			return err
 		}
-	} else {
-		_, err = tx.Exec(context.Background(), insertCommand, userId, terminalId)
+		if !exists {
+			return fmt.Errorf("terminal with ID %d: %w", terminalID, ErrNotFound)
+		}
+		// The no-op update makes RETURNING yield the ID of an existing tag.
+		var tagID int
+		err = tx.QueryRow(ctx, `INSERT INTO tags (key, value) VALUES ($1, $2)
+			ON CONFLICT (key, value) DO UPDATE SET key = EXCLUDED.key
+			RETURNING id`, tag.Key, tag.Value).Scan(&tagID)
 		if err != nil {
-			log.Error("failed to insert a value to terminal_id array: %v", err)
-			err = tx.Rollback(context.Background())
-			if err != nil {
-				return fmt.Errorf("failed to rollback tx: %v", err)
-			}
 			return err
 		}
-	}
+		_, err = tx.Exec(ctx, `INSERT INTO terminal_tags (terminal_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, terminalID, tagID)
+		return err
+	})
+}
 
-	err = tx.Commit(context.Background())
+func (tr *TerminalRepository) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	command := `DELETE FROM terminal_tags
+		WHERE terminal_id = $1 AND tag_id = (SELECT id FROM tags WHERE key = $2 AND value = $3)`
+	res, err := tr.db.Exec(ctx, command, terminalID, tag.Key, tag.Value)
 	if err != nil {
-		return fmt.Errorf("failed to commit tx: %v", err)
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("tag %s on terminal with ID %d: %w", tag, terminalID, ErrNotFound)
 	}
 	return nil
 }
 
-func (tr *TerminalRepository) GetFavoriteTerminalIds(userId int) ([]int, error) {
-	query := `SELECT terminal_id FROM favorite_terminals WHERE user_id = $1;`
-	var terminalIDs []int
-	row := tr.pgxpool.QueryRow(context.Background(), query, userId)
-	_ = row.Scan(&terminalIDs)
-	//if err != nil {
-	//	log.Errorf("failed to scan row: %v", err)
-	//	return nil, err
-	//}
-	return terminalIDs, nil
-}
-
-func (tr *TerminalRepository) GetDefaultTerminalsList() ([]domain.Terminal, error) {
-	query := `SELECT * FROM terminals`
-	tx, err := tr.pgxpool.Begin(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin tx: %v", err)
+func (tr *TerminalRepository) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error {
+	var lat, lon *float64
+	if location.Location != nil {
+		lat, lon = &location.Location.Lat, &location.Location.Lon
 	}
-	rows, err := tx.Query(context.Background(), query)
+	command := `UPDATE terminals SET latitude = $2, longitude = $3, address = $4 WHERE id = $1 AND deleted_at IS NULL`
+	res, err := tr.db.Exec(ctx, command, terminalID, lat, lon, location.Address)
 	if err != nil {
-		err = tx.Rollback(context.Background())
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("terminal with ID %d: %w", terminalID, ErrNotFound)
+	}
+	return nil
+}
+
+// geoPoint is the location stored as lat and lon, nil unless both are set.
+func geoPoint(lat, lon *float64) *domain.GeoPoint {
+	if lat == nil || lon == nil {
+		return nil
+	}
+	return &domain.GeoPoint{Lat: *lat, Lon: *lon}
+}
+
+func (tr *TerminalRepository) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error {
+	preCheckQuery := `SELECT COUNT(*) FROM favorite_terminals WHERE user_id = $1 AND $2 = ANY(terminal_id)`
+	command := `UPDATE favorite_terminals SET terminal_id = array_remove(terminal_id, $2), version = version + 1 WHERE user_id = $1`
+	forgetRemovedNotes := `DELETE FROM favorite_notes WHERE user_id = $1 AND removed_at IS NOT NULL`
+	keepRemovedNote := `INSERT INTO favorite_notes (user_id, terminal_id, removed_at) VALUES ($1, $2, now())
+		ON CONFLICT (user_id, terminal_id) DO UPDATE SET removed_at = EXCLUDED.removed_at`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx pgx.Tx) error {
+		var count int
+		err := tx.QueryRow(ctx, preCheckQuery, userId, terminalID).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("terminal with %v ID has already removed", terminalID)
+		}
+		_, err = tx.Exec(ctx, command, userId, terminalID)
+		if err != nil {
+			return err
+		}
+		// The removal is recorded even without a note, so undo knows which
+		// favorite to bring back.
+		_, err = tx.Exec(ctx, forgetRemovedNotes, userId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, keepRemovedNote, userId, terminalID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to rollback tx: %v", err)
+			return err
 		}
+		return recordWebhookEvent(ctx, tx, domain.WebhookEvent{Type: domain.WebhookFavoriteRemove, TerminalID: terminalID, UserID: userId})
+	})
+}
+
+func (tr *TerminalRepository) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error {
+	preCheckQuery := `SELECT EXISTS (SELECT 1 FROM favorite_terminals f
+		JOIN terminals t ON t.id = $2 AND t.deleted_at IS NULL
+		WHERE f.user_id = $1 AND $2 = ANY(f.terminal_id))`
+	upsertCommand := `INSERT INTO favorite_notes (user_id, terminal_id, alias, color, note) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, terminal_id) DO UPDATE
+		SET alias = EXCLUDED.alias, color = EXCLUDED.color, note = EXCLUDED.note, removed_at = NULL`
+	deleteCommand := `DELETE FROM favorite_notes WHERE user_id = $1 AND terminal_id = $2`
+
+	return WithTx(ctx, tr.db, favoritesTxOptions, func(tx pgx.Tx) error {
+		var favorited bool
+		err := tx.QueryRow(ctx, preCheckQuery, userId, terminalID).Scan(&favorited)
+		if err != nil {
+			return err
+		}
+		if !favorited {
+			return fmt.Errorf("favorite terminal with ID %d of user_id %d: %w", terminalID, userId, ErrNotFound)
+		}
+		if note.IsZero() {
+			_, err = tx.Exec(ctx, deleteCommand, userId, terminalID)
+		} else {
+			_, err = tx.Exec(ctx, upsertCommand, userId, terminalID, note.Alias, note.Color, note.Note)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE favorite_terminals SET version = version + 1 WHERE user_id = $1`, userId)
+		return err
+	})
+}
+
+func (tr *TerminalRepository) GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error) {
+	query := `SELECT terminal_id, alias, color, note FROM favorite_notes WHERE user_id = $1 AND removed_at IS NULL`
+	rows, err := tr.read.Query(ctx, query, userId)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	terminals := make([]domain.Terminal, 0)
+	notes := make(map[int]domain.FavoriteNote)
 	for rows.Next() {
-		var terminal domain.Terminal
-		err = rows.Scan(&terminal.ID, &terminal.Name, &terminal.Status)
+		var id int
+		var note domain.FavoriteNote
+		err = rows.Scan(&id, &note.Alias, &note.Color, &note.Note)
 		if err != nil {
-			err = tx.Rollback(context.Background())
-			if err != nil {
-				return nil, fmt.Errorf("failed to rollback tx: %v", err)
-			}
 			return nil, err
 		}
-		terminals = append(terminals, terminal)
+		notes[id] = note
 	}
-	return terminals, nil
+	return notes, rows.Err()
 }
 
-func (tr *TerminalRepository) RemoveFromFavoriteTerminal(terminalID int, userId int) error {
-	preCheckQuery := `SELECT COUNT(*) FROM favorite_terminals WHERE user_id = $1 AND $2 = ANY(terminal_id)`
-	command := `UPDATE favorite_terminals SET terminal_id = array_remove(terminal_id, $2) WHERE user_id = $1`
+func (tr *TerminalRepository) RestoreRemovedFavoriteNote(ctx context.Context, userId int) (int, error) {
+	restoreCommand := `UPDATE favorite_notes SET removed_at = NULL WHERE user_id = $1 AND removed_at IS NOT NULL RETURNING terminal_id`
+	// An empty note only marked which favorite was removed.
+	deleteEmptyCommand := `DELETE FROM favorite_notes
+		WHERE user_id = $1 AND terminal_id = $2 AND alias = '' AND color = '' AND note = ''`
 
-	tx, err := tr.pgxpool.Begin(context.Background())
+	var terminalID int
+	err := WithTx(ctx, tr.db, favoritesTxOptions, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, restoreCommand, userId).Scan(&terminalID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("removed favorite of user_id %d: %w", userId, ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, deleteEmptyCommand, userId, terminalID)
+		return err
+	})
+	return terminalID, err
+}
+
+func (tr *TerminalRepository) SoftDeleteTerminal(ctx context.Context, id int) error {
+	command := `UPDATE terminals SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+	tag, err := tr.db.Exec(ctx, command, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("terminal with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
 
-	var count int
-	err = tx.QueryRow(context.Background(), preCheckQuery, userId, terminalID).Scan(&count)
+func (tr *TerminalRepository) RestoreTerminal(ctx context.Context, id int) error {
+	command := `UPDATE terminals SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	tag, err := tr.db.Exec(ctx, command, id)
 	if err != nil {
-		err = tx.Rollback(context.Background())
-		if err != nil {
-			return fmt.Errorf("failed to rollback tx: %v", err)
-		}
-		return fmt.Errorf("error executing query: %v", err)
+		return err
 	}
-	if count > 0 {
-		_, err = tx.Exec(context.Background(), command, userId, terminalID)
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("deleted terminal with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (tr *TerminalRepository) GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error) {
+	query := `SELECT id, name, deleted_at FROM terminals WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`
+	return queryDeletedRecords(ctx, tr.db, query)
+}
+
+// PurgeTerminal permanently removes a soft-deleted terminal and drops it from
+// every user's favorites.
+func (tr *TerminalRepository) PurgeTerminal(ctx context.Context, id int) error {
+	return WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM terminals WHERE id = $1 AND deleted_at IS NOT NULL`, id)
 		if err != nil {
-			err = tx.Rollback(context.Background())
-			if err != nil {
-				return fmt.Errorf("failed to rollback tx: %v", err)
-			}
 			return err
 		}
-		err = tx.Commit(context.Background())
-		if err != nil {
-			return fmt.Errorf("failed to commit tx: %v", err)
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("deleted terminal with ID %d: %w", id, ErrNotFound)
 		}
-		return nil
-	} else {
-		return fmt.Errorf("terminal with %v ID has already removed", terminalID)
-	}
+		_, err = tx.Exec(ctx, `UPDATE favorite_terminals SET terminal_id = array_remove(terminal_id, $1), version = version + 1 WHERE $1 = ANY(terminal_id)`, id)
+		return err
+	})
+}
+
+// CountFavorites counts favorites of active users on terminals that are not
+// soft-deleted.
+func (tr *TerminalRepository) CountFavorites(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*)
+		FROM favorite_terminals f
+		CROSS JOIN LATERAL unnest(f.terminal_id) AS u(id)
+		JOIN terminals t ON t.id = u.id AND t.deleted_at IS NULL
+		JOIN users us ON us.id = f.user_id AND us.deleted_at IS NULL`
+	var count int
+	err := tr.db.QueryRow(ctx, query).Scan(&count)
+	return count, err
+}
 
+func (tr *TerminalRepository) GetTerminalsDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	query := `SELECT id FROM terminals WHERE deleted_at < $1 ORDER BY id`
+	return queryIDs(ctx, tr.db, query, before)
 }
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+// siteExists and siteInSubtree are run inside the writing transaction, so
+// the checks hold when the write commits.
+const (
+	siteExists    = `SELECT EXISTS (SELECT 1 FROM sites WHERE id = $1)`
+	siteInSubtree = `WITH RECURSIVE subtree (id) AS (
+			SELECT $1::int
+			UNION
+			SELECT s.id FROM sites s JOIN subtree ON s.parent_id = subtree.id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)`
+)
+
+// siteMoveTxOptions makes concurrent moves, such as A under B and B under A,
+// conflict instead of both passing the cycle check.
+var siteMoveTxOptions = TxOptions{
+	IsoLevel:   Serializable,
+	MaxRetries: 3,
+}
+
+func (tr *TerminalRepository) CreateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	command := `INSERT INTO sites (parent_id, name) VALUES (NULLIF($1, 0), $2) RETURNING id`
+	err := WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		if err := requireSite(ctx, tx, site.ParentID); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, command, site.ParentID, site.Name).Scan(&site.ID)
+	})
+	return site, err
+}
+
+func (tr *TerminalRepository) GetSites(ctx context.Context) ([]domain.Site, error) {
+	rows, err := tr.db.Query(ctx, `SELECT id, COALESCE(parent_id, 0), name FROM sites ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sites := make([]domain.Site, 0)
+	for rows.Next() {
+		var site domain.Site
+		err = rows.Scan(&site.ID, &site.ParentID, &site.Name)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rows.Err()
+}
+
+func (tr *TerminalRepository) UpdateSite(ctx context.Context, site domain.Site) error {
+	command := `UPDATE sites SET parent_id = NULLIF($2, 0), name = $3 WHERE id = $1`
+	return WithTx(ctx, tr.db, siteMoveTxOptions, func(tx pgx.Tx) error {
+		if err := requireSite(ctx, tx, site.ParentID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(ctx, command, site.ID, site.ParentID, site.Name)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("site with ID %d: %w", site.ID, ErrNotFound)
+		}
+		// The move is checked once made; it is rolled back if it closed a
+		// loop.
+		var cycle bool
+		err = tx.QueryRow(ctx, siteInSubtree, site.ID, site.ParentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("%w: site %d cannot be moved below itself", domain.ErrInvalidSite, site.ID)
+		}
+		return nil
+	})
+}
+
+func (tr *TerminalRepository) DeleteSite(ctx context.Context, id int) error {
+	notEmpty := `SELECT EXISTS (SELECT 1 FROM sites WHERE parent_id = $1)
+		OR EXISTS (SELECT 1 FROM terminals WHERE site_id = $1 AND deleted_at IS NULL)`
+	return WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		var inUse bool
+		err := tx.QueryRow(ctx, notEmpty, id).Scan(&inUse)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return fmt.Errorf("site with ID %d: %w", id, domain.ErrSiteNotEmpty)
+		}
+		res, err := tx.Exec(ctx, `DELETE FROM sites WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("site with ID %d: %w", id, ErrNotFound)
+		}
+		return nil
+	})
+}
+
+func (tr *TerminalRepository) SetTerminalSite(ctx context.Context, terminalID int, siteID int) error {
+	command := `UPDATE terminals SET site_id = NULLIF($2, 0) WHERE id = $1 AND deleted_at IS NULL`
+	return WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		if err := requireSite(ctx, tx, siteID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(ctx, command, terminalID, siteID)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("terminal with ID %d: %w", terminalID, ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// requireSite fails with ErrNotFound unless id is 0 or an existing site.
+func requireSite(ctx context.Context, tx pgx.Tx, id int) error {
+	if id == 0 {
+		return nil
+	}
+	var exists bool
+	err := tx.QueryRow(ctx, siteExists, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("site with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"net/url"
+)
+
+const selectViews = `SELECT v.id, v.name, v.user_id, u.name, v.params, v.is_default, v.shared, v.updated_at
+	FROM views v
+	JOIN users u ON u.id = v.user_id AND u.deleted_at IS NULL`
+
+func (tr *TerminalRepository) SaveView(ctx context.Context, view domain.View) (domain.View, error) {
+	clearDefaultCommand := `UPDATE views SET is_default = FALSE WHERE user_id = $1 AND name <> $2 AND is_default`
+	upsertCommand := `INSERT INTO views (user_id, name, params, is_default, shared, updated_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (user_id, name) DO UPDATE
+		SET params = EXCLUDED.params, is_default = EXCLUDED.is_default, shared = EXCLUDED.shared, updated_at = EXCLUDED.updated_at`
+
+	var saved domain.View
+	err := WithTx(ctx, tr.db, TxOptions{}, func(tx pgx.Tx) error {
+		if view.IsDefault {
+			_, err := tx.Exec(ctx, clearDefaultCommand, view.OwnerID, view.Name)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(ctx, upsertCommand, view.OwnerID, view.Name, view.Params.Encode(), view.IsDefault, view.Shared)
+		if err != nil {
+			return err
+		}
+		saved, err = scanView(tx.QueryRow(ctx, selectViews+` WHERE v.user_id = $1 AND v.name = $2`, view.OwnerID, view.Name))
+		return err
+	})
+	return saved, err
+}
+
+func (tr *TerminalRepository) GetViews(ctx context.Context, userId int) ([]domain.View, error) {
+	query := selectViews + ` WHERE v.user_id = $1 OR v.shared ORDER BY v.user_id <> $1, u.name, v.name`
+	rows, err := tr.db.Query(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]domain.View, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (tr *TerminalRepository) GetView(ctx context.Context, userId int, owner string, name string) (domain.View, error) {
+	query, args := selectViews+` WHERE v.user_id = $1 AND v.name = $2`, []any{userId, name}
+	if owner != "" {
+		query, args = selectViews+` WHERE u.name = $2 AND v.name = $3 AND (v.shared OR v.user_id = $1)`, []any{userId, owner, name}
+	}
+	view, err := scanView(tr.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return view, fmt.Errorf("view %q: %w", name, ErrNotFound)
+	}
+	return view, err
+}
+
+func (tr *TerminalRepository) GetDefaultView(ctx context.Context, userId int) (domain.View, error) {
+	view, err := scanView(tr.db.QueryRow(ctx, selectViews+` WHERE v.user_id = $1 AND v.is_default`, userId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return view, fmt.Errorf("default view of user_id %d: %w", userId, ErrNotFound)
+	}
+	return view, err
+}
+
+func (tr *TerminalRepository) DeleteView(ctx context.Context, userId int, name string) error {
+	tag, err := tr.db.Exec(ctx, `DELETE FROM views WHERE user_id = $1 AND name = $2`, userId, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("view %q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+func scanView(row pgx.Row) (domain.View, error) {
+	var view domain.View
+	var params string
+	err := row.Scan(&view.ID, &view.Name, &view.OwnerID, &view.Owner, &params, &view.IsDefault, &view.Shared, &view.UpdatedAt)
+	if err != nil {
+		return view, err
+	}
+	view.Params, err = url.ParseQuery(params)
+	return view, err
+}
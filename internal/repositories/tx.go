@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx, so a repository
+// can run either on its own or inside a caller's transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// WithTx runs fn in a transaction on db. When db is already a transaction fn
+// joins it, and the outermost WithTx owns isolation, commit and retries.
+// Otherwise a new transaction is started with opts and re-run up to
+// opts.MaxRetries times on serialization failures and deadlocks.
+func WithTx(ctx context.Context, db Querier, opts TxOptions, fn func(tx pgx.Tx) error) error {
+	if tx, ok := db.(pgx.Tx); ok {
+		return fn(tx)
+	}
+	beginner, ok := db.(txBeginner)
+	if !ok {
+		return fmt.Errorf("querier %T cannot begin transactions", db)
+	}
+	txOptions := pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(opts.IsoLevel)}
+	if opts.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	var err error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		err = runTx(ctx, beginner, txOptions, fn)
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func runTx(ctx context.Context, beginner txBeginner, txOptions pgx.TxOptions, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := beginner.BeginTx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
+
+type unitOfWork struct {
+	db Querier
+}
+
+func (u *unitOfWork) WithTx(ctx context.Context, opts TxOptions, fn func(tx *RepositoryPort) error) error {
+	return WithTx(ctx, u.db, opts, func(tx pgx.Tx) error {
+		return fn(newRepositoryPort(tx))
+	})
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeTx struct {
+	pgx.Tx
+	committed   bool
+	rolledBack  bool
+	commitErr   error
+	rollbackErr error
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.rolledBack = true
+	return tx.rollbackErr
+}
+
+type fakePool struct {
+	Querier
+	txs     []*fakeTx
+	options []pgx.TxOptions
+}
+
+func (p *fakePool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx := &fakeTx{}
+	p.txs = append(p.txs, tx)
+	p.options = append(p.options, txOptions)
+	return tx, nil
+}
+
+func TestWithTxCommit(t *testing.T) {
+	pool := &fakePool{}
+	err := WithTx(context.Background(), pool, TxOptions{IsoLevel: Serializable, ReadOnly: true}, func(tx pgx.Tx) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, pool.txs, 1)
+	require.True(t, pool.txs[0].committed)
+	require.False(t, pool.txs[0].rolledBack)
+	require.Equal(t, pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly}, pool.options[0])
+}
+
+func TestWithTxRollbackKeepsOriginalErr(t *testing.T) {
+	pool := &fakePool{}
+	expErr := errors.New("terminal with ID 4321 doesnt exist in table terminals")
+	err := WithTx(context.Background(), pool, TxOptions{}, func(tx pgx.Tx) error {
+		tx.(*fakeTx).rollbackErr = errors.New("conn busy")
+		return expErr
+	})
+	require.ErrorIs(t, err, expErr)
+	require.EqualError(t, err, "terminal with ID 4321 doesnt exist in table terminals (rollback failed: conn busy)")
+	require.True(t, pool.txs[0].rolledBack)
+	require.False(t, pool.txs[0].committed)
+}
+
+func TestWithTxRetriesSerializationFailure(t *testing.T) {
+	pool := &fakePool{}
+	calls := 0
+	err := WithTx(context.Background(), pool, TxOptions{IsoLevel: Serializable, MaxRetries: 3}, func(tx pgx.Tx) error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: sqlStateSerializationFailure}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Len(t, pool.txs, 3)
+	require.True(t, pool.txs[0].rolledBack)
+	require.True(t, pool.txs[2].committed)
+}
+
+func TestWithTxRetriesExhausted(t *testing.T) {
+	pool := &fakePool{}
+	calls := 0
+	err := WithTx(context.Background(), pool, TxOptions{MaxRetries: 1}, func(tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: sqlStateDeadlockDetected}
+	})
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, 2, calls)
+}
+
+func TestWithTxDoesNotRetryOtherErrors(t *testing.T) {
+	pool := &fakePool{}
+	calls := 0
+	err := WithTx(context.Background(), pool, TxOptions{MaxRetries: 3}, func(tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: "23505"}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestWithTxJoinsOuterTx(t *testing.T) {
+	outer := &fakeTx{}
+	var got pgx.Tx
+	err := WithTx(context.Background(), outer, TxOptions{MaxRetries: 3}, func(tx pgx.Tx) error {
+		got = tx
+		return nil
+	})
+	require.NoError(t, err)
+	require.Same(t, outer, got)
+	require.False(t, outer.committed)
+}
+
+func TestUnitOfWorkSharesTx(t *testing.T) {
+	pool := &fakePool{}
+	uow := &unitOfWork{db: pool}
+	err := uow.WithTx(context.Background(), TxOptions{}, func(tx *RepositoryPort) error {
+		return tx.WithTx(context.Background(), TxOptions{}, func(inner *RepositoryPort) error {
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Len(t, pool.txs, 1)
+	require.True(t, pool.txs[0].committed)
+}
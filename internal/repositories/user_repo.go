@@ -6,45 +6,158 @@ import (
 	"fmt"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 type UserRepository struct {
-	pgxpool *pgxpool.Pool
+	db Querier
 }
 
-func NewUserRepository(pgxpool *pgxpool.Pool) *UserRepository {
+func NewUserRepository(db Querier) *UserRepository {
 	return &UserRepository{
-		pgxpool: pgxpool,
+		db: db,
 	}
 }
-func (ur *UserRepository) CreateUser(user domain.User) error {
+func (ur *UserRepository) CreateUser(ctx context.Context, user domain.User) (int, error) {
 	existingUserQuery := `SELECT COUNT(*) FROM users WHERE name = $1`
 	var count int
-	err := ur.pgxpool.QueryRow(context.Background(), existingUserQuery, user.Name).Scan(&count)
+	err := ur.db.QueryRow(ctx, existingUserQuery, user.Name).Scan(&count)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if count > 0 {
-		return fmt.Errorf("username already exists")
+		return 0, fmt.Errorf("username already exists")
 	}
-	command := `INSERT INTO users (name, password) VALUES ($1, $2)`
-	_, err = ur.pgxpool.Exec(context.Background(), command, user.Name, user.Password)
+	command := `INSERT INTO users (name, password) VALUES ($1, $2) RETURNING id`
+	var id int
+	err = ur.db.QueryRow(ctx, command, user.Name, user.Password).Scan(&id)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return id, nil
 }
 
-func (ur *UserRepository) GetUser(username string) (domain.User, error) {
+func (ur *UserRepository) GetUser(ctx context.Context, username string) (domain.User, error) {
 	var user domain.User
-	query := `SELECT * FROM users WHERE name = $1`
-	err := ur.pgxpool.QueryRow(context.Background(), query, username).Scan(&user.ID, &user.Name, &user.Password)
+	query := `SELECT id, name, password, role FROM users WHERE name = $1 AND deleted_at IS NULL`
+	err := ur.db.QueryRow(ctx, query, username).Scan(&user.ID, &user.Name, &user.Password, &user.Role)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return domain.User{}, errors.New("no user found with given name")
 		}
 		return domain.User{}, err
 	}
 	return user, nil
 }
+
+func (ur *UserRepository) GetUserByID(ctx context.Context, id int) (domain.User, error) {
+	var user domain.User
+	query := `SELECT id, name, password, role FROM users WHERE id = $1 AND deleted_at IS NULL`
+	err := ur.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Password, &user.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.User{}, fmt.Errorf("user with ID %d: %w", id, ErrNotFound)
+		}
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (ur *UserRepository) SoftDeleteUser(ctx context.Context, id int) error {
+	command := `UPDATE users SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+	tag, err := ur.db.Exec(ctx, command, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (ur *UserRepository) RestoreUser(ctx context.Context, id int) error {
+	command := `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+	tag, err := ur.db.Exec(ctx, command, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("deleted user with ID %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (ur *UserRepository) GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error) {
+	query := `SELECT id, name, deleted_at FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at, id`
+	return queryDeletedRecords(ctx, ur.db, query)
+}
+
+// PurgeUser permanently removes a soft-deleted user together with their
+// favorites and notes.
+func (ur *UserRepository) PurgeUser(ctx context.Context, id int) error {
+	return WithTx(ctx, ur.db, TxOptions{}, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("deleted user with ID %d: %w", id, ErrNotFound)
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM favorite_terminals WHERE user_id = $1`, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM favorite_notes WHERE user_id = $1`, id)
+		return err
+	})
+}
+
+func (ur *UserRepository) GetUsersDeletedBefore(ctx context.Context, before time.Time) ([]int, error) {
+	query := `SELECT id FROM users WHERE deleted_at < $1 ORDER BY id`
+	return queryIDs(ctx, ur.db, query, before)
+}
+
+// CountActiveUsers counts users that are not soft-deleted.
+func (ur *UserRepository) CountActiveUsers(ctx context.Context) (int, error) {
+	var count int
+	err := ur.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`).Scan(&count)
+	return count, err
+}
+
+func queryDeletedRecords(ctx context.Context, db Querier, query string, args ...any) ([]domain.DeletedRecord, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]domain.DeletedRecord, 0)
+	for rows.Next() {
+		var record domain.DeletedRecord
+		err = rows.Scan(&record.ID, &record.Name, &record.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func queryIDs(ctx context.Context, db Querier, query string, args ...any) ([]int, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const selectWebhookDeliveries = `SELECT id, webhook_id, event, payload, state, attempts, next_attempt_at,
+	response_status, last_error, created_at, delivered_at
+	FROM webhook_deliveries`
+
+type WebhookRepository struct {
+	db Querier
+}
+
+func NewWebhookRepository(db Querier) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (wr *WebhookRepository) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	events, labels, err := marshalWebhookFilters(hook)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	command := `INSERT INTO webhooks (url, secret, events, terminal_id, labels)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5) RETURNING id, created_at`
+	err = wr.db.QueryRow(ctx, command, hook.URL, hook.Secret, events, hook.TerminalID, labels).Scan(&hook.ID, &hook.CreatedAt)
+	return hook, err
+}
+
+func (wr *WebhookRepository) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	query := `SELECT id, url, secret, events, COALESCE(terminal_id, 0), labels, created_at FROM webhooks ORDER BY id`
+	rows, err := wr.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := make([]domain.Webhook, 0)
+	for rows.Next() {
+		var hook domain.Webhook
+		var events, labels string
+		err = rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.TerminalID, &labels, &hook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err = unmarshalWebhookFilters(&hook, events, labels); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (wr *WebhookRepository) DeleteWebhook(ctx context.Context, id int) error {
+	tag, err := wr.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// ClaimWebhookEvents skips events claimed by a concurrent dispatcher rather
+// than waiting for it, so two dispatchers never enqueue the same event.
+func (wr *WebhookRepository) ClaimWebhookEvents(ctx context.Context, limit int) ([]domain.WebhookEvent, error) {
+	query := `WITH claimed AS (
+			DELETE FROM webhook_events
+			WHERE id IN (SELECT id FROM webhook_events ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED)
+			RETURNING id, event, occurred_at, terminal_id, status, user_id
+		)
+		SELECT event, occurred_at, terminal_id, status, user_id FROM claimed ORDER BY id`
+	rows, err := wr.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.WebhookEvent, 0)
+	for rows.Next() {
+		var event domain.WebhookEvent
+		err = rows.Scan(&event.Type, &event.OccurredAt, &event.TerminalID, &event.Status, &event.UserID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// recordWebhookEvent adds a pending webhook event in tx, so it commits or
+// rolls back with the change it describes.
+func recordWebhookEvent(ctx context.Context, tx pgx.Tx, event domain.WebhookEvent) error {
+	command := `INSERT INTO webhook_events (event, terminal_id, user_id) VALUES ($1, $2, $3)`
+	_, err := tx.Exec(ctx, command, event.Type, event.TerminalID, event.UserID)
+	return err
+}
+
+func (wr *WebhookRepository) CreateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	command := `INSERT INTO webhook_deliveries (webhook_id, event, payload, state, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err := wr.db.Exec(ctx, command, delivery.WebhookID, delivery.Event, string(delivery.Payload), domain.DeliveryPending,
+		delivery.NextAttemptAt)
+	return err
+}
+
+// ClaimDueWebhookDeliveries skips deliveries claimed by a concurrent
+// dispatcher rather than waiting for it, so no delivery is sent twice.
+func (wr *WebhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	query := `WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = now() + make_interval(secs => $3)
+			WHERE id IN (SELECT id FROM webhook_deliveries WHERE state = $1 AND next_attempt_at <= $2
+				ORDER BY id LIMIT $4 FOR UPDATE SKIP LOCKED)
+			RETURNING id, webhook_id, event, payload, state, attempts, next_attempt_at,
+				response_status, last_error, created_at, delivered_at
+		)
+		SELECT * FROM claimed ORDER BY id`
+	return wr.queryWebhookDeliveries(ctx, query, domain.DeliveryPending, now, lease.Seconds(), limit)
+}
+
+func (wr *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	command := `UPDATE webhook_deliveries SET state = $2, attempts = $3, next_attempt_at = $4,
+		response_status = $5, last_error = $6, delivered_at = $7 WHERE id = $1`
+	tag, err := wr.db.Exec(ctx, command, delivery.ID, delivery.State, delivery.Attempts, delivery.NextAttemptAt,
+		delivery.ResponseStatus, delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook delivery %d: %w", delivery.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error) {
+	query := selectWebhookDeliveries + ` WHERE webhook_id = $1`
+	args := []any{webhookID}
+	if state != "" {
+		query += ` AND state = $2`
+		args = append(args, state)
+	}
+	return wr.queryWebhookDeliveries(ctx, query+` ORDER BY id DESC`, args...)
+}
+
+func (wr *WebhookRepository) RedeliverWebhookDelivery(ctx context.Context, webhookID int, id int64) error {
+	command := `UPDATE webhook_deliveries SET state = $3, attempts = 0, next_attempt_at = now(),
+		response_status = 0, last_error = '', delivered_at = NULL WHERE webhook_id = $1 AND id = $2`
+	tag, err := wr.db.Exec(ctx, command, webhookID, id, domain.DeliveryPending)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook delivery %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (wr *WebhookRepository) queryWebhookDeliveries(ctx context.Context, query string, args ...any) ([]domain.WebhookDelivery, error) {
+	rows, err := wr.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhookDelivery(row pgx.Row) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var payload string
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.State, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt)
+	delivery.Payload = json.RawMessage(payload)
+	return delivery, err
+}
+
+// marshalWebhookFilters encodes the events and labels columns.
+func marshalWebhookFilters(hook domain.Webhook) (string, string, error) {
+	events, err := json.Marshal(nonNil(hook.Events))
+	if err != nil {
+		return "", "", err
+	}
+	labels, err := json.Marshal(nonNil(hook.Labels))
+	return string(events), string(labels), err
+}
+
+func unmarshalWebhookFilters(hook *domain.Webhook, events string, labels string) error {
+	if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+		return fmt.Errorf("webhook %d events: %w", hook.ID, err)
+	}
+	if err := json.Unmarshal([]byte(labels), &hook.Labels); err != nil {
+		return fmt.Errorf("webhook %d labels: %w", hook.ID, err)
+	}
+	if len(hook.Events) == 0 {
+		hook.Events = nil
+	}
+	if len(hook.Labels) == 0 {
+		hook.Labels = nil
+	}
+	return nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,172 @@
+package admin_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"strings"
+	"time"
+)
+
+type AdminService struct {
+	terminalRepositoryPort repositories.TerminalRepositoryPort
+	userRepositoryPort     repositories.UserRepositoryPort
+}
+
+func NewAdminService(terminalRepositoryPort repositories.TerminalRepositoryPort, userRepositoryPort repositories.UserRepositoryPort) *AdminService {
+	return &AdminService{
+		terminalRepositoryPort: terminalRepositoryPort,
+		userRepositoryPort:     userRepositoryPort,
+	}
+}
+
+func (as *AdminService) DeleteTerminal(ctx context.Context, id int) error {
+	return as.terminalRepositoryPort.SoftDeleteTerminal(ctx, id)
+}
+
+func (as *AdminService) RestoreTerminal(ctx context.Context, id int) error {
+	return as.terminalRepositoryPort.RestoreTerminal(ctx, id)
+}
+
+func (as *AdminService) PurgeTerminal(ctx context.Context, id int) error {
+	return as.terminalRepositoryPort.PurgeTerminal(ctx, id)
+}
+
+func (as *AdminService) GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error) {
+	return as.terminalRepositoryPort.GetDeletedTerminals(ctx)
+}
+
+// AttachTag tags a terminal. The tag must be valid, see domain.Tag.Validate.
+func (as *AdminService) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	err := tag.Validate()
+	if err != nil {
+		return err
+	}
+	return as.terminalRepositoryPort.AttachTag(ctx, terminalID, tag)
+}
+
+func (as *AdminService) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	return as.terminalRepositoryPort.DetachTag(ctx, terminalID, tag)
+}
+
+// SetTerminalLocation replaces where a terminal is. The location must be
+// valid, see domain.TerminalLocation.Validate.
+func (as *AdminService) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error {
+	location.Address = strings.TrimSpace(location.Address)
+	err := location.Validate()
+	if err != nil {
+		return err
+	}
+	return as.terminalRepositoryPort.SetTerminalLocation(ctx, terminalID, location)
+}
+
+// CreateSite adds a site below site.ParentID, or at the top for 0.
+func (as *AdminService) CreateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	site = site.Normalize()
+	if err := site.Validate(); err != nil {
+		return domain.Site{}, err
+	}
+	return as.terminalRepositoryPort.CreateSite(ctx, site)
+}
+
+// UpdateSite renames the site with site.ID and moves it, with the sites and
+// terminals below it, under site.ParentID.
+func (as *AdminService) UpdateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	site = site.Normalize()
+	if err := site.Validate(); err != nil {
+		return domain.Site{}, err
+	}
+	if err := as.terminalRepositoryPort.UpdateSite(ctx, site); err != nil {
+		return domain.Site{}, err
+	}
+	return site, nil
+}
+
+// DeleteSite deletes a site that has no sub-sites or terminals left.
+func (as *AdminService) DeleteSite(ctx context.Context, id int) error {
+	return as.terminalRepositoryPort.DeleteSite(ctx, id)
+}
+
+// SetTerminalSite assigns a terminal to a site, or to none for siteID 0.
+func (as *AdminService) SetTerminalSite(ctx context.Context, terminalID int, siteID int) error {
+	return as.terminalRepositoryPort.SetTerminalSite(ctx, terminalID, siteID)
+}
+
+func (as *AdminService) DeleteUser(ctx context.Context, id int) error {
+	return as.userRepositoryPort.SoftDeleteUser(ctx, id)
+}
+
+func (as *AdminService) RestoreUser(ctx context.Context, id int) error {
+	return as.userRepositoryPort.RestoreUser(ctx, id)
+}
+
+func (as *AdminService) PurgeUser(ctx context.Context, id int) error {
+	return as.userRepositoryPort.PurgeUser(ctx, id)
+}
+
+func (as *AdminService) GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error) {
+	return as.userRepositoryPort.GetDeletedUsers(ctx)
+}
+
+// PurgeExpired permanently removes terminals and users soft-deleted before
+// the given time and reports how many of each were purged. A record that is
+// restored or purged concurrently is skipped rather than failing the run.
+func (as *AdminService) PurgeExpired(ctx context.Context, before time.Time) (terminals int, users int, err error) {
+	terminalIds, err := as.terminalRepositoryPort.GetTerminalsDeletedBefore(ctx, before)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list expired terminals: %v", err)
+	}
+	for _, id := range terminalIds {
+		err = as.terminalRepositoryPort.PurgeTerminal(ctx, id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return terminals, users, fmt.Errorf("failed to purge terminal %d: %v", id, err)
+		}
+		terminals++
+	}
+
+	userIds, err := as.userRepositoryPort.GetUsersDeletedBefore(ctx, before)
+	if err != nil {
+		return terminals, 0, fmt.Errorf("failed to list expired users: %v", err)
+	}
+	for _, id := range userIds {
+		err = as.userRepositoryPort.PurgeUser(ctx, id)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return terminals, users, fmt.Errorf("failed to purge user %d: %v", id, err)
+		}
+		users++
+	}
+	return terminals, users, nil
+}
+
+// RunPurge calls PurgeExpired every interval for records deleted longer than
+// retention ago, until ctx is cancelled. A non-positive retention disables it.
+func (as *AdminService) RunPurge(ctx context.Context, retention time.Duration, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	log := logger.GetLogger()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		terminals, users, err := as.PurgeExpired(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Errorf("failed to purge soft-deleted records: %v", err)
+		} else if terminals > 0 || users > 0 {
+			log.Infof("purged %d terminals and %d users deleted more than %s ago", terminals, users, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
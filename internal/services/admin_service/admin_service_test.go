@@ -0,0 +1,131 @@
+package admin_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDeleteTerminal(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	terminalRepo.EXPECT().SoftDeleteTerminal(gomock.Any(), 4).Return(nil).Times(1)
+	err := service.DeleteTerminal(context.Background(), 4)
+	require.NoError(t, err)
+}
+
+func TestRestoreTerminalNotFound(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	expErr := fmt.Errorf("deleted terminal with ID %d: %w", 4, repositories.ErrNotFound)
+	terminalRepo.EXPECT().RestoreTerminal(gomock.Any(), 4).Return(expErr).Times(1)
+	err := service.RestoreTerminal(context.Background(), 4)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func TestGetDeletedUsers(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	records := []domain.DeletedRecord{
+		{ID: 1, Name: "Khalid", DeletedAt: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	userRepo.EXPECT().GetDeletedUsers(gomock.Any()).Return(records, nil).Times(1)
+	got, err := service.GetDeletedUsers(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, records, got)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	before := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	restored := fmt.Errorf("deleted terminal with ID %d: %w", 2, repositories.ErrNotFound)
+	terminalRepo.EXPECT().GetTerminalsDeletedBefore(gomock.Any(), before).Return([]int{1, 2, 3}, nil).Times(1)
+	terminalRepo.EXPECT().PurgeTerminal(gomock.Any(), 1).Return(nil).Times(1)
+	terminalRepo.EXPECT().PurgeTerminal(gomock.Any(), 2).Return(restored).Times(1)
+	terminalRepo.EXPECT().PurgeTerminal(gomock.Any(), 3).Return(nil).Times(1)
+	userRepo.EXPECT().GetUsersDeletedBefore(gomock.Any(), before).Return([]int{7}, nil).Times(1)
+	userRepo.EXPECT().PurgeUser(gomock.Any(), 7).Return(nil).Times(1)
+
+	terminals, users, err := service.PurgeExpired(context.Background(), before)
+	require.NoError(t, err)
+	require.Equal(t, 2, terminals)
+	require.Equal(t, 1, users)
+}
+
+func TestPurgeExpiredRepoErr(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	before := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	terminalRepo.EXPECT().GetTerminalsDeletedBefore(gomock.Any(), before).Return([]int{1}, nil).Times(1)
+	terminalRepo.EXPECT().PurgeTerminal(gomock.Any(), 1).Return(errors.New("DB is down")).Times(1)
+
+	terminals, users, err := service.PurgeExpired(context.Background(), before)
+	require.EqualError(t, err, "failed to purge terminal 1: DB is down")
+	require.Equal(t, 0, terminals)
+	require.Equal(t, 0, users)
+}
+
+func TestRunPurgeStopsOnCancel(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	terminalRepo.EXPECT().GetTerminalsDeletedBefore(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+	userRepo.EXPECT().GetUsersDeletedBefore(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, before time.Time) ([]int, error) {
+			cancel()
+			return nil, nil
+		}).Times(1)
+
+	done := make(chan struct{})
+	go func() {
+		service.RunPurge(ctx, time.Hour, time.Hour)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunPurge did not stop after cancel")
+	}
+}
+
+func TestCreateSite(t *testing.T) {
+	ctl := gomock.NewController(t)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewAdminService(terminalRepo, userRepo)
+
+	terminalRepo.EXPECT().CreateSite(gomock.Any(), domain.Site{ParentID: 1, Name: "Site B"}).
+		Return(domain.Site{ID: 2, ParentID: 1, Name: "Site B"}, nil).Times(1)
+	site, err := service.CreateSite(context.Background(), domain.Site{ParentID: 1, Name: "  Site B "})
+	require.NoError(t, err)
+	require.Equal(t, 2, site.ID)
+
+	_, err = service.CreateSite(context.Background(), domain.Site{Name: " "})
+	require.ErrorIs(t, err, domain.ErrInvalidSite)
+}
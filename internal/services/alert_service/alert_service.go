@@ -0,0 +1,317 @@
+package alert_service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"time"
+)
+
+// Notifier tells a user about an alert that fired or resolved.
+type Notifier interface {
+	NotifyAlert(ctx context.Context, userId int, n domain.Notification) error
+}
+
+type AlertService struct {
+	alertRepositoryPort    repositories.AlertRepositoryPort
+	terminalRepositoryPort repositories.TerminalRepositoryPort
+	unitOfWork             repositories.UnitOfWork
+	notifier               Notifier
+}
+
+// NewAlertService creates the service. A nil notifier sends no
+// notifications.
+func NewAlertService(alertRepositoryPort repositories.AlertRepositoryPort, terminalRepositoryPort repositories.TerminalRepositoryPort,
+	unitOfWork repositories.UnitOfWork, notifier Notifier) *AlertService {
+	return &AlertService{
+		alertRepositoryPort:    alertRepositoryPort,
+		terminalRepositoryPort: terminalRepositoryPort,
+		unitOfWork:             unitOfWork,
+		notifier:               notifier,
+	}
+}
+
+// CreateAlertRule validates rule and stores it for rule.UserID. A rule on a
+// single terminal fails with repositories.ErrNotFound unless the terminal is
+// one of the user's favorites.
+func (as *AlertService) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error) {
+	if err := rule.Validate(); err != nil {
+		return domain.AlertRule{}, err
+	}
+	if rule.TerminalID != 0 {
+		favorites, err := as.terminalRepositoryPort.GetFavoriteTerminalIds(ctx, rule.UserID)
+		if err != nil {
+			return domain.AlertRule{}, err
+		}
+		if !contains(favorites, rule.TerminalID) {
+			return domain.AlertRule{}, fmt.Errorf("terminal %d is not a favorite: %w", rule.TerminalID, repositories.ErrNotFound)
+		}
+	}
+	rule.MutedUntil = nil
+	return as.alertRepositoryPort.CreateAlertRule(ctx, rule)
+}
+
+func (as *AlertService) GetAlertRules(ctx context.Context, userId int) ([]domain.AlertRule, error) {
+	return as.alertRepositoryPort.GetAlertRules(ctx, userId)
+}
+
+func (as *AlertService) DeleteAlertRule(ctx context.Context, userId int, id int) error {
+	return as.alertRepositoryPort.DeleteAlertRule(ctx, userId, id)
+}
+
+func (as *AlertService) GetAlerts(ctx context.Context, userId int, state string) ([]domain.Alert, error) {
+	return as.alertRepositoryPort.GetAlerts(ctx, userId, state)
+}
+
+func (as *AlertService) AckAlert(ctx context.Context, userId int, id int) error {
+	return as.alertRepositoryPort.AckAlert(ctx, userId, id)
+}
+
+// MuteAlert mutes the rule that raised the alert for d, so it raises no new
+// alerts until then. Alerts already firing still resolve.
+func (as *AlertService) MuteAlert(ctx context.Context, userId int, id int, d time.Duration) error {
+	alert, err := as.alertRepositoryPort.GetAlert(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	until := time.Now().Add(d).UTC()
+	return as.alertRepositoryPort.MuteAlertRule(ctx, userId, alert.Rule.ID, &until)
+}
+
+// UnmuteAlert lifts the mute on the rule that raised the alert.
+func (as *AlertService) UnmuteAlert(ctx context.Context, userId int, id int) error {
+	alert, err := as.alertRepositoryPort.GetAlert(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	return as.alertRepositoryPort.MuteAlertRule(ctx, userId, alert.Rule.ID, nil)
+}
+
+// statusChangeBatch is how many status changes EvaluateStatusChanges
+// evaluates in one transaction.
+const statusChangeBatch = 100
+
+// statusChangePoll is how often RunAlertEvaluator looks for new status
+// changes, unless its interval is shorter.
+const statusChangePoll = time.Second
+
+// EvaluateStatusChanges evaluates every rule on the terminals whose status
+// changed since it last ran, in batches of the oldest changes first. It
+// fires and resolves alerts for those terminals like EvaluateAlerts, in the
+// transaction that claims the changes, and notifies the owners once that
+// transaction commits.
+func (as *AlertService) EvaluateStatusChanges(ctx context.Context, now time.Time) (fired int, resolved int, err error) {
+	for {
+		var claimed, batchFired, batchResolved int
+		var notifications []userNotification
+		err = as.unitOfWork.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+			changes, err := tx.ClaimStatusChanges(ctx, statusChangeBatch)
+			if err != nil || len(changes) == 0 {
+				return err
+			}
+			claimed = len(changes)
+			changed := make(map[int]bool, len(changes))
+			for _, change := range changes {
+				changed[change.TerminalID] = true
+			}
+			batchFired, batchResolved, notifications, err = evaluate(ctx, tx.AlertRepositoryPort, tx.TerminalRepositoryPort, now, changed)
+			return err
+		})
+		if err != nil {
+			return fired, resolved, err
+		}
+		fired += batchFired
+		resolved += batchResolved
+		as.notify(ctx, notifications)
+		if claimed < statusChangeBatch {
+			return fired, resolved, nil
+		}
+	}
+}
+
+// EvaluateAlerts checks the rules that depend on how long something lasts
+// against every favorite at now. It fires an alert for each terminal a
+// status rule newly holds for once the status has lasted the rule's window,
+// unless the rule is muted, and resolves the firing alerts whose rule no
+// longer holds, including flapping alerts whose changes have left the window
+// and alerts for terminals that are no longer favorites. Rules that a status
+// change makes hold at once are fired by EvaluateStatusChanges.
+func (as *AlertService) EvaluateAlerts(ctx context.Context, now time.Time) (fired int, resolved int, err error) {
+	fired, resolved, notifications, err := evaluate(ctx, as.alertRepositoryPort, as.terminalRepositoryPort, now, nil)
+	as.notify(ctx, notifications)
+	return fired, resolved, err
+}
+
+// userNotification is a notification waiting to be sent to a user.
+type userNotification struct {
+	userId       int
+	notification domain.Notification
+}
+
+// evaluate checks the rules against their owners' favorites at now. With a
+// nil changed it runs the periodic evaluation, where only status rules fire;
+// otherwise it only looks at the terminals in changed, and any rule may
+// fire. The owner gets a notification for every alert fired and for those
+// resolved while the terminal is still a favorite.
+func evaluate(ctx context.Context, alertRepo repositories.AlertRepositoryPort, terminalRepo repositories.TerminalRepositoryPort,
+	now time.Time, changed map[int]bool) (fired int, resolved int, notifications []userNotification, err error) {
+	inScope := func(terminalID int) bool {
+		return changed == nil || changed[terminalID]
+	}
+	rules, err := alertRepo.GetAllAlertRules(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	firing, err := alertRepo.GetFiringAlerts(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	terminals, err := terminalRepo.GetDefaultTerminalsList(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	favorites, err := alertRepo.GetAlertFavorites(ctx)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	var window time.Duration
+	for _, rule := range rules {
+		if rule.Kind == domain.AlertRuleFlapping && time.Duration(rule.Window) > window {
+			window = time.Duration(rule.Window)
+		}
+	}
+	changes := map[int][]time.Time{}
+	if window > 0 {
+		changes, err = alertRepo.GetStatusChanges(ctx, now.Add(-window))
+		if err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	type ruleTerminal struct{ ruleID, terminalID int }
+	holds := make(map[ruleTerminal]bool)
+	var newlyHolding []ruleTerminal
+	isFiring := make(map[ruleTerminal]bool, len(firing))
+	for _, alert := range firing {
+		isFiring[ruleTerminal{alert.Rule.ID, alert.TerminalID}] = true
+	}
+	for _, rule := range rules {
+		for _, terminal := range terminals {
+			if !inScope(terminal.ID) || (rule.TerminalID != 0 && rule.TerminalID != terminal.ID) || !contains(favorites[rule.UserID], terminal.ID) {
+				continue
+			}
+			if !rule.Holds(terminal, changes[terminal.ID], now) {
+				continue
+			}
+			key := ruleTerminal{rule.ID, terminal.ID}
+			holds[key] = true
+			if !isFiring[key] && !rule.Muted(now) && (changed != nil || rule.Kind == domain.AlertRuleStatus) {
+				newlyHolding = append(newlyHolding, key)
+			}
+		}
+	}
+
+	terminalsByID := make(map[int]domain.Terminal, len(terminals))
+	for _, terminal := range terminals {
+		terminalsByID[terminal.ID] = terminal
+	}
+	for _, alert := range firing {
+		if !inScope(alert.TerminalID) || holds[ruleTerminal{alert.Rule.ID, alert.TerminalID}] {
+			continue
+		}
+		err = alertRepo.ResolveAlert(ctx, alert.ID)
+		if err != nil {
+			return fired, resolved, notifications, fmt.Errorf("failed to resolve alert %d: %w", alert.ID, err)
+		}
+		resolved++
+		terminal, ok := terminalsByID[alert.TerminalID]
+		if ok && contains(favorites[alert.Rule.UserID], alert.TerminalID) {
+			notifications = append(notifications, userNotification{alert.Rule.UserID, domain.Notification{
+				State: domain.AlertResolved, Rule: alert.Rule.Kind, TerminalID: terminal.ID, TerminalName: terminal.Name,
+				Status: terminal.Status, Duration: now.Sub(alert.FiredAt), At: now,
+			}})
+		}
+	}
+	rulesByID := make(map[int]domain.AlertRule, len(rules))
+	for _, rule := range rules {
+		rulesByID[rule.ID] = rule
+	}
+	for _, key := range newlyHolding {
+		err = alertRepo.FireAlert(ctx, key.ruleID, key.terminalID)
+		if err != nil {
+			return fired, resolved, notifications, fmt.Errorf("failed to fire alert for rule %d on terminal %d: %w", key.ruleID, key.terminalID, err)
+		}
+		fired++
+		rule, terminal := rulesByID[key.ruleID], terminalsByID[key.terminalID]
+		var held time.Duration
+		if terminal.StatusChangedAt != nil {
+			held = now.Sub(*terminal.StatusChangedAt)
+		}
+		notifications = append(notifications, userNotification{rule.UserID, domain.Notification{
+			State: domain.AlertFiring, Rule: rule.Kind, TerminalID: terminal.ID, TerminalName: terminal.Name,
+			Status: terminal.Status, Duration: held, At: now,
+		}})
+	}
+	return fired, resolved, notifications, nil
+}
+
+// notify sends the notifications, with their durations in whole seconds, to
+// the users' notification channels and logs any failure.
+func (as *AlertService) notify(ctx context.Context, notifications []userNotification) {
+	if as.notifier == nil {
+		return
+	}
+	for _, un := range notifications {
+		n := un.notification
+		n.Duration = n.Duration.Round(time.Second)
+		if err := as.notifier.NotifyAlert(ctx, un.userId, n); err != nil {
+			logger.FromContext(ctx).Warnf("failed to notify user %d of alert on terminal %d: %v", un.userId, n.TerminalID, err)
+		}
+	}
+}
+
+// RunAlertEvaluator calls EvaluateStatusChanges every second, or every
+// interval if that is shorter, and EvaluateAlerts every interval, until ctx
+// is cancelled. A non-positive interval disables it.
+func (as *AlertService) RunAlertEvaluator(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+	poll := statusChangePoll
+	if interval < poll {
+		poll = interval
+	}
+	changes := time.NewTicker(poll)
+	defer changes.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pass := as.EvaluateAlerts
+	for {
+		fired, resolved, err := pass(ctx, time.Now())
+		if err != nil {
+			log.Errorf("failed to evaluate alert rules: %v", err)
+		} else if fired > 0 || resolved > 0 {
+			log.Infof("fired %d and resolved %d alerts", fired, resolved)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes.C:
+			pass = as.EvaluateStatusChanges
+		case <-ticker.C:
+			pass = as.EvaluateAlerts
+		}
+	}
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
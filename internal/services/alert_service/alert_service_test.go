@@ -0,0 +1,182 @@
+package alert_service
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCreateAlertRule(t *testing.T) {
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewAlertService(alertRepo, terminalRepo, repoMock.NewMockUnitOfWork(ctl), nil)
+
+	rule := domain.AlertRule{UserID: 1, TerminalID: 3, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(time.Minute)}
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{2}, nil).Times(1)
+	_, err := service.CreateAlertRule(context.Background(), rule)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	_, err = service.CreateAlertRule(context.Background(), domain.AlertRule{UserID: 1, Kind: domain.AlertRuleFlapping})
+	require.ErrorIs(t, err, domain.ErrInvalidAlertRule)
+
+	created := rule
+	created.ID = 5
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{2, 3}, nil).Times(1)
+	alertRepo.EXPECT().CreateAlertRule(gomock.Any(), rule).Return(created, nil).Times(1)
+	result, err := service.CreateAlertRule(context.Background(), rule)
+	require.NoError(t, err)
+	require.Equal(t, created, result)
+}
+
+// notifications records what NotifyAlert is called with.
+type notifications map[int][]domain.Notification
+
+func (n notifications) NotifyAlert(ctx context.Context, userId int, notification domain.Notification) error {
+	n[userId] = append(n[userId], notification)
+	return nil
+}
+
+func TestEvaluateAlerts(t *testing.T) {
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	notified := notifications{}
+	service := NewAlertService(alertRepo, terminalRepo, repoMock.NewMockUnitOfWork(ctl), notified)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	offline := domain.AlertRule{ID: 1, UserID: 1, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(5 * time.Minute)}
+	flapping := domain.AlertRule{ID: 2, UserID: 1, TerminalID: 2, Kind: domain.AlertRuleFlapping, Count: 2, Window: domain.Duration(10 * time.Minute)}
+	muted := domain.AlertRule{ID: 3, UserID: 2, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(time.Minute), MutedUntil: ago(-time.Hour)}
+	alertRepo.EXPECT().GetAllAlertRules(gomock.Any()).Return([]domain.AlertRule{offline, flapping, muted}, nil)
+	alertRepo.EXPECT().GetFiringAlerts(gomock.Any()).Return([]domain.Alert{
+		{ID: 7, Rule: offline, TerminalID: 2, FiredAt: *ago(30 * time.Minute)},
+		{ID: 8, Rule: muted, TerminalID: 3},
+	}, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{
+		{ID: 1, Name: "T-1", Status: "offline", StatusChangedAt: ago(10 * time.Minute)},
+		{ID: 2, Name: "T-2", Status: "active", StatusChangedAt: ago(time.Minute)},
+		{ID: 3, Status: "offline", StatusChangedAt: ago(10 * time.Minute)},
+		{ID: 4, Status: "offline", StatusChangedAt: ago(10 * time.Minute)},
+		{ID: 5, Status: "offline"},
+	}, nil)
+	alertRepo.EXPECT().GetAlertFavorites(gomock.Any()).Return(map[int][]int{1: {1, 2, 5}, 2: {3}}, nil)
+	alertRepo.EXPECT().GetStatusChanges(gomock.Any(), now.Add(-10*time.Minute)).Return(map[int][]time.Time{
+		2: {*ago(2 * time.Minute), *ago(time.Minute)},
+	}, nil)
+
+	// Terminal 2 is back: its alert resolves. Terminal 1 has been offline
+	// long enough, but terminal 5's change time is unknown. Terminal 2 flaps,
+	// which only a status change fires. The muted rule keeps firing for
+	// terminal 3, and terminal 4 is nobody's favorite.
+	alertRepo.EXPECT().ResolveAlert(gomock.Any(), 7).Return(nil).Times(1)
+	alertRepo.EXPECT().FireAlert(gomock.Any(), 1, 1).Return(nil).Times(1)
+
+	fired, resolved, err := service.EvaluateAlerts(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, fired)
+	require.Equal(t, 1, resolved)
+	require.Equal(t, notifications{1: {
+		{State: domain.AlertResolved, Rule: domain.AlertRuleStatus, TerminalID: 2, TerminalName: "T-2", Status: "active", Duration: 30 * time.Minute, At: now},
+		{State: domain.AlertFiring, Rule: domain.AlertRuleStatus, TerminalID: 1, TerminalName: "T-1", Status: "offline", Duration: 10 * time.Minute, At: now},
+	}}, notified)
+}
+
+func TestEvaluateStatusChanges(t *testing.T) {
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{AlertRepositoryPort: alertRepo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	notified := notifications{}
+	service := NewAlertService(alertRepo, terminalRepo, uow, notified)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	offline := domain.AlertRule{ID: 1, UserID: 1, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(5 * time.Minute)}
+	flapping := domain.AlertRule{ID: 2, UserID: 1, TerminalID: 2, Kind: domain.AlertRuleFlapping, Count: 2, Window: domain.Duration(10 * time.Minute)}
+	alertRepo.EXPECT().ClaimStatusChanges(gomock.Any(), statusChangeBatch).Return([]domain.StatusChange{
+		{ID: 4, TerminalID: 2, Status: "offline", ChangedAt: *ago(2 * time.Minute)},
+		{ID: 5, TerminalID: 2, Status: "active", ChangedAt: *ago(time.Minute)},
+	}, nil)
+	alertRepo.EXPECT().GetAllAlertRules(gomock.Any()).Return([]domain.AlertRule{offline, flapping}, nil)
+	alertRepo.EXPECT().GetFiringAlerts(gomock.Any()).Return([]domain.Alert{
+		{ID: 7, Rule: offline, TerminalID: 2, FiredAt: *ago(30 * time.Minute)},
+	}, nil)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{
+		{ID: 1, Name: "T-1", Status: "offline", StatusChangedAt: ago(10 * time.Minute)},
+		{ID: 2, Name: "T-2", Status: "active", StatusChangedAt: ago(time.Minute)},
+	}, nil)
+	alertRepo.EXPECT().GetAlertFavorites(gomock.Any()).Return(map[int][]int{1: {1, 2}}, nil)
+	alertRepo.EXPECT().GetStatusChanges(gomock.Any(), now.Add(-10*time.Minute)).Return(map[int][]time.Time{
+		2: {*ago(2 * time.Minute), *ago(time.Minute)},
+	}, nil)
+
+	// Only terminal 2 changed: it is back, so its alert resolves, and it
+	// flaps. Terminal 1 is left to EvaluateAlerts.
+	alertRepo.EXPECT().ResolveAlert(gomock.Any(), 7).Return(nil).Times(1)
+	alertRepo.EXPECT().FireAlert(gomock.Any(), 2, 2).Return(nil).Times(1)
+
+	fired, resolved, err := service.EvaluateStatusChanges(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, fired)
+	require.Equal(t, 1, resolved)
+	require.Equal(t, notifications{1: {
+		{State: domain.AlertResolved, Rule: domain.AlertRuleStatus, TerminalID: 2, TerminalName: "T-2", Status: "active", Duration: 30 * time.Minute, At: now},
+		{State: domain.AlertFiring, Rule: domain.AlertRuleFlapping, TerminalID: 2, TerminalName: "T-2", Status: "active", Duration: time.Minute, At: now},
+	}}, notified)
+}
+
+func TestEvaluateAlertsMuted(t *testing.T) {
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewAlertService(alertRepo, terminalRepo, repoMock.NewMockUnitOfWork(ctl), nil)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	until := now.Add(time.Hour)
+	rule := domain.AlertRule{ID: 1, UserID: 1, Kind: domain.AlertRuleStatus, Status: "offline", Window: domain.Duration(time.Minute), MutedUntil: &until}
+	alertRepo.EXPECT().GetAllAlertRules(gomock.Any()).Return([]domain.AlertRule{rule}, nil)
+	alertRepo.EXPECT().GetFiringAlerts(gomock.Any()).Return(nil, nil)
+	changedAt := now.Add(-time.Hour)
+	terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{{ID: 1, Status: "offline", StatusChangedAt: &changedAt}}, nil)
+	alertRepo.EXPECT().GetAlertFavorites(gomock.Any()).Return(map[int][]int{1: {1}}, nil)
+
+	fired, resolved, err := service.EvaluateAlerts(context.Background(), now)
+	require.NoError(t, err)
+	require.Zero(t, fired)
+	require.Zero(t, resolved)
+}
+
+func TestMuteAlert(t *testing.T) {
+	ctl := gomock.NewController(t)
+	alertRepo := repoMock.NewMockAlertRepositoryPort(ctl)
+	service := NewAlertService(alertRepo, repoMock.NewMockTerminalRepositoryPort(ctl), repoMock.NewMockUnitOfWork(ctl), nil)
+
+	alertRepo.EXPECT().GetAlert(gomock.Any(), 1, 7).Return(domain.Alert{ID: 7, Rule: domain.AlertRule{ID: 3}}, nil).Times(1)
+	alertRepo.EXPECT().MuteAlertRule(gomock.Any(), 1, 3, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, userId int, id int, until *time.Time) error {
+			require.NotNil(t, until)
+			require.WithinDuration(t, time.Now().Add(time.Hour), *until, time.Minute)
+			return nil
+		}).Times(1)
+	require.NoError(t, service.MuteAlert(context.Background(), 1, 7, time.Hour))
+
+	alertRepo.EXPECT().GetAlert(gomock.Any(), 2, 7).Return(domain.Alert{}, repositories.ErrNotFound).Times(1)
+	require.ErrorIs(t, service.MuteAlert(context.Background(), 2, 7, time.Hour), repositories.ErrNotFound)
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"strconv"
+	"time"
+)
+
+// Audited wraps the services so sign-ups, sign-ins, favorite changes and
+// admin actions are recorded in the audit log. Sign-ins are recorded whether
+// they succeed or not, everything else only once it took effect. A failure
+// to record is logged and does not fail the action.
+func Audited(port *ServicePort) *ServicePort {
+	audit := port.AuditServicePort
+	return &ServicePort{
+		UserServicePort:         &auditedUserService{next: port.UserServicePort, audit: audit},
+		TerminalServicePort:     &auditedTerminalService{next: port.TerminalServicePort, audit: audit},
+		AdminServicePort:        &auditedAdminService{next: port.AdminServicePort, audit: audit},
+		AuditServicePort:        audit,
+		AlertServicePort:        port.AlertServicePort,
+		WebhookServicePort:      &auditedWebhookService{next: port.WebhookServicePort, audit: audit},
+		NotificationServicePort: &auditedNotificationService{next: port.NotificationServicePort, audit: audit},
+		HeartbeatServicePort:    &auditedHeartbeatService{next: port.HeartbeatServicePort, audit: audit},
+	}
+}
+
+func record(ctx context.Context, audit AuditServicePort, entry domain.AuditEntry) {
+	if entry.Outcome == "" {
+		entry.Outcome = domain.AuditSuccess
+	}
+	err := audit.RecordAudit(ctx, entry)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to record %s in the audit log: %v", entry.Action, err)
+	}
+}
+
+type auditedUserService struct {
+	next  UserServicePort
+	audit AuditServicePort
+}
+
+func (s *auditedUserService) CreateUser(ctx context.Context, user domain.User) error {
+	err := s.next.CreateUser(ctx, user)
+	if err == nil {
+		record(ctx, s.audit, domain.AuditEntry{
+			Action:     domain.AuditSignUp,
+			ActorName:  user.Name,
+			TargetType: domain.AuditTargetUser,
+			TargetID:   user.Name,
+			After:      audit_service.Snapshot(map[string]string{"name": user.Name, "role": domain.RoleUser}),
+		})
+	}
+	return err
+}
+
+func (s *auditedUserService) GenerateToken(ctx context.Context, user domain.User) (string, error) {
+	token, err := s.next.GenerateToken(ctx, user)
+	entry := domain.AuditEntry{
+		Action:     domain.AuditSignIn,
+		ActorName:  user.Name,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   user.Name,
+	}
+	if err != nil {
+		entry.Outcome = domain.AuditFailure
+		entry.After = audit_service.Snapshot(map[string]string{"reason": err.Error()})
+	} else if userId, parseErr := s.next.ParseToken(token); parseErr == nil {
+		if id, ok := userId.(float64); ok {
+			entry.ActorID = int(id)
+		}
+	}
+	record(ctx, s.audit, entry)
+	return token, err
+}
+
+func (s *auditedUserService) ParseToken(tokenStr string) (interface{}, error) {
+	return s.next.ParseToken(tokenStr)
+}
+
+func (s *auditedUserService) IsActive(ctx context.Context, userId int) (bool, error) {
+	return s.next.IsActive(ctx, userId)
+}
+
+func (s *auditedUserService) IsAdmin(ctx context.Context, userId int) (bool, error) {
+	return s.next.IsAdmin(ctx, userId)
+}
+
+type auditedTerminalService struct {
+	next  TerminalServicePort
+	audit AuditServicePort
+}
+
+// favoritesChange records a favorite change with the user's favorites before
+// it and, derived from them, after it. change returns the terminal it added
+// or removed and must pass ctx on, so the terminal service can report the
+// favorites it read in the change's own transaction. If it does not, the
+// entry has neither snapshot.
+func (s *auditedTerminalService) favoritesChange(ctx context.Context, action string, userId int, change func(ctx context.Context) (int, error)) error {
+	var before []int
+	observed := false
+	ctx = terminal_service.ObserveFavorites(ctx, func(ids []int) {
+		before, observed = ids, true
+	})
+	terminalId, err := change(ctx)
+	if err != nil {
+		return err
+	}
+	entry := domain.AuditEntry{
+		Action:     action,
+		ActorID:    userId,
+		TargetType: domain.AuditTargetTerminal,
+		TargetID:   strconv.Itoa(terminalId),
+	}
+	if observed {
+		after := make([]int, 0, len(before)+1)
+		for _, id := range before {
+			if id != terminalId {
+				after = append(after, id)
+			}
+		}
+		if action == domain.AuditFavoriteAdd {
+			after = append(after, terminalId)
+		}
+		entry.Before = audit_service.Snapshot(map[string][]int{"favorites": append([]int{}, before...)})
+		entry.After = audit_service.Snapshot(map[string][]int{"favorites": after})
+	}
+	record(ctx, s.audit, entry)
+	return nil
+}
+
+func (s *auditedTerminalService) AddToFavorite(ctx context.Context, terminalId int, userId int) error {
+	return s.favoritesChange(ctx, domain.AuditFavoriteAdd, userId, func(ctx context.Context) (int, error) {
+		return terminalId, s.next.AddToFavorite(ctx, terminalId, userId)
+	})
+}
+
+func (s *auditedTerminalService) SortTerminals(ctx context.Context, userTerminalIDs []int, notes map[int]domain.FavoriteNote, opts terminal_service.ListOptions) ([]domain.FakeTerminal, error) {
+	return s.next.SortTerminals(ctx, userTerminalIDs, notes, opts)
+}
+
+func (s *auditedTerminalService) GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error) {
+	return s.next.GetFavoriteTerminalIds(ctx, userId)
+}
+
+func (s *auditedTerminalService) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error {
+	return s.favoritesChange(ctx, domain.AuditFavoriteRemove, userId, func(ctx context.Context) (int, error) {
+		return terminalID, s.next.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+	})
+}
+
+func (s *auditedTerminalService) GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error) {
+	return s.next.GetFavoriteNotes(ctx, userId)
+}
+
+// SetFavoriteNote is not audited: notes are private to the user and do not
+// change which terminals are favorites.
+func (s *auditedTerminalService) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error {
+	return s.next.SetFavoriteNote(ctx, userId, terminalID, note)
+}
+
+// UndoRemoveFavorite is recorded as the add it amounts to.
+func (s *auditedTerminalService) UndoRemoveFavorite(ctx context.Context, userId int) (int, error) {
+	var terminalID int
+	err := s.favoritesChange(ctx, domain.AuditFavoriteAdd, userId, func(ctx context.Context) (int, error) {
+		var err error
+		terminalID, err = s.next.UndoRemoveFavorite(ctx, userId)
+		return terminalID, err
+	})
+	return terminalID, err
+}
+
+func (s *auditedTerminalService) SaveView(ctx context.Context, view domain.View) (domain.View, error) {
+	return s.next.SaveView(ctx, view)
+}
+
+func (s *auditedTerminalService) GetViews(ctx context.Context, userId int) ([]domain.View, error) {
+	return s.next.GetViews(ctx, userId)
+}
+
+func (s *auditedTerminalService) DeleteView(ctx context.Context, userId int, name string) error {
+	return s.next.DeleteView(ctx, userId, name)
+}
+
+func (s *auditedTerminalService) ResolveView(ctx context.Context, userId int, ref string) (domain.View, error) {
+	return s.next.ResolveView(ctx, userId, ref)
+}
+
+func (s *auditedTerminalService) GetFavoritesVersion(ctx context.Context, userId int) (int64, error) {
+	return s.next.GetFavoritesVersion(ctx, userId)
+}
+
+func (s *auditedTerminalService) GetSiteTree(ctx context.Context) ([]domain.SiteNode, error) {
+	return s.next.GetSiteTree(ctx)
+}
+
+func (s *auditedTerminalService) GetSite(ctx context.Context, id int) (domain.SiteNode, error) {
+	return s.next.GetSite(ctx, id)
+}
+
+func (s *auditedTerminalService) AddToFavoriteIfMatch(ctx context.Context, terminalId int, userId int, version int64) error {
+	return s.favoritesChange(ctx, domain.AuditFavoriteAdd, userId, func(ctx context.Context) (int, error) {
+		return terminalId, s.next.AddToFavoriteIfMatch(ctx, terminalId, userId, version)
+	})
+}
+
+func (s *auditedTerminalService) RemoveFromFavoriteTerminalIfMatch(ctx context.Context, terminalID int, userId int, version int64) error {
+	return s.favoritesChange(ctx, domain.AuditFavoriteRemove, userId, func(ctx context.Context) (int, error) {
+		return terminalID, s.next.RemoveFromFavoriteTerminalIfMatch(ctx, terminalID, userId, version)
+	})
+}
+
+type auditedAdminService struct {
+	next  AdminServicePort
+	audit AuditServicePort
+}
+
+var (
+	notDeleted = audit_service.Snapshot(map[string]bool{"deleted": false})
+	deleted    = audit_service.Snapshot(map[string]bool{"deleted": true})
+)
+
+// adminAction records an admin action on the target with id once it took
+// effect and returns err.
+func adminAction(ctx context.Context, audit AuditServicePort, action string, targetType string, id int, before, after json.RawMessage, err error) error {
+	if err == nil {
+		record(ctx, audit, domain.AuditEntry{
+			Action:     action,
+			TargetType: targetType,
+			TargetID:   strconv.Itoa(id),
+			Before:     before,
+			After:      after,
+		})
+	}
+	return err
+}
+
+func (s *auditedAdminService) DeleteTerminal(ctx context.Context, id int) error {
+	err := s.next.DeleteTerminal(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditTerminalDelete, domain.AuditTargetTerminal, id, notDeleted, deleted, err)
+}
+
+func (s *auditedAdminService) RestoreTerminal(ctx context.Context, id int) error {
+	err := s.next.RestoreTerminal(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditTerminalRestore, domain.AuditTargetTerminal, id, deleted, notDeleted, err)
+}
+
+func (s *auditedAdminService) PurgeTerminal(ctx context.Context, id int) error {
+	err := s.next.PurgeTerminal(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditTerminalPurge, domain.AuditTargetTerminal, id, deleted, nil, err)
+}
+
+func (s *auditedAdminService) GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error) {
+	return s.next.GetDeletedTerminals(ctx)
+}
+
+func (s *auditedAdminService) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	err := s.next.AttachTag(ctx, terminalID, tag)
+	return adminAction(ctx, s.audit, domain.AuditTerminalTag, domain.AuditTargetTerminal, terminalID,
+		nil, audit_service.Snapshot(map[string]string{"tag": tag.String()}), err)
+}
+
+func (s *auditedAdminService) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error {
+	err := s.next.DetachTag(ctx, terminalID, tag)
+	return adminAction(ctx, s.audit, domain.AuditTerminalUntag, domain.AuditTargetTerminal, terminalID,
+		audit_service.Snapshot(map[string]string{"tag": tag.String()}), nil, err)
+}
+
+func (s *auditedAdminService) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error {
+	err := s.next.SetTerminalLocation(ctx, terminalID, location)
+	return adminAction(ctx, s.audit, domain.AuditTerminalLocate, domain.AuditTargetTerminal, terminalID,
+		nil, audit_service.Snapshot(location), err)
+}
+
+func (s *auditedAdminService) CreateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	created, err := s.next.CreateSite(ctx, site)
+	return created, adminAction(ctx, s.audit, domain.AuditSiteCreate, domain.AuditTargetSite, created.ID,
+		nil, audit_service.Snapshot(created), err)
+}
+
+func (s *auditedAdminService) UpdateSite(ctx context.Context, site domain.Site) (domain.Site, error) {
+	updated, err := s.next.UpdateSite(ctx, site)
+	return updated, adminAction(ctx, s.audit, domain.AuditSiteUpdate, domain.AuditTargetSite, site.ID,
+		nil, audit_service.Snapshot(updated), err)
+}
+
+func (s *auditedAdminService) DeleteSite(ctx context.Context, id int) error {
+	err := s.next.DeleteSite(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditSiteDelete, domain.AuditTargetSite, id, nil, nil, err)
+}
+
+func (s *auditedAdminService) SetTerminalSite(ctx context.Context, terminalID int, siteID int) error {
+	err := s.next.SetTerminalSite(ctx, terminalID, siteID)
+	return adminAction(ctx, s.audit, domain.AuditTerminalSite, domain.AuditTargetTerminal, terminalID,
+		nil, audit_service.Snapshot(map[string]int{"site_id": siteID}), err)
+}
+
+func (s *auditedAdminService) DeleteUser(ctx context.Context, id int) error {
+	err := s.next.DeleteUser(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditUserDelete, domain.AuditTargetUser, id, notDeleted, deleted, err)
+}
+
+func (s *auditedAdminService) RestoreUser(ctx context.Context, id int) error {
+	err := s.next.RestoreUser(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditUserRestore, domain.AuditTargetUser, id, deleted, notDeleted, err)
+}
+
+func (s *auditedAdminService) PurgeUser(ctx context.Context, id int) error {
+	err := s.next.PurgeUser(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditUserPurge, domain.AuditTargetUser, id, deleted, nil, err)
+}
+
+func (s *auditedAdminService) GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error) {
+	return s.next.GetDeletedUsers(ctx)
+}
+
+func (s *auditedAdminService) PurgeExpired(ctx context.Context, before time.Time) (int, int, error) {
+	return s.next.PurgeExpired(ctx, before)
+}
+
+func (s *auditedAdminService) RunPurge(ctx context.Context, retention time.Duration, interval time.Duration) {
+	s.next.RunPurge(ctx, retention, interval)
+}
+
+// redacted replaces secrets in audit snapshots.
+const redacted = "[redacted]"
+
+type auditedWebhookService struct {
+	next  WebhookServicePort
+	audit AuditServicePort
+}
+
+func (s *auditedWebhookService) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	created, err := s.next.CreateWebhook(ctx, hook)
+	return created, adminAction(ctx, s.audit, domain.AuditWebhookCreate, domain.AuditTargetWebhook, created.ID,
+		nil, audit_service.Snapshot(redactWebhook(created)), err)
+}
+
+func (s *auditedWebhookService) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s *auditedWebhookService) DeleteWebhook(ctx context.Context, id int) error {
+	err := s.next.DeleteWebhook(ctx, id)
+	return adminAction(ctx, s.audit, domain.AuditWebhookDelete, domain.AuditTargetWebhook, id, nil, nil, err)
+}
+
+func (s *auditedWebhookService) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error) {
+	return s.next.GetWebhookDeliveries(ctx, webhookID, state)
+}
+
+func (s *auditedWebhookService) RedeliverWebhook(ctx context.Context, webhookID int, id int64) error {
+	err := s.next.RedeliverWebhook(ctx, webhookID, id)
+	return adminAction(ctx, s.audit, domain.AuditWebhookRedeliver, domain.AuditTargetWebhook, webhookID,
+		nil, audit_service.Snapshot(map[string]int64{"delivery_id": id}), err)
+}
+
+func (s *auditedWebhookService) DispatchWebhooks(ctx context.Context, now time.Time) (int, int, error) {
+	return s.next.DispatchWebhooks(ctx, now)
+}
+
+func (s *auditedWebhookService) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	s.next.RunWebhookDispatcher(ctx, interval)
+}
+
+// redactWebhook hides the signing secret, if the webhook has one.
+func redactWebhook(hook domain.Webhook) domain.Webhook {
+	if hook.Secret != "" {
+		hook.Secret = redacted
+	}
+	return hook
+}
+
+// auditedNotificationService records changes to team channels, which only
+// admins can make. Users' own channels are not audited.
+type auditedNotificationService struct {
+	next  NotificationServicePort
+	audit AuditServicePort
+}
+
+func (s *auditedNotificationService) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	created, err := s.next.CreateNotificationChannel(ctx, channel)
+	return created, s.teamAction(ctx, domain.AuditChannelCreate, channel.UserID, created.ID, redactChannel(created), err)
+}
+
+func (s *auditedNotificationService) GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error) {
+	return s.next.GetNotificationChannels(ctx, userId)
+}
+
+func (s *auditedNotificationService) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	updated, err := s.next.UpdateNotificationChannel(ctx, channel)
+	return updated, s.teamAction(ctx, domain.AuditChannelUpdate, channel.UserID, channel.ID, redactChannel(updated), err)
+}
+
+func (s *auditedNotificationService) DeleteNotificationChannel(ctx context.Context, userId int, id int) error {
+	err := s.next.DeleteNotificationChannel(ctx, userId, id)
+	return s.teamAction(ctx, domain.AuditChannelDelete, userId, id, nil, err)
+}
+
+func (s *auditedNotificationService) TestNotificationChannel(ctx context.Context, userId int, id int) error {
+	return s.next.TestNotificationChannel(ctx, userId, id)
+}
+
+// teamAction records action on the channel with id if it is a team channel,
+// that is if userId is 0.
+func (s *auditedNotificationService) teamAction(ctx context.Context, action string, userId int, id int, after any, err error) error {
+	if userId != 0 {
+		return err
+	}
+	var snapshot json.RawMessage
+	if after != nil {
+		snapshot = audit_service.Snapshot(after)
+	}
+	return adminAction(ctx, s.audit, action, domain.AuditTargetNotificationChannel, id, nil, snapshot, err)
+}
+
+// redactChannel hides the target, which may be a webhook URL with a token
+// in it.
+func redactChannel(channel domain.NotificationChannel) domain.NotificationChannel {
+	channel.Target = redacted
+	return channel
+}
+
+// auditedHeartbeatService records issued and revoked heartbeat tokens, never
+// the token itself.
+type auditedHeartbeatService struct {
+	next  HeartbeatServicePort
+	audit AuditServicePort
+}
+
+func (s *auditedHeartbeatService) IssueHeartbeatToken(ctx context.Context, terminalID int) (string, error) {
+	token, err := s.next.IssueHeartbeatToken(ctx, terminalID)
+	return token, adminAction(ctx, s.audit, domain.AuditHeartbeatTokenIssue, domain.AuditTargetTerminal, terminalID, nil, nil, err)
+}
+
+func (s *auditedHeartbeatService) RevokeHeartbeatToken(ctx context.Context, terminalID int) error {
+	err := s.next.RevokeHeartbeatToken(ctx, terminalID)
+	return adminAction(ctx, s.audit, domain.AuditHeartbeatTokenRevoke, domain.AuditTargetTerminal, terminalID, nil, nil, err)
+}
+
+func (s *auditedHeartbeatService) RecordHeartbeat(ctx context.Context, token string, heartbeat domain.Heartbeat) error {
+	return s.next.RecordHeartbeat(ctx, token, heartbeat)
+}
+
+func (s *auditedHeartbeatService) GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error) {
+	return s.next.GetHeartbeats(ctx)
+}
+
+func (s *auditedHeartbeatService) SweepHeartbeats(ctx context.Context, now time.Time, timeout time.Duration) (int, error) {
+	return s.next.SweepHeartbeats(ctx, now, timeout)
+}
+
+func (s *auditedHeartbeatService) RunHeartbeatSweeper(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	s.next.RunHeartbeatSweeper(ctx, interval, timeout)
+}
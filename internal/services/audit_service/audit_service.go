@@ -0,0 +1,107 @@
+package audit_service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"time"
+)
+
+type clientKey struct{}
+
+type actorKey struct{}
+
+type client struct {
+	ip        string
+	requestID string
+}
+
+// WithClient returns a copy of ctx that carries the caller's IP and request
+// ID, which Record copies into every entry made for the request.
+func WithClient(ctx context.Context, ip string, requestID string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client{ip: ip, requestID: requestID})
+}
+
+// WithActor returns a copy of ctx that carries the signed-in user's ID.
+func WithActor(ctx context.Context, userId int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userId)
+}
+
+// ActorFromContext returns the ID stored by WithActor, or 0.
+func ActorFromContext(ctx context.Context) int {
+	userId, _ := ctx.Value(actorKey{}).(int)
+	return userId
+}
+
+// Snapshot encodes v for the Before and After fields of an entry.
+func Snapshot(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+type AuditService struct {
+	auditRepositoryPort repositories.AuditRepositoryPort
+}
+
+func NewAuditService(auditRepositoryPort repositories.AuditRepositoryPort) *AuditService {
+	return &AuditService{
+		auditRepositoryPort: auditRepositoryPort,
+	}
+}
+
+// RecordAudit appends entry to the audit log, filling in the actor, IP and
+// request ID from ctx where the entry leaves them empty.
+func (as *AuditService) RecordAudit(ctx context.Context, entry domain.AuditEntry) error {
+	if entry.ActorID == 0 {
+		entry.ActorID = ActorFromContext(ctx)
+	}
+	if c, ok := ctx.Value(clientKey{}).(client); ok {
+		if entry.IP == "" {
+			entry.IP = c.ip
+		}
+		if entry.RequestID == "" {
+			entry.RequestID = c.requestID
+		}
+	}
+	return as.auditRepositoryPort.InsertAuditEntry(ctx, entry)
+}
+
+func (as *AuditService) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	return as.auditRepositoryPort.GetAuditEntries(ctx, filter)
+}
+
+// PurgeAuditBefore removes entries recorded before the given time and
+// reports how many there were.
+func (as *AuditService) PurgeAuditBefore(ctx context.Context, before time.Time) (int, error) {
+	return as.auditRepositoryPort.DeleteAuditEntriesBefore(ctx, before)
+}
+
+// RunAuditRetention calls PurgeAuditBefore every interval for entries older
+// than retention, until ctx is cancelled. A non-positive retention keeps
+// entries forever.
+func (as *AuditService) RunAuditRetention(ctx context.Context, retention time.Duration, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+	log := logger.GetLogger()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := as.PurgeAuditBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Errorf("failed to purge audit log: %v", err)
+		} else if purged > 0 {
+			log.Infof("purged %d audit entries older than %s", purged, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
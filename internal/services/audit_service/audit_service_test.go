@@ -0,0 +1,65 @@
+package audit_service
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRecordAuditFillsRequestFields(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockAuditRepositoryPort(ctl)
+	service := NewAuditService(repo)
+
+	ctx := WithActor(WithClient(context.Background(), "10.0.0.1", "req-1"), 7)
+	repo.EXPECT().InsertAuditEntry(gomock.Any(), domain.AuditEntry{
+		Action:     domain.AuditTerminalDelete,
+		Outcome:    domain.AuditSuccess,
+		ActorID:    7,
+		TargetType: domain.AuditTargetTerminal,
+		TargetID:   "4",
+		IP:         "10.0.0.1",
+		RequestID:  "req-1",
+	}).Return(nil).Times(1)
+	err := service.RecordAudit(ctx, domain.AuditEntry{
+		Action:     domain.AuditTerminalDelete,
+		Outcome:    domain.AuditSuccess,
+		TargetType: domain.AuditTargetTerminal,
+		TargetID:   "4",
+	})
+	require.NoError(t, err)
+}
+
+func TestRecordAuditKeepsExplicitActor(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockAuditRepositoryPort(ctl)
+	service := NewAuditService(repo)
+
+	repo.EXPECT().InsertAuditEntry(gomock.Any(), domain.AuditEntry{Action: domain.AuditSignIn, ActorID: 3}).Return(nil).Times(1)
+	err := service.RecordAudit(WithActor(context.Background(), 7), domain.AuditEntry{Action: domain.AuditSignIn, ActorID: 3})
+	require.NoError(t, err)
+}
+
+func TestRunAuditRetention(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockAuditRepositoryPort(ctl)
+	service := NewAuditService(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.EXPECT().DeleteAuditEntriesBefore(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, before time.Time) (int, error) {
+		require.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Minute)
+		cancel()
+		return 2, nil
+	}).Times(1)
+	service.RunAuditRetention(ctx, 24*time.Hour, time.Hour)
+}
+
+func TestRunAuditRetentionDisabled(t *testing.T) {
+	ctl := gomock.NewController(t)
+	service := NewAuditService(repoMock.NewMockAuditRepositoryPort(ctl))
+	service.RunAuditRetention(context.Background(), 0, time.Hour)
+}
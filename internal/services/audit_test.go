@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/notification_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/webhook_service"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newAuditedPort(ctl *gomock.Controller) (*ServicePort, *repositories.RepositoryPort, *repoMock.MockAuditRepositoryPort) {
+	auditRepo := repoMock.NewMockAuditRepositoryPort(ctl)
+	repo := &repositories.RepositoryPort{
+		UserRepositoryPort:         repoMock.NewMockUserRepositoryPort(ctl),
+		TerminalRepositoryPort:     repoMock.NewMockTerminalRepositoryPort(ctl),
+		AuditRepositoryPort:        auditRepo,
+		WebhookRepositoryPort:      repoMock.NewMockWebhookRepositoryPort(ctl),
+		NotificationRepositoryPort: repoMock.NewMockNotificationRepositoryPort(ctl),
+		HeartbeatRepositoryPort:    repoMock.NewMockHeartbeatRepositoryPort(ctl),
+		UnitOfWork:                 repoMock.NewMockUnitOfWork(ctl),
+	}
+	return Audited(NewServicePort(repo, user_service.AuthConfig{}, webhook_service.Config{}, notification_service.Config{})), repo, auditRepo
+}
+
+// expectFavoritesTx expects a change to the favorites in a transaction that
+// runs on repo, and reports whether it is running.
+func expectFavoritesTx(repo *repositories.RepositoryPort) *bool {
+	inTx := new(bool)
+	repo.UnitOfWork.(*repoMock.MockUnitOfWork).EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			*inTx = true
+			defer func() { *inTx = false }()
+			return fn(repo)
+		}).Times(1)
+	return inTx
+}
+
+func TestAuditedSignInFailure(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+
+	expErr := errors.New("no user found with given name")
+	repo.UserRepositoryPort.(*repoMock.MockUserRepositoryPort).EXPECT().GetUser(gomock.Any(), "Khalid").Return(domain.User{}, expErr).Times(1)
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		require.Equal(t, domain.AuditSignIn, entry.Action)
+		require.Equal(t, domain.AuditFailure, entry.Outcome)
+		require.Equal(t, "Khalid", entry.ActorName)
+		require.Zero(t, entry.ActorID)
+		require.Equal(t, "10.0.0.1", entry.IP)
+		require.Equal(t, "req-1", entry.RequestID)
+		require.JSONEq(t, `{"reason":"no user found with given name"}`, string(entry.After))
+		return nil
+	}).Times(1)
+
+	ctx := audit_service.WithClient(context.Background(), "10.0.0.1", "req-1")
+	_, err := port.GenerateToken(ctx, domain.User{Name: "Khalid", Password: "secret"})
+	require.Equal(t, expErr, err)
+}
+
+func TestAuditedFavoriteChanges(t *testing.T) {
+	cases := []struct {
+		name   string
+		action string
+		change func(port *ServicePort, terminalRepo *repoMock.MockTerminalRepositoryPort) error
+		after  string
+	}{
+		{
+			name:   "add",
+			action: domain.AuditFavoriteAdd,
+			change: func(port *ServicePort, terminalRepo *repoMock.MockTerminalRepositoryPort) error {
+				terminalRepo.EXPECT().AddToFavorites(gomock.Any(), 4, 1).Return(nil).Times(1)
+				return port.AddToFavorite(context.Background(), 4, 1)
+			},
+			after: `{"favorites":[1,2,4]}`,
+		},
+		{
+			name:   "remove",
+			action: domain.AuditFavoriteRemove,
+			change: func(port *ServicePort, terminalRepo *repoMock.MockTerminalRepositoryPort) error {
+				terminalRepo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), 2, 1).Return(nil).Times(1)
+				return port.RemoveFromFavoriteTerminal(context.Background(), 2, 1)
+			},
+			after: `{"favorites":[1]}`,
+		},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			port, repo, auditRepo := newAuditedPort(ctl)
+			terminalRepo := repo.TerminalRepositoryPort.(*repoMock.MockTerminalRepositoryPort)
+
+			inTx := expectFavoritesTx(repo)
+			terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).DoAndReturn(func(context.Context, int) ([]int, error) {
+				require.True(t, *inTx, "the favorites before the change must be read in its transaction")
+				return []int{1, 2}, nil
+			}).Times(1)
+			auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+				require.Equal(t, tCase.action, entry.Action)
+				require.Equal(t, domain.AuditSuccess, entry.Outcome)
+				require.Equal(t, 1, entry.ActorID)
+				require.Equal(t, domain.AuditTargetTerminal, entry.TargetType)
+				require.JSONEq(t, `{"favorites":[1,2]}`, string(entry.Before))
+				require.JSONEq(t, tCase.after, string(entry.After))
+				return nil
+			}).Times(1)
+			require.NoError(t, tCase.change(port, terminalRepo))
+		})
+	}
+}
+
+func TestAuditedUndoRemoveFavorite(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+	terminalRepo := repo.TerminalRepositoryPort.(*repoMock.MockTerminalRepositoryPort)
+
+	expectFavoritesTx(repo)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{1}, nil).Times(1)
+	terminalRepo.EXPECT().RestoreRemovedFavoriteNote(gomock.Any(), 1).Return(2, nil).Times(1)
+	terminalRepo.EXPECT().AddToFavorites(gomock.Any(), 2, 1).Return(nil).Times(1)
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		require.Equal(t, domain.AuditFavoriteAdd, entry.Action)
+		require.Equal(t, "2", entry.TargetID)
+		require.JSONEq(t, `{"favorites":[1]}`, string(entry.Before))
+		require.JSONEq(t, `{"favorites":[1,2]}`, string(entry.After))
+		return nil
+	}).Times(1)
+	id, err := port.UndoRemoveFavorite(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, id)
+}
+
+func TestAuditedFailedChangeIsNotRecorded(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, _ := newAuditedPort(ctl)
+
+	expErr := errors.New("terminal with ID 4321 doesnt exist in table terminals")
+	terminalRepo := repo.TerminalRepositoryPort.(*repoMock.MockTerminalRepositoryPort)
+	expectFavoritesTx(repo)
+	terminalRepo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), 1).Return([]int{}, nil).Times(1)
+	terminalRepo.EXPECT().AddToFavorites(gomock.Any(), 4321, 1).Return(expErr).Times(1)
+	require.Equal(t, expErr, port.AddToFavorite(context.Background(), 4321, 1))
+}
+
+func TestAuditedAdminAction(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+
+	repo.TerminalRepositoryPort.(*repoMock.MockTerminalRepositoryPort).EXPECT().SoftDeleteTerminal(gomock.Any(), 4).Return(nil).Times(1)
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		require.Equal(t, domain.AuditTerminalDelete, entry.Action)
+		require.Equal(t, 9, entry.ActorID)
+		require.Equal(t, "4", entry.TargetID)
+		require.JSONEq(t, `{"deleted":false}`, string(entry.Before))
+		require.JSONEq(t, `{"deleted":true}`, string(entry.After))
+		return errors.New("DB is down")
+	}).Times(1)
+	require.NoError(t, port.DeleteTerminal(audit_service.WithActor(context.Background(), 9), 4))
+}
+
+func TestAuditedTagChange(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+
+	tag := domain.Tag{Key: "type", Value: "atm"}
+	repo.TerminalRepositoryPort.(*repoMock.MockTerminalRepositoryPort).EXPECT().AttachTag(gomock.Any(), 4, tag).Return(nil).Times(1)
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		require.Equal(t, domain.AuditTerminalTag, entry.Action)
+		require.Equal(t, "4", entry.TargetID)
+		require.Empty(t, entry.Before)
+		require.JSONEq(t, `{"tag":"type=atm"}`, string(entry.After))
+		return nil
+	}).Times(1)
+	require.NoError(t, port.AttachTag(context.Background(), 4, tag))
+
+	// An invalid tag never reaches the repository and is not recorded.
+	require.ErrorIs(t, port.AttachTag(context.Background(), 4, domain.Tag{Key: "type"}), domain.ErrInvalidTag)
+}
+
+func TestAuditedWebhookChanges(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+	webhookRepo := repo.WebhookRepositoryPort.(*repoMock.MockWebhookRepositoryPort)
+
+	hook := domain.Webhook{URL: "https://hooks.example.com", Secret: "0123456789abcdef"}
+	webhookRepo.EXPECT().CreateWebhook(gomock.Any(), hook).DoAndReturn(func(_ context.Context, hook domain.Webhook) (domain.Webhook, error) {
+		hook.ID = 3
+		return hook, nil
+	}).Times(1)
+	webhookRepo.EXPECT().RedeliverWebhookDelivery(gomock.Any(), 3, int64(8)).Return(nil).Times(1)
+	webhookRepo.EXPECT().DeleteWebhook(gomock.Any(), 3).Return(nil).Times(1)
+	var entries []domain.AuditEntry
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}).Times(3)
+
+	created, err := port.CreateWebhook(context.Background(), hook)
+	require.NoError(t, err)
+	require.Equal(t, hook.Secret, created.Secret, "only the audit entry is redacted")
+	require.NoError(t, port.RedeliverWebhook(context.Background(), 3, 8))
+	require.NoError(t, port.DeleteWebhook(context.Background(), 3))
+
+	require.Equal(t, domain.AuditWebhookCreate, entries[0].Action)
+	require.Equal(t, domain.AuditTargetWebhook, entries[0].TargetType)
+	require.Equal(t, "3", entries[0].TargetID)
+	require.JSONEq(t, `{"id":3,"url":"https://hooks.example.com","secret":"[redacted]","created_at":"0001-01-01T00:00:00Z"}`, string(entries[0].After))
+	require.Equal(t, domain.AuditWebhookRedeliver, entries[1].Action)
+	require.JSONEq(t, `{"delivery_id":8}`, string(entries[1].After))
+	require.Equal(t, domain.AuditWebhookDelete, entries[2].Action)
+	require.Equal(t, "3", entries[2].TargetID)
+}
+
+func TestAuditedTeamChannelChanges(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+	notificationRepo := repo.NotificationRepositoryPort.(*repoMock.MockNotificationRepositoryPort)
+
+	team := domain.NotificationChannel{Name: "ops", Kind: domain.ChannelSlack, Target: "https://hooks.example.com/T0/secret"}
+	notificationRepo.EXPECT().CreateNotificationChannel(gomock.Any(), team).DoAndReturn(
+		func(_ context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+			channel.ID = 5
+			return channel, nil
+		}).Times(1)
+	notificationRepo.EXPECT().DeleteNotificationChannel(gomock.Any(), 0, 5).Return(nil).Times(1)
+	var entries []domain.AuditEntry
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}).Times(2)
+
+	_, err := port.CreateNotificationChannel(context.Background(), team)
+	require.NoError(t, err)
+	require.NoError(t, port.DeleteNotificationChannel(context.Background(), 0, 5))
+	require.Equal(t, domain.AuditChannelCreate, entries[0].Action)
+	require.Equal(t, domain.AuditTargetNotificationChannel, entries[0].TargetType)
+	require.Equal(t, "5", entries[0].TargetID)
+	require.NotContains(t, string(entries[0].After), "secret")
+	require.Contains(t, string(entries[0].After), `"target":"[redacted]"`)
+	require.Equal(t, domain.AuditChannelDelete, entries[1].Action)
+
+	// A user's own channel is not an admin action.
+	notificationRepo.EXPECT().DeleteNotificationChannel(gomock.Any(), 1, 6).Return(nil).Times(1)
+	require.NoError(t, port.DeleteNotificationChannel(context.Background(), 1, 6))
+}
+
+func TestAuditedHeartbeatTokens(t *testing.T) {
+	ctl := gomock.NewController(t)
+	port, repo, auditRepo := newAuditedPort(ctl)
+	heartbeatRepo := repo.HeartbeatRepositoryPort.(*repoMock.MockHeartbeatRepositoryPort)
+
+	heartbeatRepo.EXPECT().SetHeartbeatToken(gomock.Any(), 4, gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	heartbeatRepo.EXPECT().DeleteHeartbeatToken(gomock.Any(), 4).Return(nil).Times(1)
+	var entries []domain.AuditEntry
+	auditRepo.EXPECT().InsertAuditEntry(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry domain.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}).Times(2)
+
+	token, err := port.IssueHeartbeatToken(context.Background(), 4)
+	require.NoError(t, err)
+	require.NoError(t, port.RevokeHeartbeatToken(context.Background(), 4))
+	require.Equal(t, domain.AuditHeartbeatTokenIssue, entries[0].Action)
+	require.Equal(t, domain.AuditTargetTerminal, entries[0].TargetType)
+	require.Equal(t, "4", entries[0].TargetID)
+	require.Equal(t, domain.AuditHeartbeatTokenRevoke, entries[1].Action)
+	for _, entry := range entries {
+		require.Empty(t, entry.Before)
+		require.Empty(t, entry.After)
+		b, err := json.Marshal(entry)
+		require.NoError(t, err)
+		require.NotContains(t, string(b), token)
+	}
+}
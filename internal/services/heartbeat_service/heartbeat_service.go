@@ -0,0 +1,116 @@
+package heartbeat_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"time"
+)
+
+type HeartbeatService struct {
+	heartbeatRepositoryPort repositories.HeartbeatRepositoryPort
+	unitOfWork              repositories.UnitOfWork
+}
+
+func NewHeartbeatService(heartbeatRepositoryPort repositories.HeartbeatRepositoryPort, unitOfWork repositories.UnitOfWork) *HeartbeatService {
+	return &HeartbeatService{
+		heartbeatRepositoryPort: heartbeatRepositoryPort,
+		unitOfWork:              unitOfWork,
+	}
+}
+
+// IssueHeartbeatToken generates a new token for the terminal, replacing any
+// earlier one. Only its hash is stored, so the token cannot be shown again.
+func (hs *HeartbeatService) IssueHeartbeatToken(ctx context.Context, terminalID int) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate heartbeat token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := hs.heartbeatRepositoryPort.SetHeartbeatToken(ctx, terminalID, hashToken(token), time.Now()); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (hs *HeartbeatService) RevokeHeartbeatToken(ctx context.Context, terminalID int) error {
+	return hs.heartbeatRepositoryPort.DeleteHeartbeatToken(ctx, terminalID)
+}
+
+// RecordHeartbeat stores a heartbeat from the terminal holding token and
+// brings it back online if the sweeper had marked it offline. An unknown
+// token fails with repositories.ErrNotFound.
+func (hs *HeartbeatService) RecordHeartbeat(ctx context.Context, token string, heartbeat domain.Heartbeat) error {
+	if err := heartbeat.Validate(); err != nil {
+		return err
+	}
+	var terminalID int
+	var online bool
+	err := hs.unitOfWork.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		var err error
+		terminalID, err = tx.RecordHeartbeat(ctx, hashToken(token), heartbeat, time.Now())
+		if err != nil {
+			return err
+		}
+		online, err = tx.MarkTerminalOnline(ctx, terminalID, "heartbeat resumed")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if online {
+		logger.GetLogger().Infof("terminal %d is back online", terminalID)
+	}
+	return nil
+}
+
+func (hs *HeartbeatService) GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error) {
+	return hs.heartbeatRepositoryPort.GetHeartbeats(ctx)
+}
+
+// SweepHeartbeats marks the active terminals that have not reported within
+// timeout of now, or of their token being issued if they never reported,
+// offline and returns how many it marked.
+func (hs *HeartbeatService) SweepHeartbeats(ctx context.Context, now time.Time, timeout time.Duration) (int, error) {
+	reason := fmt.Sprintf("heartbeat timeout after %s", timeout)
+	terminals, err := hs.heartbeatRepositoryPort.MarkTerminalsOffline(ctx, now.Add(-timeout), reason)
+	if err != nil {
+		return 0, err
+	}
+	log := logger.GetLogger()
+	for _, terminal := range terminals {
+		log.Infof("terminal %d (%s) went offline after no heartbeat for %s", terminal.ID, terminal.Name, timeout)
+	}
+	return len(terminals), nil
+}
+
+// RunHeartbeatSweeper sweeps every interval until ctx is done. It returns
+// at once if interval or timeout is not positive.
+func (hs *HeartbeatService) RunHeartbeatSweeper(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	if interval <= 0 || timeout <= 0 {
+		return
+	}
+	log := logger.GetLogger()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := hs.SweepHeartbeats(ctx, time.Now(), timeout); err != nil {
+			log.Errorf("failed to sweep heartbeats: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,62 @@
+package heartbeat_service
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newService(t *testing.T) (*HeartbeatService, *repoMock.MockHeartbeatRepositoryPort) {
+	ctl := gomock.NewController(t)
+	heartbeatRepo := repoMock.NewMockHeartbeatRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{HeartbeatRepositoryPort: heartbeatRepo, UnitOfWork: uow})
+		}).AnyTimes()
+	return NewHeartbeatService(heartbeatRepo, uow), heartbeatRepo
+}
+
+func TestIssueAndRecordHeartbeat(t *testing.T) {
+	service, heartbeatRepo := newService(t)
+	var stored string
+	heartbeatRepo.EXPECT().SetHeartbeatToken(gomock.Any(), 3, gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, _ int, hash string, _ time.Time) error {
+		stored = hash
+		return nil
+	}).Times(1)
+	token, err := service.IssueHeartbeatToken(context.Background(), 3)
+	require.NoError(t, err)
+	require.Len(t, token, 64)
+	require.NotEqual(t, token, stored, "only the hash is stored")
+	require.Equal(t, hashToken(token), stored)
+
+	heartbeat := domain.Heartbeat{Firmware: "2.1", IP: "10.0.0.7"}
+	heartbeatRepo.EXPECT().RecordHeartbeat(gomock.Any(), stored, heartbeat, gomock.Any()).Return(3, nil).Times(1)
+	heartbeatRepo.EXPECT().MarkTerminalOnline(gomock.Any(), 3, "heartbeat resumed").Return(true, nil).Times(1)
+	require.NoError(t, service.RecordHeartbeat(context.Background(), token, heartbeat))
+
+	heartbeatRepo.EXPECT().RecordHeartbeat(gomock.Any(), hashToken("unknown"), domain.Heartbeat{}, gomock.Any()).
+		Return(0, repositories.ErrNotFound).Times(1)
+	require.ErrorIs(t, service.RecordHeartbeat(context.Background(), "unknown", domain.Heartbeat{}), repositories.ErrNotFound)
+
+	err = service.RecordHeartbeat(context.Background(), token, domain.Heartbeat{IP: "not-an-ip"})
+	require.ErrorIs(t, err, domain.ErrInvalidHeartbeat)
+}
+
+func TestSweepHeartbeats(t *testing.T) {
+	service, heartbeatRepo := newService(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	heartbeatRepo.EXPECT().MarkTerminalsOffline(gomock.Any(), now.Add(-5*time.Minute), "heartbeat timeout after 5m0s").Return([]domain.Terminal{
+		{ID: 1, Name: "T-1", Status: domain.TerminalOffline},
+		{ID: 2, Name: "T-2", Status: domain.TerminalOffline},
+	}, nil).Times(1)
+
+	n, err := service.SweepHeartbeats(context.Background(), now, 5*time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
@@ -0,0 +1,187 @@
+package notification_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout        = 10 * time.Second
+	DefaultTelegramAPIURL = "https://api.telegram.org"
+)
+
+// Config holds the sender settings. Email channels need SMTPAddr and
+// SMTPFrom, and Telegram channels TelegramToken; TelegramAPIURL may point at
+// a compatible server instead of Telegram's. Notifications link to
+// DashboardURL/terminals/<id> if DashboardURL is set. Zero values of
+// Timeout and TelegramAPIURL use the defaults.
+type Config struct {
+	SMTPAddr       string
+	SMTPFrom       string
+	SMTPUsername   string
+	SMTPPassword   string
+	TelegramToken  string
+	TelegramAPIURL string
+	DashboardURL   string
+	Timeout        time.Duration
+}
+
+type NotificationService struct {
+	notificationRepositoryPort repositories.NotificationRepositoryPort
+	userRepositoryPort         repositories.UserRepositoryPort
+	cfg                        Config
+	senders                    map[string]sender
+}
+
+func NewNotificationService(notificationRepositoryPort repositories.NotificationRepositoryPort, userRepositoryPort repositories.UserRepositoryPort,
+	cfg Config) *NotificationService {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.TelegramAPIURL == "" {
+		cfg.TelegramAPIURL = DefaultTelegramAPIURL
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	senders := map[string]sender{
+		domain.ChannelSlack: slackSender{client: client},
+	}
+	if cfg.SMTPAddr != "" && cfg.SMTPFrom != "" {
+		senders[domain.ChannelEmail] = smtpSender{
+			addr: cfg.SMTPAddr, from: cfg.SMTPFrom, username: cfg.SMTPUsername, password: cfg.SMTPPassword, timeout: cfg.Timeout,
+		}
+	}
+	if cfg.TelegramToken != "" {
+		senders[domain.ChannelTelegram] = telegramSender{client: client, apiURL: cfg.TelegramAPIURL, token: cfg.TelegramToken}
+	}
+	return &NotificationService{
+		notificationRepositoryPort: notificationRepositoryPort,
+		userRepositoryPort:         userRepositoryPort,
+		cfg:                        cfg,
+		senders:                    senders,
+	}
+}
+
+// CreateNotificationChannel validates channel and stores it. A channel of a
+// kind this server is not configured to send fails validation, and a team
+// channel with an unknown member fails with repositories.ErrNotFound.
+func (ns *NotificationService) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	if err := ns.check(ctx, channel); err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	return ns.notificationRepositoryPort.CreateNotificationChannel(ctx, channel)
+}
+
+// GetNotificationChannels lists the user's channels, or the team channels
+// for userId 0.
+func (ns *NotificationService) GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error) {
+	return ns.notificationRepositoryPort.GetNotificationChannels(ctx, userId)
+}
+
+// UpdateNotificationChannel replaces the channel with channel.ID, checked
+// as by CreateNotificationChannel, and returns it as stored.
+func (ns *NotificationService) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error) {
+	if err := ns.check(ctx, channel); err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	err := ns.notificationRepositoryPort.UpdateNotificationChannel(ctx, channel)
+	if err != nil {
+		return domain.NotificationChannel{}, err
+	}
+	return ns.notificationRepositoryPort.GetNotificationChannel(ctx, channel.UserID, channel.ID)
+}
+
+func (ns *NotificationService) DeleteNotificationChannel(ctx context.Context, userId int, id int) error {
+	return ns.notificationRepositoryPort.DeleteNotificationChannel(ctx, userId, id)
+}
+
+// TestNotificationChannel sends domain.SampleNotification to the channel.
+// A failed send wraps domain.ErrNotificationFailed.
+func (ns *NotificationService) TestNotificationChannel(ctx context.Context, userId int, id int) error {
+	channel, err := ns.notificationRepositoryPort.GetNotificationChannel(ctx, userId, id)
+	if err != nil {
+		return err
+	}
+	sample := domain.SampleNotification
+	sample.Link = ns.link(sample.TerminalID)
+	return ns.send(ctx, channel, sample)
+}
+
+// NotifyAlert sends n to the user's own channels and to the team channels
+// they are a member of. It tries every channel and returns the errors of
+// those that failed.
+func (ns *NotificationService) NotifyAlert(ctx context.Context, userId int, n domain.Notification) error {
+	channels, err := ns.notificationRepositoryPort.GetNotificationChannels(ctx, userId)
+	if err != nil {
+		return err
+	}
+	teams, err := ns.notificationRepositoryPort.GetNotificationChannels(ctx, 0)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		if contains(team.Members, userId) {
+			channels = append(channels, team)
+		}
+	}
+	n.Link = ns.link(n.TerminalID)
+	var errs []error
+	for _, channel := range channels {
+		if err = ns.send(ctx, channel, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (ns *NotificationService) send(ctx context.Context, channel domain.NotificationChannel, n domain.Notification) error {
+	s, ok := ns.senders[channel.Kind]
+	if !ok {
+		return fmt.Errorf("%w: channel %d: %s is not configured", domain.ErrNotificationFailed, channel.ID, channel.Kind)
+	}
+	text, err := channel.Render(n)
+	if err != nil {
+		return fmt.Errorf("%w: channel %d: %v", domain.ErrNotificationFailed, channel.ID, err)
+	}
+	if err = s.send(ctx, channel.Target, n.Subject(), text); err != nil {
+		return fmt.Errorf("%w: channel %d: %v", domain.ErrNotificationFailed, channel.ID, err)
+	}
+	return nil
+}
+
+func (ns *NotificationService) check(ctx context.Context, channel domain.NotificationChannel) error {
+	if err := channel.Validate(); err != nil {
+		return err
+	}
+	if _, ok := ns.senders[channel.Kind]; !ok {
+		return fmt.Errorf("%w: %s is not configured on this server", domain.ErrInvalidNotificationChannel, channel.Kind)
+	}
+	for _, member := range channel.Members {
+		if _, err := ns.userRepositoryPort.GetUserByID(ctx, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ns *NotificationService) link(terminalID int) string {
+	if ns.cfg.DashboardURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(ns.cfg.DashboardURL, "/") + "/terminals/" + strconv.Itoa(terminalID)
+}
+
+func contains(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
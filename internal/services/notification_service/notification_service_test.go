@@ -0,0 +1,166 @@
+package notification_service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStandIn accepts mail on a local port and hands every message it
+// receives, recipient first, to the returned channel.
+func smtpStandIn(t *testing.T) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	messages := make(chan []string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(textproto.NewConn(conn), messages)
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func serveSMTP(conn *textproto.Conn, messages chan<- []string) {
+	defer conn.Close()
+	var rcpt string
+	_ = conn.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO", "HELO", "MAIL":
+			_ = conn.PrintfLine("250 OK")
+		case "RCPT":
+			rcpt = line
+			_ = conn.PrintfLine("250 OK")
+		case "DATA":
+			_ = conn.PrintfLine("354 go ahead")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			messages <- []string{rcpt, string(data)}
+			_ = conn.PrintfLine("250 OK")
+		case "QUIT":
+			_ = conn.PrintfLine("221 bye")
+			return
+		default:
+			_ = conn.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func TestNotifyAlert(t *testing.T) {
+	smtpAddr, mails := smtpStandIn(t)
+	var slack, telegram []map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.URL.Path {
+		case "/slack":
+			slack = append(slack, body)
+		case "/botsecret-token/sendMessage":
+			telegram = append(telegram, body)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	ctl := gomock.NewController(t)
+	notificationRepo := repoMock.NewMockNotificationRepositoryPort(ctl)
+	service := NewNotificationService(notificationRepo, repoMock.NewMockUserRepositoryPort(ctl), Config{
+		SMTPAddr: smtpAddr, SMTPFrom: "alerts@example.com", TelegramToken: "secret-token", TelegramAPIURL: api.URL,
+		DashboardURL: "https://dash.example.com/",
+	})
+
+	notificationRepo.EXPECT().GetNotificationChannels(gomock.Any(), 1).Return([]domain.NotificationChannel{
+		{ID: 1, UserID: 1, Name: "mail", Kind: domain.ChannelEmail, Target: "khalid@example.com"},
+		{ID: 2, UserID: 1, Name: "slack", Kind: domain.ChannelSlack, Target: api.URL + "/slack", Template: "{{.State}} {{.TerminalName}} {{.Link}}"},
+	}, nil)
+	notificationRepo.EXPECT().GetNotificationChannels(gomock.Any(), 0).Return([]domain.NotificationChannel{
+		{ID: 3, Name: "ops", Kind: domain.ChannelTelegram, Target: "-100", Members: []int{1}},
+		{ID: 4, Name: "others", Kind: domain.ChannelTelegram, Target: "-200", Members: []int{2}},
+	}, nil)
+
+	err := service.NotifyAlert(context.Background(), 1, domain.Notification{
+		State: domain.AlertFiring, TerminalID: 7, TerminalName: "T-7", Status: "offline", Duration: 5 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	select {
+	case mail := <-mails:
+		require.Equal(t, "RCPT TO:<khalid@example.com>", mail[0])
+		require.Contains(t, mail[1], "\nSubject: [firing] T-7 is offline\n")
+		require.Contains(t, mail[1], "\n\nT-7 has been offline for 5m0s\nhttps://dash.example.com/terminals/7\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	require.Equal(t, []map[string]string{{"text": "firing T-7 https://dash.example.com/terminals/7"}}, slack)
+	require.Equal(t, []map[string]string{{"chat_id": "-100", "text": "T-7 has been offline for 5m0s\nhttps://dash.example.com/terminals/7"}}, telegram)
+}
+
+func TestNotifyAlertFailures(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer api.Close()
+
+	ctl := gomock.NewController(t)
+	notificationRepo := repoMock.NewMockNotificationRepositoryPort(ctl)
+	service := NewNotificationService(notificationRepo, repoMock.NewMockUserRepositoryPort(ctl), Config{})
+
+	notificationRepo.EXPECT().GetNotificationChannels(gomock.Any(), 1).Return([]domain.NotificationChannel{
+		{ID: 1, UserID: 1, Kind: domain.ChannelSlack, Target: api.URL},
+		{ID: 2, UserID: 1, Kind: domain.ChannelEmail, Target: "khalid@example.com"},
+	}, nil)
+	notificationRepo.EXPECT().GetNotificationChannels(gomock.Any(), 0).Return(nil, nil)
+
+	err := service.NotifyAlert(context.Background(), 1, domain.SampleNotification)
+	require.ErrorIs(t, err, domain.ErrNotificationFailed)
+	require.ErrorContains(t, err, "channel 1: unexpected response 403 Forbidden")
+	require.ErrorContains(t, err, "channel 2: email is not configured")
+}
+
+func TestCreateNotificationChannel(t *testing.T) {
+	ctl := gomock.NewController(t)
+	notificationRepo := repoMock.NewMockNotificationRepositoryPort(ctl)
+	userRepo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewNotificationService(notificationRepo, userRepo, Config{})
+
+	// Without an SMTP server, email channels cannot be created.
+	_, err := service.CreateNotificationChannel(context.Background(), domain.NotificationChannel{
+		UserID: 1, Name: "mail", Kind: domain.ChannelEmail, Target: "khalid@example.com",
+	})
+	require.ErrorIs(t, err, domain.ErrInvalidNotificationChannel)
+
+	team := domain.NotificationChannel{Name: "ops", Kind: domain.ChannelSlack, Target: "https://hooks.example.com", Members: []int{1, 2}}
+	userRepo.EXPECT().GetUserByID(gomock.Any(), 1).Return(domain.User{ID: 1}, nil).Times(2)
+	userRepo.EXPECT().GetUserByID(gomock.Any(), 2).Return(domain.User{}, repositories.ErrNotFound).Times(1)
+	_, err = service.CreateNotificationChannel(context.Background(), team)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	team.Members = []int{1}
+	notificationRepo.EXPECT().CreateNotificationChannel(gomock.Any(), team).Return(team, nil).Times(1)
+	_, err = service.CreateNotificationChannel(context.Background(), team)
+	require.NoError(t, err)
+}
@@ -0,0 +1,146 @@
+package notification_service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sender delivers a rendered message to a channel's target.
+type sender interface {
+	send(ctx context.Context, target string, subject string, text string) error
+}
+
+// smtpSender mails the message as plain text. It uses STARTTLS when the
+// server offers it and authenticates only if a username is set.
+type smtpSender struct {
+	addr     string
+	from     string
+	username string
+	password string
+	timeout  time.Duration
+}
+
+func (s smtpSender) send(ctx context.Context, target string, subject string, text string) error {
+	to, err := mail.ParseAddress(target)
+	if err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(s.timeout))
+	host, _, _ := net.SplitHostPort(s.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err = client.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(s.from); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(s.message(to, subject, text)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s smtpSender) message(to *mail.Address, subject string, text string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// slackSender posts {"text": ...} to a Slack-compatible incoming webhook,
+// the channel's target.
+type slackSender struct {
+	client *http.Client
+}
+
+func (s slackSender) send(ctx context.Context, target string, subject string, text string) error {
+	return postJSON(ctx, s.client, target, map[string]string{"text": text})
+}
+
+// telegramSender calls sendMessage of the Telegram Bot API, or a server
+// compatible with it at apiURL, with the channel's target as the chat ID.
+type telegramSender struct {
+	client *http.Client
+	apiURL string
+	token  string
+}
+
+func (s telegramSender) send(ctx context.Context, target string, subject string, text string) error {
+	endpoint := strings.TrimSuffix(s.apiURL, "/") + "/bot" + s.token + "/sendMessage"
+	return postJSON(ctx, s.client, endpoint, map[string]string{"chat_id": target, "text": text})
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may hold a token, so leave it out of the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
@@ -1,33 +1,136 @@
 package services
 
 import (
+	"context"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/services/admin_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/alert_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/audit_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/heartbeat_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/notification_service"
 	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
 	"github.com/dvdxa/add-to-favorites/internal/services/user_service"
+	"github.com/dvdxa/add-to-favorites/internal/services/webhook_service"
+	"time"
 )
 
 type UserServicePort interface {
-	CreateUser(user domain.User) error
-	GenerateToken(user domain.User) (tokenString string, err error)
+	CreateUser(ctx context.Context, user domain.User) error
+	GenerateToken(ctx context.Context, user domain.User) (tokenString string, err error)
 	ParseToken(tokenStr string) (interface{}, error)
+	IsActive(ctx context.Context, userId int) (bool, error)
+	IsAdmin(ctx context.Context, userId int) (bool, error)
 }
 
 type TerminalServicePort interface {
-	AddToFavorite(terminalId int, userId int) error
-	SortTerminals(userTerminalIDs []int) ([]domain.FakeTerminal, error)
-	GetFavoriteTerminalIds(userId int) ([]int, error)
-	RemoveFromFavoriteTerminal(terminalID int, userId int) error
+	AddToFavorite(ctx context.Context, terminalId int, userId int) error
+	SortTerminals(ctx context.Context, userTerminalIDs []int, notes map[int]domain.FavoriteNote, opts terminal_service.ListOptions) ([]domain.FakeTerminal, error)
+	GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error)
+	RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error
+	GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error)
+	SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error
+	UndoRemoveFavorite(ctx context.Context, userId int) (int, error)
+	SaveView(ctx context.Context, view domain.View) (domain.View, error)
+	GetViews(ctx context.Context, userId int) ([]domain.View, error)
+	DeleteView(ctx context.Context, userId int, name string) error
+	ResolveView(ctx context.Context, userId int, ref string) (domain.View, error)
+	GetFavoritesVersion(ctx context.Context, userId int) (int64, error)
+	GetSiteTree(ctx context.Context) ([]domain.SiteNode, error)
+	GetSite(ctx context.Context, id int) (domain.SiteNode, error)
+	AddToFavoriteIfMatch(ctx context.Context, terminalId int, userId int, version int64) error
+	RemoveFromFavoriteTerminalIfMatch(ctx context.Context, terminalID int, userId int, version int64) error
+}
+
+type AdminServicePort interface {
+	DeleteTerminal(ctx context.Context, id int) error
+	RestoreTerminal(ctx context.Context, id int) error
+	PurgeTerminal(ctx context.Context, id int) error
+	GetDeletedTerminals(ctx context.Context) ([]domain.DeletedRecord, error)
+	AttachTag(ctx context.Context, terminalID int, tag domain.Tag) error
+	DetachTag(ctx context.Context, terminalID int, tag domain.Tag) error
+	SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) error
+	CreateSite(ctx context.Context, site domain.Site) (domain.Site, error)
+	UpdateSite(ctx context.Context, site domain.Site) (domain.Site, error)
+	DeleteSite(ctx context.Context, id int) error
+	SetTerminalSite(ctx context.Context, terminalID int, siteID int) error
+	DeleteUser(ctx context.Context, id int) error
+	RestoreUser(ctx context.Context, id int) error
+	PurgeUser(ctx context.Context, id int) error
+	GetDeletedUsers(ctx context.Context) ([]domain.DeletedRecord, error)
+	PurgeExpired(ctx context.Context, before time.Time) (terminals int, users int, err error)
+	RunPurge(ctx context.Context, retention time.Duration, interval time.Duration)
+}
+
+type AuditServicePort interface {
+	RecordAudit(ctx context.Context, entry domain.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	PurgeAuditBefore(ctx context.Context, before time.Time) (int, error)
+	RunAuditRetention(ctx context.Context, retention time.Duration, interval time.Duration)
+}
+
+type AlertServicePort interface {
+	CreateAlertRule(ctx context.Context, rule domain.AlertRule) (domain.AlertRule, error)
+	GetAlertRules(ctx context.Context, userId int) ([]domain.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, userId int, id int) error
+	GetAlerts(ctx context.Context, userId int, state string) ([]domain.Alert, error)
+	AckAlert(ctx context.Context, userId int, id int) error
+	MuteAlert(ctx context.Context, userId int, id int, d time.Duration) error
+	UnmuteAlert(ctx context.Context, userId int, id int) error
+	EvaluateStatusChanges(ctx context.Context, now time.Time) (fired int, resolved int, err error)
+	EvaluateAlerts(ctx context.Context, now time.Time) (fired int, resolved int, err error)
+	RunAlertEvaluator(ctx context.Context, interval time.Duration)
+}
+
+type WebhookServicePort interface {
+	CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, webhookID int, id int64) error
+	DispatchWebhooks(ctx context.Context, now time.Time) (delivered int, failed int, err error)
+	RunWebhookDispatcher(ctx context.Context, interval time.Duration)
+}
+
+type NotificationServicePort interface {
+	CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error)
+	GetNotificationChannels(ctx context.Context, userId int) ([]domain.NotificationChannel, error)
+	UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (domain.NotificationChannel, error)
+	DeleteNotificationChannel(ctx context.Context, userId int, id int) error
+	TestNotificationChannel(ctx context.Context, userId int, id int) error
+}
+
+type HeartbeatServicePort interface {
+	IssueHeartbeatToken(ctx context.Context, terminalID int) (string, error)
+	RevokeHeartbeatToken(ctx context.Context, terminalID int) error
+	RecordHeartbeat(ctx context.Context, token string, heartbeat domain.Heartbeat) error
+	GetHeartbeats(ctx context.Context) ([]domain.TerminalHeartbeat, error)
+	SweepHeartbeats(ctx context.Context, now time.Time, timeout time.Duration) (int, error)
+	RunHeartbeatSweeper(ctx context.Context, interval time.Duration, timeout time.Duration)
 }
 
 type ServicePort struct {
 	UserServicePort
 	TerminalServicePort
+	AdminServicePort
+	AuditServicePort
+	AlertServicePort
+	WebhookServicePort
+	NotificationServicePort
+	HeartbeatServicePort
 }
 
-func NewServicePort(repo *repositories.RepositoryPort) *ServicePort {
+func NewServicePort(repo *repositories.RepositoryPort, auth user_service.AuthConfig, webhooks webhook_service.Config,
+	notificationConfig notification_service.Config) *ServicePort {
+	notifications := notification_service.NewNotificationService(repo.NotificationRepositoryPort, repo.UserRepositoryPort, notificationConfig)
 	return &ServicePort{
-		UserServicePort:     user_service.NewUserService(repo.UserRepositoryPort),
-		TerminalServicePort: terminal_service.NewTerminalService(repo.TerminalRepositoryPort),
+		UserServicePort:         user_service.NewUserService(repo.UserRepositoryPort, repo.UnitOfWork, auth),
+		TerminalServicePort:     terminal_service.NewTerminalService(repo.TerminalRepositoryPort, repo.UnitOfWork),
+		AdminServicePort:        admin_service.NewAdminService(repo.TerminalRepositoryPort, repo.UserRepositoryPort),
+		AuditServicePort:        audit_service.NewAuditService(repo.AuditRepositoryPort),
+		AlertServicePort:        alert_service.NewAlertService(repo.AlertRepositoryPort, repo.TerminalRepositoryPort, repo.UnitOfWork, notifications),
+		WebhookServicePort:      webhook_service.NewWebhookService(repo.WebhookRepositoryPort, repo.TerminalRepositoryPort, repo.UnitOfWork, webhooks),
+		NotificationServicePort: notifications,
+		HeartbeatServicePort:    heartbeat_service.NewHeartbeatService(repo.HeartbeatRepositoryPort, repo.UnitOfWork),
 	}
 }
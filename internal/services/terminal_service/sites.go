@@ -0,0 +1,50 @@
+package terminal_service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+)
+
+// GetSiteTree returns the site hierarchy with the terminals in each site
+// counted by status, including those in sites below it.
+func (ts *TerminalService) GetSiteTree(ctx context.Context) ([]domain.SiteNode, error) {
+	sites, err := ts.terminalRepositoryPort.GetSites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terminals, err := ts.terminalRepositoryPort.GetDefaultTerminalsList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewSiteTree(sites, terminals), nil
+}
+
+// GetSite returns one site of GetSiteTree with the sites below it, or
+// repositories.ErrNotFound.
+func (ts *TerminalService) GetSite(ctx context.Context, id int) (domain.SiteNode, error) {
+	trees, err := ts.GetSiteTree(ctx)
+	if err != nil {
+		return domain.SiteNode{}, err
+	}
+	node, ok := domain.FindSiteNode(trees, id)
+	if !ok {
+		return domain.SiteNode{}, fmt.Errorf("site with ID %d: %w", id, repositories.ErrNotFound)
+	}
+	return node, nil
+}
+
+// siteSubtree returns the IDs of the site and every site below it, or
+// repositories.ErrNotFound.
+func (ts *TerminalService) siteSubtree(ctx context.Context, id int) (map[int]bool, error) {
+	sites, err := ts.terminalRepositoryPort.GetSites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subtree := domain.SiteSubtree(sites, id)
+	if len(subtree) == 0 {
+		return nil, fmt.Errorf("site with ID %d: %w", id, repositories.ErrNotFound)
+	}
+	return subtree, nil
+}
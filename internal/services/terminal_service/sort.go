@@ -0,0 +1,112 @@
+package terminal_service
+
+import (
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortKey is what the terminal listing is ordered by.
+type SortKey string
+
+const (
+	SortByID           SortKey = "id"
+	SortByName         SortKey = "name"
+	SortByStatus       SortKey = "status"
+	SortByStatusChange SortKey = "status_changed"
+	SortByFavorite     SortKey = "favorite"
+	SortByDistance     SortKey = "distance"
+)
+
+var sortKeys = []SortKey{SortByID, SortByName, SortByStatus, SortByStatusChange, SortByFavorite, SortByDistance}
+
+// ParseSortKey checks that key is one of the sort keys. An empty key sorts
+// by ID.
+func ParseSortKey(key string) (SortKey, error) {
+	if key == "" {
+		return SortByID, nil
+	}
+	for _, k := range sortKeys {
+		if SortKey(key) == k {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort %q, must be one of %v", key, sortKeys)
+}
+
+// statusSeverity ranks statuses from least to most severe. Statuses it does
+// not know rank between active and offline.
+func statusSeverity(status string) int {
+	switch status {
+	case "active":
+		return 0
+	case "offline":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// sortTerminals orders terminals as opts asks. rank holds the position of
+// each favorite in the user's list. Terminals that compare equal are ordered
+// by ID, so the result does not depend on the input order.
+func sortTerminals(terminals []domain.FakeTerminal, rank map[int]int, opts ListOptions) {
+	compare := func(a, b domain.FakeTerminal) int {
+		switch opts.SortBy {
+		case SortByName:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case SortByStatus:
+			return statusSeverity(a.Status) - statusSeverity(b.Status)
+		case SortByStatusChange:
+			return compareTimes(a.StatusChangedAt, b.StatusChangedAt)
+		case SortByFavorite:
+			return rank[a.ID] - rank[b.ID]
+		case SortByDistance:
+			return compareDistances(a.Distance, b.Distance)
+		default:
+			return a.ID - b.ID
+		}
+	}
+	sort.SliceStable(terminals, func(i, j int) bool {
+		a, b := terminals[i], terminals[j]
+		if a.IsFavorite != b.IsFavorite && (!opts.MixFavorites || opts.SortBy == SortByFavorite) {
+			return a.IsFavorite
+		}
+		if c := compare(a, b); c != 0 {
+			return (c < 0) != opts.Desc
+		}
+		return a.ID < b.ID
+	})
+}
+
+// compareTimes orders unknown times before every known one.
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
+
+// compareDistances orders unknown distances after every known one.
+func compareDistances(a, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case *a < *b:
+		return -1
+	case *a > *b:
+		return 1
+	}
+	return 0
+}
@@ -1,69 +1,271 @@
 package terminal_service
 
 import (
+	"context"
+	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/dvdxa/add-to-favorites/internal/repositories"
-	"sort"
+	"math"
+	"strings"
 )
 
+// ErrVersionMismatch is returned by the IfMatch methods when the user's
+// favorites changed since the version the caller last saw.
+var ErrVersionMismatch = errors.New("favorites were modified by another request")
+
+var favoritesTxOptions = repositories.TxOptions{
+	IsoLevel:   repositories.Serializable,
+	MaxRetries: 3,
+}
+
 type TerminalService struct {
 	terminalRepositoryPort repositories.TerminalRepositoryPort
+	unitOfWork             repositories.UnitOfWork
 }
 
-func NewTerminalService(terminalRepositoryPort repositories.TerminalRepositoryPort) *TerminalService {
+func NewTerminalService(terminalRepositoryPort repositories.TerminalRepositoryPort, unitOfWork repositories.UnitOfWork) *TerminalService {
 	return &TerminalService{
 		terminalRepositoryPort: terminalRepositoryPort,
+		unitOfWork:             unitOfWork,
+	}
+}
+
+type favoritesObserverKey struct{}
+
+// ObserveFavorites returns a context under which a successful change to a
+// user's favorites passes observe the favorites it started from. They are
+// read in the same serializable transaction as the change, so no concurrent
+// change comes in between.
+func ObserveFavorites(ctx context.Context, observe func(before []int)) context.Context {
+	return context.WithValue(ctx, favoritesObserverKey{}, observe)
+}
+
+func favoritesObserver(ctx context.Context) func(before []int) {
+	observe, _ := ctx.Value(favoritesObserverKey{}).(func(before []int))
+	return observe
+}
+
+// changeFavorites runs change in a serializable transaction that first reads
+// the user's favorites if ctx observes them.
+func (ts *TerminalService) changeFavorites(ctx context.Context, userId int, change func(tx *repositories.RepositoryPort) error) error {
+	observe := favoritesObserver(ctx)
+	var before []int
+	err := ts.unitOfWork.WithTx(ctx, favoritesTxOptions, func(tx *repositories.RepositoryPort) error {
+		if observe != nil {
+			var err error
+			before, err = tx.GetFavoriteTerminalIds(ctx, userId)
+			if err != nil {
+				return err
+			}
+		}
+		return change(tx)
+	})
+	if err == nil && observe != nil {
+		observe(before)
 	}
+	return err
 }
 
-func (ts *TerminalService) AddToFavorite(terminalId int, userId int) error {
-	return ts.terminalRepositoryPort.AddToFavorites(terminalId, userId)
+// AddToFavorite adds the terminal to the user's favorites, in a transaction
+// only if the change is observed.
+func (ts *TerminalService) AddToFavorite(ctx context.Context, terminalId int, userId int) error {
+	if favoritesObserver(ctx) == nil {
+		return ts.terminalRepositoryPort.AddToFavorites(ctx, terminalId, userId)
+	}
+	return ts.changeFavorites(ctx, userId, func(tx *repositories.RepositoryPort) error {
+		return tx.AddToFavorites(ctx, terminalId, userId)
+	})
 }
 
-func (ts *TerminalService) SortTerminals(userTerminalIDs []int) ([]domain.FakeTerminal, error) {
+// ListOptions narrows and orders the terminal listing. The zero value lists
+// every terminal.
+type ListOptions struct {
+	// Selectors must all match a terminal's tags for it to be listed.
+	Selectors []domain.TagSelector
+	// Search, if set, must occur in the terminal's name or in the alias or
+	// note the user gave it, ignoring case.
+	Search string
+	// SortBy orders the listing, by ID if empty, and Desc reverses it.
+	SortBy SortKey
+	Desc   bool
+	// MixFavorites sorts favorites among the other terminals instead of
+	// listing them first. Sorting by favorite rank always lists them first.
+	MixFavorites bool
+	// FavoritesOnly leaves out the terminals that are not favorites.
+	FavoritesOnly bool
+	// Near, if set, lists only terminals with a known location, each with
+	// its distance from Near, and Radius, if positive, only those within
+	// that many meters.
+	Near   *domain.GeoPoint
+	Radius float64
+	// Site, if set, lists only terminals in that site or a site below it.
+	Site int
+
+	// sites holds Site and the sites below it once SortTerminals has
+	// looked them up.
+	sites map[int]bool
+}
+
+// Matches reports whether terminal passes the filters in opts. The
+// distance filter needs terminal.Distance to be set already, and the site
+// filter only applies within SortTerminals.
+func (opts ListOptions) Matches(terminal domain.FakeTerminal) bool {
+	if opts.FavoritesOnly && !terminal.IsFavorite {
+		return false
+	}
+	if opts.sites != nil && !opts.sites[terminal.SiteID] {
+		return false
+	}
+	if opts.Near != nil {
+		if terminal.Distance == nil || (opts.Radius > 0 && *terminal.Distance > opts.Radius) {
+			return false
+		}
+	}
+	for _, sel := range opts.Selectors {
+		if !sel.Matches(terminal.Tags) {
+			return false
+		}
+	}
+	if opts.Search != "" {
+		query := strings.ToLower(opts.Search)
+		note := domain.FavoriteNote{Alias: terminal.Alias, Note: terminal.Note}
+		if !strings.Contains(strings.ToLower(terminal.Name), query) && !note.Contains(query) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortTerminals lists the terminals selected by opts in the order it asks,
+// favorites first unless it mixes them in, with the user's notes on their
+// favorites. Distances are rounded to whole meters. An unknown Site fails
+// with repositories.ErrNotFound.
+func (ts *TerminalService) SortTerminals(ctx context.Context, userTerminalIDs []int, notes map[int]domain.FavoriteNote, opts ListOptions) ([]domain.FakeTerminal, error) {
 	idIndexMap := make(map[int]int)
 	for i, id := range userTerminalIDs {
 		idIndexMap[id] = i
 	}
-	terminals, err := ts.terminalRepositoryPort.GetDefaultTerminalsList()
+	if opts.Site != 0 {
+		var err error
+		opts.sites, err = ts.siteSubtree(ctx, opts.Site)
+		if err != nil {
+			return nil, err
+		}
+	}
+	terminals, err := ts.terminalRepositoryPort.GetDefaultTerminalsList(ctx)
 	if err != nil {
 		return nil, err
 	}
-	joinTerminals := make([]domain.FakeTerminal, len(terminals))
-	for i, val := range terminals {
-		joinTerminals[i] = ConvertToFakeTerminal(val)
-		joinTerminals[i].IsFavorite = false
-		for _, userTerminalID := range userTerminalIDs {
-			if userTerminalID == val.ID {
-				joinTerminals[i].IsFavorite = true
-			}
+	joinTerminals := make([]domain.FakeTerminal, 0, len(terminals))
+	for _, val := range terminals {
+		fakeTerminal := ConvertToFakeTerminal(val)
+		_, fakeTerminal.IsFavorite = idIndexMap[val.ID]
+		if fakeTerminal.IsFavorite {
+			note := notes[val.ID]
+			fakeTerminal.Alias, fakeTerminal.Color, fakeTerminal.Note = note.Alias, note.Color, note.Note
 		}
+		if opts.Near != nil && fakeTerminal.Location != nil {
+			distance := math.Round(opts.Near.DistanceTo(*fakeTerminal.Location))
+			fakeTerminal.Distance = &distance
+		}
+		if !opts.Matches(fakeTerminal) {
+			continue
+		}
+		joinTerminals = append(joinTerminals, fakeTerminal)
 	}
-	sort.Slice(joinTerminals, func(i, j int) bool {
-		_, iExists := idIndexMap[joinTerminals[i].ID]
-		_, jExists := idIndexMap[joinTerminals[j].ID]
+	sortTerminals(joinTerminals, idIndexMap, opts)
+	return joinTerminals, nil
+}
 
-		if iExists == jExists {
-			return false
+func (ts *TerminalService) GetFavoriteTerminalIds(ctx context.Context, userId int) ([]int, error) {
+	return ts.terminalRepositoryPort.GetFavoriteTerminalIds(ctx, userId)
+}
+
+// RemoveFromFavoriteTerminal is the removing counterpart of AddToFavorite.
+func (ts *TerminalService) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) error {
+	if favoritesObserver(ctx) == nil {
+		return ts.terminalRepositoryPort.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+	}
+	return ts.changeFavorites(ctx, userId, func(tx *repositories.RepositoryPort) error {
+		return tx.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+	})
+}
+
+func (ts *TerminalService) GetFavoriteNotes(ctx context.Context, userId int) (map[int]domain.FavoriteNote, error) {
+	return ts.terminalRepositoryPort.GetFavoriteNotes(ctx, userId)
+}
+
+// SetFavoriteNote trims and validates note and stores it on one of the
+// user's favorites, replacing what was there.
+func (ts *TerminalService) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) error {
+	note = note.Normalize()
+	if err := note.Validate(); err != nil {
+		return err
+	}
+	return ts.terminalRepositoryPort.SetFavoriteNote(ctx, userId, terminalID, note)
+}
+
+// UndoRemoveFavorite adds back the favorite the user removed last, with its
+// note, and returns its terminal ID. The favorite goes to the end of the
+// list. It fails with repositories.ErrNotFound if there is nothing to undo.
+func (ts *TerminalService) UndoRemoveFavorite(ctx context.Context, userId int) (int, error) {
+	var terminalID int
+	err := ts.changeFavorites(ctx, userId, func(tx *repositories.RepositoryPort) error {
+		var err error
+		terminalID, err = tx.RestoreRemovedFavoriteNote(ctx, userId)
+		if err != nil {
+			return err
 		}
-		return iExists
+		return tx.AddToFavorites(ctx, terminalID, userId)
 	})
-	return joinTerminals, nil
+	return terminalID, err
 }
 
-func (ts *TerminalService) GetFavoriteTerminalIds(userId int) ([]int, error) {
-	return ts.terminalRepositoryPort.GetFavoriteTerminalIds(userId)
+func (ts *TerminalService) GetFavoritesVersion(ctx context.Context, userId int) (int64, error) {
+	return ts.terminalRepositoryPort.GetFavoritesVersion(ctx, userId)
 }
 
-func (ts *TerminalService) RemoveFromFavoriteTerminal(terminalID int, userId int) error {
-	return ts.terminalRepositoryPort.RemoveFromFavoriteTerminal(terminalID, userId)
+// AddToFavoriteIfMatch adds the terminal only if the user's favorites are
+// still at version, otherwise it returns ErrVersionMismatch.
+func (ts *TerminalService) AddToFavoriteIfMatch(ctx context.Context, terminalId int, userId int, version int64) error {
+	return ts.ifVersion(ctx, userId, version, func(tx *repositories.RepositoryPort) error {
+		return tx.AddToFavorites(ctx, terminalId, userId)
+	})
+}
+
+// RemoveFromFavoriteTerminalIfMatch is the conditional form of
+// RemoveFromFavoriteTerminal, see AddToFavoriteIfMatch.
+func (ts *TerminalService) RemoveFromFavoriteTerminalIfMatch(ctx context.Context, terminalID int, userId int, version int64) error {
+	return ts.ifVersion(ctx, userId, version, func(tx *repositories.RepositoryPort) error {
+		return tx.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+	})
+}
+
+// ifVersion checks the version and runs fn in one serializable transaction,
+// so a concurrent change between the check and the write fails and is retried.
+func (ts *TerminalService) ifVersion(ctx context.Context, userId int, version int64, fn func(tx *repositories.RepositoryPort) error) error {
+	return ts.changeFavorites(ctx, userId, func(tx *repositories.RepositoryPort) error {
+		current, err := tx.GetFavoritesVersion(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if current != version {
+			return ErrVersionMismatch
+		}
+		return fn(tx)
+	})
 }
 
 func ConvertToFakeTerminal(terminal domain.Terminal) domain.FakeTerminal {
 	fakeTerminal := domain.FakeTerminal{
-		ID:     terminal.ID,
-		Name:   terminal.Name,
-		Status: terminal.Status,
+		ID:              terminal.ID,
+		Name:            terminal.Name,
+		Status:          terminal.Status,
+		Tags:            terminal.Tags,
+		StatusChangedAt: terminal.StatusChangedAt,
+		Location:        terminal.Location,
+		Address:         terminal.Address,
+		SiteID:          terminal.SiteID,
 	}
 	return fakeTerminal
 }
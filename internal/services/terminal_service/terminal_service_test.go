@@ -1,19 +1,22 @@
 package terminal_service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestAddToFavorite(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	service := NewTerminalService(repo)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
 	cases := []struct {
 		name       string
 		terminalId int
@@ -36,8 +39,8 @@ func TestAddToFavorite(t *testing.T) {
 
 	for _, tCase := range cases {
 		t.Run(tCase.name, func(t *testing.T) {
-			repo.EXPECT().AddToFavorites(tCase.terminalId, tCase.userId).Return(tCase.mockErr).Times(1)
-			err := service.AddToFavorite(tCase.terminalId, tCase.userId)
+			repo.EXPECT().AddToFavorites(gomock.Any(), tCase.terminalId, tCase.userId).Return(tCase.mockErr).Times(1)
+			err := service.AddToFavorite(context.Background(), tCase.terminalId, tCase.userId)
 			require.Error(t, err)
 			require.EqualError(t, err, tCase.mockErr.Error())
 		})
@@ -47,7 +50,7 @@ func TestAddToFavorite(t *testing.T) {
 func TestSortTerminals(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	service := NewTerminalService(repo)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
 
 	userTerminalIDs := []int{1, 2, 4}
 	mockResp := []domain.Terminal{
@@ -99,34 +102,179 @@ func TestSortTerminals(t *testing.T) {
 			IsFavorite: false,
 		},
 	}
-	repo.EXPECT().GetDefaultTerminalsList().Return(mockResp, nil).Times(1)
-	terminals, err := service.SortTerminals(userTerminalIDs)
+	repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(mockResp, nil).Times(1)
+	terminals, err := service.SortTerminals(context.Background(), userTerminalIDs, nil, ListOptions{})
 	require.NoError(t, err)
 	require.Equal(t, expTerminals, terminals)
 }
 
+func TestSortTerminalsSelectors(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
+
+	atm := domain.Tag{Key: "type", Value: "atm"}
+	kiosk := domain.Tag{Key: "type", Value: "kiosk"}
+	north := domain.Tag{Key: "zone", Value: "north"}
+	south := domain.Tag{Key: "zone", Value: "south"}
+	mockResp := []domain.Terminal{
+		{ID: 1, Name: "terminal1", Status: "active", Tags: []domain.Tag{atm, north}},
+		{ID: 2, Name: "terminal2", Status: "active", Tags: []domain.Tag{atm, south}},
+		{ID: 3, Name: "terminal3", Status: "active", Tags: []domain.Tag{kiosk, south}},
+		{ID: 4, Name: "terminal4", Status: "active", Tags: []domain.Tag{atm}},
+	}
+
+	cases := []struct {
+		name      string
+		selectors []string
+		expIDs    []int
+	}{
+		{name: "none", expIDs: []int{3, 1, 2, 4}},
+		{name: "equals", selectors: []string{"type=atm"}, expIDs: []int{1, 2, 4}},
+		{name: "double_equals", selectors: []string{"type==kiosk"}, expIDs: []int{3}},
+		{name: "and", selectors: []string{"type=atm", "zone!=north"}, expIDs: []int{2, 4}},
+		{name: "exists", selectors: []string{"zone"}, expIDs: []int{3, 1, 2}},
+		{name: "not_exists", selectors: []string{"!zone"}, expIDs: []int{4}},
+		{name: "no_match", selectors: []string{"type=atm", "type=kiosk"}, expIDs: []int{}},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			var opts ListOptions
+			for _, raw := range tCase.selectors {
+				sel, err := domain.ParseTagSelector(raw)
+				require.NoError(t, err)
+				opts.Selectors = append(opts.Selectors, sel)
+			}
+			repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(mockResp, nil).Times(1)
+			terminals, err := service.SortTerminals(context.Background(), []int{3}, nil, opts)
+			require.NoError(t, err)
+			ids := []int{}
+			for _, terminal := range terminals {
+				ids = append(ids, terminal.ID)
+				require.Equal(t, terminal.ID == 3, terminal.IsFavorite)
+				require.NotEmpty(t, terminal.Tags)
+			}
+			require.Equal(t, tCase.expIDs, ids)
+		})
+	}
+}
+
+func TestSortTerminalsModes(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
+
+	at := func(minute int) *time.Time {
+		changed := time.Date(2024, 5, 1, 12, minute, 0, 0, time.UTC)
+		return &changed
+	}
+	mockResp := []domain.Terminal{
+		{ID: 1, Name: "delta", Status: "active", StatusChangedAt: at(30)},
+		{ID: 2, Name: "Bravo", Status: "offline", StatusChangedAt: at(10)},
+		{ID: 3, Name: "charlie", Status: "maintenance"},
+		{ID: 4, Name: "alpha", Status: "offline", StatusChangedAt: at(10)},
+		{ID: 5, Name: "echo", Status: "active", StatusChangedAt: at(20)},
+	}
+	favorites := []int{5, 2}
+
+	cases := []struct {
+		name   string
+		opts   ListOptions
+		expIDs []int
+	}{
+		{name: "default", opts: ListOptions{}, expIDs: []int{2, 5, 1, 3, 4}},
+		{name: "id_desc", opts: ListOptions{Desc: true}, expIDs: []int{5, 2, 4, 3, 1}},
+		{name: "name", opts: ListOptions{SortBy: SortByName}, expIDs: []int{2, 5, 4, 3, 1}},
+		{name: "name_mixed", opts: ListOptions{SortBy: SortByName, MixFavorites: true}, expIDs: []int{4, 2, 3, 1, 5}},
+		{name: "status_desc_mixed", opts: ListOptions{SortBy: SortByStatus, Desc: true, MixFavorites: true}, expIDs: []int{2, 4, 3, 1, 5}},
+		{name: "status_ties_by_id", opts: ListOptions{SortBy: SortByStatus, MixFavorites: true}, expIDs: []int{1, 5, 3, 2, 4}},
+		{name: "status_changed", opts: ListOptions{SortBy: SortByStatusChange, MixFavorites: true}, expIDs: []int{3, 2, 4, 5, 1}},
+		{name: "status_changed_desc", opts: ListOptions{SortBy: SortByStatusChange, Desc: true, MixFavorites: true}, expIDs: []int{1, 5, 2, 4, 3}},
+		{name: "favorite", opts: ListOptions{SortBy: SortByFavorite}, expIDs: []int{5, 2, 1, 3, 4}},
+		{name: "favorite_desc", opts: ListOptions{SortBy: SortByFavorite, Desc: true, MixFavorites: true}, expIDs: []int{2, 5, 1, 3, 4}},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(mockResp, nil).Times(1)
+			terminals, err := service.SortTerminals(context.Background(), favorites, nil, tCase.opts)
+			require.NoError(t, err)
+			ids := []int{}
+			for _, terminal := range terminals {
+				ids = append(ids, terminal.ID)
+			}
+			require.Equal(t, tCase.expIDs, ids)
+		})
+	}
+}
+
+func TestSortTerminalsNear(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
+
+	mockResp := []domain.Terminal{
+		{ID: 1, Name: "gate", Status: "active", Location: &domain.GeoPoint{Lat: 52.5163, Lon: 13.3777}},
+		{ID: 2, Name: "tower", Status: "active", Location: &domain.GeoPoint{Lat: 52.5208, Lon: 13.4094}, Address: "Panoramastr. 1A"},
+		{ID: 3, Name: "unknown", Status: "active"},
+		{ID: 4, Name: "square", Status: "active", Location: &domain.GeoPoint{Lat: 52.5096, Lon: 13.3760}},
+		{ID: 5, Name: "hamburg", Status: "active", Location: &domain.GeoPoint{Lat: 53.5511, Lon: 9.9937}},
+	}
+	favorites := []int{4, 2}
+	near := &domain.GeoPoint{Lat: 52.5219, Lon: 13.4132}
+
+	cases := []struct {
+		name         string
+		opts         ListOptions
+		expIDs       []int
+		expDistances []float64
+	}{
+		{name: "near", opts: ListOptions{Near: near}, expIDs: []int{2, 4, 1, 5}, expDistances: []float64{285, 2865, 2481, 255641}},
+		{name: "radius", opts: ListOptions{Near: near, Radius: 2000}, expIDs: []int{2}, expDistances: []float64{285}},
+		{name: "distance_mixed", opts: ListOptions{Near: near, Radius: 3000, SortBy: SortByDistance, MixFavorites: true}, expIDs: []int{2, 1, 4}, expDistances: []float64{285, 2481, 2865}},
+		{name: "distance_desc", opts: ListOptions{Near: near, SortBy: SortByDistance, Desc: true}, expIDs: []int{4, 2, 5, 1}, expDistances: []float64{2865, 285, 255641, 2481}},
+		{name: "favorites_only", opts: ListOptions{Near: near, FavoritesOnly: true, SortBy: SortByDistance}, expIDs: []int{2, 4}, expDistances: []float64{285, 2865}},
+		{name: "no_near", opts: ListOptions{FavoritesOnly: true}, expIDs: []int{2, 4}, expDistances: []float64{}},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(mockResp, nil).Times(1)
+			terminals, err := service.SortTerminals(context.Background(), favorites, nil, tCase.opts)
+			require.NoError(t, err)
+			ids, distances := []int{}, []float64{}
+			for _, terminal := range terminals {
+				ids = append(ids, terminal.ID)
+				if terminal.Distance != nil {
+					distances = append(distances, *terminal.Distance)
+				}
+			}
+			require.Equal(t, tCase.expIDs, ids)
+			require.Equal(t, tCase.expDistances, distances)
+		})
+	}
+}
+
 func TestSortTerminalsRepoErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	service := NewTerminalService(repo)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
 
 	expErr := errors.New("DB is down")
-	repo.EXPECT().GetDefaultTerminalsList().Return(nil, expErr).Times(1)
+	repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(nil, expErr).Times(1)
 	userTerminalIDs := []int{1, 2, 4}
-	_, err := service.SortTerminals(userTerminalIDs)
+	_, err := service.SortTerminals(context.Background(), userTerminalIDs, nil, ListOptions{})
 	require.Equal(t, expErr, err)
 }
 
 func TestGetFavoriteTerminalIds(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	service := NewTerminalService(repo)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
 
 	userId := 1
 	favoriteTerminalIDs := []int{1, 2, 3}
 
-	repo.EXPECT().GetFavoriteTerminalIds(userId).Return(favoriteTerminalIDs, nil).Times(1)
-	ids, err := service.GetFavoriteTerminalIds(userId)
+	repo.EXPECT().GetFavoriteTerminalIds(gomock.Any(), userId).Return(favoriteTerminalIDs, nil).Times(1)
+	ids, err := service.GetFavoriteTerminalIds(context.Background(), userId)
 	require.NoError(t, err)
 	require.Equal(t, favoriteTerminalIDs, ids)
 }
@@ -134,12 +282,120 @@ func TestGetFavoriteTerminalIds(t *testing.T) {
 func TestRemoveFromFavoriteTerminal(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
-	service := NewTerminalService(repo)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
 
 	terminalID := 4
 	userID := 1
 
-	repo.EXPECT().RemoveFromFavoriteTerminal(terminalID, userID).Return(nil).Times(1)
-	err := service.RemoveFromFavoriteTerminal(terminalID, userID)
+	repo.EXPECT().RemoveFromFavoriteTerminal(gomock.Any(), terminalID, userID).Return(nil).Times(1)
+	err := service.RemoveFromFavoriteTerminal(context.Background(), terminalID, userID)
+	require.NoError(t, err)
+}
+
+func TestAddToFavoriteIfMatch(t *testing.T) {
+	cases := []struct {
+		name           string
+		currentVersion int64
+		version        int64
+		expErr         error
+	}{
+		{
+			name:           "current_version",
+			currentVersion: 3,
+			version:        3,
+		},
+		{
+			name:           "stale_version",
+			currentVersion: 4,
+			version:        3,
+			expErr:         ErrVersionMismatch,
+		},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+			uow := repoMock.NewMockUnitOfWork(ctl)
+			service := NewTerminalService(repo, uow)
+
+			uow.EXPECT().WithTx(gomock.Any(), favoritesTxOptions, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+					return fn(&repositories.RepositoryPort{TerminalRepositoryPort: repo, UnitOfWork: uow})
+				}).Times(1)
+			repo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(tCase.currentVersion, nil).Times(1)
+			if tCase.expErr == nil {
+				repo.EXPECT().AddToFavorites(gomock.Any(), 4, 1).Return(nil).Times(1)
+			}
+			err := service.AddToFavoriteIfMatch(context.Background(), 4, 1, tCase.version)
+			require.ErrorIs(t, err, tCase.expErr)
+		})
+	}
+}
+
+func TestRemoveFromFavoriteTerminalIfMatchRepoErr(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	service := NewTerminalService(repo, uow)
+
+	expErr := errors.New("DB is down")
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{TerminalRepositoryPort: repo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().GetFavoritesVersion(gomock.Any(), 1).Return(int64(0), expErr).Times(1)
+	err := service.RemoveFromFavoriteTerminalIfMatch(context.Background(), 4, 1, 0)
+	require.EqualError(t, err, expErr.Error())
+}
+
+func TestSortTerminalsSite(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
+
+	sites := []domain.Site{{ID: 1, Name: "North"}, {ID: 2, ParentID: 1, Name: "Site B"}, {ID: 3, Name: "South"}}
+	mockResp := []domain.Terminal{
+		{ID: 1, Name: "a", Status: "active", SiteID: 1},
+		{ID: 2, Name: "b", Status: "offline", SiteID: 2},
+		{ID: 3, Name: "c", Status: "active", SiteID: 3},
+		{ID: 4, Name: "d", Status: "active"},
+	}
+
+	repo.EXPECT().GetSites(gomock.Any()).Return(sites, nil).Times(1)
+	repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(mockResp, nil).Times(1)
+	terminals, err := service.SortTerminals(context.Background(), nil, nil, ListOptions{Site: 1})
 	require.NoError(t, err)
+	require.Len(t, terminals, 2)
+	require.Equal(t, 1, terminals[0].ID)
+	require.Equal(t, 2, terminals[1].ID)
+	require.Equal(t, 2, terminals[1].SiteID)
+
+	repo.EXPECT().GetSites(gomock.Any()).Return(sites, nil).Times(1)
+	_, err = service.SortTerminals(context.Background(), nil, nil, ListOptions{Site: 9})
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func TestGetSite(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	service := NewTerminalService(repo, repoMock.NewMockUnitOfWork(ctl))
+
+	sites := []domain.Site{{ID: 1, Name: "North"}, {ID: 2, ParentID: 1, Name: "Site B"}}
+	terminals := []domain.Terminal{
+		{ID: 1, Status: "active", SiteID: 1},
+		{ID: 2, Status: "offline", SiteID: 2},
+		{ID: 3, Status: "offline", SiteID: 2},
+	}
+	repo.EXPECT().GetSites(gomock.Any()).Return(sites, nil).Times(2)
+	repo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return(terminals, nil).Times(2)
+
+	node, err := service.GetSite(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, node.Terminals)
+	require.Equal(t, map[string]int{"active": 1, "offline": 2}, node.StatusCounts)
+	require.Len(t, node.Children, 1)
+	require.Equal(t, map[string]int{"offline": 2}, node.Children[0].StatusCounts)
+
+	_, err = service.GetSite(context.Background(), 5)
+	require.ErrorIs(t, err, repositories.ErrNotFound)
 }
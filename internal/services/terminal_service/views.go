@@ -0,0 +1,40 @@
+package terminal_service
+
+import (
+	"context"
+	"errors"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+)
+
+// SaveView validates view and stores it for view.OwnerID, replacing the
+// owner's view with the same name.
+func (ts *TerminalService) SaveView(ctx context.Context, view domain.View) (domain.View, error) {
+	if err := view.Validate(); err != nil {
+		return domain.View{}, err
+	}
+	return ts.terminalRepositoryPort.SaveView(ctx, view)
+}
+
+func (ts *TerminalService) GetViews(ctx context.Context, userId int) ([]domain.View, error) {
+	return ts.terminalRepositoryPort.GetViews(ctx, userId)
+}
+
+func (ts *TerminalService) DeleteView(ctx context.Context, userId int, name string) error {
+	return ts.terminalRepositoryPort.DeleteView(ctx, userId, name)
+}
+
+// ResolveView returns the view ref selects, "name" for the user's own view
+// or "owner/name" for one shared with them. An empty ref selects the user's
+// default view, and yields the zero View if they have none.
+func (ts *TerminalService) ResolveView(ctx context.Context, userId int, ref string) (domain.View, error) {
+	if ref == "" {
+		view, err := ts.terminalRepositoryPort.GetDefaultView(ctx, userId)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return domain.View{}, nil
+		}
+		return view, err
+	}
+	owner, name := domain.ParseViewRef(ref)
+	return ts.terminalRepositoryPort.GetView(ctx, userId, owner, name)
+}
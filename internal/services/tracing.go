@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/services/terminal_service"
+	"github.com/dvdxa/add-to-favorites/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Traced wraps the services so every method that takes a context records a
+// span named after the service and method. RunPurge, RunAuditRetention,
+// RunAlertEvaluator, RunWebhookDispatcher and RunHeartbeatSweeper run for
+// the life of the process and ParseToken has no context, so none of them is
+// traced.
+func Traced(port *ServicePort) *ServicePort {
+	tracer := tracing.Tracer()
+	return &ServicePort{
+		UserServicePort:         &tracedUserService{next: port.UserServicePort, tracer: tracer},
+		TerminalServicePort:     &tracedTerminalService{next: port.TerminalServicePort, tracer: tracer},
+		AdminServicePort:        &tracedAdminService{next: port.AdminServicePort, tracer: tracer},
+		AuditServicePort:        &tracedAuditService{next: port.AuditServicePort, tracer: tracer},
+		AlertServicePort:        &tracedAlertService{next: port.AlertServicePort, tracer: tracer},
+		WebhookServicePort:      &tracedWebhookService{next: port.WebhookServicePort, tracer: tracer},
+		NotificationServicePort: &tracedNotificationService{next: port.NotificationServicePort, tracer: tracer},
+		HeartbeatServicePort:    &tracedHeartbeatService{next: port.HeartbeatServicePort, tracer: tracer},
+	}
+}
+
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, func(err *error)) {
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
+
+var (
+	userIDKey     = attribute.Key("app.user_id")
+	terminalIDKey = attribute.Key("app.terminal_id")
+	siteIDKey     = attribute.Key("app.site_id")
+)
+
+type tracedUserService struct {
+	next   UserServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedUserService) CreateUser(ctx context.Context, user domain.User) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "UserService.CreateUser")
+	defer end(&err)
+	return s.next.CreateUser(ctx, user)
+}
+
+func (s *tracedUserService) GenerateToken(ctx context.Context, user domain.User) (tokenString string, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "UserService.GenerateToken")
+	defer end(&err)
+	return s.next.GenerateToken(ctx, user)
+}
+
+func (s *tracedUserService) ParseToken(tokenStr string) (interface{}, error) {
+	return s.next.ParseToken(tokenStr)
+}
+
+func (s *tracedUserService) IsActive(ctx context.Context, userId int) (result bool, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "UserService.IsActive", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.IsActive(ctx, userId)
+}
+
+func (s *tracedUserService) IsAdmin(ctx context.Context, userId int) (result bool, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "UserService.IsAdmin", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.IsAdmin(ctx, userId)
+}
+
+type tracedTerminalService struct {
+	next   TerminalServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedTerminalService) AddToFavorite(ctx context.Context, terminalId int, userId int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.AddToFavorite", terminalIDKey.Int(terminalId), userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.AddToFavorite(ctx, terminalId, userId)
+}
+
+func (s *tracedTerminalService) SortTerminals(ctx context.Context, userTerminalIDs []int, notes map[int]domain.FavoriteNote, opts terminal_service.ListOptions) (result []domain.FakeTerminal, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.SortTerminals", attribute.Int("app.favorites", len(userTerminalIDs)))
+	defer end(&err)
+	return s.next.SortTerminals(ctx, userTerminalIDs, notes, opts)
+}
+
+func (s *tracedTerminalService) GetFavoriteTerminalIds(ctx context.Context, userId int) (result []int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetFavoriteTerminalIds", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetFavoriteTerminalIds(ctx, userId)
+}
+
+func (s *tracedTerminalService) RemoveFromFavoriteTerminal(ctx context.Context, terminalID int, userId int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.RemoveFromFavoriteTerminal", terminalIDKey.Int(terminalID), userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.RemoveFromFavoriteTerminal(ctx, terminalID, userId)
+}
+
+func (s *tracedTerminalService) GetFavoriteNotes(ctx context.Context, userId int) (result map[int]domain.FavoriteNote, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetFavoriteNotes", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetFavoriteNotes(ctx, userId)
+}
+
+func (s *tracedTerminalService) SetFavoriteNote(ctx context.Context, userId int, terminalID int, note domain.FavoriteNote) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.SetFavoriteNote", terminalIDKey.Int(terminalID), userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.SetFavoriteNote(ctx, userId, terminalID, note)
+}
+
+func (s *tracedTerminalService) UndoRemoveFavorite(ctx context.Context, userId int) (result int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.UndoRemoveFavorite", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.UndoRemoveFavorite(ctx, userId)
+}
+
+func (s *tracedTerminalService) SaveView(ctx context.Context, view domain.View) (result domain.View, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.SaveView", userIDKey.Int(view.OwnerID))
+	defer end(&err)
+	return s.next.SaveView(ctx, view)
+}
+
+func (s *tracedTerminalService) GetViews(ctx context.Context, userId int) (result []domain.View, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetViews", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetViews(ctx, userId)
+}
+
+func (s *tracedTerminalService) DeleteView(ctx context.Context, userId int, name string) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.DeleteView", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.DeleteView(ctx, userId, name)
+}
+
+func (s *tracedTerminalService) ResolveView(ctx context.Context, userId int, ref string) (result domain.View, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.ResolveView", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.ResolveView(ctx, userId, ref)
+}
+
+func (s *tracedTerminalService) GetFavoritesVersion(ctx context.Context, userId int) (result int64, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetFavoritesVersion", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetFavoritesVersion(ctx, userId)
+}
+
+func (s *tracedTerminalService) GetSiteTree(ctx context.Context) (result []domain.SiteNode, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetSiteTree")
+	defer end(&err)
+	return s.next.GetSiteTree(ctx)
+}
+
+func (s *tracedTerminalService) GetSite(ctx context.Context, id int) (result domain.SiteNode, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.GetSite", siteIDKey.Int(id))
+	defer end(&err)
+	return s.next.GetSite(ctx, id)
+}
+
+func (s *tracedTerminalService) AddToFavoriteIfMatch(ctx context.Context, terminalId int, userId int, version int64) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.AddToFavoriteIfMatch", terminalIDKey.Int(terminalId), userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.AddToFavoriteIfMatch(ctx, terminalId, userId, version)
+}
+
+func (s *tracedTerminalService) RemoveFromFavoriteTerminalIfMatch(ctx context.Context, terminalID int, userId int, version int64) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "TerminalService.RemoveFromFavoriteTerminalIfMatch", terminalIDKey.Int(terminalID), userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.RemoveFromFavoriteTerminalIfMatch(ctx, terminalID, userId, version)
+}
+
+type tracedAdminService struct {
+	next   AdminServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedAdminService) DeleteTerminal(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.DeleteTerminal", terminalIDKey.Int(id))
+	defer end(&err)
+	return s.next.DeleteTerminal(ctx, id)
+}
+
+func (s *tracedAdminService) RestoreTerminal(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.RestoreTerminal", terminalIDKey.Int(id))
+	defer end(&err)
+	return s.next.RestoreTerminal(ctx, id)
+}
+
+func (s *tracedAdminService) PurgeTerminal(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.PurgeTerminal", terminalIDKey.Int(id))
+	defer end(&err)
+	return s.next.PurgeTerminal(ctx, id)
+}
+
+func (s *tracedAdminService) GetDeletedTerminals(ctx context.Context) (result []domain.DeletedRecord, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.GetDeletedTerminals")
+	defer end(&err)
+	return s.next.GetDeletedTerminals(ctx)
+}
+
+func (s *tracedAdminService) AttachTag(ctx context.Context, terminalID int, tag domain.Tag) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.AttachTag", terminalIDKey.Int(terminalID), attribute.String("app.tag", tag.String()))
+	defer end(&err)
+	return s.next.AttachTag(ctx, terminalID, tag)
+}
+
+func (s *tracedAdminService) DetachTag(ctx context.Context, terminalID int, tag domain.Tag) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.DetachTag", terminalIDKey.Int(terminalID), attribute.String("app.tag", tag.String()))
+	defer end(&err)
+	return s.next.DetachTag(ctx, terminalID, tag)
+}
+
+func (s *tracedAdminService) SetTerminalLocation(ctx context.Context, terminalID int, location domain.TerminalLocation) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.SetTerminalLocation", terminalIDKey.Int(terminalID))
+	defer end(&err)
+	return s.next.SetTerminalLocation(ctx, terminalID, location)
+}
+
+func (s *tracedAdminService) CreateSite(ctx context.Context, site domain.Site) (result domain.Site, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.CreateSite")
+	defer end(&err)
+	return s.next.CreateSite(ctx, site)
+}
+
+func (s *tracedAdminService) UpdateSite(ctx context.Context, site domain.Site) (result domain.Site, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.UpdateSite", siteIDKey.Int(site.ID))
+	defer end(&err)
+	return s.next.UpdateSite(ctx, site)
+}
+
+func (s *tracedAdminService) DeleteSite(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.DeleteSite", siteIDKey.Int(id))
+	defer end(&err)
+	return s.next.DeleteSite(ctx, id)
+}
+
+func (s *tracedAdminService) SetTerminalSite(ctx context.Context, terminalID int, siteID int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.SetTerminalSite", terminalIDKey.Int(terminalID), siteIDKey.Int(siteID))
+	defer end(&err)
+	return s.next.SetTerminalSite(ctx, terminalID, siteID)
+}
+
+func (s *tracedAdminService) DeleteUser(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.DeleteUser", userIDKey.Int(id))
+	defer end(&err)
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *tracedAdminService) RestoreUser(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.RestoreUser", userIDKey.Int(id))
+	defer end(&err)
+	return s.next.RestoreUser(ctx, id)
+}
+
+func (s *tracedAdminService) PurgeUser(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.PurgeUser", userIDKey.Int(id))
+	defer end(&err)
+	return s.next.PurgeUser(ctx, id)
+}
+
+func (s *tracedAdminService) GetDeletedUsers(ctx context.Context) (result []domain.DeletedRecord, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.GetDeletedUsers")
+	defer end(&err)
+	return s.next.GetDeletedUsers(ctx)
+}
+
+func (s *tracedAdminService) PurgeExpired(ctx context.Context, before time.Time) (terminals int, users int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AdminService.PurgeExpired")
+	defer end(&err)
+	return s.next.PurgeExpired(ctx, before)
+}
+
+func (s *tracedAdminService) RunPurge(ctx context.Context, retention time.Duration, interval time.Duration) {
+	s.next.RunPurge(ctx, retention, interval)
+}
+
+type tracedAuditService struct {
+	next   AuditServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedAuditService) RecordAudit(ctx context.Context, entry domain.AuditEntry) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AuditService.RecordAudit", attribute.String("app.audit_action", entry.Action))
+	defer end(&err)
+	return s.next.RecordAudit(ctx, entry)
+}
+
+func (s *tracedAuditService) GetAuditEntries(ctx context.Context, filter domain.AuditFilter) (result []domain.AuditEntry, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AuditService.GetAuditEntries")
+	defer end(&err)
+	return s.next.GetAuditEntries(ctx, filter)
+}
+
+func (s *tracedAuditService) PurgeAuditBefore(ctx context.Context, before time.Time) (result int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AuditService.PurgeAuditBefore")
+	defer end(&err)
+	return s.next.PurgeAuditBefore(ctx, before)
+}
+
+func (s *tracedAuditService) RunAuditRetention(ctx context.Context, retention time.Duration, interval time.Duration) {
+	s.next.RunAuditRetention(ctx, retention, interval)
+}
+
+var alertIDKey = attribute.Key("app.alert_id")
+
+type tracedAlertService struct {
+	next   AlertServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedAlertService) CreateAlertRule(ctx context.Context, rule domain.AlertRule) (result domain.AlertRule, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.CreateAlertRule", userIDKey.Int(rule.UserID))
+	defer end(&err)
+	return s.next.CreateAlertRule(ctx, rule)
+}
+
+func (s *tracedAlertService) GetAlertRules(ctx context.Context, userId int) (result []domain.AlertRule, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.GetAlertRules", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetAlertRules(ctx, userId)
+}
+
+func (s *tracedAlertService) DeleteAlertRule(ctx context.Context, userId int, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.DeleteAlertRule", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.DeleteAlertRule(ctx, userId, id)
+}
+
+func (s *tracedAlertService) GetAlerts(ctx context.Context, userId int, state string) (result []domain.Alert, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.GetAlerts", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetAlerts(ctx, userId, state)
+}
+
+func (s *tracedAlertService) AckAlert(ctx context.Context, userId int, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.AckAlert", userIDKey.Int(userId), alertIDKey.Int(id))
+	defer end(&err)
+	return s.next.AckAlert(ctx, userId, id)
+}
+
+func (s *tracedAlertService) MuteAlert(ctx context.Context, userId int, id int, d time.Duration) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.MuteAlert", userIDKey.Int(userId), alertIDKey.Int(id))
+	defer end(&err)
+	return s.next.MuteAlert(ctx, userId, id, d)
+}
+
+func (s *tracedAlertService) UnmuteAlert(ctx context.Context, userId int, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.UnmuteAlert", userIDKey.Int(userId), alertIDKey.Int(id))
+	defer end(&err)
+	return s.next.UnmuteAlert(ctx, userId, id)
+}
+
+func (s *tracedAlertService) EvaluateStatusChanges(ctx context.Context, now time.Time) (fired int, resolved int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.EvaluateStatusChanges")
+	defer end(&err)
+	return s.next.EvaluateStatusChanges(ctx, now)
+}
+
+func (s *tracedAlertService) EvaluateAlerts(ctx context.Context, now time.Time) (fired int, resolved int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "AlertService.EvaluateAlerts")
+	defer end(&err)
+	return s.next.EvaluateAlerts(ctx, now)
+}
+
+func (s *tracedAlertService) RunAlertEvaluator(ctx context.Context, interval time.Duration) {
+	s.next.RunAlertEvaluator(ctx, interval)
+}
+
+var (
+	webhookIDKey  = attribute.Key("app.webhook_id")
+	deliveryIDKey = attribute.Key("app.webhook_delivery_id")
+)
+
+type tracedWebhookService struct {
+	next   WebhookServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedWebhookService) CreateWebhook(ctx context.Context, hook domain.Webhook) (result domain.Webhook, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.CreateWebhook")
+	defer end(&err)
+	return s.next.CreateWebhook(ctx, hook)
+}
+
+func (s *tracedWebhookService) GetWebhooks(ctx context.Context) (result []domain.Webhook, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.GetWebhooks")
+	defer end(&err)
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s *tracedWebhookService) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.DeleteWebhook", webhookIDKey.Int(id))
+	defer end(&err)
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s *tracedWebhookService) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) (result []domain.WebhookDelivery, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.GetWebhookDeliveries", webhookIDKey.Int(webhookID))
+	defer end(&err)
+	return s.next.GetWebhookDeliveries(ctx, webhookID, state)
+}
+
+func (s *tracedWebhookService) RedeliverWebhook(ctx context.Context, webhookID int, id int64) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.RedeliverWebhook", webhookIDKey.Int(webhookID), deliveryIDKey.Int64(id))
+	defer end(&err)
+	return s.next.RedeliverWebhook(ctx, webhookID, id)
+}
+
+func (s *tracedWebhookService) DispatchWebhooks(ctx context.Context, now time.Time) (delivered int, failed int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "WebhookService.DispatchWebhooks")
+	defer end(&err)
+	return s.next.DispatchWebhooks(ctx, now)
+}
+
+func (s *tracedWebhookService) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	s.next.RunWebhookDispatcher(ctx, interval)
+}
+
+var channelIDKey = attribute.Key("app.notification_channel_id")
+
+type tracedNotificationService struct {
+	next   NotificationServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedNotificationService) CreateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (result domain.NotificationChannel, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "NotificationService.CreateNotificationChannel", userIDKey.Int(channel.UserID))
+	defer end(&err)
+	return s.next.CreateNotificationChannel(ctx, channel)
+}
+
+func (s *tracedNotificationService) GetNotificationChannels(ctx context.Context, userId int) (result []domain.NotificationChannel, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "NotificationService.GetNotificationChannels", userIDKey.Int(userId))
+	defer end(&err)
+	return s.next.GetNotificationChannels(ctx, userId)
+}
+
+func (s *tracedNotificationService) UpdateNotificationChannel(ctx context.Context, channel domain.NotificationChannel) (result domain.NotificationChannel, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "NotificationService.UpdateNotificationChannel", userIDKey.Int(channel.UserID), channelIDKey.Int(channel.ID))
+	defer end(&err)
+	return s.next.UpdateNotificationChannel(ctx, channel)
+}
+
+func (s *tracedNotificationService) DeleteNotificationChannel(ctx context.Context, userId int, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "NotificationService.DeleteNotificationChannel", userIDKey.Int(userId), channelIDKey.Int(id))
+	defer end(&err)
+	return s.next.DeleteNotificationChannel(ctx, userId, id)
+}
+
+func (s *tracedNotificationService) TestNotificationChannel(ctx context.Context, userId int, id int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "NotificationService.TestNotificationChannel", userIDKey.Int(userId), channelIDKey.Int(id))
+	defer end(&err)
+	return s.next.TestNotificationChannel(ctx, userId, id)
+}
+
+type tracedHeartbeatService struct {
+	next   HeartbeatServicePort
+	tracer trace.Tracer
+}
+
+func (s *tracedHeartbeatService) IssueHeartbeatToken(ctx context.Context, terminalID int) (token string, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "HeartbeatService.IssueHeartbeatToken", terminalIDKey.Int(terminalID))
+	defer end(&err)
+	return s.next.IssueHeartbeatToken(ctx, terminalID)
+}
+
+func (s *tracedHeartbeatService) RevokeHeartbeatToken(ctx context.Context, terminalID int) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "HeartbeatService.RevokeHeartbeatToken", terminalIDKey.Int(terminalID))
+	defer end(&err)
+	return s.next.RevokeHeartbeatToken(ctx, terminalID)
+}
+
+func (s *tracedHeartbeatService) RecordHeartbeat(ctx context.Context, token string, heartbeat domain.Heartbeat) (err error) {
+	ctx, end := startSpan(ctx, s.tracer, "HeartbeatService.RecordHeartbeat")
+	defer end(&err)
+	return s.next.RecordHeartbeat(ctx, token, heartbeat)
+}
+
+func (s *tracedHeartbeatService) GetHeartbeats(ctx context.Context) (result []domain.TerminalHeartbeat, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "HeartbeatService.GetHeartbeats")
+	defer end(&err)
+	return s.next.GetHeartbeats(ctx)
+}
+
+func (s *tracedHeartbeatService) SweepHeartbeats(ctx context.Context, now time.Time, timeout time.Duration) (marked int, err error) {
+	ctx, end := startSpan(ctx, s.tracer, "HeartbeatService.SweepHeartbeats")
+	defer end(&err)
+	return s.next.SweepHeartbeats(ctx, now, timeout)
+}
+
+func (s *tracedHeartbeatService) RunHeartbeatSweeper(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	s.next.RunHeartbeatSweeper(ctx, interval, timeout)
+}
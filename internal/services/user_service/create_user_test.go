@@ -1,9 +1,11 @@
 package user_service
 
 import (
+	"context"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -13,7 +15,9 @@ import (
 func TestCreateUser(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	service := NewUserService(repo, uow, AuthConfig{})
 
 	password := "12345yusuf"
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -31,15 +35,22 @@ func TestCreateUser(t *testing.T) {
 		Password: "12345yusuf",
 	}
 
-	repo.EXPECT().CreateUser(gomock.AssignableToTypeOf(mockUser)).Return(nil).Times(1)
-	err = service.CreateUser(expUser)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{UserRepositoryPort: repo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.AssignableToTypeOf(mockUser)).Return(1, nil).Times(1)
+	terminalRepo.EXPECT().CreateFavorites(gomock.Any(), 1).Return(nil).Times(1)
+	err = service.CreateUser(context.Background(), expUser)
 	require.NoError(t, err)
 }
 
 func TestCreateUserPassErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	service := NewUserService(repo, uow, AuthConfig{})
 
 	pass := "123456Yusuf"
 	bytesHash, err := service.HashPassword(pass, 14)
@@ -57,7 +68,33 @@ func TestCreateUserPassErr(t *testing.T) {
 		Password: "123456Yusuf",
 	}
 	expErr := errors.New("DB is down")
-	repo.EXPECT().CreateUser(gomock.AssignableToTypeOf(expUser)).Return(expErr).Times(1)
-	err = service.CreateUser(mockUser)
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{UserRepositoryPort: repo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.AssignableToTypeOf(expUser)).Return(0, expErr).Times(1)
+	err = service.CreateUser(context.Background(), mockUser)
+	require.EqualError(t, err, expErr.Error())
+}
+
+func TestCreateUserFavoritesErr(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	terminalRepo := repoMock.NewMockTerminalRepositoryPort(ctl)
+	uow := repoMock.NewMockUnitOfWork(ctl)
+	service := NewUserService(repo, uow, AuthConfig{})
+
+	user := domain.User{
+		Name:     "Yusuf",
+		Password: "123456Yusuf",
+	}
+	expErr := errors.New("failed to create favorites: DB is down")
+	uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{UserRepositoryPort: repo, TerminalRepositoryPort: terminalRepo, UnitOfWork: uow})
+		}).Times(1)
+	repo.EXPECT().CreateUser(gomock.Any(), gomock.AssignableToTypeOf(user)).Return(7, nil).Times(1)
+	terminalRepo.EXPECT().CreateFavorites(gomock.Any(), 7).Return(expErr).Times(1)
+	err := service.CreateUser(context.Background(), user)
 	require.EqualError(t, err, expErr.Error())
 }
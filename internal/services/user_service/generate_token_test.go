@@ -1,14 +1,16 @@
 package user_service
 
 import (
+	"context"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"testing"
+	"time"
 )
 
 type Token interface {
@@ -18,11 +20,7 @@ type Token interface {
 func TestGenerateToken(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		require.Error(t, err)
-	}
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{SigningKey: testSigningKey})
 
 	user := domain.User{
 		Name:     "Yahya",
@@ -40,15 +38,15 @@ func TestGenerateToken(t *testing.T) {
 		Password: string(passwordHash),
 	}
 
-	repo.EXPECT().GetUser(user.Name).Return(expUser, nil).Times(1)
-	_, err = service.GenerateToken(user)
+	repo.EXPECT().GetUser(gomock.Any(), user.Name).Return(expUser, nil).Times(1)
+	_, err = service.GenerateToken(context.Background(), user)
 	require.NoError(t, err)
 }
 
 func TestGenerateTokenErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{})
 
 	user := domain.User{
 		Name:     "Yahya",
@@ -65,8 +63,8 @@ func TestGenerateTokenErr(t *testing.T) {
 		Password: string(passwordHash2),
 	}
 	expErr := errors.New("crypto/bcrypt: hashedPassword is not the hash of the given password")
-	repo.EXPECT().GetUser(user.Name).Return(expUser, nil).Times(1)
-	_, err = service.GenerateToken(user)
+	repo.EXPECT().GetUser(gomock.Any(), user.Name).Return(expUser, nil).Times(1)
+	_, err = service.GenerateToken(context.Background(), user)
 	require.Error(t, err)
 	require.EqualError(t, err, expErr.Error())
 }
@@ -74,15 +72,44 @@ func TestGenerateTokenErr(t *testing.T) {
 func TestGenerateTokenRepoErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{})
 
 	user := domain.User{
 		Name:     "Yahya",
 		Password: "123",
 	}
 	expErr := errors.New("DB is down")
-	repo.EXPECT().GetUser(user.Name).Return(domain.User{}, expErr).Times(1)
-	_, err := service.GenerateToken(user)
+	repo.EXPECT().GetUser(gomock.Any(), user.Name).Return(domain.User{}, expErr).Times(1)
+	_, err := service.GenerateToken(context.Background(), user)
 	require.Error(t, err)
 	require.EqualError(t, err, expErr.Error())
 }
+
+func TestGenerateTokenUsesAuthConfig(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	ttl := 15 * time.Minute
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{
+		SigningKey: testSigningKey,
+		TokenTTL:   ttl,
+		BcryptCost: bcrypt.MinCost,
+	})
+
+	passwordHash, err := service.HashPassword("12345Yahya", service.auth.BcryptCost)
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost(passwordHash)
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.MinCost, cost)
+
+	repo.EXPECT().GetUser(gomock.Any(), "Yahya").Return(domain.User{ID: 1, Name: "Yahya", Password: string(passwordHash)}, nil)
+	tokenString, err := service.GenerateToken(context.Background(), domain.User{Name: "Yahya", Password: "12345Yahya"})
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return testSigningKey, nil
+	})
+	require.NoError(t, err)
+	exp := time.Unix(int64(claims["exp"].(float64)), 0)
+	require.WithinDuration(t, time.Now().Add(ttl), exp, 5*time.Second)
+}
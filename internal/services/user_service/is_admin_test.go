@@ -0,0 +1,60 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsAdmin(t *testing.T) {
+	ctl := gomock.NewController(t)
+	repo := repoMock.NewMockUserRepositoryPort(ctl)
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{})
+
+	cases := []struct {
+		name    string
+		user    domain.User
+		repoErr error
+		exp     bool
+		expErr  error
+	}{
+		{
+			name: "admin",
+			user: domain.User{ID: 1, Name: "Khalid", Role: domain.RoleAdmin},
+			exp:  true,
+		},
+		{
+			name: "user",
+			user: domain.User{ID: 1, Name: "Khalid", Role: domain.RoleUser},
+			exp:  false,
+		},
+		{
+			name:    "deleted_user",
+			repoErr: fmt.Errorf("user with ID %d: %w", 1, repositories.ErrNotFound),
+			exp:     false,
+		},
+		{
+			name:    "db_err",
+			repoErr: errors.New("DB is down"),
+			expErr:  errors.New("DB is down"),
+		},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			repo.EXPECT().GetUserByID(gomock.Any(), 1).Return(tCase.user, tCase.repoErr).Times(1)
+			isAdmin, err := service.IsAdmin(context.Background(), 1)
+			if tCase.expErr != nil {
+				require.EqualError(t, err, tCase.expErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tCase.exp, isAdmin)
+		})
+	}
+}
@@ -5,22 +5,18 @@ import (
 	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
 	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
-	"os"
 	"testing"
 	"time"
 )
 
+var testSigningKey = []byte("test-signing-key")
+
 func TestParseToken(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		require.Error(t, err)
-	}
-	var MySigningKey = []byte(os.Getenv("SECRET_KEY"))
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{SigningKey: testSigningKey})
+	var MySigningKey = testSigningKey
 
 	expUserID := float64(1)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -53,12 +49,8 @@ func (c InvalidClaims) Valid() error {
 func TestParseTokenErr(t *testing.T) {
 	ctl := gomock.NewController(t)
 	repo := repoMock.NewMockUserRepositoryPort(ctl)
-	service := NewUserService(repo)
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		require.Error(t, err)
-	}
-	var MySigningKey = []byte(os.Getenv("SECRET_KEY"))
+	service := NewUserService(repo, repoMock.NewMockUnitOfWork(ctl), AuthConfig{SigningKey: testSigningKey})
+	var MySigningKey = testSigningKey
 	mockUserID := 1
 
 	tokenStr1 := ""
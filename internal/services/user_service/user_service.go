@@ -1,12 +1,13 @@
 package user_service
 
 import (
+	"context"
 	"errors"
 	"github.com/dvdxa/add-to-favorites/internal/domain"
 	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/internal/tracing"
 	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
-	"os"
 	"time"
 )
 
@@ -18,33 +19,63 @@ var (
 	ErrInvalidToken         = errors.New("signature is invalid")
 )
 
+const (
+	DefaultTokenTTL   = 24 * time.Hour
+	DefaultBcryptCost = 14
+)
+
+// AuthConfig holds the token and password settings. A zero TokenTTL or
+// BcryptCost uses DefaultTokenTTL or DefaultBcryptCost.
+type AuthConfig struct {
+	SigningKey []byte
+	TokenTTL   time.Duration
+	BcryptCost int
+}
+
 type UserService struct {
 	userRepositoryPort repositories.UserRepositoryPort
+	unitOfWork         repositories.UnitOfWork
+	auth               AuthConfig
 }
 
-func NewUserService(userRepositoryPort repositories.UserRepositoryPort) *UserService {
+func NewUserService(userRepositoryPort repositories.UserRepositoryPort, unitOfWork repositories.UnitOfWork, auth AuthConfig) *UserService {
+	if auth.TokenTTL <= 0 {
+		auth.TokenTTL = DefaultTokenTTL
+	}
+	if auth.BcryptCost == 0 {
+		auth.BcryptCost = DefaultBcryptCost
+	}
 	return &UserService{
 		userRepositoryPort: userRepositoryPort,
+		unitOfWork:         unitOfWork,
+		auth:               auth,
 	}
 }
 
-func (us *UserService) CreateUser(user domain.User) error {
-	passHash, _ := us.HashPassword(user.Password, 14)
+// CreateUser stores the user together with their empty favorites list, so
+// neither exists without the other.
+func (us *UserService) CreateUser(ctx context.Context, user domain.User) error {
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.GenerateFromPassword")
+	passHash, _ := us.HashPassword(user.Password, us.auth.BcryptCost)
+	span.End()
 	user.Password = string(passHash)
-	err := us.userRepositoryPort.CreateUser(user)
-	if err != nil {
-		return err
-	}
-	return nil
+	return us.unitOfWork.WithTx(ctx, repositories.TxOptions{IsoLevel: repositories.ReadCommitted}, func(tx *repositories.RepositoryPort) error {
+		userId, err := tx.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		return tx.CreateFavorites(ctx, userId)
+	})
 }
 
-func (us *UserService) GenerateToken(user domain.User) (tokenString string, err error) {
-	var MySigningKey = []byte(os.Getenv("SECRET_KEY"))
-	exUser, err := us.userRepositoryPort.GetUser(user.Name)
+func (us *UserService) GenerateToken(ctx context.Context, user domain.User) (tokenString string, err error) {
+	exUser, err := us.userRepositoryPort.GetUser(ctx, user.Name)
 	if err != nil {
 		return "", err
 	}
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.CompareHashAndPassword")
 	err = bcrypt.CompareHashAndPassword([]byte(exUser.Password), []byte(user.Password))
+	span.End()
 	if err != nil {
 		return "", err
 	}
@@ -52,9 +83,9 @@ func (us *UserService) GenerateToken(user domain.User) (tokenString string, err
 		"authorized": true,
 		"userId":     exUser.ID,
 		"iss":        "jwtgo.io",
-		"exp":        time.Now().Add(time.Hour * 24).Unix(),
+		"exp":        time.Now().Add(us.auth.TokenTTL).Unix(),
 	})
-	tokenString, err = token.SignedString(MySigningKey)
+	tokenString, err = token.SignedString(us.auth.SigningKey)
 	if err != nil {
 		return "", err
 	}
@@ -62,7 +93,6 @@ func (us *UserService) GenerateToken(user domain.User) (tokenString string, err
 }
 
 func (us *UserService) ParseToken(tokenStr string) (interface{}, error) {
-	var MySigningKey = []byte(os.Getenv("SECRET_KEY"))
 	if len(tokenStr) == 0 {
 		return 0, ErrEmptyToken
 	}
@@ -70,7 +100,7 @@ func (us *UserService) ParseToken(tokenStr string) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidSigningMethod
 		}
-		return MySigningKey, nil
+		return us.auth.SigningKey, nil
 	})
 	if err != nil {
 		return 0, err
@@ -101,3 +131,31 @@ func (us *UserService) HashPassword(password string, cost int) ([]byte, error) {
 	}
 	return passHash, nil
 }
+
+// IsActive reports whether the user exists and is not deleted. It is read
+// from storage on every call, so deleting a user locks out the tokens
+// already issued to them.
+func (us *UserService) IsActive(ctx context.Context, userId int) (bool, error) {
+	_, err := us.userRepositoryPort.GetUserByID(ctx, userId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsAdmin reports whether the user exists, is not deleted and has the admin
+// role. The role is read from storage on every call, so revoking it takes
+// effect without waiting for issued tokens to expire.
+func (us *UserService) IsAdmin(ctx context.Context, userId int) (bool, error) {
+	user, err := us.userRepositoryPort.GetUserByID(ctx, userId)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.Role == domain.RoleAdmin, nil
+}
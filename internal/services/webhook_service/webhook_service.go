@@ -0,0 +1,309 @@
+package webhook_service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/dvdxa/add-to-favorites/pkg/logger"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultTimeout     = 10 * time.Second
+
+	// maxBackoff caps the wait between two attempts.
+	maxBackoff = 6 * time.Hour
+	// batchSize bounds the events handled per query.
+	batchSize = 100
+	// deliveryBatch is how many deliveries are claimed at a time. They are
+	// sent one after another, so it bounds how long a claim has to last.
+	deliveryBatch = 10
+)
+
+// Config holds the delivery settings. A delivery that failed MaxAttempts
+// times is dead; the first retry waits Backoff and every further one twice
+// as long as the last. Zero values use the defaults.
+type Config struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	Timeout     time.Duration
+}
+
+type WebhookService struct {
+	webhookRepositoryPort  repositories.WebhookRepositoryPort
+	terminalRepositoryPort repositories.TerminalRepositoryPort
+	unitOfWork             repositories.UnitOfWork
+	cfg                    Config
+	client                 *http.Client
+}
+
+func NewWebhookService(webhookRepositoryPort repositories.WebhookRepositoryPort, terminalRepositoryPort repositories.TerminalRepositoryPort,
+	unitOfWork repositories.UnitOfWork, cfg Config) *WebhookService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &WebhookService{
+		webhookRepositoryPort:  webhookRepositoryPort,
+		terminalRepositoryPort: terminalRepositoryPort,
+		unitOfWork:             unitOfWork,
+		cfg:                    cfg,
+		client:                 &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// CreateWebhook validates hook and stores it. Without a secret one is
+// generated; the result is the only place the secret is shown. A webhook on
+// an unknown terminal fails with repositories.ErrNotFound.
+func (ws *WebhookService) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	if err := hook.Validate(); err != nil {
+		return domain.Webhook{}, err
+	}
+	if hook.TerminalID != 0 {
+		terminals, err := ws.terminalRepositoryPort.GetDefaultTerminalsList(ctx)
+		if err != nil {
+			return domain.Webhook{}, err
+		}
+		if _, ok := byID(terminals)[hook.TerminalID]; !ok {
+			return domain.Webhook{}, fmt.Errorf("terminal %d: %w", hook.TerminalID, repositories.ErrNotFound)
+		}
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return domain.Webhook{}, fmt.Errorf("failed to generate a webhook secret: %v", err)
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+	return ws.webhookRepositoryPort.CreateWebhook(ctx, hook)
+}
+
+// GetWebhooks lists the webhooks without their secrets.
+func (ws *WebhookService) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	hooks, err := ws.webhookRepositoryPort.GetWebhooks(ctx)
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, err
+}
+
+func (ws *WebhookService) DeleteWebhook(ctx context.Context, id int) error {
+	return ws.webhookRepositoryPort.DeleteWebhook(ctx, id)
+}
+
+// GetWebhookDeliveries lists the webhook's deliveries, newest first. It
+// fails with repositories.ErrNotFound for an unknown webhook.
+func (ws *WebhookService) GetWebhookDeliveries(ctx context.Context, webhookID int, state string) ([]domain.WebhookDelivery, error) {
+	hooks, err := ws.webhookRepositoryPort.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := hooksByID(hooks)[webhookID]; !ok {
+		return nil, fmt.Errorf("webhook %d: %w", webhookID, repositories.ErrNotFound)
+	}
+	return ws.webhookRepositoryPort.GetWebhookDeliveries(ctx, webhookID, state)
+}
+
+// RedeliverWebhook sends a delivery again on the next dispatch, with a
+// fresh set of attempts, whether it was delivered, pending or dead.
+func (ws *WebhookService) RedeliverWebhook(ctx context.Context, webhookID int, id int64) error {
+	return ws.webhookRepositoryPort.RedeliverWebhookDelivery(ctx, webhookID, id)
+}
+
+// DispatchWebhooks turns the pending status change and favorite change
+// events into deliveries for the webhooks they match, then claims and sends
+// the deliveries due by now, so that dispatchers running side by side send
+// each delivery once. It returns how many were delivered and how many
+// attempts failed.
+func (ws *WebhookService) DispatchWebhooks(ctx context.Context, now time.Time) (delivered int, failed int, err error) {
+	for {
+		more, err := ws.enqueue(ctx, now)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+		}
+		if !more {
+			break
+		}
+	}
+	var byHook map[int]domain.Webhook
+	for {
+		// Each batch is leased for as long as sending all of it may take.
+		due, err := ws.webhookRepositoryPort.ClaimDueWebhookDeliveries(ctx, now, deliveryBatch*ws.cfg.Timeout, deliveryBatch)
+		if err != nil || len(due) == 0 {
+			return delivered, failed, err
+		}
+		if byHook == nil {
+			hooks, err := ws.webhookRepositoryPort.GetWebhooks(ctx)
+			if err != nil {
+				return delivered, failed, err
+			}
+			byHook = hooksByID(hooks)
+		}
+		for _, delivery := range due {
+			hook, ok := byHook[delivery.WebhookID]
+			if !ok {
+				continue
+			}
+			delivery = ws.attempt(ctx, hook, delivery, now)
+			if delivery.State == domain.DeliveryDelivered {
+				delivered++
+			} else {
+				failed++
+			}
+			err = ws.webhookRepositoryPort.UpdateWebhookDelivery(ctx, delivery)
+			if err != nil {
+				return delivered, failed, fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, err)
+			}
+		}
+		if len(due) < deliveryBatch {
+			return delivered, failed, nil
+		}
+	}
+}
+
+// enqueue claims one batch of pending events and turns them into
+// deliveries due at now in the same transaction, so each event is enqueued
+// once. It reports whether a batch was full and more may follow.
+func (ws *WebhookService) enqueue(ctx context.Context, now time.Time) (bool, error) {
+	var more bool
+	err := ws.unitOfWork.WithTx(ctx, repositories.TxOptions{}, func(tx *repositories.RepositoryPort) error {
+		events, err := tx.ClaimWebhookEvents(ctx, batchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		more = len(events) == batchSize
+
+		hooks, err := tx.GetWebhooks(ctx)
+		if err != nil {
+			return err
+		}
+		terminals, err := tx.GetDefaultTerminalsList(ctx)
+		if err != nil {
+			return err
+		}
+		terminalsByID := byID(terminals)
+		for _, event := range events {
+			event.OccurredAt = event.OccurredAt.UTC()
+			terminal := terminalsByID[event.TerminalID]
+			event.TerminalName = terminal.Name
+			for _, hook := range hooks {
+				if !hook.Matches(event, terminal.Tags) {
+					continue
+				}
+				payload, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				err = tx.CreateWebhookDelivery(ctx, domain.WebhookDelivery{
+					WebhookID: hook.ID, Event: event.Type, Payload: payload, NextAttemptAt: &now,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return more, err
+}
+
+// attempt posts the delivery's payload to the webhook and returns the
+// delivery updated with the outcome.
+func (ws *WebhookService) attempt(ctx context.Context, hook domain.Webhook, delivery domain.WebhookDelivery, now time.Time) domain.WebhookDelivery {
+	delivery.Attempts++
+	delivery.LastError = ""
+	status, err := ws.post(ctx, hook, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.State = domain.DeliveryDelivered
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		return delivery
+	}
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= ws.cfg.MaxAttempts {
+		delivery.State = domain.DeliveryDead
+		delivery.NextAttemptAt = nil
+		return delivery
+	}
+	next := now.Add(domain.Backoff(ws.cfg.Backoff, maxBackoff, delivery.Attempts))
+	delivery.NextAttemptAt = &next
+	return delivery
+}
+
+// post sends one delivery. Any response other than 2xx is an error.
+func (ws *WebhookService) post(ctx context.Context, hook domain.Webhook, delivery domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(domain.SignatureHeader, hook.Sign(delivery.Payload))
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// RunWebhookDispatcher calls DispatchWebhooks every interval until ctx is
+// cancelled. A non-positive interval disables it.
+func (ws *WebhookService) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	log := logger.GetLogger()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		delivered, failed, err := ws.DispatchWebhooks(ctx, time.Now())
+		if err != nil {
+			log.Errorf("failed to dispatch webhooks: %v", err)
+		} else if failed > 0 {
+			log.Warnf("delivered %d webhooks, %d attempts failed", delivered, failed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func byID(terminals []domain.Terminal) map[int]domain.Terminal {
+	m := make(map[int]domain.Terminal, len(terminals))
+	for _, terminal := range terminals {
+		m[terminal.ID] = terminal
+	}
+	return m
+}
+
+func hooksByID(hooks []domain.Webhook) map[int]domain.Webhook {
+	m := make(map[int]domain.Webhook, len(hooks))
+	for _, hook := range hooks {
+		m[hook.ID] = hook
+	}
+	return m
+}
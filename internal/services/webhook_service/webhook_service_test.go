@@ -0,0 +1,207 @@
+package webhook_service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dvdxa/add-to-favorites/internal/domain"
+	repoMock "github.com/dvdxa/add-to-favorites/internal/mocks"
+	"github.com/dvdxa/add-to-favorites/internal/repositories"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type mocks struct {
+	webhookRepo  *repoMock.MockWebhookRepositoryPort
+	terminalRepo *repoMock.MockTerminalRepositoryPort
+	uow          *repoMock.MockUnitOfWork
+}
+
+func newService(t *testing.T, cfg Config) (*WebhookService, mocks) {
+	ctl := gomock.NewController(t)
+	m := mocks{
+		webhookRepo:  repoMock.NewMockWebhookRepositoryPort(ctl),
+		terminalRepo: repoMock.NewMockTerminalRepositoryPort(ctl),
+		uow:          repoMock.NewMockUnitOfWork(ctl),
+	}
+	m.uow.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, opts repositories.TxOptions, fn func(tx *repositories.RepositoryPort) error) error {
+			return fn(&repositories.RepositoryPort{WebhookRepositoryPort: m.webhookRepo, TerminalRepositoryPort: m.terminalRepo, UnitOfWork: m.uow})
+		}).AnyTimes()
+	return NewWebhookService(m.webhookRepo, m.terminalRepo, m.uow, cfg), m
+}
+
+// expectNoEvents makes the enqueue step find nothing pending.
+func expectNoEvents(m mocks) {
+	m.webhookRepo.EXPECT().ClaimWebhookEvents(gomock.Any(), batchSize).Return([]domain.WebhookEvent{}, nil)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	service, m := newService(t, Config{})
+
+	_, err := service.CreateWebhook(context.Background(), domain.Webhook{URL: "ftp://example.com"})
+	require.ErrorIs(t, err, domain.ErrInvalidWebhook)
+
+	m.terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{{ID: 1}}, nil).Times(1)
+	_, err = service.CreateWebhook(context.Background(), domain.Webhook{URL: "https://hooks.example.com", TerminalID: 2})
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+
+	m.webhookRepo.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+			require.Len(t, hook.Secret, 64, "a secret is generated")
+			hook.ID = 1
+			return hook, nil
+		}).Times(1)
+	hook, err := service.CreateWebhook(context.Background(), domain.Webhook{URL: "https://hooks.example.com"})
+	require.NoError(t, err)
+	require.NotEmpty(t, hook.Secret, "the secret is shown once")
+
+	m.webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{hook}, nil).Times(1)
+	hooks, err := service.GetWebhooks(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, hooks[0].Secret)
+}
+
+func TestDispatchWebhooksEnqueues(t *testing.T) {
+	service, m := newService(t, Config{})
+	changedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	m.webhookRepo.EXPECT().ClaimWebhookEvents(gomock.Any(), batchSize).Return([]domain.WebhookEvent{
+		{Type: domain.WebhookFavoriteAdd, OccurredAt: changedAt.In(time.FixedZone("CEST", 2*60*60)), TerminalID: 1, UserID: 3},
+		{Type: domain.WebhookStatusChange, OccurredAt: changedAt.Add(time.Minute), TerminalID: 2, Status: "offline"},
+	}, nil)
+	m.webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{
+		{ID: 1, URL: "https://all.example.com"},
+		{ID: 2, URL: "https://north.example.com", Events: []string{domain.WebhookStatusChange}, Labels: []string{"zone=north"}},
+		{ID: 3, URL: "https://south.example.com", Labels: []string{"zone=south"}},
+	}, nil)
+	m.terminalRepo.EXPECT().GetDefaultTerminalsList(gomock.Any()).Return([]domain.Terminal{
+		{ID: 1, Name: "T-1", Tags: []domain.Tag{{Key: "zone", Value: "north"}}},
+		{ID: 2, Name: "T-2", Tags: []domain.Tag{{Key: "zone", Value: "north"}}},
+	}, nil)
+
+	var created []domain.WebhookDelivery
+	m.webhookRepo.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, delivery domain.WebhookDelivery) error {
+			created = append(created, delivery)
+			return nil
+		}).Times(3)
+	m.webhookRepo.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any(), deliveryBatch*DefaultTimeout, deliveryBatch).Return(nil, nil)
+
+	now := time.Now()
+	delivered, failed, err := service.DispatchWebhooks(context.Background(), now)
+	require.NoError(t, err)
+	require.Zero(t, delivered)
+	require.Zero(t, failed)
+
+	// Events go out in order, to every webhook they match, with their
+	// time in UTC.
+	require.Len(t, created, 3)
+	require.Equal(t, 1, created[0].WebhookID)
+	require.Equal(t, domain.WebhookFavoriteAdd, created[0].Event)
+	require.Equal(t, &now, created[0].NextAttemptAt, "new deliveries are due in the same dispatch")
+	require.JSONEq(t, `{"event":"favorite.add","occurred_at":"2024-05-01T12:00:00Z","terminal_id":1,"terminal_name":"T-1","user_id":3}`,
+		string(created[0].Payload))
+	require.Equal(t, 1, created[1].WebhookID)
+	require.Equal(t, 2, created[2].WebhookID)
+	require.JSONEq(t, `{"event":"terminal.status_change","occurred_at":"2024-05-01T12:01:00Z","terminal_id":2,"terminal_name":"T-2","status":"offline"}`,
+		string(created[2].Payload))
+}
+
+func TestDispatchWebhooksDelivers(t *testing.T) {
+	service, m := newService(t, Config{Backoff: time.Minute})
+	hook := domain.Webhook{ID: 1, Secret: "0123456789abcdef"}
+	payload := json.RawMessage(`{"event":"favorite.add","terminal_id":1}`)
+
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, string(payload), string(body))
+		require.Equal(t, hook.Sign(body), r.Header.Get(domain.SignatureHeader))
+		require.Equal(t, domain.WebhookFavoriteAdd, r.Header.Get("X-Webhook-Event"))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		require.Equal(t, "7", r.Header.Get("X-Webhook-Delivery"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	hook.URL = receiver.URL + "/up"
+	down := domain.Webhook{ID: 2, URL: receiver.URL + "/down", Secret: hook.Secret}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expectNoEvents(m)
+	m.webhookRepo.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), now, deliveryBatch*DefaultTimeout, deliveryBatch).Return([]domain.WebhookDelivery{
+		{ID: 7, WebhookID: 1, Event: domain.WebhookFavoriteAdd, Payload: payload, State: domain.DeliveryPending},
+		{ID: 8, WebhookID: 2, Event: domain.WebhookFavoriteAdd, Payload: payload, State: domain.DeliveryPending, Attempts: 2},
+		{ID: 9, WebhookID: 3, Event: domain.WebhookFavoriteAdd, Payload: payload, State: domain.DeliveryPending},
+	}, nil)
+	m.webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{hook, down}, nil)
+
+	updated := map[int64]domain.WebhookDelivery{}
+	m.webhookRepo.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, delivery domain.WebhookDelivery) error {
+			updated[delivery.ID] = delivery
+			return nil
+		}).Times(2)
+
+	delivered, failed, err := service.DispatchWebhooks(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.Equal(t, 1, failed)
+	require.Equal(t, 2, requests, "deliveries of deleted webhooks are skipped")
+
+	ok := updated[7]
+	require.Equal(t, domain.DeliveryDelivered, ok.State)
+	require.Equal(t, 1, ok.Attempts)
+	require.Equal(t, http.StatusNoContent, ok.ResponseStatus)
+	require.Equal(t, &now, ok.DeliveredAt)
+	require.Nil(t, ok.NextAttemptAt)
+
+	retry := updated[8]
+	require.Equal(t, domain.DeliveryPending, retry.State)
+	require.Equal(t, 3, retry.Attempts)
+	require.Equal(t, http.StatusServiceUnavailable, retry.ResponseStatus)
+	require.Equal(t, "unexpected response 503 Service Unavailable", retry.LastError)
+	require.Equal(t, now.Add(4*time.Minute), *retry.NextAttemptAt, "the third failure waits four times the backoff")
+}
+
+func TestDispatchWebhooksDeadLetter(t *testing.T) {
+	service, m := newService(t, Config{MaxAttempts: 3})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	expectNoEvents(m)
+	m.webhookRepo.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any(), deliveryBatch*DefaultTimeout, deliveryBatch).Return([]domain.WebhookDelivery{
+		{ID: 7, WebhookID: 1, Payload: json.RawMessage(`{}`), State: domain.DeliveryPending, Attempts: 2},
+	}, nil)
+	m.webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{{ID: 1, URL: receiver.URL, Secret: "0123456789abcdef"}}, nil)
+	m.webhookRepo.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, delivery domain.WebhookDelivery) error {
+			require.Equal(t, domain.DeliveryDead, delivery.State)
+			require.Equal(t, 3, delivery.Attempts)
+			require.Nil(t, delivery.NextAttemptAt)
+			return nil
+		}).Times(1)
+
+	_, failed, err := service.DispatchWebhooks(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, failed)
+}
+
+func TestGetWebhookDeliveriesUnknownWebhook(t *testing.T) {
+	service, m := newService(t, Config{})
+	m.webhookRepo.EXPECT().GetWebhooks(gomock.Any()).Return([]domain.Webhook{{ID: 1}}, nil)
+	_, err := service.GetWebhookDeliveries(context.Background(), 2, "")
+	require.ErrorIs(t, err, repositories.ErrNotFound)
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx that carries l. Request middleware uses it
+// to hand a logger with request fields down to handlers and repositories.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the global logger if
+// there is none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return GetLogger()
+}
+
+// For returns the logger carried by ctx, or l itself if there is none.
+func (l Logger) For(ctx context.Context) *Logger {
+	if ctxLogger, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return ctxLogger
+	}
+	return &l
+}
+
+// WithFields returns a logger that adds fields to every entry.
+func (l Logger) WithFields(fields logrus.Fields) *Logger {
+	return &Logger{l.Entry.WithFields(fields)}
+}
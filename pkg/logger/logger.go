@@ -1,27 +1,71 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 	"io"
-	"log"
 	"os"
 	"path"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Config describes where log lines go and how they look. The zero value logs
+// info and above as text to stdout.
+type Config struct {
+	// Level is the minimum level logged: "trace", "debug", "info", "warn",
+	// "error", "fatal" or "panic". Defaults to "info".
+	Level string
+	// Format is "text" (default) or "json".
+	Format string
+	// Outputs receive the log lines. Empty means stdout only.
+	Outputs []Output
+}
+
+// Output is one destination for log lines.
+type Output struct {
+	// Path is "stdout", "stderr" or a file path.
+	Path string
+	// Levels restricts the output to these levels, for example ["error",
+	// "fatal", "panic"] for an error log. Empty means every logged level.
+	Levels []string
+
+	// The rotation settings apply to files only. A file is rotated once it
+	// reaches MaxSizeMB megabytes (100 if zero) or, if set, every
+	// RotateInterval. Rotated files older than MaxAgeDays or beyond the
+	// newest MaxBackups are removed; zero keeps them. Compress gzips them.
+	MaxSizeMB      int
+	RotateInterval time.Duration
+	MaxAgeDays     int
+	MaxBackups     int
+	Compress       bool
+}
+
 type writeHook struct {
 	Writer    []io.Writer
 	LogLevels []logrus.Level
 }
 
 func (hook *writeHook) Fire(entry *logrus.Entry) error {
-	line, err := entry.String()
+	line, err := entry.Bytes()
 	if err != nil {
 		return err
 	}
 	for _, w := range hook.Writer {
-		_, err := w.Write([]byte(line))
+		_, err := w.Write(line)
 		if err != nil {
 			return err
 		}
@@ -34,45 +78,174 @@ func (hook *writeHook) Levels() []logrus.Level {
 	return hook.LogLevels
 }
 
-var e *logrus.Entry
-
 type Logger struct {
 	*logrus.Entry
 }
 
+var (
+	global      atomic.Pointer[Logger]
+	defaultOnce sync.Once
+	defaultLog  *Logger
+)
+
+// GetLogger returns the logger installed with SetDefault, or a text logger
+// on stdout at info level if there is none.
 func GetLogger() *Logger {
-	return &Logger{e}
+	if l := global.Load(); l != nil {
+		return l
+	}
+	defaultOnce.Do(func() {
+		l, _, _ := New(Config{})
+		defaultLog = l
+	})
+	return defaultLog
 }
 
-func init() {
-	l := logrus.New()
-	l.SetReportCaller(true)
-	l.Formatter = &logrus.TextFormatter{
-		CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-			filename := path.Base(frame.File)
-			return fmt.Sprintf("%s()", frame.Function), fmt.Sprintf("%s:%d", filename, frame.Line)
-		},
-		DisableColors: false,
-		FullTimestamp: true,
-	}
+// SetDefault makes l the logger returned by GetLogger and FromContext.
+func SetDefault(l *Logger) {
+	global.Store(l)
+}
 
-	err := os.MkdirAll("./internal/logs", 0777)
-	if err != nil {
-		log.Fatal(err)
+// New builds a logger from cfg. The returned close function flushes and
+// closes the log files and stops their rotation timers.
+func New(cfg Config) (*Logger, func() error, error) {
+	level := logrus.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		level, err = logrus.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-
-	allFile, err := os.OpenFile("./internal/logs/all.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0777)
+	formatter, err := newFormatter(cfg.Format)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
+
+	l := logrus.New()
+	l.SetReportCaller(true)
+	l.Formatter = formatter
+	l.SetLevel(level)
 	l.SetOutput(io.Discard)
 
-	l.AddHook(&writeHook{
-		Writer:    []io.Writer{allFile, os.Stdout},
-		LogLevels: logrus.AllLevels,
-	})
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{{Path: OutputStdout}}
+	}
+	var closers []func() error
+	closeAll := func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c())
+		}
+		return errors.Join(errs...)
+	}
+	for _, out := range outputs {
+		levels, err := parseLevels(out.Levels)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("log output %q: %w", out.Path, err)
+		}
+		w, closeOutput, err := openOutput(out)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("log output %q: %w", out.Path, err)
+		}
+		if closeOutput != nil {
+			closers = append(closers, closeOutput)
+		}
+		l.AddHook(&writeHook{
+			Writer:    []io.Writer{w},
+			LogLevels: levels,
+		})
+	}
+	return &Logger{logrus.NewEntry(l)}, closeAll, nil
+}
 
-	l.SetLevel(logrus.TraceLevel)
+func newFormatter(format string) (logrus.Formatter, error) {
+	callerPrettyfier := func(frame *runtime.Frame) (function string, file string) {
+		filename := path.Base(frame.File)
+		return fmt.Sprintf("%s()", frame.Function), fmt.Sprintf("%s:%d", filename, frame.Line)
+	}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return &logrus.TextFormatter{
+			CallerPrettyfier: callerPrettyfier,
+			FullTimestamp:    true,
+		}, nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{
+			CallerPrettyfier: callerPrettyfier,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
 
-	e = logrus.NewEntry(l)
+func parseLevels(names []string) ([]logrus.Level, error) {
+	if len(names) == 0 {
+		return logrus.AllLevels, nil
+	}
+	levels := make([]logrus.Level, 0, len(names))
+	for _, name := range names {
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// openOutput returns the writer for out and, for files, a function that
+// closes it.
+func openOutput(out Output) (io.Writer, func() error, error) {
+	switch out.Path {
+	case OutputStdout:
+		return os.Stdout, nil, nil
+	case OutputStderr:
+		return os.Stderr, nil, nil
+	case "":
+		return nil, nil, errors.New("empty path")
+	}
+	file := &lumberjack.Logger{
+		Filename:   out.Path,
+		MaxSize:    out.MaxSizeMB,
+		MaxAge:     out.MaxAgeDays,
+		MaxBackups: out.MaxBackups,
+		Compress:   out.Compress,
+		LocalTime:  true,
+	}
+	// lumberjack opens the file lazily; open it now so a bad path fails
+	// at startup instead of on the first log line.
+	if _, err := file.Write(nil); err != nil {
+		return nil, nil, err
+	}
+	if out.RotateInterval <= 0 {
+		return file, file.Close, nil
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(out.RotateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				file.Rotate()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	closeFile := func() error {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+		return file.Close()
+	}
+	return file, closeFile, nil
 }
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSplitsOutputsByLevel(t *testing.T) {
+	dir := t.TempDir()
+	allPath := filepath.Join(dir, "logs", "all.log")
+	errPath := filepath.Join(dir, "logs", "error.log")
+	l, closeLog, err := New(Config{
+		Level:  "debug",
+		Format: FormatJSON,
+		Outputs: []Output{
+			{Path: allPath},
+			{Path: errPath, Levels: []string{"error", "fatal", "panic"}},
+		},
+	})
+	require.NoError(t, err)
+
+	l.Trace("dropped")
+	l.Debug("debug line")
+	l.WithField("request_id", "abc").Error("error line")
+	require.NoError(t, closeLog())
+
+	all := readLines(t, allPath)
+	require.Len(t, all, 2)
+	require.Equal(t, "debug line", all[0]["msg"])
+	require.Equal(t, "error line", all[1]["msg"])
+	require.Equal(t, "abc", all[1]["request_id"])
+	require.Contains(t, all[1]["file"], "logger_test.go")
+
+	errs := readLines(t, errPath)
+	require.Len(t, errs, 1)
+	require.Equal(t, "error", errs[0]["level"])
+}
+
+func TestNewRotatesAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "all.log")
+	l, closeLog, err := New(Config{
+		Outputs: []Output{{Path: path, MaxSizeMB: 1, Compress: true}},
+	})
+	require.NoError(t, err)
+
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		l.Info(line)
+	}
+	require.NoError(t, closeLog())
+
+	require.Eventually(t, func() bool {
+		compressed, _ := filepath.Glob(filepath.Join(dir, "all-*.log.gz"))
+		uncompressed, _ := filepath.Glob(filepath.Join(dir, "all-*.log"))
+		return len(compressed) == 1 && len(uncompressed) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, info.Size(), int64(1<<20))
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "level", cfg: Config{Level: "loud"}},
+		{name: "format", cfg: Config{Format: "xml"}},
+		{name: "output_level", cfg: Config{Outputs: []Output{{Path: OutputStdout, Levels: []string{"loud"}}}}},
+		{name: "empty_path", cfg: Config{Outputs: []Output{{}}}},
+	}
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			_, _, err := New(tCase.cfg)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGetLoggerReturnsDefault(t *testing.T) {
+	require.NotNil(t, GetLogger())
+
+	l, _, err := New(Config{Level: "warn"})
+	require.NoError(t, err)
+	SetDefault(l)
+	t.Cleanup(func() { global.Store(nil) })
+	require.Same(t, l, GetLogger())
+}
+
+func readLines(t *testing.T, path string) []map[string]interface{} {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
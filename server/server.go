@@ -2,25 +2,112 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Server struct {
+	mu         sync.Mutex
 	httpServer *http.Server
+	onShutdown []func()
+	inFlight   atomic.Int64
+	addr       net.Addr
 }
 
+// Run serves handler on port until Shutdown is called, in which case it
+// returns nil.
 func (s *Server) Run(port string, handler http.Handler) error {
+	return s.serve(":"+port, handler, nil)
+}
+
+// RunLocal is Run on the loopback interface only, for endpoints that must
+// not be reachable from other hosts.
+func (s *Server) RunLocal(port string, handler http.Handler) error {
+	return s.serve("127.0.0.1:"+port, handler, nil)
+}
+
+// RunTLS is Run over TLS. The certificate, key and client CA files are read
+// before listening and reloaded whenever they change.
+func (s *Server) RunTLS(port string, handler http.Handler, cfg TLSConfig) error {
+	reloader, err := newCertReloader(cfg)
+	if err != nil {
+		return err
+	}
+	return s.serve(":"+port, handler, reloader.tlsConfig())
+}
+
+func (s *Server) serve(addr string, handler http.Handler, tlsConfig *tls.Config) error {
+	s.mu.Lock()
 	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
+		Addr:           addr,
+		Handler:        s.track(handler),
 		MaxHeaderBytes: 1 << 20,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
+		TLSConfig:      tlsConfig,
+	}
+	for _, f := range s.onShutdown {
+		s.httpServer.RegisterOnShutdown(f)
+	}
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	ln, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.addr = ln.Addr()
+	s.mu.Unlock()
+	if tlsConfig != nil {
+		err = httpServer.ServeTLS(ln, "", "")
+	} else {
+		err = httpServer.Serve(ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// OnShutdown registers f to be called when Shutdown starts. Handlers that keep
+// connections open, such as streams, use it to finish, since Shutdown waits
+// for them otherwise.
+func (s *Server) OnShutdown(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, f)
+	if s.httpServer != nil {
+		s.httpServer.RegisterOnShutdown(f)
 	}
-	return s.httpServer.ListenAndServe()
 }
 
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish until ctx is done.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.mu.Lock()
+	httpServer := s.httpServer
+	s.mu.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
+
+// InFlight returns the number of requests being served right now.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+func (s *Server) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		handler.ServeHTTP(w, r)
+	})
 }
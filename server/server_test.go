@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdownBeforeRun(t *testing.T) {
+	srv := new(Server)
+	require.NoError(t, srv.Shutdown(context.Background()))
+}
+
+func TestRunReturnsNilAfterShutdown(t *testing.T) {
+	srv := new(Server)
+	shutdownCalled := make(chan struct{})
+	srv.OnShutdown(func() { close(shutdownCalled) })
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Run("0", http.NotFoundHandler())
+	}()
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return srv.httpServer != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	select {
+	case err := <-errs:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+	select {
+	case <-shutdownCalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnShutdown hook was not called")
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	srv := new(Server)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := srv.track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/terminals", nil))
+		close(done)
+	}()
+	<-started
+	require.Equal(t, int64(1), srv.InFlight())
+	close(release)
+	<-done
+	require.Equal(t, int64(0), srv.InFlight())
+}
+
+func TestRunLocalListensOnLoopback(t *testing.T) {
+	srv := new(Server)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.RunLocal("0", http.NotFoundHandler())
+	}()
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return srv.addr != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	srv.mu.Lock()
+	addr := srv.addr.(*net.TCPAddr)
+	srv.mu.Unlock()
+	require.True(t, addr.IP.IsLoopback())
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	require.NoError(t, <-errs)
+}
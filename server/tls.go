@@ -0,0 +1,282 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+
+	// DefaultReloadInterval is how often the certificate files are checked
+	// for changes, at most, when a connection comes in.
+	DefaultReloadInterval = 5 * time.Second
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig configures RunTLS.
+type TLSConfig struct {
+	// CertFile and KeyFile hold the PEM server certificate chain and key.
+	// They are reloaded when either file changes, without a restart.
+	CertFile string
+	KeyFile  string
+	// MinVersion is "1.0", "1.1", "1.2" (default) or "1.3".
+	MinVersion string
+	// ClientCAFile is a PEM bundle of CAs that client certificates are
+	// verified against. It is reloaded along with the certificate.
+	ClientCAFile string
+	// ClientAuth is "none", "optional" (verify a certificate if one is sent)
+	// or "require". It defaults to "require" when ClientCAFile is set.
+	ClientAuth string
+	// ReloadInterval defaults to DefaultReloadInterval.
+	ReloadInterval time.Duration
+	// OnReload, if set, is called after every reload attempt, with the
+	// error if the files could not be loaded. The previous certificate
+	// stays in use in that case.
+	OnReload func(err error)
+}
+
+// ParseTLSVersion turns "1.2" into tls.VersionTLS12; empty means 1.2.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", version)
+	}
+	return v, nil
+}
+
+func (cfg TLSConfig) clientAuth() (tls.ClientAuthType, error) {
+	mode := cfg.ClientAuth
+	if mode == "" {
+		mode = ClientAuthNone
+		if cfg.ClientCAFile != "" {
+			mode = ClientAuthRequire
+		}
+	}
+	switch mode {
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("unknown client auth mode %q", mode)
+}
+
+// Validate reports problems that would keep RunTLS from starting, without
+// reading the files.
+func (cfg TLSConfig) Validate() error {
+	var errs []error
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		errs = append(errs, errors.New("both a certificate and a key file are required for TLS"))
+	}
+	if _, err := ParseTLSVersion(cfg.MinVersion); err != nil {
+		errs = append(errs, err)
+	}
+	auth, err := cfg.clientAuth()
+	if err != nil {
+		errs = append(errs, err)
+	} else if auth != tls.NoClientCert && cfg.ClientCAFile == "" {
+		errs = append(errs, errors.New("client certificate verification needs a client CA file"))
+	}
+	return errors.Join(errs...)
+}
+
+// certReloader builds the tls.Config from the files and rebuilds it when
+// they change. Changes are noticed on the next handshake after
+// ReloadInterval has passed since the last check.
+type certReloader struct {
+	cfg        TLSConfig
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+
+	mu        sync.Mutex
+	current   *tls.Config
+	stamps    []fileStamp
+	lastCheck time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertReloader(cfg TLSConfig) (*certReloader, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultReloadInterval
+	}
+	r := &certReloader{cfg: cfg}
+	r.minVersion, _ = ParseTLSVersion(cfg.MinVersion)
+	r.clientAuth, _ = cfg.clientAuth()
+	r.current, r.stamps, err = r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.lastCheck = time.Now()
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) stat() ([]fileStamp, error) {
+	var stamps []fileStamp
+	for _, name := range r.files() {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stamps, nil
+}
+
+func (r *certReloader) load() (*tls.Config, []fileStamp, error) {
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.minVersion,
+		ClientAuth:   r.clientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+		config.ClientCAs = pool
+	}
+	return config, stamps, nil
+}
+
+// config returns the current tls.Config, reloading it first if the files
+// changed since the last check.
+func (r *certReloader) config() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) < r.cfg.ReloadInterval {
+		return r.current
+	}
+	r.lastCheck = time.Now()
+	stamps, err := r.stat()
+	if err == nil && equalStamps(stamps, r.stamps) {
+		return r.current
+	}
+	var next *tls.Config
+	if err == nil {
+		next, stamps, err = r.load()
+	}
+	if err == nil {
+		r.current, r.stamps = next, stamps
+	}
+	if r.cfg.OnReload != nil {
+		r.cfg.OnReload(err)
+	}
+	return r.current
+}
+
+func equalStamps(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config(), nil
+		},
+		// ListenAndServeTLS only checks that a certificate source is set;
+		// handshakes use the config returned above.
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.config().Certificates[0], nil
+		},
+	}
+}
+
+// ClientIdentity describes the verified client certificate of a mutual TLS
+// connection.
+type ClientIdentity struct {
+	Subject      string   `json:"subject"`
+	CommonName   string   `json:"common_name"`
+	DNSNames     []string `json:"dns_names,omitempty"`
+	Emails       []string `json:"emails,omitempty"`
+	URIs         []string `json:"uris,omitempty"`
+	Issuer       string   `json:"issuer"`
+	SerialNumber string   `json:"serial_number"`
+	// Fingerprint is the hex SHA-256 of the certificate.
+	Fingerprint string    `json:"fingerprint"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// ClientIdentityFromRequest returns the identity of the client certificate
+// the request came with, if it was verified against the client CA.
+func ClientIdentityFromRequest(r *http.Request) (ClientIdentity, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ClientIdentity{}, false
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	sum := sha256.Sum256(cert.Raw)
+	id := ClientIdentity{
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		Emails:       cert.EmailAddresses,
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		Fingerprint:  hex.EncodeToString(sum[:]),
+		NotAfter:     cert.NotAfter,
+	}
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+	return id, true
+}
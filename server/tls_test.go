@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, commonName string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM(), c.keyPEM(t))
+	require.NoError(t, err)
+	return cert
+}
+
+// writePair writes the certificate and key and moves their modification
+// time forward, so a rewrite within the same clock tick is still noticed.
+func writePair(t *testing.T, dir string, c *testCert, at time.Time) (string, string) {
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, c.certPEM(), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, c.keyPEM(t), 0o600))
+	require.NoError(t, os.Chtimes(certFile, at, at))
+	require.NoError(t, os.Chtimes(keyFile, at, at))
+	return certFile, keyFile
+}
+
+func startTLS(t *testing.T, cfg TLSConfig, handler http.Handler) string {
+	srv := new(Server)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.RunTLS("0", handler, cfg)
+	}()
+	var port int
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		if srv.addr != nil {
+			port = srv.addr.(*net.TCPAddr).Port
+		}
+		return port != 0
+	}, 5*time.Second, 10*time.Millisecond)
+	t.Cleanup(func() {
+		require.NoError(t, srv.Shutdown(context.Background()))
+		require.NoError(t, <-errs)
+	})
+	return "https://127.0.0.1:" + strconv.Itoa(port)
+}
+
+func newClient(ca *testCert, cert *tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+}
+
+func servedSerial(t *testing.T, client *http.Client, url string) int64 {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestRunTLSReloadsCertificate(t *testing.T) {
+	ca := newTestCert(t, 1, "test ca", nil, true)
+	dir := t.TempDir()
+	now := time.Now()
+	certFile, keyFile := writePair(t, dir, newTestCert(t, 10, "server", ca, false), now)
+	reloaded := make(chan error, 10)
+	url := startTLS(t, TLSConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: time.Millisecond,
+		OnReload:       func(err error) { reloaded <- err },
+	}, http.NotFoundHandler())
+	client := newClient(ca, nil)
+	require.Equal(t, int64(10), servedSerial(t, client, url))
+
+	writePair(t, dir, newTestCert(t, 11, "server", ca, false), now.Add(time.Second))
+	time.Sleep(5 * time.Millisecond)
+	require.Equal(t, int64(11), servedSerial(t, client, url))
+	require.NoError(t, <-reloaded)
+
+	// A broken key keeps the last good certificate in use.
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, now.Add(2*time.Second), now.Add(2*time.Second)))
+	time.Sleep(5 * time.Millisecond)
+	require.Equal(t, int64(11), servedSerial(t, client, url))
+	require.Error(t, <-reloaded)
+}
+
+func TestRunTLSMinVersion(t *testing.T) {
+	ca := newTestCert(t, 1, "test ca", nil, true)
+	certFile, keyFile := writePair(t, t.TempDir(), newTestCert(t, 10, "server", ca, false), time.Now())
+	url := startTLS(t, TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"}, http.NotFoundHandler())
+
+	client := newClient(ca, nil)
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+	_, err := client.Get(url)
+	require.Error(t, err)
+
+	resp, err := newClient(ca, nil).Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+}
+
+func TestRunTLSClientCertificates(t *testing.T) {
+	ca := newTestCert(t, 1, "test ca", nil, true)
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, newTestCert(t, 10, "server", ca, false), time.Now())
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM(), 0o600))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := ClientIdentityFromRequest(r)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(id)
+	})
+	url := startTLS(t, TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}, handler)
+
+	_, err := newClient(ca, nil).Get(url)
+	require.Error(t, err, "a client certificate is required")
+
+	other := newTestCert(t, 2, "other ca", nil, true)
+	stranger := newTestCert(t, 30, "stranger", other, false).tlsCertificate(t)
+	_, err = newClient(ca, &stranger).Get(url)
+	require.Error(t, err, "a certificate from another CA is rejected")
+
+	client := newTestCert(t, 20, "terminal-monitor", ca, false)
+	clientCert := client.tlsCertificate(t)
+	resp, err := newClient(ca, &clientCert).Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var id ClientIdentity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&id))
+	require.Equal(t, "terminal-monitor", id.CommonName)
+	require.Equal(t, "CN=terminal-monitor", id.Subject)
+	require.Equal(t, "CN=test ca", id.Issuer)
+	require.Equal(t, "20", id.SerialNumber)
+	require.Len(t, id.Fingerprint, 64)
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	require.NoError(t, TLSConfig{CertFile: "a", KeyFile: "b"}.Validate())
+	require.NoError(t, TLSConfig{CertFile: "a", KeyFile: "b", ClientCAFile: "c", ClientAuth: ClientAuthOptional}.Validate())
+
+	err := TLSConfig{CertFile: "a", MinVersion: "1.4", ClientAuth: ClientAuthRequire}.Validate()
+	require.ErrorContains(t, err, "both a certificate and a key file")
+	require.ErrorContains(t, err, `unknown TLS version "1.4"`)
+	require.ErrorContains(t, err, "needs a client CA file")
+}